@@ -0,0 +1,89 @@
+// Command minttoken creates signed bearer tokens for testing the emulator's
+// Microsoft Entra ID authentication.
+//
+// On first use it generates an RSA signing key and writes the matching JSON
+// Web Key Set; point the emulator's ENTRA_JWKS_FILE at that file.
+//
+//	go run ./cmd/minttoken -roles "Search Index Data Reader"
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"ai-search-emulator/internal/infrastructure"
+)
+
+func main() {
+	keyPath := flag.String("key", "entra-key.pem", "RSA private key (PEM); generated if missing")
+	jwksPath := flag.String("jwks", "entra-jwks.json", "JWKS file written for the emulator's ENTRA_JWKS_FILE")
+	issuer := flag.String("issuer", infrastructure.DefaultTokenIssuer, "token issuer (iss)")
+	audience := flag.String("audience", infrastructure.DefaultTokenAudience, "token audience (aud)")
+	subject := flag.String("subject", "test-user", "principal object ID (sub and oid)")
+	roles := flag.String("roles", "", "comma-separated role names or role definition IDs")
+	ttl := flag.Duration("ttl", time.Hour, "token lifetime")
+	flag.Parse()
+
+	key, err := loadOrCreateKey(*keyPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	jwks, err := json.MarshalIndent(infrastructure.JWKS{Keys: []infrastructure.JWK{infrastructure.JWKFromKey(key)}}, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*jwksPath, jwks, 0o644); err != nil {
+		log.Fatal(err)
+	}
+
+	var roleList []string
+	for _, r := range strings.Split(*roles, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			roleList = append(roleList, r)
+		}
+	}
+	token, err := infrastructure.MintJWT(key, infrastructure.TokenRequest{
+		Issuer:   *issuer,
+		Audience: *audience,
+		Subject:  *subject,
+		Roles:    roleList,
+		TTL:      *ttl,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(token)
+}
+
+func loadOrCreateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+		if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data", path)
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiVersionKey is the gin context key holding the validated api-version.
+const apiVersionKey = "apiVersion"
+
+// supportedAPIVersions lists the data plane api-versions the emulator
+// accepts, in release order.
+var supportedAPIVersions = []string{
+	"2020-06-30", "2023-07-01-Preview", "2023-10-01-Preview", "2023-11-01",
+	"2024-03-01-Preview", "2024-05-01-Preview", "2024-07-01", "2024-09-01-preview",
+	"2024-11-01-preview", "2025-03-01-preview", "2025-05-01-preview",
+	"2025-08-01-preview", "2025-09-01",
+}
+
+// isSupportedAPIVersion compares case-insensitively, as Azure does.
+func isSupportedAPIVersion(version string) bool {
+	for _, v := range supportedAPIVersions {
+		if strings.EqualFold(v, version) {
+			return true
+		}
+	}
+	return false
+}
+
+// apiFeature describes when a feature was introduced. Versions are compared
+// by their date prefix.
+type apiFeature struct {
+	name  string
+	since string
+}
+
+var (
+	featureVectorSearch   = apiFeature{name: "Vector search", since: "2023-07-01"}
+	featureSemanticSearch = apiFeature{name: "Semantic search", since: "2023-07-01"}
+)
+
+// supports reports whether the feature exists in the given api-version.
+func (f apiFeature) supports(version string) bool {
+	version = strings.ToLower(version)
+	return len(version) >= len(f.since) && version[:len(f.since)] >= f.since
+}
+
+// APIVersionMiddleware rejects requests whose api-version query parameter is
+// missing or not a version the emulator supports, and records the version for
+// feature checks.
+func APIVersionMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		version := c.Query("api-version")
+		if version == "" {
+			abortErr(c, http.StatusBadRequest, "MissingApiVersionParameter",
+				"The api-version query parameter (?api-version=) is required for all requests.")
+			return
+		}
+		if !isSupportedAPIVersion(version) {
+			abortErr(c, http.StatusBadRequest, "InvalidApiVersionParameter",
+				fmt.Sprintf("The api-version '%s' is invalid. The supported versions are '%s'.", version, strings.Join(supportedAPIVersions, "', '")))
+			return
+		}
+		c.Set(apiVersionKey, version)
+		c.Next()
+	}
+}
+
+// requestAPIVersion returns the api-version validated by APIVersionMiddleware,
+// or "" when the middleware is not installed.
+func requestAPIVersion(c *gin.Context) string {
+	return c.GetString(apiVersionKey)
+}
+
+// checkFeature writes a 400 response and returns false when the request's
+// api-version predates the feature. Requests without a recorded version are
+// not restricted.
+func checkFeature(c *gin.Context, f apiFeature) bool {
+	version := requestAPIVersion(c)
+	if version == "" || f.supports(version) {
+		return true
+	}
+	err400InvalidParam(c, fmt.Sprintf("%s is not supported in api-version '%s'. Use a newer api-version.", f.name, version))
+	return false
+}
+
+// checkIndexFeatures rejects index definitions that use vector or semantic
+// configuration the request's api-version does not know about.
+func checkIndexFeatures(c *gin.Context, body []byte) bool {
+	var props map[string]json.RawMessage
+	if err := json.Unmarshal(body, &props); err != nil {
+		return true
+	}
+	if hasValue(props["vectorSearch"]) && !checkFeature(c, featureVectorSearch) {
+		return false
+	}
+	if (hasValue(props["semantic"]) || hasValue(props["semanticSearch"])) && !checkFeature(c, featureSemanticSearch) {
+		return false
+	}
+	return true
+}
+
+// checkSearchFeatures rejects search requests that use vector queries or
+// semantic ranking the request's api-version does not know about.
+func checkSearchFeatures(c *gin.Context, vectorQueries bool, queryType string) bool {
+	if vectorQueries && !checkFeature(c, featureVectorSearch) {
+		return false
+	}
+	if strings.EqualFold(queryType, "semantic") && !checkFeature(c, featureSemanticSearch) {
+		return false
+	}
+	return true
+}
+
+// hasValue reports whether a raw JSON property is present and not null.
+func hasValue(raw json.RawMessage) bool {
+	return len(raw) > 0 && string(raw) != "null"
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAPIFeature_Supports(t *testing.T) {
+	tests := []struct {
+		feature apiFeature
+		version string
+		want    bool
+	}{
+		{featureVectorSearch, "2020-06-30", false},
+		{featureVectorSearch, "2023-07-01-Preview", true},
+		{featureVectorSearch, "2023-11-01", true},
+		{featureSemanticSearch, "2020-06-30", false},
+		{featureSemanticSearch, "2024-07-01", true},
+	}
+	for _, tt := range tests {
+		if got := tt.feature.supports(tt.version); got != tt.want {
+			t.Errorf("%s in %s = %v, want %v", tt.feature.name, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestAPIVersion_MissingReturns400(t *testing.T) {
+	r := setupRouter(t)
+	rec := doRequest(t, r, http.MethodGet, "/indexes?api-version=", "")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+	if code := errCode(t, rec); code != "MissingApiVersionParameter" {
+		t.Errorf("error.code = %q, want MissingApiVersionParameter", code)
+	}
+}
+
+func TestAPIVersion_UnknownReturns400(t *testing.T) {
+	r := setupRouter(t)
+	rec := doRequest(t, r, http.MethodGet, "/indexes?api-version=2099-01-01", "")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+	if code := errCode(t, rec); code != "InvalidApiVersionParameter" {
+		t.Errorf("error.code = %q, want InvalidApiVersionParameter", code)
+	}
+}
+
+func TestAPIVersion_CaseInsensitive(t *testing.T) {
+	r := setupRouter(t)
+	for _, v := range []string{"2025-09-01", "2024-05-01-preview", "2024-11-01-Preview"} {
+		if rec := doRequest(t, r, http.MethodGet, "/indexes?api-version="+v, ""); rec.Code != http.StatusOK {
+			t.Errorf("%s: status = %d, want 200", v, rec.Code)
+		}
+	}
+}
+
+func TestAPIVersion_VectorIndexRequiresNewerVersion(t *testing.T) {
+	r := setupRouter(t)
+	schema := `{"name":"movies","fields":[{"name":"id","type":"Edm.String","key":true}],"vectorSearch":{"profiles":[]}}`
+	rec := doRequest(t, r, http.MethodPost, "/indexes?api-version=2020-06-30", schema)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+	if code := errCode(t, rec); code != "InvalidRequestParameter" {
+		t.Errorf("error.code = %q, want InvalidRequestParameter", code)
+	}
+	if rec := doRequest(t, r, http.MethodPost, "/indexes?api-version=2023-11-01", schema); rec.Code != http.StatusCreated {
+		t.Errorf("2023-11-01: status = %d, want 201, body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAPIVersion_SearchFeatures(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
+
+	if rec := doRequest(t, r, http.MethodPost, "/indexes/movies/docs/search?api-version=2020-06-30",
+		`{"search":"*","vectorQueries":[{"kind":"vector","vector":[1],"fields":"v"}]}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("vectorQueries: status = %d, want 400", rec.Code)
+	}
+	if rec := doRequest(t, r, http.MethodGet, "/indexes/movies/docs?search=*&queryType=semantic&api-version=2020-06-30", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("GET queryType=semantic: status = %d, want 400", rec.Code)
+	}
+	if rec := doRequest(t, r, http.MethodPost, "/indexes/movies/docs/search?api-version=2024-07-01",
+		`{"search":"*","queryType":"semantic"}`); rec.Code != http.StatusOK {
+		t.Errorf("semantic in 2024-07-01: status = %d, want 200", rec.Code)
+	}
+}
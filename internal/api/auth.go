@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"ai-search-emulator/internal/application"
+	"ai-search-emulator/internal/domain"
+)
+
+// permission is the kind of access an operation needs.
+type permission int
+
+const (
+	permManage    permission = iota // index definitions, statistics and keys
+	permDataRead                    // querying and looking up documents
+	permDataWrite                   // indexing documents
+)
+
+// dataRoutes classifies document operations by method and route pattern.
+// Every other route needs permManage.
+var dataRoutes = map[string]permission{
+	"GET /indexes/:index/docs":         permDataRead,
+	"GET /indexes/:index/docs/:key":    permDataRead,
+	"GET /indexes/:index/docs/$count":  permDataRead,
+	"POST /indexes/:index/docs/search": permDataRead,
+	"POST /indexes/:index/docs/index":  permDataWrite,
+	"POST /indexes/:index/docs":        permDataWrite,
+}
+
+func routePermission(c *gin.Context) permission {
+	if p, ok := dataRoutes[c.Request.Method+" "+c.FullPath()]; ok {
+		return p
+	}
+	return permManage
+}
+
+// rolePermissions lists what each built-in search role grants, mirroring
+// Azure RBAC: the service contributor manages indexes but cannot read or
+// write documents.
+var rolePermissions = map[string][]permission{
+	domain.RoleSearchServiceContributor:   {permManage},
+	domain.RoleSearchIndexDataContributor: {permDataRead, permDataWrite},
+	domain.RoleSearchIndexDataReader:      {permDataRead},
+}
+
+func principalAllows(p *domain.Principal, need permission) bool {
+	for _, role := range p.Roles {
+		for _, granted := range rolePermissions[role] {
+			if granted == need {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ApiKeyAuthMiddleware authenticates requests by api-key only.
+func ApiKeyAuthMiddleware(keys *application.KeyService) gin.HandlerFunc {
+	return AuthMiddleware(keys, nil)
+}
+
+// AuthMiddleware authenticates the api-key header against the admin and
+// query keys held by keys, or, when tokens is non-nil and no api-key is
+// sent, an "Authorization: Bearer" token. Query keys and bearer principals
+// are authorized per route; operations they may not perform answer 403.
+func AuthMiddleware(keys *application.KeyService, tokens domain.TokenVerifier) gin.HandlerFunc {
+	if !keys.HasAdminKey() && tokens == nil {
+		if len(keys.ListQueryKeys(context.Background())) == 0 {
+			log.Println("[WARN] API_KEY is not set — all requests will be rejected")
+		} else {
+			log.Println("[WARN] API_KEY is not set — only document queries with a query key will be accepted")
+		}
+	}
+	return func(c *gin.Context) {
+		apiKey := c.GetHeader("api-key")
+		if apiKey == "" {
+			apiKey = c.GetHeader("Api-Key")
+		}
+		if apiKey == "" {
+			if token, ok := bearerToken(c.GetHeader("Authorization")); ok {
+				authorizeBearer(c, tokens, token)
+				return
+			}
+		}
+		switch keys.Authenticate(apiKey) {
+		case domain.KeyAdmin:
+			c.Next()
+		case domain.KeyQuery:
+			if routePermission(c) != permDataRead {
+				err403(c, "A query key can only be used to query documents; this operation requires an admin key")
+				return
+			}
+			c.Next()
+		default:
+			err401(c, "API key required or invalid")
+		}
+	}
+}
+
+func authorizeBearer(c *gin.Context, tokens domain.TokenVerifier, token string) {
+	if tokens == nil {
+		err401(c, "Bearer token authentication is not enabled on this service")
+		return
+	}
+	p, err := tokens.Verify(token)
+	if err != nil {
+		if !errors.Is(err, domain.ErrInvalidToken) {
+			log.Printf("[ERROR] token verification: %v", err)
+		}
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		err401(c, "The bearer token is invalid: "+err.Error())
+		return
+	}
+	if !principalAllows(p, routePermission(c)) {
+		err403(c, "The principal '"+p.Subject+"' does not have a role assignment that permits this operation")
+		return
+	}
+	c.Next()
+}
+
+// bearerToken extracts the token from an Authorization header.
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ai-search-emulator/internal/domain"
+)
+
+// fakeVerifier accepts the tokens in its map.
+type fakeVerifier map[string]*domain.Principal
+
+func (f fakeVerifier) Verify(token string) (*domain.Principal, error) {
+	if p, ok := f[token]; ok {
+		return p, nil
+	}
+	return nil, fmt.Errorf("%w: unknown test token", domain.ErrInvalidToken)
+}
+
+var testTokens = fakeVerifier{
+	"reader":      {Subject: "reader", Roles: []string{domain.RoleSearchIndexDataReader}},
+	"contributor": {Subject: "contributor", Roles: []string{domain.RoleSearchIndexDataContributor}},
+	"service":     {Subject: "service", Roles: []string{domain.RoleSearchServiceContributor}},
+	"none":        {Subject: "none"},
+}
+
+// doBearerRequest sends a request with an Authorization: Bearer header.
+func doBearerRequest(t *testing.T, r http.Handler, token, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	var req *http.Request
+	if body != "" {
+		req = httptest.NewRequest(method, withAPIVersion(path), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req = httptest.NewRequest(method, withAPIVersion(path), nil)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestBearerAuth_RolesPerRoute(t *testing.T) {
+	r := setupRouterWithTokens(t, testTokens)
+	upload := `{"value":[{"@search.action":"upload","id":"1","title":"a"}]}`
+
+	tests := []struct {
+		token, method, path, body string
+		want                      int
+	}{
+		{"service", http.MethodPost, "/indexes", apiTestSchema, http.StatusCreated},
+		{"service", http.MethodGet, "/indexes/movies", "", http.StatusOK},
+		{"service", http.MethodPost, "/indexes/movies/docs/index", upload, http.StatusForbidden},
+		{"service", http.MethodGet, "/indexes/movies/docs?search=*", "", http.StatusForbidden},
+		{"contributor", http.MethodPost, "/indexes/movies/docs/index", upload, http.StatusOK},
+		{"contributor", http.MethodGet, "/indexes/movies/docs/1", "", http.StatusOK},
+		{"contributor", http.MethodDelete, "/indexes/movies", "", http.StatusForbidden},
+		{"reader", http.MethodPost, "/indexes/movies/docs/search", `{"search":"*"}`, http.StatusOK},
+		{"reader", http.MethodGet, "/indexes/movies/docs/$count", "", http.StatusOK},
+		{"reader", http.MethodPost, "/indexes/movies/docs/index", upload, http.StatusForbidden},
+		{"reader", http.MethodGet, "/indexes", "", http.StatusForbidden},
+		{"none", http.MethodGet, "/indexes/movies/docs?search=*", "", http.StatusForbidden},
+		{"forged", http.MethodGet, "/indexes/movies/docs?search=*", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		rec := doBearerRequest(t, r, tt.token, tt.method, tt.path, tt.body)
+		if rec.Code != tt.want {
+			t.Errorf("%s %s %s: status = %d, want %d, body=%s", tt.token, tt.method, tt.path, rec.Code, tt.want, rec.Body.String())
+		}
+	}
+}
+
+func TestBearerAuth_InvalidTokenSetsWWWAuthenticate(t *testing.T) {
+	r := setupRouterWithTokens(t, testTokens)
+	rec := doBearerRequest(t, r, "forged", http.MethodGet, "/indexes", "")
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", rec.Code)
+	}
+	if !strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), "Bearer") {
+		t.Errorf("WWW-Authenticate = %q", rec.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestBearerAuth_DisabledWithoutVerifier(t *testing.T) {
+	r := setupRouter(t)
+	if rec := doBearerRequest(t, r, "reader", http.MethodGet, "/indexes", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", rec.Code)
+	}
+}
+
+func TestBearerAuth_ApiKeyTakesPrecedence(t *testing.T) {
+	r := setupRouterWithTokens(t, testTokens)
+	req := httptest.NewRequest(http.MethodGet, withAPIVersion("/indexes"), nil)
+	req.Header.Set("api-key", apiTestKey)
+	req.Header.Set("Authorization", "Bearer reader")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200 with an admin key", rec.Code)
+	}
+}
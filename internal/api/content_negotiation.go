@@ -0,0 +1,207 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OData metadata levels requested with Accept: application/json;odata.metadata=...
+const (
+	metadataNone    = "none"
+	metadataMinimal = "minimal"
+	metadataFull    = "full"
+)
+
+// metadataLevelKey is the gin context key holding the negotiated level.
+const metadataLevelKey = "odataMetadata"
+
+// ContentNegotiationMiddleware checks the Accept header against the media
+// types the route can produce, answering 406 when none is acceptable, and
+// shapes successful JSON responses by the requested odata.metadata level.
+// minimal is Azure's default and leaves responses untouched.
+func ContentNegotiationMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		level, err := negotiateMetadata(c.GetHeader("Accept"), producedMediaTypes(c.Request.URL.Path))
+		if err != nil {
+			abortErr(c, http.StatusNotAcceptable, "NotAcceptable", err.Error())
+			return
+		}
+		c.Set(metadataLevelKey, level)
+		if level == metadataMinimal {
+			c.Next()
+			return
+		}
+
+		w := &bufferedWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		body := w.buf.Bytes()
+		status := c.Writer.Status()
+		if status >= 200 && status < 300 && strings.HasPrefix(c.Writer.Header().Get("Content-Type"), "application/json") {
+			if shaped, err := shapeMetadata(c, level, body); err == nil {
+				body = shaped
+				c.Header("Content-Type", "application/json; odata.metadata="+level)
+			}
+		}
+		_, _ = c.Writer.Write(body)
+	}
+}
+
+// producedMediaTypes lists the media types a path can be served as.
+func producedMediaTypes(path string) []string {
+	switch {
+	case strings.HasSuffix(path, "/$metadata"):
+		return []string{"application/xml"}
+	case strings.HasSuffix(path, "/docs/$count"):
+		return []string{"text/plain", "application/json"}
+	default:
+		return []string{"application/json"}
+	}
+}
+
+// negotiateMetadata picks the odata.metadata level from an Accept header.
+// An empty header accepts anything. Ranges with q=0 are ignored.
+func negotiateMetadata(accept string, produced []string) (string, error) {
+	if strings.TrimSpace(accept) == "" {
+		return metadataMinimal, nil
+	}
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || isZeroQuality(params["q"]) {
+			continue
+		}
+		if !acceptsAny(mediaType, produced) {
+			continue
+		}
+		switch level := strings.ToLower(params["odata.metadata"]); level {
+		case "":
+			return metadataMinimal, nil
+		case metadataNone, metadataMinimal, metadataFull:
+			return level, nil
+		default:
+			return "", fmt.Errorf("the odata.metadata value '%s' is not supported; use none, minimal or full", params["odata.metadata"])
+		}
+	}
+	return "", fmt.Errorf("none of the media types in the Accept header '%s' are supported; this resource is available as %s", accept, strings.Join(produced, ", "))
+}
+
+// isZeroQuality reports whether a q parameter, such as "0" or "0.000",
+// marks a media range as not acceptable.
+func isZeroQuality(q string) bool {
+	v, err := strconv.ParseFloat(q, 64)
+	return err == nil && v == 0
+}
+
+// acceptsAny reports whether a media range from Accept matches a produced type.
+func acceptsAny(mediaRange string, produced []string) bool {
+	for _, p := range produced {
+		if mediaRange == "*/*" || mediaRange == p || strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(p, strings.TrimSuffix(mediaRange, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedWriter holds the response body so it can be reshaped before it is
+// sent. Status and headers still go to the wrapped writer.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	buf bytes.Buffer
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	return w.buf.Write(b)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.buf.WriteString(s)
+}
+
+// shapeMetadata rewrites a JSON response body for the none or full level.
+func shapeMetadata(c *gin.Context, level string, body []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	switch level {
+	case metadataNone:
+		v = stripODataAnnotations(v)
+	case metadataFull:
+		addFullMetadata(c, v)
+	}
+	return json.Marshal(v)
+}
+
+// stripODataAnnotations removes @odata.* control information, keeping the
+// nextLink and count that clients need to page results.
+func stripODataAnnotations(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			if strings.HasPrefix(k, "@odata.") && k != "@odata.nextLink" && k != "@odata.count" {
+				delete(val, k)
+				continue
+			}
+			val[k] = stripODataAnnotations(child)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = stripODataAnnotations(item)
+		}
+	}
+	return v
+}
+
+// addFullMetadata annotates entities with @odata.type and, for indexes,
+// @odata.id and @odata.editLink, according to the matched route.
+func addFullMetadata(c *gin.Context, v interface{}) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return
+	}
+	ns := metadataNamespace(requestAPIVersion(c))
+	baseURL := requestBaseURL(c.Request)
+	annotateIndex := func(m map[string]interface{}) {
+		m["@odata.type"] = "#" + ns + ".Index"
+		if name, ok := m["name"].(string); ok {
+			m["@odata.id"] = baseURL + "/indexes('" + name + "')"
+			m["@odata.editLink"] = "indexes('" + name + "')"
+		}
+	}
+	eachValue := func(fn func(m map[string]interface{})) {
+		items, _ := obj["value"].([]interface{})
+		for _, item := range items {
+			if m, ok := item.(map[string]interface{}); ok {
+				fn(m)
+			}
+		}
+	}
+
+	switch c.FullPath() {
+	case "/indexes":
+		if c.Request.Method == http.MethodGet {
+			eachValue(annotateIndex)
+		} else {
+			annotateIndex(obj)
+		}
+	case "/indexes/:index":
+		annotateIndex(obj)
+	case "/indexes/:index/stats":
+		obj["@odata.type"] = "#" + ns + ".IndexStatistics"
+	case "/indexes/:index/docs/:key":
+		obj["@odata.type"] = "#" + ns + ".Document"
+	case "/indexes/:index/docs", "/indexes/:index/docs/search":
+		eachValue(func(m map[string]interface{}) { m["@odata.type"] = "#" + ns + ".Document" })
+	}
+}
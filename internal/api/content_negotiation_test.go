@@ -0,0 +1,153 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ai-search-emulator/internal/application"
+)
+
+func TestNegotiateMetadata(t *testing.T) {
+	jsonOnly := []string{"application/json"}
+	tests := []struct {
+		accept   string
+		produced []string
+		want     string
+		wantErr  bool
+	}{
+		{"", jsonOnly, metadataMinimal, false},
+		{"*/*", jsonOnly, metadataMinimal, false},
+		{"application/json", jsonOnly, metadataMinimal, false},
+		{"application/json;odata.metadata=none", jsonOnly, metadataNone, false},
+		{"application/json; odata.metadata=FULL; odata.streaming=true", jsonOnly, metadataFull, false},
+		{"application/*;odata.metadata=none", jsonOnly, metadataNone, false},
+		{"text/html, application/json;q=0.5", jsonOnly, metadataMinimal, false},
+		{"text/html", jsonOnly, "", true},
+		{"application/json;q=0", jsonOnly, "", true},
+		{"application/json;q=0.0, text/html", jsonOnly, "", true},
+		{"application/json;q=0.000", jsonOnly, "", true},
+		{"application/json;q=0.001", jsonOnly, metadataMinimal, false},
+		{"application/json;odata.metadata=verbose", jsonOnly, "", true},
+		{"application/xml", []string{"application/xml"}, metadataMinimal, false},
+		{"text/plain", []string{"text/plain", "application/json"}, metadataMinimal, false},
+	}
+	for _, tt := range tests {
+		got, err := negotiateMetadata(tt.accept, tt.produced)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("negotiateMetadata(%q) = %q, %v; want %q, error %v", tt.accept, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// doAcceptRequest sends a GET with the given Accept header.
+func doAcceptRequest(t *testing.T, r http.Handler, path, accept string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, withAPIVersion(path), nil)
+	req.Header.Set("api-key", apiTestKey)
+	req.Header.Set("Accept", accept)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestContentNegotiation_UnsupportedMediaTypeReturns406(t *testing.T) {
+	r := setupRouter(t)
+	for _, accept := range []string{"text/html", "application/json;odata.metadata=verbose"} {
+		rec := doAcceptRequest(t, r, "/indexes", accept)
+		if rec.Code != http.StatusNotAcceptable {
+			t.Errorf("%s: status = %d, want 406", accept, rec.Code)
+			continue
+		}
+		if code := errCode(t, rec); code != "NotAcceptable" {
+			t.Errorf("%s: error.code = %q, want NotAcceptable", accept, code)
+		}
+	}
+	if rec := doAcceptRequest(t, r, "/$metadata", "application/json"); rec.Code != http.StatusNotAcceptable {
+		t.Errorf("$metadata as JSON: status = %d, want 406", rec.Code)
+	}
+	if rec := doAcceptRequest(t, r, "/$metadata", "application/xml"); rec.Code != http.StatusOK {
+		t.Errorf("$metadata as XML: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestContentNegotiation_MetadataNone(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
+	actions := make([]string, 0, application.DefaultTop+1)
+	for i := 0; i <= application.DefaultTop; i++ {
+		actions = append(actions, fmt.Sprintf(`{"@search.action":"upload","id":"%d","title":"t%d"}`, i+1, i))
+	}
+	doRequest(t, r, http.MethodPost, "/indexes/movies/docs/index", `{"value":[`+strings.Join(actions, ",")+`]}`)
+	accept := "application/json;odata.metadata=none"
+
+	for _, path := range []string{
+		"/indexes",
+		"/indexes/movies",
+		"/indexes/movies/docs/1",
+		"/indexes/movies/docs?search=*",
+	} {
+		rec := doAcceptRequest(t, r, path, accept)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body=%s", path, rec.Code, rec.Body.String())
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.Contains(ct, "odata.metadata=none") {
+			t.Errorf("%s: Content-Type = %q", path, ct)
+		}
+		body := rec.Body.String()
+		if strings.Contains(body, "@odata.context") || strings.Contains(body, "@odata.etag") {
+			t.Errorf("%s: odata annotations should be dropped, got %s", path, body)
+		}
+		if path == "/indexes/movies/docs?search=*" && !strings.Contains(body, "@odata.nextLink") {
+			t.Errorf("%s: @odata.nextLink must be kept for paging, got %s", path, body)
+		}
+		if strings.Contains(path, "search=") && !strings.Contains(body, "@search.score") {
+			t.Errorf("%s: @search annotations must be kept, got %s", path, body)
+		}
+	}
+}
+
+func TestContentNegotiation_MetadataFull(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
+	doRequest(t, r, http.MethodPost, "/indexes/movies/docs/index",
+		`{"value":[{"@search.action":"upload","id":"1","title":"a"}]}`)
+	accept := "application/json;odata.metadata=full"
+
+	rec := doAcceptRequest(t, r, "/indexes/movies", accept)
+	var idx map[string]interface{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &idx)
+	if idx["@odata.type"] != "#Microsoft.Azure.Search.V2024_07_01.Index" {
+		t.Errorf("@odata.type = %v", idx["@odata.type"])
+	}
+	if idx["@odata.editLink"] != "indexes('movies')" {
+		t.Errorf("@odata.editLink = %v", idx["@odata.editLink"])
+	}
+
+	rec = doAcceptRequest(t, r, "/indexes/movies/docs?search=*", accept)
+	var result struct {
+		Value []map[string]interface{} `json:"value"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &result)
+	if len(result.Value) != 1 || result.Value[0]["@odata.type"] != "#Microsoft.Azure.Search.V2024_07_01.Document" {
+		t.Errorf("search results should carry @odata.type, got %s", rec.Body.String())
+	}
+
+	rec = doAcceptRequest(t, r, "/indexes/movies/stats", accept)
+	if !strings.Contains(rec.Body.String(), `"@odata.type":"#Microsoft.Azure.Search.V2024_07_01.IndexStatistics"`) {
+		t.Errorf("stats should carry @odata.type, got %s", rec.Body.String())
+	}
+}
+
+func TestContentNegotiation_MinimalLeavesResponseUntouched(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
+	plain := doRequest(t, r, http.MethodGet, "/indexes/movies", "")
+	minimal := doAcceptRequest(t, r, "/indexes/movies", "application/json;odata.metadata=minimal")
+	if plain.Body.String() != minimal.Body.String() {
+		t.Errorf("minimal response differs from default:\n%s\n%s", plain.Body.String(), minimal.Body.String())
+	}
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"ai-search-emulator/internal/application"
+	"ai-search-emulator/internal/domain"
+)
+
+func registerDataSourceRoutes(r *gin.Engine, svc *application.DataSourceService) {
+	// データソース作成API
+	r.POST("/datasources", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			err400(c, "Failed to read request body")
+			return
+		}
+		ds, err := svc.CreateDataSource(c.Request.Context(), body)
+		if err != nil {
+			handleDataSourceError(c, err)
+			return
+		}
+		respondDataSource(c, http.StatusCreated, svc, ds.Name)
+	})
+	// データソース一覧API
+	r.GET("/datasources", func(c *gin.Context) {
+		list, err := svc.ListDataSources(c.Request.Context())
+		if err != nil {
+			err500(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"@odata.context": requestBaseURL(c.Request) + "/$metadata#datasources",
+			"value":          list,
+		})
+	})
+	// データソース取得API
+	r.GET("/datasources/:name", func(c *gin.Context) {
+		respondDataSource(c, http.StatusOK, svc, c.Param("name"))
+	})
+	// データソース更新API（create-or-update）
+	r.PUT("/datasources/:name", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			err400(c, "Failed to read request body")
+			return
+		}
+		ds, created, err := svc.CreateOrUpdateDataSource(c.Request.Context(), c.Param("name"), body, accessCondition(c))
+		if err != nil {
+			handleDataSourceError(c, err)
+			return
+		}
+		status := http.StatusOK
+		if created {
+			status = http.StatusCreated
+		}
+		respondDataSource(c, status, svc, ds.Name)
+	})
+	// データソース削除API
+	r.DELETE("/datasources/:name", func(c *gin.Context) {
+		if err := svc.DeleteDataSource(c.Request.Context(), c.Param("name"), accessCondition(c)); err != nil {
+			handleDataSourceError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
+
+// respondDataSource writes the stored data source, without credentials.
+func respondDataSource(c *gin.Context, status int, svc *application.DataSourceService, name string) {
+	ds, err := svc.GetDataSource(c.Request.Context(), name)
+	if err != nil {
+		handleDataSourceError(c, err)
+		return
+	}
+	if etag, ok := ds["@odata.etag"].(string); ok {
+		c.Header("ETag", etag)
+	}
+	c.JSON(status, ds)
+}
+
+func handleDataSourceError(c *gin.Context, err error) {
+	if errors.Is(err, domain.ErrDataSourceNotFound) {
+		err404(c, "Data source not found")
+	} else if errors.Is(err, domain.ErrDataSourceAlreadyExists) {
+		err409(c, "Data source already exists")
+	} else if errors.Is(err, domain.ErrPreconditionFailed) {
+		err412(c, "The data source was modified or does not match the If-Match/If-None-Match condition")
+	} else if errors.Is(err, domain.ErrInvalidName) {
+		err400InvalidName(c, err.Error())
+	} else if errors.Is(err, domain.ErrInvalidDataSource) {
+		err400InvalidParam(c, err.Error())
+	} else {
+		err500(c, err)
+	}
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const apiTestDataSource = `{
+	"name": "files",
+	"type": "azureblob",
+	"credentials": {"connectionString": "UseDevelopmentStorage=true"},
+	"container": {"name": "docs"}
+}`
+
+func TestDataSources_CRUD(t *testing.T) {
+	r := setupRouter(t)
+
+	rec := doRequest(t, r, http.MethodPost, "/datasources", apiTestDataSource)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("ETag") == "" {
+		t.Errorf("create response should carry an ETag header")
+	}
+
+	rec = doRequest(t, r, http.MethodGet, "/datasources/files", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var got map[string]interface{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &got)
+	creds, _ := got["credentials"].(map[string]interface{})
+	if cs, ok := creds["connectionString"]; !ok || cs != nil {
+		t.Errorf("connectionString = %v, want null", creds["connectionString"])
+	}
+	if got["type"] != "azureblob" {
+		t.Errorf("type = %v, want azureblob", got["type"])
+	}
+
+	rec = doRequest(t, r, http.MethodGet, "/datasources", "")
+	var list struct {
+		Value []map[string]interface{} `json:"value"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &list)
+	if rec.Code != http.StatusOK || len(list.Value) != 1 {
+		t.Errorf("list status = %d, value = %v", rec.Code, list.Value)
+	}
+
+	update := `{"name":"files","type":"azureblob","credentials":{"connectionString":"<unchanged>"},"container":{"name":"docs","query":"2024"}}`
+	if rec = doRequest(t, r, http.MethodPut, "/datasources/files", update); rec.Code != http.StatusOK {
+		t.Errorf("update status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	if rec = doRequest(t, r, http.MethodPut, "/datasources/other", strings.Replace(apiTestDataSource, `"files"`, `"other"`, 1)); rec.Code != http.StatusCreated {
+		t.Errorf("put-create status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+
+	if rec = doRequest(t, r, http.MethodDelete, "/datasources/files", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("delete status = %d", rec.Code)
+	}
+	if rec = doRequest(t, r, http.MethodGet, "/datasources/files", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("get after delete status = %d, want 404", rec.Code)
+	}
+}
+
+func TestDataSources_Errors(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/datasources", apiTestDataSource)
+
+	rec := doRequest(t, r, http.MethodPost, "/datasources", apiTestDataSource)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("duplicate status = %d, want 409", rec.Code)
+	}
+
+	rec = doRequest(t, r, http.MethodPost, "/datasources", strings.Replace(apiTestDataSource, `"files"`, `"Bad Name"`, 1))
+	if rec.Code != http.StatusBadRequest || errCode(t, rec) != "InvalidName" {
+		t.Errorf("bad name: status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+
+	rec = doRequest(t, r, http.MethodPost, "/datasources", `{"name":"xy","type":"azureblob","credentials":{"connectionString":"Foo=bar"},"container":{"name":"docs"}}`)
+	if rec.Code != http.StatusBadRequest || errCode(t, rec) != "InvalidRequestParameter" {
+		t.Errorf("bad credentials: status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, withAPIVersion("/datasources/files"), nil)
+	req.Header.Set("api-key", apiTestKey)
+	req.Header.Set("If-Match", `"stale"`)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("stale If-Match status = %d, want 412", rec.Code)
+	}
+
+	if rec = doRequest(t, r, http.MethodDelete, "/datasources/missing", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("delete missing status = %d, want 404", rec.Code)
+	}
+}
+
+func TestDataSources_ODataPath(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/datasources", apiTestDataSource)
+	h := ODataPathRewriter(r)
+
+	rec := doRequest(t, h, http.MethodGet, "/datasources('files')", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"name":"files"`) {
+		t.Errorf("body = %s", rec.Body.String())
+	}
+}
@@ -34,10 +34,30 @@ func err400Missing(c *gin.Context, message string) {
 	jsonErr(c, http.StatusBadRequest, "MissingRequiredProperty", message)
 }
 
+func err400CannotChange(c *gin.Context, message string) {
+	jsonErr(c, http.StatusBadRequest, "CannotChangeExistingField", message)
+}
+
+func err400NotAllowed(c *gin.Context, message string) {
+	jsonErr(c, http.StatusBadRequest, "OperationNotAllowed", message)
+}
+
+func err400InvalidName(c *gin.Context, message string) {
+	jsonErr(c, http.StatusBadRequest, "InvalidName", message)
+}
+
+func err400InvalidParam(c *gin.Context, message string) {
+	jsonErr(c, http.StatusBadRequest, "InvalidRequestParameter", message)
+}
+
 func err401(c *gin.Context, message string) {
 	abortErr(c, http.StatusUnauthorized, "AuthenticationFailed", message)
 }
 
+func err403(c *gin.Context, message string) {
+	abortErr(c, http.StatusForbidden, "Forbidden", message)
+}
+
 func err404(c *gin.Context, message string) {
 	jsonErr(c, http.StatusNotFound, "ResourceNotFound", message)
 }
@@ -46,6 +66,18 @@ func err409(c *gin.Context, message string) {
 	jsonErr(c, http.StatusConflict, "ResourceAlreadyExists", message)
 }
 
+func err413(c *gin.Context, message string) {
+	jsonErr(c, http.StatusRequestEntityTooLarge, "RequestEntityTooLarge", message)
+}
+
+func err412(c *gin.Context, message string) {
+	jsonErr(c, http.StatusPreconditionFailed, "PreconditionFailed", message)
+}
+
+func err503(c *gin.Context, message string) {
+	jsonErr(c, http.StatusServiceUnavailable, "ServiceUnavailable", message)
+}
+
 // err500 logs the internal error server-side and returns a generic message to
 // the client so that internal details (DB errors, stack traces, etc.) are
 // never exposed in the response body.
@@ -8,16 +8,18 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
-	"os"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+// indexUnavailableMessage is returned while an index is offline after an
+// update applied with allowIndexDowntime=true.
+const indexUnavailableMessage = "The index is temporarily unavailable while an index update is applied. Retry the request shortly."
+
 func RegisterHealthCheck(r *gin.Engine) {
 	r.GET("/healthz", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
@@ -25,6 +27,13 @@ func RegisterHealthCheck(r *gin.Engine) {
 }
 
 func RegisterRoutes(r *gin.Engine, app *application.AppServices) {
+	// Route on the escaped path so document keys containing '/' (sent as %2F)
+	// stay a single :key segment; gin unescapes the parameter values.
+	r.UseRawPath = true
+	registerKeyRoutes(r, app.KeyService)
+	registerDataSourceRoutes(r, app.DataSourceService)
+	registerSkillsetRoutes(r, app.SkillsetService)
+	registerIndexerRoutes(r, app.IndexerService)
 	// インデックス作成API
 	r.POST("/indexes", func(c *gin.Context) {
 		body, err := io.ReadAll(c.Request.Body)
@@ -42,16 +51,24 @@ func RegisterRoutes(r *gin.Engine, app *application.AppServices) {
 			err400(c, "Invalid request body")
 			return
 		}
-		err = app.IndexService.CreateIndex(c.Request.Context(), req.Name, io.NopCloser(bytes.NewReader(body)))
+		if !checkIndexFeatures(c, body) {
+			return
+		}
+		idx, err := app.IndexService.CreateIndex(c.Request.Context(), req.Name, io.NopCloser(bytes.NewReader(body)))
 		if err != nil {
 			if errors.Is(err, domain.ErrIndexAlreadyExists) {
 				err409(c, "Index already exists")
+			} else if errors.Is(err, domain.ErrInvalidName) {
+				err400InvalidName(c, err.Error())
+			} else if errors.Is(err, domain.ErrInvalidIndexDefinition) {
+				err400InvalidParam(c, err.Error())
 			} else {
 				err500(c, err)
 			}
 			return
 		}
-		c.Data(http.StatusCreated, "application/json", body)
+		c.Header("ETag", idx.ETag)
+		c.Data(http.StatusCreated, "application/json", withODataETag(body, idx.ETag))
 	})
 	// ドキュメント追加API（1件追加、Azure仕様に準拠）
 	r.POST("/indexes/:index/docs", func(c *gin.Context) {
@@ -61,12 +78,16 @@ func RegisterRoutes(r *gin.Engine, app *application.AppServices) {
 			err400(c, "Invalid document body")
 			return
 		}
-		err := app.DocumentService.AddOrUpdateSingleDoc(c.Request.Context(), indexName, doc)
+		err := app.DocumentService.AddOrUpdateSingleDoc(c.Request.Context(), indexName, doc, allowUnsafeKeys(c))
 		if err != nil {
 			if errors.Is(err, domain.ErrIndexNotFound) {
 				err404(c, "Index not found")
+			} else if errors.Is(err, domain.ErrIndexUnavailable) {
+				err503(c, indexUnavailableMessage)
 			} else if errors.Is(err, domain.ErrMissingKeyField) {
 				err400Missing(c, "Document missing key field")
+			} else if errors.Is(err, domain.ErrInvalidDocumentKey) {
+				err400(c, err.Error())
 			} else {
 				err500(c, err)
 			}
@@ -87,6 +108,33 @@ func RegisterRoutes(r *gin.Engine, app *application.AppServices) {
 			"value":          indexes,
 		})
 	})
+	// サービスのメタデータ取得API (CSDL)
+	r.GET("/$metadata", func(c *gin.Context) {
+		doc, err := serviceMetadata(requestAPIVersion(c))
+		if err != nil {
+			err500(c, err)
+			return
+		}
+		c.Data(http.StatusOK, "application/xml", doc)
+	})
+	// インデックスのドキュメントメタデータ取得API (CSDL)
+	r.GET("/indexes/:index/$metadata", func(c *gin.Context) {
+		model, err := app.IndexService.GetDocumentModel(c.Request.Context(), c.Param("index"))
+		if err != nil {
+			if errors.Is(err, domain.ErrIndexNotFound) {
+				err404(c, "Index not found")
+			} else {
+				err500(c, err)
+			}
+			return
+		}
+		doc, err := indexMetadata(requestAPIVersion(c), model)
+		if err != nil {
+			err500(c, err)
+			return
+		}
+		c.Data(http.StatusOK, "application/xml", doc)
+	})
 	// インデックス取得API
 	r.GET("/indexes/:index", func(c *gin.Context) {
 		indexName := c.Param("index")
@@ -99,6 +147,9 @@ func RegisterRoutes(r *gin.Engine, app *application.AppServices) {
 			}
 			return
 		}
+		if etag, ok := idx["@odata.etag"].(string); ok {
+			c.Header("ETag", etag)
+		}
 		c.JSON(http.StatusOK, idx)
 	})
 	// インデックス更新API（create-or-update）
@@ -119,24 +170,43 @@ func RegisterRoutes(r *gin.Engine, app *application.AppServices) {
 			err400(c, "Invalid request body")
 			return
 		}
-		created, err := app.IndexService.CreateOrUpdateIndex(c.Request.Context(), indexName, io.NopCloser(bytes.NewReader(body)))
+		if !checkIndexFeatures(c, body) {
+			return
+		}
+		allowDowntime := strings.EqualFold(c.Query("allowIndexDowntime"), "true")
+		idx, created, err := app.IndexService.CreateOrUpdateIndex(c.Request.Context(), indexName, io.NopCloser(bytes.NewReader(body)), accessCondition(c), allowDowntime)
 		if err != nil {
-			err500(c, err)
+			if errors.Is(err, domain.ErrPreconditionFailed) {
+				err412(c, "The index was modified or does not match the If-Match/If-None-Match condition")
+			} else if errors.Is(err, domain.ErrCannotChangeExistingField) {
+				err400CannotChange(c, err.Error())
+			} else if errors.Is(err, domain.ErrIndexDowntimeRequired) {
+				err400NotAllowed(c, err.Error())
+			} else if errors.Is(err, domain.ErrInvalidName) {
+				err400InvalidName(c, err.Error())
+			} else if errors.Is(err, domain.ErrInvalidIndexDefinition) {
+				err400InvalidParam(c, err.Error())
+			} else {
+				err500(c, err)
+			}
 			return
 		}
+		c.Header("ETag", idx.ETag)
 		if created {
-			c.Data(http.StatusCreated, "application/json", body)
+			c.Data(http.StatusCreated, "application/json", withODataETag(body, idx.ETag))
 		} else {
-			c.Data(http.StatusOK, "application/json", body)
+			c.Data(http.StatusOK, "application/json", withODataETag(body, idx.ETag))
 		}
 	})
 	// インデックス削除API
 	r.DELETE("/indexes/:index", func(c *gin.Context) {
 		indexName := c.Param("index")
-		err := app.IndexService.DeleteIndex(c.Request.Context(), indexName)
+		err := app.IndexService.DeleteIndex(c.Request.Context(), indexName, accessCondition(c))
 		if err != nil {
 			if errors.Is(err, domain.ErrIndexNotFound) {
 				err404(c, "Index not found")
+			} else if errors.Is(err, domain.ErrPreconditionFailed) {
+				err412(c, "The index was modified or does not match the If-Match/If-None-Match condition")
 			} else {
 				err500(c, err)
 			}
@@ -161,17 +231,27 @@ func RegisterRoutes(r *gin.Engine, app *application.AppServices) {
 	// ドキュメントバッチ操作API（upload/merge/mergeOrUpload/delete対応）
 	r.POST("/indexes/:index/docs/index", func(c *gin.Context) {
 		indexName := c.Param("index")
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, application.MaxBatchPayloadBytes)
 		var req struct {
 			Value []map[string]interface{} `json:"value" binding:"required"`
 		}
 		if err := c.ShouldBindJSON(&req); err != nil {
-			err400(c, "Invalid batch request body")
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				err413(c, fmt.Sprintf("The request payload exceeds the maximum of %d bytes for an indexing batch", application.MaxBatchPayloadBytes))
+			} else {
+				err400(c, "Invalid batch request body")
+			}
 			return
 		}
-		results, err := app.DocumentService.BatchOperation(c.Request.Context(), indexName, req.Value)
+		results, err := app.DocumentService.BatchOperation(c.Request.Context(), indexName, req.Value, allowUnsafeKeys(c))
 		if err != nil {
-			if errors.Is(err, domain.ErrIndexNotFound) {
+			if errors.Is(err, domain.ErrBatchTooLarge) {
+				err400(c, err.Error())
+			} else if errors.Is(err, domain.ErrIndexNotFound) {
 				err404(c, "Index not found")
+			} else if errors.Is(err, domain.ErrIndexUnavailable) {
+				err503(c, indexUnavailableMessage)
 			} else {
 				err500(c, err)
 			}
@@ -190,10 +270,15 @@ func RegisterRoutes(r *gin.Engine, app *application.AppServices) {
 	r.GET("/indexes/:index/docs/:key", func(c *gin.Context) {
 		indexName := c.Param("index")
 		key := c.Param("key")
-		doc, err := app.DocumentService.GetDocument(c.Request.Context(), indexName, key)
+		selectPaths := splitCommaList(c.Query("$select"))
+		doc, err := app.DocumentService.GetDocument(c.Request.Context(), indexName, key, selectPaths)
 		if err != nil {
-			if errors.Is(err, domain.ErrIndexNotFound) {
+			if errors.Is(err, domain.ErrInvalidSelect) {
+				err400(c, err.Error())
+			} else if errors.Is(err, domain.ErrIndexNotFound) {
 				err404(c, "Index not found")
+			} else if errors.Is(err, domain.ErrIndexUnavailable) {
+				err503(c, indexUnavailableMessage)
 			} else if errors.Is(err, domain.ErrDocumentNotFound) {
 				err404(c, "Document not found")
 			} else {
@@ -201,6 +286,7 @@ func RegisterRoutes(r *gin.Engine, app *application.AppServices) {
 			}
 			return
 		}
+		doc["@odata.context"] = lookupODataContext(requestBaseURL(c.Request), indexName, selectPaths)
 		c.JSON(http.StatusOK, doc)
 	})
 	// ドキュメント件数取得API
@@ -210,6 +296,8 @@ func RegisterRoutes(r *gin.Engine, app *application.AppServices) {
 		if err != nil {
 			if errors.Is(err, domain.ErrIndexNotFound) {
 				err404(c, "Index not found")
+			} else if errors.Is(err, domain.ErrIndexUnavailable) {
+				err503(c, indexUnavailableMessage)
 			} else {
 				err500(c, err)
 			}
@@ -220,7 +308,14 @@ func RegisterRoutes(r *gin.Engine, app *application.AppServices) {
 	// ドキュメント検索API (GET)
 	r.GET("/indexes/:index/docs", func(c *gin.Context) {
 		indexName := c.Param("index")
-		params := parseSearchParamsFromQuery(c)
+		params, err := parseSearchParamsFromQuery(c)
+		if err != nil {
+			err400(c, err.Error())
+			return
+		}
+		if !checkSearchFeatures(c, false, c.Query("queryType")) {
+			return
+		}
 		result, err := app.DocumentService.SearchDocuments(c.Request.Context(), indexName, params)
 		if err != nil {
 			handleSearchError(c, err)
@@ -231,9 +326,22 @@ func RegisterRoutes(r *gin.Engine, app *application.AppServices) {
 	// ドキュメント検索API (POST)
 	r.POST("/indexes/:index/docs/search", func(c *gin.Context) {
 		indexName := c.Param("index")
-		params, err := parseSearchParamsFromBody(c)
+		raw, err := io.ReadAll(c.Request.Body)
 		if err != nil {
-			err400(c, "Invalid request body")
+			err400(c, "Failed to read request body")
+			return
+		}
+		params, err := parseSearchParamsFromBody(raw)
+		if err != nil {
+			err400(c, "Invalid request body: "+err.Error())
+			return
+		}
+		var features struct {
+			QueryType     string          `json:"queryType"`
+			VectorQueries json.RawMessage `json:"vectorQueries"`
+		}
+		_ = json.Unmarshal(raw, &features)
+		if !checkSearchFeatures(c, hasValue(features.VectorQueries), features.QueryType) {
 			return
 		}
 		result, err := app.DocumentService.SearchDocuments(c.Request.Context(), indexName, params)
@@ -245,105 +353,198 @@ func RegisterRoutes(r *gin.Engine, app *application.AppServices) {
 	})
 }
 
-// parseSearchParamsFromQuery reads OData parameters from GET query string.
-func parseSearchParamsFromQuery(c *gin.Context) application.SearchParams {
-	top, _ := strconv.Atoi(c.Query("$top"))
-	skip, _ := strconv.Atoi(c.Query("$skip"))
-	count := strings.EqualFold(c.Query("$count"), "true")
+// accessCondition reads the optimistic concurrency headers of the request.
+func accessCondition(c *gin.Context) application.AccessCondition {
+	return application.AccessCondition{
+		IfMatch:     c.GetHeader("If-Match"),
+		IfNoneMatch: c.GetHeader("If-None-Match"),
+	}
+}
 
-	var selectFields []string
-	if s := c.Query("$select"); s != "" {
-		for _, f := range strings.Split(s, ",") {
-			if f = strings.TrimSpace(f); f != "" {
-				selectFields = append(selectFields, f)
-			}
-		}
+// allowUnsafeKeys reports whether the request opted out of Azure's document
+// key character rules with allowUnsafeKeys=true.
+func allowUnsafeKeys(c *gin.Context) bool {
+	return strings.EqualFold(c.Query("allowUnsafeKeys"), "true")
+}
+
+// withODataETag inserts "@odata.etag" as the first property of a JSON object
+// body, leaving the rest of the client's formatting untouched. A stale
+// "@odata.etag" sent by the client is dropped first.
+func withODataETag(body []byte, etag string) []byte {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return body
+	}
+	if _, ok := obj["@odata.etag"]; ok {
+		delete(obj, "@odata.etag")
+		body, _ = json.Marshal(obj)
+	}
+	trimmed := bytes.TrimLeft(body, " \t\r\n")
+	etagJSON, _ := json.Marshal(etag)
+	out := make([]byte, 0, len(body)+len(etagJSON)+20)
+	out = append(out, `{"@odata.etag":`...)
+	out = append(out, etagJSON...)
+	rest := bytes.TrimLeft(trimmed[1:], " \t\r\n")
+	if len(rest) > 0 && rest[0] != '}' {
+		out = append(out, ',')
 	}
+	return append(out, trimmed[1:]...)
+}
 
-	var searchFields []string
-	if s := c.Query("searchFields"); s != "" {
-		for _, f := range strings.Split(s, ",") {
-			if f = strings.TrimSpace(f); f != "" {
-				searchFields = append(searchFields, f)
-			}
+// parseSearchParamsFromQuery reads OData parameters from GET query string.
+func parseSearchParamsFromQuery(c *gin.Context) (application.SearchParams, error) {
+	var top *int
+	if c.Query("$top") != "" {
+		t, err := queryInt(c, "$top")
+		if err != nil {
+			return application.SearchParams{}, err
 		}
+		top = &t
+	}
+	skip, err := queryInt(c, "$skip")
+	if err != nil {
+		return application.SearchParams{}, err
+	}
+	count := false
+	switch v := c.Query("$count"); strings.ToLower(v) {
+	case "", "false":
+	case "true":
+		count = true
+	default:
+		return application.SearchParams{}, fmt.Errorf("invalid value '%s' for $count; expected 'true' or 'false'", v)
 	}
 
 	return application.SearchParams{
 		Search:       c.Query("search"),
 		Filter:       c.Query("$filter"),
 		OrderBy:      c.Query("$orderby"),
-		Select:       selectFields,
-		SearchFields: searchFields,
+		Select:       splitCommaList(c.Query("$select")),
+		SearchFields: splitCommaList(c.Query("searchFields")),
 		Top:          top,
 		Skip:         skip,
 		IncludeCount: count,
+	}, nil
+}
+
+// queryInt parses an optional integer query parameter; absent means 0.
+func queryInt(c *gin.Context, name string) (int, error) {
+	v := c.Query(name)
+	if v == "" {
+		return 0, nil
 	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value '%s' for %s; expected an integer", v, name)
+	}
+	return n, nil
 }
 
 // searchBody mirrors the Azure AI Search POST /docs/search request body.
+// Azure names the properties without the OData "$" prefix; the prefixed
+// forms are still accepted for clients written against earlier emulator
+// versions.
 type searchBody struct {
 	Search       string `json:"search"`
-	Filter       string `json:"$filter"`
-	OrderBy      string `json:"$orderby"`
-	Select       string `json:"$select"`
+	Filter       string `json:"filter"`
+	OrderBy      string `json:"orderby"`
+	Select       string `json:"select"`
 	SearchFields string `json:"searchFields"`
-	Top          *int   `json:"$top"`
-	Skip         *int   `json:"$skip"`
-	Count        bool   `json:"$count"`
+	Top          *int   `json:"top"`
+	Skip         *int   `json:"skip"`
+	Count        bool   `json:"count"`
+
+	DollarFilter  string `json:"$filter"`
+	DollarOrderBy string `json:"$orderby"`
+	DollarSelect  string `json:"$select"`
+	DollarTop     *int   `json:"$top"`
+	DollarSkip    *int   `json:"$skip"`
+	DollarCount   bool   `json:"$count"`
 }
 
-// parseSearchParamsFromBody reads OData parameters from a POST JSON body.
-func parseSearchParamsFromBody(c *gin.Context) (application.SearchParams, error) {
-	var b searchBody
-	if err := c.ShouldBindJSON(&b); err != nil {
-		return application.SearchParams{}, err
-	}
+// searchBodyProperties lists every property Azure accepts in a POST search
+// body. Properties the emulator does not implement are accepted and ignored;
+// anything else is rejected as Azure does.
+var searchBodyProperties = map[string]struct{}{
+	"search": {}, "filter": {}, "orderby": {}, "select": {}, "searchFields": {},
+	"top": {}, "skip": {}, "count": {}, "facets": {}, "highlight": {},
+	"highlightPreTag": {}, "highlightPostTag": {}, "minimumCoverage": {},
+	"queryType": {}, "searchMode": {}, "scoringProfile": {}, "scoringParameters": {},
+	"scoringStatistics": {}, "sessionId": {}, "semanticConfiguration": {},
+	"semanticErrorHandling": {}, "semanticMaxWaitInMilliseconds": {}, "semanticQuery": {},
+	"answers": {}, "captions": {}, "queryLanguage": {}, "speller": {}, "debug": {},
+	"vectorQueries": {}, "vectorFilterMode": {}, "hybridSearch": {},
+	"$filter": {}, "$orderby": {}, "$select": {}, "$top": {}, "$skip": {}, "$count": {},
+}
 
-	top := 0
-	if b.Top != nil {
-		top = *b.Top
+// parseSearchParamsFromBody reads OData parameters from a POST JSON body.
+func parseSearchParamsFromBody(raw []byte) (application.SearchParams, error) {
+	var props map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &props); err != nil {
+		return application.SearchParams{}, fmt.Errorf("malformed JSON")
 	}
-	skip := 0
-	if b.Skip != nil {
-		skip = *b.Skip
+	for name := range props {
+		if _, ok := searchBodyProperties[name]; !ok {
+			return application.SearchParams{}, fmt.Errorf("the property '%s' does not exist on type 'SearchRequest'", name)
+		}
 	}
-
-	var selectFields []string
-	if b.Select != "" {
-		for _, f := range strings.Split(b.Select, ",") {
-			if f = strings.TrimSpace(f); f != "" {
-				selectFields = append(selectFields, f)
-			}
+	var b searchBody
+	if err := json.Unmarshal(raw, &b); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return application.SearchParams{}, fmt.Errorf("the property '%s' has an invalid value; expected %s", typeErr.Field, typeErr.Type)
 		}
+		return application.SearchParams{}, err
 	}
 
-	var searchFields []string
-	if b.SearchFields != "" {
-		for _, f := range strings.Split(b.SearchFields, ",") {
-			if f = strings.TrimSpace(f); f != "" {
-				searchFields = append(searchFields, f)
-			}
-		}
+	top := firstInt(b.Top, b.DollarTop)
+	skip := 0
+	if s := firstInt(b.Skip, b.DollarSkip); s != nil {
+		skip = *s
 	}
 
 	return application.SearchParams{
 		Search:       b.Search,
-		Filter:       b.Filter,
-		OrderBy:      b.OrderBy,
-		Select:       selectFields,
-		SearchFields: searchFields,
+		Filter:       firstNonEmpty(b.Filter, b.DollarFilter),
+		OrderBy:      firstNonEmpty(b.OrderBy, b.DollarOrderBy),
+		Select:       splitCommaList(firstNonEmpty(b.Select, b.DollarSelect)),
+		SearchFields: splitCommaList(b.SearchFields),
 		Top:          top,
 		Skip:         skip,
-		IncludeCount: b.Count,
+		IncludeCount: b.Count || b.DollarCount,
 	}, nil
 }
 
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func firstInt(values ...*int) *int {
+	for _, v := range values {
+		if v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
 func handleSearchError(c *gin.Context, err error) {
 	if errors.Is(err, domain.ErrIndexNotFound) {
 		err404(c, "Index not found")
 		return
 	}
+	if errors.Is(err, domain.ErrIndexUnavailable) {
+		err503(c, indexUnavailableMessage)
+		return
+	}
+	if errors.Is(err, domain.ErrInvalidSearchParameter) {
+		err400(c, err.Error())
+		return
+	}
 	msg := err.Error()
 	if strings.HasPrefix(msg, "invalid $filter") || strings.HasPrefix(msg, "invalid $orderby") {
 		err400(c, msg)
@@ -352,6 +553,27 @@ func handleSearchError(c *gin.Context, err error) {
 	err500(c, err)
 }
 
+// splitCommaList splits a comma-separated query value, trimming blanks.
+func splitCommaList(s string) []string {
+	var out []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// lookupODataContext builds the @odata.context of a document lookup response,
+// e.g. ".../indexes('hotels')/$metadata#docs(HotelId,Rooms/Type)/$entity".
+func lookupODataContext(baseURL, indexName string, selectPaths []string) string {
+	projection := "*"
+	if len(selectPaths) > 0 {
+		projection = strings.Join(selectPaths, ",")
+	}
+	return fmt.Sprintf("%s/indexes('%s')/$metadata#docs(%s)/$entity", baseURL, indexName, projection)
+}
+
 func requestBaseURL(r *http.Request) string {
 	scheme := "http"
 	if r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
@@ -364,13 +586,32 @@ func requestBaseURL(r *http.Request) string {
 	return scheme + "://" + host
 }
 
-func buildNextLink(baseURL, indexName string, params application.SearchParams, total int64) string {
-	top := params.Top
-	if top <= 0 {
-		top = application.DefaultTop
+// nextPage computes the $skip and $top of the page following the one just
+// returned, or ok=false when there is none. Without $top Azure pages by
+// DefaultTop; a $top above MaxTop is served MaxTop at a time with the
+// remainder carried into the next request.
+func nextPage(params application.SearchParams, total int64) (skip, top int, ok bool) {
+	pageSize := application.DefaultTop
+	if params.Top != nil {
+		if *params.Top <= application.MaxTop {
+			// An explicit $top the service can serve in one page is a
+			// complete request: Azure only continues default-sized and
+			// oversized pages.
+			return 0, 0, false
+		}
+		pageSize = application.MaxTop
+		top = *params.Top - application.MaxTop
 	}
-	nextSkip := params.Skip + top
-	if int64(nextSkip) >= total {
+	skip = params.Skip + pageSize
+	if int64(skip) >= total {
+		return 0, 0, false
+	}
+	return skip, top, true
+}
+
+func buildNextLink(baseURL, indexName string, params application.SearchParams, total int64) string {
+	nextSkip, nextTop, ok := nextPage(params, total)
+	if !ok {
 		return ""
 	}
 	u, _ := url.Parse(baseURL + "/indexes/" + url.PathEscape(indexName) + "/docs")
@@ -390,8 +631,8 @@ func buildNextLink(baseURL, indexName string, params application.SearchParams, t
 	if len(params.SearchFields) > 0 {
 		q.Set("searchFields", strings.Join(params.SearchFields, ","))
 	}
-	if params.Top > 0 {
-		q.Set("$top", strconv.Itoa(params.Top))
+	if nextTop > 0 {
+		q.Set("$top", strconv.Itoa(nextTop))
 	}
 	q.Set("$skip", strconv.Itoa(nextSkip))
 	if params.IncludeCount {
@@ -403,6 +644,47 @@ func buildNextLink(baseURL, indexName string, params application.SearchParams, t
 	return u.String()
 }
 
+// buildNextPageParameters returns the request body of the next POST search
+// page (Azure's @search.nextPageParameters), or nil when there is none.
+func buildNextPageParameters(params application.SearchParams, total int64) map[string]interface{} {
+	nextSkip, nextTop, ok := nextPage(params, total)
+	if !ok {
+		return nil
+	}
+	next := map[string]interface{}{"skip": nextSkip}
+	if params.Search != "" {
+		next["search"] = params.Search
+	}
+	if params.Filter != "" {
+		next["filter"] = params.Filter
+	}
+	if params.OrderBy != "" {
+		next["orderby"] = params.OrderBy
+	}
+	if len(params.Select) > 0 {
+		next["select"] = strings.Join(params.Select, ",")
+	}
+	if len(params.SearchFields) > 0 {
+		next["searchFields"] = strings.Join(params.SearchFields, ",")
+	}
+	if nextTop > 0 {
+		next["top"] = nextTop
+	}
+	if params.IncludeCount {
+		next["count"] = true
+	}
+	return next
+}
+
+// postSearchNextLink is the URL the next POST search page is sent to.
+func postSearchNextLink(c *gin.Context, baseURL, indexName string) string {
+	link := fmt.Sprintf("%s/indexes('%s')/docs/search.post.search", baseURL, indexName)
+	if v := c.Query("api-version"); v != "" {
+		link += "?api-version=" + url.QueryEscape(v)
+	}
+	return link
+}
+
 func respondSearch(c *gin.Context, indexName string, params application.SearchParams, result *application.SearchResult) {
 	baseURL := requestBaseURL(c.Request)
 	odataCtx := fmt.Sprintf("%s/indexes('%s')/$metadata#docs(*)", baseURL, indexName)
@@ -413,7 +695,11 @@ func respondSearch(c *gin.Context, indexName string, params application.SearchPa
 		for k, v := range doc {
 			d[k] = v
 		}
-		d["@search.score"] = 1.0
+		score := 1.0
+		if i < len(result.Scores) {
+			score = result.Scores[i]
+		}
+		d["@search.score"] = score
 		docs[i] = d
 	}
 
@@ -425,26 +711,15 @@ func respondSearch(c *gin.Context, indexName string, params application.SearchPa
 	if params.IncludeCount {
 		resp["@odata.count"] = result.Total
 	}
-	if nextLink := buildNextLink(baseURL, indexName, params, result.Total); nextLink != "" {
+	if c.Request.Method == http.MethodPost {
+		// POST paging continues with another POST; the SDKs send
+		// @search.nextPageParameters as the body of that request.
+		if next := buildNextPageParameters(params, result.Total); next != nil {
+			resp["@search.nextPageParameters"] = next
+			resp["@odata.nextLink"] = postSearchNextLink(c, baseURL, indexName)
+		}
+	} else if nextLink := buildNextLink(baseURL, indexName, params, result.Total); nextLink != "" {
 		resp["@odata.nextLink"] = nextLink
 	}
 	c.JSON(http.StatusOK, resp)
 }
-
-func ApiKeyAuthMiddleware() gin.HandlerFunc {
-	apiKeyEnv := os.Getenv("API_KEY")
-	if apiKeyEnv == "" {
-		log.Println("[WARN] API_KEY is not set — all requests will be rejected")
-	}
-	return func(c *gin.Context) {
-		apiKey := c.GetHeader("api-key")
-		if apiKey == "" {
-			apiKey = c.GetHeader("Api-Key")
-		}
-		if apiKeyEnv == "" || apiKey != apiKeyEnv {
-			err401(c, "API key required or invalid")
-			return
-		}
-		c.Next()
-	}
-}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"strings"
 	"testing"
@@ -16,11 +17,15 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 
 	"ai-search-emulator/internal/application"
+	"ai-search-emulator/internal/domain"
 	"ai-search-emulator/internal/infrastructure"
 )
 
 const apiTestKey = "test-api-key"
 
+// apiTestQueryKey is a read-only query key configured by setupRouter.
+const apiTestQueryKey = "test-query-key"
+
 const apiTestSchema = `{
 	"name": "movies",
 	"fields": [
@@ -32,7 +37,8 @@ const apiTestSchema = `{
 const apiTestSchemaSQL = `
 CREATE TABLE IF NOT EXISTS indexes (
     name TEXT PRIMARY KEY,
-    schema TEXT NOT NULL
+    schema TEXT NOT NULL,
+    etag TEXT NOT NULL DEFAULT ''
 );
 CREATE TABLE IF NOT EXISTS documents (
     index_name TEXT NOT NULL,
@@ -40,15 +46,40 @@ CREATE TABLE IF NOT EXISTS documents (
     content TEXT NOT NULL,
     PRIMARY KEY (index_name, key),
     FOREIGN KEY (index_name) REFERENCES indexes(name) ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS datasources (
+    name TEXT PRIMARY KEY,
+    definition TEXT NOT NULL,
+    etag TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS indexers (
+    name TEXT PRIMARY KEY,
+    definition TEXT NOT NULL,
+    etag TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS indexer_status (
+    name TEXT PRIMARY KEY,
+    status TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS skillsets (
+    name TEXT PRIMARY KEY,
+    definition TEXT NOT NULL,
+    etag TEXT NOT NULL DEFAULT ''
 );`
 
 // setupRouter wires up an in-memory SQLite-backed router that mirrors the
 // production main.go bootstrap. The DB is closed automatically when the test
 // completes so no files are left behind.
 func setupRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	return setupRouterWithTokens(t, nil)
+}
+
+// setupRouterWithTokens is setupRouter with bearer token authentication
+// backed by tokens.
+func setupRouterWithTokens(t *testing.T, tokens domain.TokenVerifier) *gin.Engine {
 	t.Helper()
 	gin.SetMode(gin.TestMode)
-	t.Setenv("API_KEY", apiTestKey)
 
 	db, err := sql.Open("sqlite3", ":memory:")
 	if err != nil {
@@ -63,22 +94,42 @@ func setupRouter(t *testing.T) *gin.Engine {
 
 	idxRepo := infrastructure.NewSQLiteIndexRepository(db)
 	docRepo := infrastructure.NewSQLiteDocumentRepository(db)
+	availability := application.NewIndexAvailability(application.DefaultIndexDowntime)
 	apps := &application.AppServices{
 		IndexService:    application.NewIndexService(idxRepo, docRepo),
 		DocumentService: application.NewDocumentService(docRepo, idxRepo),
-	}
+		KeyService:      application.NewKeyService(apiTestKey, "", []string{apiTestQueryKey}),
+	}
+	apps.IndexService.Availability = availability
+	apps.DocumentService.Availability = availability
+	sourceRoot := t.TempDir()
+	blobConnector := infrastructure.NewBlobDirectoryConnector(sourceRoot)
+	apps.DataSourceService = application.NewDataSourceService(infrastructure.NewSQLiteDataSourceRepository(db), map[string]domain.SourceConnector{
+		"azureblob": blobConnector,
+		"adlsgen2":  blobConnector,
+		"azuresql":  infrastructure.NewSQLiteTableConnector(sourceRoot),
+	})
+	apps.SkillsetService = application.NewSkillsetService(infrastructure.NewSQLiteSkillsetRepository(db))
+	apps.IndexerService = application.NewIndexerService(infrastructure.NewSQLiteIndexerRepository(db), apps.DataSourceService, apps.DocumentService)
+	apps.IndexerService.Skillsets = apps.SkillsetService
 
 	r := gin.New()
 	RegisterHealthCheck(r)
-	r.Use(ApiKeyAuthMiddleware())
+	r.Use(AuthMiddleware(apps.KeyService, tokens))
+	r.Use(APIVersionMiddleware())
+	r.Use(ContentNegotiationMiddleware())
 	RegisterRoutes(r, apps)
 	return r
 }
 
-// doRequest sends a request with the API key header pre-set. Pass an empty
-// string for body when there is no payload.
-func doRequest(t *testing.T, r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+// apiTestVersion is appended to request paths that do not set api-version.
+const apiTestVersion = "2024-07-01"
+
+// doRequest sends a request with the API key header pre-set and api-version
+// defaulted. Pass an empty string for body when there is no payload.
+func doRequest(t *testing.T, r http.Handler, method, path, body string) *httptest.ResponseRecorder {
 	t.Helper()
+	path = withAPIVersion(path)
 	var reader io.Reader
 	if body != "" {
 		reader = strings.NewReader(body)
@@ -93,6 +144,17 @@ func doRequest(t *testing.T, r *gin.Engine, method, path, body string) *httptest
 	return rec
 }
 
+// withAPIVersion adds api-version=apiTestVersion unless path already has one.
+func withAPIVersion(path string) string {
+	if strings.Contains(path, "api-version=") {
+		return path
+	}
+	if strings.Contains(path, "?") {
+		return path + "&api-version=" + apiTestVersion
+	}
+	return path + "?api-version=" + apiTestVersion
+}
+
 // --- Health check ---
 
 func TestHealthCheck_NoAuthRequired(t *testing.T) {
@@ -135,7 +197,7 @@ func TestApiKeyMiddleware_WrongKeyRejected(t *testing.T) {
 
 func TestApiKeyMiddleware_AcceptsCapitalizedHeader(t *testing.T) {
 	r := setupRouter(t)
-	req := httptest.NewRequest(http.MethodGet, "/indexes", nil)
+	req := httptest.NewRequest(http.MethodGet, withAPIVersion("/indexes"), nil)
 	// canonical: Api-Key (Go normalizes to Api-Key already).
 	req.Header.Set("Api-Key", apiTestKey)
 	rec := httptest.NewRecorder()
@@ -145,10 +207,9 @@ func TestApiKeyMiddleware_AcceptsCapitalizedHeader(t *testing.T) {
 	}
 }
 
-func TestApiKeyMiddleware_RejectsAllWhenEnvUnset(t *testing.T) {
-	t.Setenv("API_KEY", "")
-	// Build router manually so the env var (or lack thereof) is captured by
-	// the middleware closure.
+func TestApiKeyMiddleware_RejectsAllWhenNoAdminKey(t *testing.T) {
+	// Build router manually so the middleware sees a key service without
+	// admin keys.
 	gin.SetMode(gin.TestMode)
 	db, _ := sql.Open("sqlite3", ":memory:")
 	db.SetMaxOpenConns(1)
@@ -156,22 +217,29 @@ func TestApiKeyMiddleware_RejectsAllWhenEnvUnset(t *testing.T) {
 	t.Cleanup(func() { _ = db.Close() })
 	idxRepo := infrastructure.NewSQLiteIndexRepository(db)
 	docRepo := infrastructure.NewSQLiteDocumentRepository(db)
+	availability := application.NewIndexAvailability(application.DefaultIndexDowntime)
 	apps := &application.AppServices{
 		IndexService:    application.NewIndexService(idxRepo, docRepo),
 		DocumentService: application.NewDocumentService(docRepo, idxRepo),
+		KeyService:      application.NewKeyService("", "", nil),
 	}
+	apps.IndexService.Availability = availability
+	apps.DocumentService.Availability = availability
 	r := gin.New()
 	RegisterHealthCheck(r)
-	r.Use(ApiKeyAuthMiddleware())
+	r.Use(ApiKeyAuthMiddleware(apps.KeyService))
+	r.Use(APIVersionMiddleware())
+	r.Use(ContentNegotiationMiddleware())
 	RegisterRoutes(r, apps)
 
-	// Even providing some api-key should be rejected because env is empty.
+	// Even providing some api-key should be rejected because no admin key is
+	// configured.
 	req := httptest.NewRequest(http.MethodGet, "/indexes", nil)
 	req.Header.Set("api-key", "anything")
 	rec := httptest.NewRecorder()
 	r.ServeHTTP(rec, req)
 	if rec.Code != http.StatusUnauthorized {
-		t.Errorf("status = %d, want 401 when API_KEY env not set", rec.Code)
+		t.Errorf("status = %d, want 401 when no admin key is configured", rec.Code)
 	}
 	// /healthz must remain accessible because it is registered before the
 	// middleware.
@@ -282,7 +350,7 @@ func TestUpdateIndex_Success(t *testing.T) {
 	r := setupRouter(t)
 	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
 
-	updated := `{"name":"movies","fields":[{"name":"id","key":true}]}`
+	updated := `{"name":"movies","fields":[{"name":"id","type":"Edm.String","key":true},{"name":"title","type":"Edm.String"},{"name":"year","type":"Edm.Int32"}]}`
 	rec := doRequest(t, r, http.MethodPut, "/indexes/movies", updated)
 	if rec.Code != http.StatusOK {
 		t.Errorf("status = %d, body=%s", rec.Code, rec.Body.String())
@@ -291,7 +359,7 @@ func TestUpdateIndex_Success(t *testing.T) {
 
 func TestUpdateIndex_CreatesWhenNotFound(t *testing.T) {
 	r := setupRouter(t)
-	rec := doRequest(t, r, http.MethodPut, "/indexes/missing", apiTestSchema)
+	rec := doRequest(t, r, http.MethodPut, "/indexes/movies", apiTestSchema)
 	if rec.Code != http.StatusCreated {
 		t.Errorf("status = %d, want 201 (PUT creates when index absent)", rec.Code)
 	}
@@ -306,8 +374,129 @@ func TestUpdateIndex_InvalidJSON(t *testing.T) {
 	}
 }
 
+func TestUpdateIndex_IfMatch(t *testing.T) {
+	r := setupRouter(t)
+	rec := doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatalf("ETag header missing on create")
+	}
+	var created map[string]interface{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &created)
+	if created["@odata.etag"] != etag {
+		t.Errorf("@odata.etag = %v, want %v", created["@odata.etag"], etag)
+	}
+
+	put := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, withAPIVersion("/indexes/movies"), strings.NewReader(apiTestSchema))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("api-key", apiTestKey)
+		req.Header.Set(header, value)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := put("If-None-Match", "*"); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("If-None-Match:* status = %d, want 412", rec.Code)
+	} else if errCode(t, rec) != "PreconditionFailed" {
+		t.Errorf("error code = %q", errCode(t, rec))
+	}
+	rec = put("If-Match", etag)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("If-Match status = %d, want 200", rec.Code)
+	}
+	newETag := rec.Header().Get("ETag")
+	if newETag == "" || newETag == etag {
+		t.Errorf("ETag should change on update, got %q (was %q)", newETag, etag)
+	}
+	if rec := put("If-Match", etag); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("stale If-Match status = %d, want 412", rec.Code)
+	}
+
+	rec = doRequest(t, r, http.MethodGet, "/indexes/movies", "")
+	if rec.Header().Get("ETag") != newETag {
+		t.Errorf("GET ETag = %q, want %q", rec.Header().Get("ETag"), newETag)
+	}
+}
+
+func TestUpdateIndex_ChangingExistingFieldReturns400(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
+
+	changed := `{"name":"movies","fields":[{"name":"id","type":"Edm.String","key":true},{"name":"title","type":"Edm.Int32"}]}`
+	rec := doRequest(t, r, http.MethodPut, "/indexes/movies", changed)
+	if rec.Code != http.StatusBadRequest || errCode(t, rec) != "CannotChangeExistingField" {
+		t.Errorf("status = %d code = %q, want 400 CannotChangeExistingField", rec.Code, errCode(t, rec))
+	}
+	removed := `{"name":"movies","fields":[{"name":"id","type":"Edm.String","key":true}]}`
+	rec = doRequest(t, r, http.MethodPut, "/indexes/movies", removed)
+	if rec.Code != http.StatusBadRequest || errCode(t, rec) != "CannotChangeExistingField" {
+		t.Errorf("status = %d code = %q, want 400 CannotChangeExistingField", rec.Code, errCode(t, rec))
+	}
+}
+
+func TestUpdateIndex_AllowIndexDowntime(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
+
+	withAnalyzer := `{"name":"movies","fields":[{"name":"id","type":"Edm.String","key":true},{"name":"title","type":"Edm.String"}],` +
+		`"analyzers":[{"name":"my","@odata.type":"#Microsoft.Azure.Search.CustomAnalyzer","tokenizer":"standard_v2"}]}`
+	rec := doRequest(t, r, http.MethodPut, "/indexes/movies", withAnalyzer)
+	if rec.Code != http.StatusBadRequest || errCode(t, rec) != "OperationNotAllowed" {
+		t.Fatalf("status = %d code = %q, want 400 OperationNotAllowed", rec.Code, errCode(t, rec))
+	}
+	rec = doRequest(t, r, http.MethodPut, "/indexes/movies?allowIndexDowntime=true", withAnalyzer)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	rec = doRequest(t, r, http.MethodGet, "/indexes/movies/docs?search=*", "")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("search during downtime status = %d, want 503", rec.Code)
+	}
+}
+
+func TestCreateIndex_InvalidNamesReturn400(t *testing.T) {
+	r := setupRouter(t)
+	tests := []struct {
+		body string
+		code string
+	}{
+		{`{"name":"Movies","fields":[{"name":"id","type":"Edm.String","key":true}]}`, "InvalidName"},
+		{`{"name":"movies","fields":[{"name":"id","type":"Edm.String","key":true},{"name":"azureSearchX","type":"Edm.String"}]}`, "InvalidName"},
+		{`{"name":"movies","fields":[{"name":"id","type":"Edm.Int32","key":true}]}`, "InvalidRequestParameter"},
+	}
+	for _, tt := range tests {
+		rec := doRequest(t, r, http.MethodPost, "/indexes", tt.body)
+		if rec.Code != http.StatusBadRequest || errCode(t, rec) != tt.code {
+			t.Errorf("%s: status = %d code = %q, want 400 %s", tt.body, rec.Code, errCode(t, rec), tt.code)
+		}
+	}
+}
+
+func TestUpdateIndex_URLNameMustMatchBody(t *testing.T) {
+	r := setupRouter(t)
+	rec := doRequest(t, r, http.MethodPut, "/indexes/other", apiTestSchema)
+	if rec.Code != http.StatusBadRequest || errCode(t, rec) != "InvalidRequestParameter" {
+		t.Errorf("status = %d code = %q, want 400 InvalidRequestParameter", rec.Code, errCode(t, rec))
+	}
+}
+
 // --- DELETE /indexes/:index ---
 
+func TestDeleteIndex_IfMatchMismatchReturns412(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
+
+	req := httptest.NewRequest(http.MethodDelete, withAPIVersion("/indexes/movies"), nil)
+	req.Header.Set("api-key", apiTestKey)
+	req.Header.Set("If-Match", `"0xDEAD"`)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("status = %d, want 412", rec.Code)
+	}
+}
+
 func TestDeleteIndex_Success(t *testing.T) {
 	r := setupRouter(t)
 	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
@@ -463,6 +652,74 @@ func TestBatchOperation_PartialFailureReturns207(t *testing.T) {
 	}
 }
 
+func TestBatchOperation_TooManyActionsReturns400(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
+
+	actions := make([]string, application.MaxBatchActions+1)
+	for i := range actions {
+		actions[i] = fmt.Sprintf(`{"@search.action":"upload","id":"%d"}`, i)
+	}
+	rec := doRequest(t, r, http.MethodPost, "/indexes/movies/docs/index", `{"value":[`+strings.Join(actions, ",")+`]}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestBatchOperation_PayloadTooLargeReturns413(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
+
+	big := strings.Repeat("x", application.MaxBatchPayloadBytes)
+	rec := doRequest(t, r, http.MethodPost, "/indexes/movies/docs/index", `{"value":[{"@search.action":"upload","id":"1","title":"`+big+`"}]}`)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want 413", rec.Code)
+	}
+	if errCode(t, rec) != "RequestEntityTooLarge" {
+		t.Errorf("code = %q", errCode(t, rec))
+	}
+}
+
+func TestBatchOperation_InvalidKeyIsPerItem400(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
+
+	batch := `{"value":[
+		{"@search.action":"upload","id":"ok_key-1=","title":"a"},
+		{"@search.action":"upload","id":"bad key","title":"b"}
+	]}`
+	rec := doRequest(t, r, http.MethodPost, "/indexes/movies/docs/index", batch)
+	if rec.Code != http.StatusMultiStatus {
+		t.Fatalf("status = %d, want 207", rec.Code)
+	}
+	var body struct {
+		Value []map[string]interface{} `json:"value"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	if body.Value[0]["statusCode"] != float64(201) {
+		t.Errorf("valid key statusCode = %v, want 201", body.Value[0]["statusCode"])
+	}
+	if body.Value[1]["statusCode"] != float64(400) {
+		t.Errorf("invalid key statusCode = %v, want 400", body.Value[1]["statusCode"])
+	}
+	if msg, _ := body.Value[1]["errorMessage"].(string); !strings.HasPrefix(msg, "Invalid document key") {
+		t.Errorf("errorMessage = %q", msg)
+	}
+}
+
+func TestAddSingleDoc_InvalidKeyReturns400(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
+	rec := doRequest(t, r, http.MethodPost, "/indexes/movies/docs", `{"id":"a.b","title":"x"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+	rec = doRequest(t, r, http.MethodPost, "/indexes/movies/docs?allowUnsafeKeys=true", `{"id":"a.b","title":"x"}`)
+	if rec.Code != http.StatusCreated {
+		t.Errorf("allowUnsafeKeys status = %d, want 201", rec.Code)
+	}
+}
+
 func TestBatchOperation_MergeOrUploadReturnsCorrectStatusCodes(t *testing.T) {
 	r := setupRouter(t)
 	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
@@ -547,6 +804,31 @@ func TestGetDocument_Success(t *testing.T) {
 	}
 }
 
+func TestGetDocument_SelectAndODataContext(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
+	doRequest(t, r, http.MethodPost, "/indexes/movies/docs", `{"id":"1","title":"hi"}`)
+
+	rec := doRequest(t, r, http.MethodGet, "/indexes/movies/docs/1?$select=title", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var body map[string]interface{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	if _, ok := body["id"]; ok {
+		t.Errorf("id should be excluded by $select, body=%v", body)
+	}
+	ctx, _ := body["@odata.context"].(string)
+	if !strings.HasSuffix(ctx, "/indexes('movies')/$metadata#docs(title)/$entity") {
+		t.Errorf("@odata.context = %q", ctx)
+	}
+
+	rec = doRequest(t, r, http.MethodGet, "/indexes/movies/docs/1?$select=nope", "")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown $select field: status = %d, want 400", rec.Code)
+	}
+}
+
 func TestGetDocument_DocNotFound(t *testing.T) {
 	r := setupRouter(t)
 	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
@@ -564,6 +846,41 @@ func TestGetDocument_IndexNotFound(t *testing.T) {
 	}
 }
 
+func TestGetDocument_UnsafeKeyViaODataPath(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
+	h := ODataPathRewriter(r)
+
+	batch := `{"value":[{"@search.action":"upload","id":"it's a/b(c)","title":"odd"}]}`
+	rec := doRequest(t, r, http.MethodPost, "/indexes/movies/docs/index", batch)
+	if rec.Code != http.StatusMultiStatus {
+		t.Fatalf("unsafe key without allowUnsafeKeys: status = %d, want 207", rec.Code)
+	}
+	rec = doRequest(t, r, http.MethodPost, "/indexes/movies/docs/index?allowUnsafeKeys=true", batch)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("allowUnsafeKeys upload: status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+
+	for _, path := range []string{
+		"/indexes('movies')/docs('it''s%20a%2Fb(c)')",
+		"/indexes/movies/docs/it%27s%20a%2Fb%28c%29",
+	} {
+		req := httptest.NewRequest(http.MethodGet, withAPIVersion(path), nil)
+		req.Header.Set("api-key", apiTestKey)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: status = %d, body=%s", path, rec.Code, rec.Body.String())
+			continue
+		}
+		var body map[string]interface{}
+		_ = json.Unmarshal(rec.Body.Bytes(), &body)
+		if body["title"] != "odd" {
+			t.Errorf("%s: title = %v", path, body["title"])
+		}
+	}
+}
+
 // --- GET /indexes/:index/docs/$count ---
 
 func TestCountDocuments_Success(t *testing.T) {
@@ -581,6 +898,20 @@ func TestCountDocuments_Success(t *testing.T) {
 	}
 }
 
+func TestCountDocuments_EscapedDollar(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
+	doRequest(t, r, http.MethodPost, "/indexes/movies/docs", `{"id":"1"}`)
+
+	rec := doRequest(t, ODataPathRewriter(r), http.MethodGet, "/indexes/movies/docs/%24count", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := strings.TrimSpace(rec.Body.String()); got != "1" {
+		t.Errorf("count body = %q, want '1'", got)
+	}
+}
+
 func TestCountDocuments_IndexNotFound(t *testing.T) {
 	r := setupRouter(t)
 	rec := doRequest(t, r, http.MethodGet, "/indexes/missing/docs/$count", "")
@@ -655,6 +986,25 @@ func TestSearchDocuments_POST_IndexNotFound(t *testing.T) {
 	}
 }
 
+func TestSearchDocuments_FullQueryTypeOnlyRejectedInIsMatch(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
+	// The top-level queryType is accepted as before; only search.ismatch
+	// rejects the full syntax it cannot evaluate.
+	rec := doRequest(t, r, http.MethodGet, "/indexes/movies/docs?search=alpha&queryType=full", "")
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET: status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	rec = doRequest(t, r, http.MethodPost, "/indexes/movies/docs/search", `{"search":"alpha","queryType":"Full"}`)
+	if rec.Code != http.StatusOK {
+		t.Errorf("POST: status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	rec = doRequest(t, r, http.MethodGet, "/indexes/movies/docs?$filter="+url.QueryEscape("search.ismatch('alpha', 'title', 'full', 'any')"), "")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("search.ismatch: status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+}
+
 // --- End-to-end happy path through the full handler stack ---
 
 func TestEndToEnd_CreateAndQuery(t *testing.T) {
@@ -797,15 +1147,24 @@ func TestSearchDocuments_GET_ODataCount(t *testing.T) {
 	}
 }
 
+// uploadNumberedDocs uploads n documents with zero-padded ids 00, 01, ...
+func uploadNumberedDocs(t *testing.T, r http.Handler, n int) {
+	t.Helper()
+	actions := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		actions = append(actions, fmt.Sprintf(`{"@search.action":"upload","id":"%02d","title":"doc%d"}`, i, i))
+	}
+	if rec := doRequest(t, r, http.MethodPost, "/indexes/movies/docs/index", `{"value":[`+strings.Join(actions, ",")+`]}`); rec.Code != http.StatusOK {
+		t.Fatalf("upload: status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+}
+
 func TestSearchDocuments_GET_NextLink(t *testing.T) {
 	r := setupRouter(t)
 	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
-	for i := 1; i <= 5; i++ {
-		doRequest(t, r, http.MethodPost, "/indexes/movies/docs",
-			fmt.Sprintf(`{"id":"%d","title":"doc%d"}`, i, i))
-	}
+	uploadNumberedDocs(t, r, 60)
 
-	rec := doRequest(t, r, http.MethodGet, "/indexes/movies/docs?$top=2&$skip=0", "")
+	rec := doRequest(t, r, http.MethodGet, "/indexes/movies/docs?$skip=0", "")
 	if rec.Code != http.StatusOK {
 		t.Fatalf("status = %d", rec.Code)
 	}
@@ -816,11 +1175,185 @@ func TestSearchDocuments_GET_NextLink(t *testing.T) {
 	if !ok || nextLink == "" {
 		t.Fatalf("@odata.nextLink missing, body=%s", rec.Body.String())
 	}
-	if !strings.Contains(nextLink, "$skip=2") {
-		t.Errorf("@odata.nextLink = %q, expected $skip=2", nextLink)
+	if !strings.Contains(nextLink, "$skip=50") {
+		t.Errorf("@odata.nextLink = %q, expected $skip=50", nextLink)
+	}
+	if strings.Contains(nextLink, "$top") {
+		t.Errorf("@odata.nextLink = %q, must not set $top when the request did not", nextLink)
+	}
+}
+
+func TestSearchDocuments_NoNextLinkForExplicitTop(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
+	uploadNumberedDocs(t, r, 60)
+
+	rec := doRequest(t, r, http.MethodGet, "/indexes/movies/docs?$top=2", "")
+	var body map[string]interface{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	if values, _ := body["value"].([]interface{}); rec.Code != http.StatusOK || len(values) != 2 {
+		t.Fatalf("status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	if _, ok := body["@odata.nextLink"]; ok {
+		t.Errorf("an explicit $top should get no @odata.nextLink, body=%s", rec.Body.String())
+	}
+	rec = doRequest(t, r, http.MethodPost, "/indexes/movies/docs/search", `{"search":"*","top":2}`)
+	if strings.Contains(rec.Body.String(), "nextPageParameters") || strings.Contains(rec.Body.String(), "nextLink") {
+		t.Errorf("an explicit top should get no continuation, body=%s", rec.Body.String())
+	}
+}
+
+func TestSearchDocuments_POST_NextPageParameters(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
+	uploadNumberedDocs(t, r, 60)
+
+	rec := doRequest(t, r, http.MethodPost, "/indexes/movies/docs/search?api-version=2024-07-01",
+		`{"search":"*","filter":"id ne '99'","orderby":"id","count":true}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var body map[string]interface{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+
+	next, ok := body["@search.nextPageParameters"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("@search.nextPageParameters missing, body=%s", rec.Body.String())
+	}
+	want := map[string]interface{}{"search": "*", "filter": "id ne '99'", "orderby": "id", "skip": 50.0, "count": true}
+	for k, v := range want {
+		if next[k] != v {
+			t.Errorf("nextPageParameters[%s] = %v, want %v", k, next[k], v)
+		}
+	}
+	link, _ := body["@odata.nextLink"].(string)
+	if !strings.HasSuffix(link, "/indexes('movies')/docs/search.post.search?api-version=2024-07-01") {
+		t.Errorf("@odata.nextLink = %q", link)
+	}
+
+	// Posting nextPageParameters back (through the OData rewriter) returns the next page.
+	nextBody, _ := json.Marshal(next)
+	req := httptest.NewRequest(http.MethodPost, strings.TrimPrefix(link, "http://example.com"), bytes.NewReader(nextBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("api-key", apiTestKey)
+	rec = httptest.NewRecorder()
+	ODataPathRewriter(r).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("next page status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var page struct {
+		Value []map[string]interface{} `json:"value"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &page)
+	if len(page.Value) != 10 || page.Value[0]["id"] != "50" {
+		t.Errorf("second page = %v, want ids 50-59", page.Value)
+	}
+}
+
+func TestSearchDocuments_POST_DefaultPageSize(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
+	uploadNumberedDocs(t, r, 60)
+
+	rec := doRequest(t, r, http.MethodPost, "/indexes/movies/docs/search", `{"search":"*"}`)
+	var body struct {
+		Value []map[string]interface{} `json:"value"`
+		Next  map[string]interface{}   `json:"@search.nextPageParameters"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	if len(body.Value) != application.DefaultTop {
+		t.Errorf("page size = %d, want %d", len(body.Value), application.DefaultTop)
+	}
+	if body.Next["skip"] != float64(application.DefaultTop) {
+		t.Errorf("next skip = %v, want %d", body.Next["skip"], application.DefaultTop)
+	}
+	if _, ok := body.Next["top"]; ok {
+		t.Errorf("next page must not set top when the request did not, got %v", body.Next["top"])
+	}
+}
+
+func TestNextPage_CapsAtMaxTop(t *testing.T) {
+	oversized := 1500
+	skip, top, ok := nextPage(application.SearchParams{Top: &oversized, Skip: 10}, 5000)
+	if !ok || skip != 1010 || top != 500 {
+		t.Errorf("nextPage = (%d, %d, %v), want (1010, 500, true)", skip, top, ok)
+	}
+	if _, _, ok := nextPage(application.SearchParams{Top: &oversized}, 1000); ok {
+		t.Errorf("no continuation expected when all results were served")
+	}
+}
+
+func TestSearchDocuments_TopZeroReturnsOnlyTheCount(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
+	uploadNumberedDocs(t, r, 3)
+
+	for _, req := range []struct{ method, path, body string }{
+		{http.MethodGet, "/indexes/movies/docs?search=*&$top=0&$count=true", ""},
+		{http.MethodPost, "/indexes/movies/docs/search", `{"search":"*","top":0,"count":true}`},
+		{http.MethodPost, "/indexes/movies/docs/search", `{"search":"*","orderby":"search.score() asc","top":0,"count":true}`},
+	} {
+		rec := doRequest(t, r, req.method, req.path, req.body)
+		var body struct {
+			Count *float64                 `json:"@odata.count"`
+			Value []map[string]interface{} `json:"value"`
+		}
+		_ = json.Unmarshal(rec.Body.Bytes(), &body)
+		if rec.Code != http.StatusOK || len(body.Value) != 0 || body.Count == nil || *body.Count != 3 {
+			t.Errorf("%s %s: status = %d, body=%s; want no documents and a count of 3", req.method, req.body, rec.Code, rec.Body.String())
+		}
+		if strings.Contains(rec.Body.String(), "nextPageParameters") || strings.Contains(rec.Body.String(), "nextLink") {
+			t.Errorf("%s %s: $top=0 should not continue, body=%s", req.method, req.body, rec.Body.String())
+		}
+	}
+}
+
+func TestSearchDocuments_InvalidParametersReturn400(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
+
+	for _, q := range []string{
+		"$top=abc",
+		"$top=-1",
+		"$skip=1.5",
+		"$skip=100001",
+		"$count=yes",
+		"search=" + strings.Repeat("a", 8193),
+	} {
+		rec := doRequest(t, r, http.MethodGet, "/indexes/movies/docs?"+q, "")
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%.40s: status = %d, want 400", q, rec.Code)
+			continue
+		}
+		var body map[string]map[string]string
+		_ = json.Unmarshal(rec.Body.Bytes(), &body)
+		if body["error"]["message"] == "" {
+			t.Errorf("%.40s: error body should carry a message, got %s", q, rec.Body.String())
+		}
 	}
-	if !strings.Contains(nextLink, "$top=2") {
-		t.Errorf("@odata.nextLink = %q, expected $top=2", nextLink)
+	if rec := doRequest(t, r, http.MethodGet, "/indexes/movies/docs?$skip=100000&$count=TRUE", ""); rec.Code != http.StatusOK {
+		t.Errorf("boundary values: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestSearchDocuments_POST_InvalidBodyReturns400(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
+
+	for _, body := range []string{
+		`{"search":"*","bogus":1}`,
+		`{"top":"ten"}`,
+		`{"skip":-5}`,
+		`{"skip":200000}`,
+	} {
+		rec := doRequest(t, r, http.MethodPost, "/indexes/movies/docs/search", body)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, rec.Code)
+		}
+	}
+	rec := doRequest(t, r, http.MethodPost, "/indexes/movies/docs/search", `{"search":"*","queryType":"simple","searchMode":"any"}`)
+	if rec.Code != http.StatusOK {
+		t.Errorf("documented but unimplemented properties should be accepted, status = %d", rec.Code)
 	}
 }
 
@@ -842,6 +1375,125 @@ func TestSearchDocuments_GET_NoNextLinkOnLastPage(t *testing.T) {
 	}
 }
 
+func TestSearchDocuments_GET_FilterIsMatchScoring(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
+	doRequest(t, r, http.MethodPost, "/indexes/movies/docs", `{"id":"1","title":"Luxury Hotel"}`)
+	doRequest(t, r, http.MethodPost, "/indexes/movies/docs", `{"id":"2","title":"Budget Motel"}`)
+	doRequest(t, r, http.MethodPost, "/indexes/movies/docs", `{"id":"3","title":"Luxury Motel"}`)
+
+	filter := url.QueryEscape("search.ismatchscoring('luxury', 'title') and not search.ismatch('motel', 'title')")
+	rec := doRequest(t, r, http.MethodGet, "/indexes/movies/docs?$filter="+filter, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Value []map[string]interface{} `json:"value"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	if len(body.Value) != 1 || body.Value[0]["id"] != "1" {
+		t.Fatalf("expected only doc 1, got %v", body.Value)
+	}
+	if score, _ := body.Value[0]["@search.score"].(float64); score <= 1.0 {
+		t.Errorf("@search.score = %v, want > 1.0", body.Value[0]["@search.score"])
+	}
+}
+
+func TestSearchDocuments_GET_FilterTypedLiterals(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/indexes", `{
+		"name": "hotels",
+		"fields": [
+			{"name": "id", "type": "Edm.String", "key": true},
+			{"name": "opened", "type": "Edm.DateTimeOffset"},
+			{"name": "score", "type": "Edm.Double"},
+			{"name": "tags", "type": "Collection(Edm.String)"}
+		]
+	}`)
+	batch := `{"value":[
+		{"@search.action":"upload","id":"1","opened":"2024-01-01T08:00:00+09:00","score":"INF","tags":["pool"]},
+		{"@search.action":"upload","id":"2","opened":"2023-12-31T23:30:00Z","score":1.5e2,"tags":["wifi","bar"]}
+	]}`
+	doRequest(t, r, http.MethodPost, "/indexes/hotels/docs/index", batch)
+
+	ids := func(filter string) []string {
+		t.Helper()
+		rec := doRequest(t, r, http.MethodGet, "/indexes/hotels/docs?$orderby=id&$filter="+url.QueryEscape(filter), "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body=%s", filter, rec.Code, rec.Body.String())
+		}
+		var body struct {
+			Value []map[string]interface{} `json:"value"`
+		}
+		_ = json.Unmarshal(rec.Body.Bytes(), &body)
+		var out []string
+		for _, d := range body.Value {
+			out = append(out, d["id"].(string))
+		}
+		return out
+	}
+
+	// 08:00+09:00 is 23:00Z on the previous day, i.e. earlier than doc 2.
+	if got := ids("opened lt 2023-12-31T23:15:00Z"); len(got) != 1 || got[0] != "1" {
+		t.Errorf("date comparison = %v, want [1]", got)
+	}
+	if got := ids("score gt 1e3"); len(got) != 1 || got[0] != "1" {
+		t.Errorf("INF should compare greater than finite values, got %v", got)
+	}
+	if got := ids("search.in(tags, 'wifi')"); len(got) != 1 || got[0] != "2" {
+		t.Errorf("search.in over collection = %v, want [2]", got)
+	}
+
+	rec := doRequest(t, r, http.MethodGet, "/indexes/hotels/docs?$filter="+url.QueryEscape("score eq 'high'"), "")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("type mismatch status = %d, want 400", rec.Code)
+	}
+}
+
+func TestSearchDocuments_GET_OrderByGeoDistance(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/indexes", `{
+		"name": "places",
+		"fields": [
+			{"name": "id", "type": "Edm.String", "key": true},
+			{"name": "location", "type": "Edm.GeographyPoint"}
+		]
+	}`)
+	batch := `{"value":[
+		{"@search.action":"upload","id":"osaka","location":{"type":"Point","coordinates":[135.50,34.69]}},
+		{"@search.action":"upload","id":"tokyo","location":{"type":"Point","coordinates":[139.69,35.69]}},
+		{"@search.action":"upload","id":"sapporo","location":{"type":"Point","coordinates":[141.35,43.06]}}
+	]}`
+	doRequest(t, r, http.MethodPost, "/indexes/places/docs/index", batch)
+
+	// Reference point: Yokohama.
+	orderby := url.QueryEscape("geo.distance(location, geography'POINT(139.64 35.44)') asc")
+	rec := doRequest(t, r, http.MethodGet, "/indexes/places/docs?$orderby="+orderby, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Value []map[string]interface{} `json:"value"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	var got []string
+	for _, d := range body.Value {
+		got = append(got, d["id"].(string))
+	}
+	if strings.Join(got, ",") != "tokyo,osaka,sapporo" {
+		t.Errorf("order = %v, want [tokyo osaka sapporo]", got)
+	}
+}
+
+func TestSearchDocuments_GET_OrderByMalformedReturns400(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
+	rec := doRequest(t, r, http.MethodGet, "/indexes/movies/docs?$orderby="+url.QueryEscape("title') DESC --"), "")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
+
 // --- Structured error responses ---
 
 // errCode extracts the "code" field from an Azure-structured error body.
@@ -924,7 +1576,6 @@ func TestErrorFormat_409_ResourceAlreadyExists(t *testing.T) {
 
 func TestErrorFormat_500_InternalServerError(t *testing.T) {
 	gin.SetMode(gin.TestMode)
-	t.Setenv("API_KEY", apiTestKey)
 
 	db, err := sql.Open("sqlite3", ":memory:")
 	if err != nil {
@@ -938,13 +1589,19 @@ func TestErrorFormat_500_InternalServerError(t *testing.T) {
 
 	idxRepo := infrastructure.NewSQLiteIndexRepository(db)
 	docRepo := infrastructure.NewSQLiteDocumentRepository(db)
+	availability := application.NewIndexAvailability(application.DefaultIndexDowntime)
 	apps := &application.AppServices{
 		IndexService:    application.NewIndexService(idxRepo, docRepo),
 		DocumentService: application.NewDocumentService(docRepo, idxRepo),
+		KeyService:      application.NewKeyService(apiTestKey, "", []string{apiTestQueryKey}),
 	}
+	apps.IndexService.Availability = availability
+	apps.DocumentService.Availability = availability
 	r := gin.New()
 	RegisterHealthCheck(r)
-	r.Use(ApiKeyAuthMiddleware())
+	r.Use(ApiKeyAuthMiddleware(apps.KeyService))
+	r.Use(APIVersionMiddleware())
+	r.Use(ContentNegotiationMiddleware())
 	RegisterRoutes(r, apps)
 
 	// Create an index while the DB is healthy, then close it to force errors.
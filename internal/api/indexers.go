@@ -0,0 +1,164 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"ai-search-emulator/internal/application"
+	"ai-search-emulator/internal/domain"
+)
+
+func registerIndexerRoutes(r *gin.Engine, svc *application.IndexerService) {
+	// インデクサー作成API
+	r.POST("/indexers", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			err400(c, "Failed to read request body")
+			return
+		}
+		ix, err := svc.CreateIndexer(c.Request.Context(), body)
+		if err != nil {
+			handleIndexerError(c, err)
+			return
+		}
+		respondIndexer(c, http.StatusCreated, svc, ix.Name)
+	})
+	// インデクサー一覧API
+	r.GET("/indexers", func(c *gin.Context) {
+		list, err := svc.ListIndexers(c.Request.Context())
+		if err != nil {
+			err500(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"@odata.context": requestBaseURL(c.Request) + "/$metadata#indexers",
+			"value":          list,
+		})
+	})
+	// インデクサー取得API
+	r.GET("/indexers/:name", func(c *gin.Context) {
+		respondIndexer(c, http.StatusOK, svc, c.Param("name"))
+	})
+	// インデクサー更新API（create-or-update）
+	r.PUT("/indexers/:name", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			err400(c, "Failed to read request body")
+			return
+		}
+		ix, created, err := svc.CreateOrUpdateIndexer(c.Request.Context(), c.Param("name"), body, accessCondition(c))
+		if err != nil {
+			handleIndexerError(c, err)
+			return
+		}
+		status := http.StatusOK
+		if created {
+			status = http.StatusCreated
+		}
+		respondIndexer(c, status, svc, ix.Name)
+	})
+	// インデクサー削除API
+	r.DELETE("/indexers/:name", func(c *gin.Context) {
+		if err := svc.DeleteIndexer(c.Request.Context(), c.Param("name"), accessCondition(c)); errors.Is(err, domain.ErrIndexerRunning) {
+			err409(c, "The indexer is running; it can be deleted once the run completes")
+			return
+		} else if err != nil {
+			handleIndexerError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+	// インデクサー実行API
+	r.POST("/indexers/:name/run", func(c *gin.Context) {
+		if err := svc.StartIndexer(c.Request.Context(), c.Param("name")); err != nil {
+			handleIndexerError(c, err)
+			return
+		}
+		c.Status(http.StatusAccepted)
+	})
+	// インデクサーリセットAPI
+	r.POST("/indexers/:name/reset", func(c *gin.Context) {
+		if err := svc.ResetIndexer(c.Request.Context(), c.Param("name")); err != nil {
+			handleIndexerError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+	// ドキュメント単位のリセットAPI
+	r.POST("/indexers/:name/resetdocs", func(c *gin.Context) {
+		var req struct {
+			DocumentKeys          []string `json:"documentKeys"`
+			DatasourceDocumentIds []string `json:"datasourceDocumentIds"`
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			err400(c, "Failed to read request body")
+			return
+		}
+		if len(body) > 0 {
+			if err := json.Unmarshal(body, &req); err != nil {
+				err400InvalidParam(c, "The request body is not valid JSON")
+				return
+			}
+		}
+		overwrite := strings.EqualFold(c.Query("overwrite"), "true")
+		if err := svc.ResetDocuments(c.Request.Context(), c.Param("name"), req.DocumentKeys, req.DatasourceDocumentIds, overwrite); err != nil {
+			handleIndexerError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+	// インデクサーステータス取得API
+	r.GET("/indexers/:name/status", func(c *gin.Context) {
+		status, err := svc.GetIndexerStatus(c.Request.Context(), c.Param("name"))
+		if err != nil {
+			handleIndexerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"@odata.context":   requestBaseURL(c.Request) + "/$metadata#" + metadataNamespace(requestAPIVersion(c)) + ".IndexerExecutionInfo",
+			"name":             status.Name,
+			"status":           status.Status,
+			"lastResult":       status.LastResult,
+			"executionHistory": status.ExecutionHistory,
+			"limits":           status.Limits,
+			"currentState":     status.CurrentState,
+		})
+	})
+}
+
+// respondIndexer writes the stored indexer definition.
+func respondIndexer(c *gin.Context, status int, svc *application.IndexerService, name string) {
+	ix, err := svc.GetIndexer(c.Request.Context(), name)
+	if err != nil {
+		handleIndexerError(c, err)
+		return
+	}
+	if etag, ok := ix["@odata.etag"].(string); ok {
+		c.Header("ETag", etag)
+	}
+	c.JSON(status, ix)
+}
+
+func handleIndexerError(c *gin.Context, err error) {
+	if errors.Is(err, domain.ErrIndexerNotFound) {
+		err404(c, "Indexer not found")
+	} else if errors.Is(err, domain.ErrIndexerAlreadyExists) {
+		err409(c, "Indexer already exists")
+	} else if errors.Is(err, domain.ErrIndexerRunning) {
+		err409(c, "Another indexer invocation is currently in progress; concurrent invocations are not allowed")
+	} else if errors.Is(err, domain.ErrPreconditionFailed) {
+		err412(c, "The indexer was modified or does not match the If-Match/If-None-Match condition")
+	} else if errors.Is(err, domain.ErrInvalidName) {
+		err400InvalidName(c, err.Error())
+	} else if errors.Is(err, domain.ErrInvalidIndexer) {
+		err400InvalidParam(c, err.Error())
+	} else {
+		err500(c, err)
+	}
+}
@@ -0,0 +1,438 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// setupIndexerRouter creates the movies index and a blob data source over a
+// local directory holding the files "a" and "b", whose names are valid keys.
+func setupIndexerRouter(t *testing.T) http.Handler {
+	t.Helper()
+	r := setupRouter(t)
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "films"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b"} {
+		if err := os.WriteFile(filepath.Join(dir, "films", name), []byte("film "+name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	doRequest(t, r, http.MethodPost, "/indexes", `{"name":"films","fields":[
+		{"name":"metadata_storage_name","type":"Edm.String","key":true},
+		{"name":"content","type":"Edm.String","searchable":true}]}`)
+	ds, _ := json.Marshal(map[string]interface{}{
+		"name":        "films",
+		"type":        "azureblob",
+		"credentials": map[string]string{"connectionString": "LocalPath=" + dir},
+		"container":   map[string]string{"name": "films"},
+	})
+	if rec := doRequest(t, r, http.MethodPost, "/datasources", string(ds)); rec.Code != http.StatusCreated {
+		t.Fatalf("create data source: %d %s", rec.Code, rec.Body.String())
+	}
+	return ODataPathRewriter(r)
+}
+
+// waitForIndexer polls the status of an indexer until its run has
+// finished, as clients of the asynchronous run API do.
+func waitForIndexer(t *testing.T, h http.Handler, name string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rec := doRequest(t, h, http.MethodGet, "/indexers/"+name+"/status", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status of %s = %d, body=%s", name, rec.Code, rec.Body.String())
+		}
+		var st struct {
+			LastResult *struct {
+				Status string `json:"status"`
+			} `json:"lastResult"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &st); err != nil {
+			t.Fatal(err)
+		}
+		if st.LastResult == nil || st.LastResult.Status != "inProgress" {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the run of %s did not finish", name)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+const apiTestIndexer = `{"name":"films-ix","dataSourceName":"films","targetIndexName":"films"}`
+
+func TestIndexers_CreateRunAndStatus(t *testing.T) {
+	h := setupIndexerRouter(t)
+
+	rec := doRequest(t, h, http.MethodPost, "/indexers", apiTestIndexer)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	waitForIndexer(t, h, "films-ix")
+	if rec = doRequest(t, h, http.MethodGet, "/indexes/films/docs/a", ""); rec.Code != http.StatusOK {
+		t.Fatalf("document a should be indexed on create: %d %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"content":"film a"`) {
+		t.Errorf("document = %s", rec.Body.String())
+	}
+
+	if rec = doRequest(t, h, http.MethodPost, "/indexers('films-ix')/search.run", ""); rec.Code != http.StatusAccepted {
+		t.Fatalf("run status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	waitForIndexer(t, h, "films-ix")
+	rec = doRequest(t, h, http.MethodGet, "/indexers/films-ix/search.status", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var st struct {
+		Name       string `json:"name"`
+		Status     string `json:"status"`
+		LastResult struct {
+			Status         string        `json:"status"`
+			ItemsProcessed int           `json:"itemsProcessed"`
+			ItemsFailed    int           `json:"itemsFailed"`
+			Errors         []interface{} `json:"errors"`
+			Warnings       []interface{} `json:"warnings"`
+		} `json:"lastResult"`
+		ExecutionHistory []interface{} `json:"executionHistory"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &st); err != nil {
+		t.Fatal(err)
+	}
+	// Unchanged blobs are below the high-water mark and skipped.
+	if st.Name != "films-ix" || st.Status != "running" || st.LastResult.Status != "success" ||
+		st.LastResult.ItemsProcessed != 0 || st.LastResult.ItemsFailed != 0 || len(st.ExecutionHistory) != 2 {
+		t.Errorf("status = %+v", st)
+	}
+	if st.LastResult.Errors == nil || st.LastResult.Warnings == nil {
+		t.Errorf("errors and warnings should be empty arrays, body=%s", rec.Body.String())
+	}
+}
+
+func TestIndexers_RunIsAsynchronous(t *testing.T) {
+	release := make(chan struct{})
+	var once sync.Once
+	unblock := func() { once.Do(func() { close(release) }) }
+	// The skill holds every run until release is closed.
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		var req struct {
+			Values []struct {
+				RecordID string `json:"recordId"`
+			} `json:"values"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		out := []map[string]interface{}{}
+		for _, v := range req.Values {
+			out = append(out, map[string]interface{}{"recordId": v.RecordID, "data": map[string]string{"echo": "ok"}})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"values": out})
+	}))
+	defer mock.Close()
+	defer unblock()
+
+	h := setupIndexerRouter(t)
+	ss, _ := json.Marshal(map[string]interface{}{
+		"name": "slow",
+		"skills": []map[string]interface{}{{
+			"@odata.type": "#Microsoft.Skills.Custom.WebApiSkill",
+			"uri":         mock.URL,
+			"inputs":      []map[string]string{{"name": "text", "source": "/document/content"}},
+			"outputs":     []map[string]string{{"name": "echo"}},
+		}},
+	})
+	if rec := doRequest(t, h, http.MethodPost, "/skillsets", string(ss)); rec.Code != http.StatusCreated {
+		t.Fatalf("create skillset: %d %s", rec.Code, rec.Body.String())
+	}
+	ix := `{"name":"films-ix","dataSourceName":"films","targetIndexName":"films","skillsetName":"slow"}`
+	if rec := doRequest(t, h, http.MethodPost, "/indexers", ix); rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	for i := 0; i < 3; i++ {
+		rec := doRequest(t, h, http.MethodGet, "/indexers/films-ix/search.status", "")
+		if !strings.Contains(rec.Body.String(), `"lastResult":{"status":"inProgress"`) {
+			t.Fatalf("status during the run = %s", rec.Body.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if rec := doRequest(t, h, http.MethodPost, "/indexers/films-ix/search.run", ""); rec.Code != http.StatusConflict {
+		t.Errorf("run while running: status = %d, want 409", rec.Code)
+	}
+
+	unblock()
+	waitForIndexer(t, h, "films-ix")
+	rec := doRequest(t, h, http.MethodGet, "/indexers/films-ix/search.status", "")
+	if !strings.Contains(rec.Body.String(), `"lastResult":{"status":"success"`) || !strings.Contains(rec.Body.String(), `"itemsProcessed":2`) {
+		t.Errorf("status after the run = %s", rec.Body.String())
+	}
+
+	if rec := doRequest(t, h, http.MethodPost, "/indexers/films-ix/search.run", ""); rec.Code != http.StatusAccepted {
+		t.Fatalf("run status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	waitForIndexer(t, h, "films-ix")
+}
+
+func TestIndexers_ResetAndResetDocs(t *testing.T) {
+	h := setupIndexerRouter(t)
+	doRequest(t, h, http.MethodPost, "/indexers", apiTestIndexer)
+	waitForIndexer(t, h, "films-ix")
+
+	rec := doRequest(t, h, http.MethodPost, "/indexers/films-ix/search.resetdocs?overwrite=true", `{"documentKeys":["b"]}`)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("resetdocs status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	rec = doRequest(t, h, http.MethodGet, "/indexers/films-ix/status", "")
+	if !strings.Contains(rec.Body.String(), `"mode":"indexingResetDocs"`) {
+		t.Errorf("currentState should list the reset documents, body=%s", rec.Body.String())
+	}
+	if rec = doRequest(t, h, http.MethodPost, "/indexers/films-ix/reset", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("reset status = %d", rec.Code)
+	}
+	rec = doRequest(t, h, http.MethodGet, "/indexers/films-ix/status", "")
+	if !strings.Contains(rec.Body.String(), `"lastResult":{"status":"reset"`) {
+		t.Errorf("lastResult should be the reset, body=%s", rec.Body.String())
+	}
+}
+
+func TestIndexers_CRUDAndErrors(t *testing.T) {
+	h := setupIndexerRouter(t)
+
+	if rec := doRequest(t, h, http.MethodPut, "/indexers/films-ix", apiTestIndexer); rec.Code != http.StatusCreated {
+		t.Fatalf("put-create status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	waitForIndexer(t, h, "films-ix")
+	if rec := doRequest(t, h, http.MethodPut, "/indexers/films-ix", apiTestIndexer); rec.Code != http.StatusOK {
+		t.Errorf("put-update status = %d", rec.Code)
+	}
+	waitForIndexer(t, h, "films-ix")
+	if rec := doRequest(t, h, http.MethodPost, "/indexers", apiTestIndexer); rec.Code != http.StatusConflict {
+		t.Errorf("duplicate status = %d, want 409", rec.Code)
+	}
+	rec := doRequest(t, h, http.MethodGet, "/indexers", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"films-ix"`) {
+		t.Errorf("list status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+
+	rec = doRequest(t, h, http.MethodPost, "/indexers", `{"name":"other","dataSourceName":"missing","targetIndexName":"films"}`)
+	if rec.Code != http.StatusBadRequest || errCode(t, rec) != "InvalidRequestParameter" {
+		t.Errorf("missing data source: status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	if rec = doRequest(t, h, http.MethodPost, "/indexers/missing/run", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("run missing status = %d, want 404", rec.Code)
+	}
+
+	if rec = doRequest(t, h, http.MethodDelete, "/indexers('films-ix')", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("delete status = %d", rec.Code)
+	}
+	if rec = doRequest(t, h, http.MethodGet, "/indexers/films-ix/status", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("status after delete = %d, want 404", rec.Code)
+	}
+}
+
+func TestIndexers_SQLiteChangeAndDeletionDetection(t *testing.T) {
+	r := setupRouter(t)
+	h := ODataPathRewriter(r)
+	path := filepath.Join(t.TempDir(), "hotels.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE hotels (id TEXT PRIMARY KEY, title TEXT, version INTEGER, deleted INTEGER);
+		INSERT INTO hotels VALUES ('1', 'One', 1, 0), ('2', 'Two', 2, 0);`); err != nil {
+		t.Fatal(err)
+	}
+	doRequest(t, h, http.MethodPost, "/indexes", apiTestSchema)
+	ds, _ := json.Marshal(map[string]interface{}{
+		"name":        "hotels",
+		"type":        "azuresql",
+		"credentials": map[string]string{"connectionString": "Data Source=" + path},
+		"container":   map[string]string{"name": "hotels"},
+		"dataChangeDetectionPolicy": map[string]string{
+			"@odata.type":             "#Microsoft.Azure.Search.HighWaterMarkChangeDetectionPolicy",
+			"highWaterMarkColumnName": "version",
+		},
+		"dataDeletionDetectionPolicy": map[string]string{
+			"@odata.type":           "#Microsoft.Azure.Search.SoftDeleteColumnDeletionDetectionPolicy",
+			"softDeleteColumnName":  "deleted",
+			"softDeleteMarkerValue": "true",
+		},
+	})
+	if rec := doRequest(t, h, http.MethodPost, "/datasources", string(ds)); rec.Code != http.StatusCreated {
+		t.Fatalf("create data source: %d %s", rec.Code, rec.Body.String())
+	}
+	ix := `{"name":"hotels-ix","dataSourceName":"hotels","targetIndexName":"movies","schedule":{"interval":"PT5M"}}`
+	if rec := doRequest(t, h, http.MethodPost, "/indexers", ix); rec.Code != http.StatusCreated {
+		t.Fatalf("create indexer: %d %s", rec.Code, rec.Body.String())
+	}
+	waitForIndexer(t, h, "hotels-ix")
+	if rec := doRequest(t, h, http.MethodGet, "/indexes/movies/docs/$count", ""); rec.Body.String() != "2" {
+		t.Fatalf("count after first run = %s, want 2", rec.Body.String())
+	}
+
+	if _, err := db.Exec(`UPDATE hotels SET deleted = 1, version = 3 WHERE id = '1'`); err != nil {
+		t.Fatal(err)
+	}
+	doRequest(t, h, http.MethodPost, "/indexers/hotels-ix/search.run", "")
+	waitForIndexer(t, h, "hotels-ix")
+	if rec := doRequest(t, h, http.MethodGet, "/indexes/movies/docs/1", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("soft-deleted row: status = %d, want 404", rec.Code)
+	}
+	rec := doRequest(t, h, http.MethodGet, "/indexers/hotels-ix/search.status", "")
+	if !strings.Contains(rec.Body.String(), `"itemsProcessed":1`) || !strings.Contains(rec.Body.String(), `\"highWaterMark\":3`) {
+		t.Errorf("status = %s", rec.Body.String())
+	}
+}
+
+func TestIndexers_SQLiteDateTimeHighWaterMark(t *testing.T) {
+	r := setupRouter(t)
+	h := ODataPathRewriter(r)
+	path := filepath.Join(t.TempDir(), "hotels.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE hotels (id TEXT PRIMARY KEY, title TEXT, modified DATETIME);
+		INSERT INTO hotels VALUES ('1', 'One', '2026-01-05 09:00:00'), ('2', 'Two', '2026-01-05 10:00:00');`); err != nil {
+		t.Fatal(err)
+	}
+	doRequest(t, h, http.MethodPost, "/indexes", apiTestSchema)
+	ds, _ := json.Marshal(map[string]interface{}{
+		"name":        "hotels",
+		"type":        "azuresql",
+		"credentials": map[string]string{"connectionString": "Data Source=" + path},
+		"container":   map[string]string{"name": "hotels"},
+		"dataChangeDetectionPolicy": map[string]string{
+			"@odata.type":             "#Microsoft.Azure.Search.HighWaterMarkChangeDetectionPolicy",
+			"highWaterMarkColumnName": "modified",
+		},
+	})
+	if rec := doRequest(t, h, http.MethodPost, "/datasources", string(ds)); rec.Code != http.StatusCreated {
+		t.Fatalf("create data source: %d %s", rec.Code, rec.Body.String())
+	}
+	ix := `{"name":"hotels-ix","dataSourceName":"hotels","targetIndexName":"movies"}`
+	if rec := doRequest(t, h, http.MethodPost, "/indexers", ix); rec.Code != http.StatusCreated {
+		t.Fatalf("create indexer: %d %s", rec.Code, rec.Body.String())
+	}
+	waitForIndexer(t, h, "hotels-ix")
+	rec := doRequest(t, h, http.MethodGet, "/indexers/hotels-ix/search.status", "")
+	if !strings.Contains(rec.Body.String(), `\"highWaterMark\":\"2026-01-05T10:00:00Z\"`) {
+		t.Fatalf("status after first run = %s", rec.Body.String())
+	}
+
+	// A row updated later the same day is past the mark; the other is not.
+	if _, err := db.Exec(`UPDATE hotels SET title = 'Uno', modified = '2026-01-05 15:30:00' WHERE id = '1'`); err != nil {
+		t.Fatal(err)
+	}
+	doRequest(t, h, http.MethodPost, "/indexers/hotels-ix/search.run", "")
+	waitForIndexer(t, h, "hotels-ix")
+	rec = doRequest(t, h, http.MethodGet, "/indexers/hotels-ix/search.status", "")
+	if !strings.Contains(rec.Body.String(), `"itemsProcessed":1`) || !strings.Contains(rec.Body.String(), `\"highWaterMark\":\"2026-01-05T15:30:00Z\"`) {
+		t.Errorf("status after update = %s", rec.Body.String())
+	}
+	if rec := doRequest(t, h, http.MethodGet, "/indexes/movies/docs/1", ""); !strings.Contains(rec.Body.String(), "Uno") {
+		t.Errorf("updated row was not reindexed: %s", rec.Body.String())
+	}
+}
+
+func TestIndexers_BlobRewrittenWithinTheSameSecond(t *testing.T) {
+	r := setupRouter(t)
+	dir := t.TempDir()
+	blob := filepath.Join(dir, "films", "a")
+	base := time.Date(2026, 1, 1, 0, 0, 0, 100*int(time.Millisecond), time.UTC)
+	write := func(content string, modified time.Time) {
+		t.Helper()
+		if err := os.WriteFile(blob, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(blob, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(blob), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	write("first", base)
+
+	doRequest(t, r, http.MethodPost, "/indexes", `{"name":"films","fields":[
+		{"name":"metadata_storage_name","type":"Edm.String","key":true},
+		{"name":"content","type":"Edm.String","searchable":true}]}`)
+	ds, _ := json.Marshal(map[string]interface{}{
+		"name": "films", "type": "azureblob",
+		"credentials": map[string]string{"connectionString": "LocalPath=" + dir},
+		"container":   map[string]string{"name": "films"},
+	})
+	doRequest(t, r, http.MethodPost, "/datasources", string(ds))
+	if rec := doRequest(t, r, http.MethodPost, "/indexers", apiTestIndexer); rec.Code != http.StatusCreated {
+		t.Fatalf("create indexer: %d %s", rec.Code, rec.Body.String())
+	}
+	waitForIndexer(t, r, "films-ix")
+
+	write("second", base.Add(500*time.Millisecond))
+	if rec := doRequest(t, r, http.MethodPost, "/indexers/films-ix/run", ""); rec.Code != http.StatusAccepted {
+		t.Fatalf("run: %d %s", rec.Code, rec.Body.String())
+	}
+	waitForIndexer(t, r, "films-ix")
+	if rec := doRequest(t, r, http.MethodGet, "/indexes/films/docs/a", ""); !strings.Contains(rec.Body.String(), `"content":"second"`) {
+		t.Errorf("a blob rewritten within the same second should be reindexed: %s", rec.Body.String())
+	}
+}
+
+func TestIndexers_DelimitedTextParsing(t *testing.T) {
+	r := setupRouter(t)
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "hotels"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "hotels", "hotels.csv"), []byte("id;name\n1;Grand\n2;Inn\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	doRequest(t, r, http.MethodPost, "/indexes", `{"name":"hotels","fields":[
+		{"name":"key","type":"Edm.String","key":true},
+		{"name":"id","type":"Edm.String"},
+		{"name":"name","type":"Edm.String","searchable":true},
+		{"name":"metadata_storage_name","type":"Edm.String"}]}`)
+	ds, _ := json.Marshal(map[string]interface{}{
+		"name":        "hotels",
+		"type":        "azureblob",
+		"credentials": map[string]string{"connectionString": "LocalPath=" + dir},
+		"container":   map[string]string{"name": "hotels"},
+	})
+	doRequest(t, r, http.MethodPost, "/datasources", string(ds))
+	h := ODataPathRewriter(r)
+
+	ix := `{"name":"hotels-ix","dataSourceName":"hotels","targetIndexName":"hotels",
+		"parameters":{"configuration":{"parsingMode":"delimitedText","delimitedTextDelimiter":";","firstLineContainsHeaders":true}}}`
+	if rec := doRequest(t, h, http.MethodPost, "/indexers", ix); rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	waitForIndexer(t, h, "hotels-ix")
+	rec := doRequest(t, h, http.MethodGet, "/indexes/hotels/docs?search=*&$orderby=id", "")
+	var body struct {
+		Value []map[string]interface{} `json:"value"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	if len(body.Value) != 2 || body.Value[0]["name"] != "Grand" || body.Value[1]["metadata_storage_name"] != "hotels.csv" {
+		t.Fatalf("documents = %s", rec.Body.String())
+	}
+	if body.Value[0]["key"] == body.Value[1]["key"] {
+		t.Errorf("rows should get distinct generated keys: %s", rec.Body.String())
+	}
+
+	bad := `{"name":"bad-ix","dataSourceName":"hotels","targetIndexName":"hotels","parameters":{"configuration":{"parsingMode":"xml"}}}`
+	if rec := doRequest(t, h, http.MethodPost, "/indexers", bad); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown parsingMode: status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"ai-search-emulator/internal/application"
+	"ai-search-emulator/internal/domain"
+)
+
+// registerKeyRoutes adds api-key management endpoints modelled on Azure's
+// management plane (listAdminKeys, regenerateAdminKey, listQueryKeys,
+// createQueryKey, deleteQueryKey). They require an admin key.
+func registerKeyRoutes(r *gin.Engine, keys *application.KeyService) {
+	// 管理キー取得API
+	r.GET("/adminkeys", func(c *gin.Context) {
+		c.JSON(http.StatusOK, keys.GetAdminKeys(c.Request.Context()))
+	})
+	// 管理キー再生成API (keyKind: primary | secondary)
+	r.POST("/adminkeys/:keyKind/regenerate", func(c *gin.Context) {
+		admin, err := keys.RegenerateAdminKey(c.Request.Context(), c.Param("keyKind"))
+		if err != nil {
+			if errors.Is(err, domain.ErrInvalidKeyKind) {
+				err400InvalidParam(c, err.Error())
+			} else {
+				err500(c, err)
+			}
+			return
+		}
+		c.JSON(http.StatusOK, admin)
+	})
+	// クエリキー一覧API
+	r.GET("/querykeys", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"value": keys.ListQueryKeys(c.Request.Context())})
+	})
+	// クエリキー作成API
+	r.POST("/querykeys/:name", func(c *gin.Context) {
+		q, err := keys.CreateQueryKey(c.Request.Context(), c.Param("name"))
+		if err != nil {
+			if errors.Is(err, domain.ErrQueryKeyLimit) {
+				err400NotAllowed(c, err.Error())
+			} else {
+				err500(c, err)
+			}
+			return
+		}
+		c.JSON(http.StatusOK, q)
+	})
+	// クエリキー削除API
+	r.DELETE("/querykeys/:key", func(c *gin.Context) {
+		if err := keys.DeleteQueryKey(c.Request.Context(), c.Param("key")); err != nil {
+			if errors.Is(err, domain.ErrKeyNotFound) {
+				err404(c, "Query key not found")
+			} else {
+				err500(c, err)
+			}
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// doKeyRequest sends a request authenticated with the given api-key.
+func doKeyRequest(t *testing.T, r http.Handler, key, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	var req *http.Request
+	if body != "" {
+		req = httptest.NewRequest(method, withAPIVersion(path), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req = httptest.NewRequest(method, withAPIVersion(path), nil)
+	}
+	req.Header.Set("api-key", key)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestQueryKey_CanOnlyReadDocuments(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/indexes", apiTestSchema)
+	doRequest(t, r, http.MethodPost, "/indexes/movies/docs/index", `{"value":[{"@search.action":"upload","id":"1","title":"a"}]}`)
+
+	for _, tc := range []struct{ method, path, body string }{
+		{http.MethodGet, "/indexes/movies/docs?search=*", ""},
+		{http.MethodPost, "/indexes/movies/docs/search", `{"search":"*"}`},
+		{http.MethodGet, "/indexes/movies/docs/1", ""},
+		{http.MethodGet, "/indexes/movies/docs/$count", ""},
+	} {
+		if rec := doKeyRequest(t, r, apiTestQueryKey, tc.method, tc.path, tc.body); rec.Code != http.StatusOK {
+			t.Errorf("%s %s: status = %d, want 200", tc.method, tc.path, rec.Code)
+		}
+	}
+
+	for _, tc := range []struct{ method, path, body string }{
+		{http.MethodGet, "/indexes", ""},
+		{http.MethodGet, "/indexes/movies", ""},
+		{http.MethodPost, "/indexes", apiTestSchema},
+		{http.MethodDelete, "/indexes/movies", ""},
+		{http.MethodPost, "/indexes/movies/docs/index", `{"value":[{"@search.action":"delete","id":"1"}]}`},
+		{http.MethodPost, "/indexes/movies/docs", `{"id":"2","title":"b"}`},
+		{http.MethodGet, "/querykeys", ""},
+	} {
+		rec := doKeyRequest(t, r, apiTestQueryKey, tc.method, tc.path, tc.body)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("%s %s: status = %d, want 403", tc.method, tc.path, rec.Code)
+			continue
+		}
+		if code := errCode(t, rec); code != "Forbidden" {
+			t.Errorf("%s %s: error.code = %q, want Forbidden", tc.method, tc.path, code)
+		}
+	}
+}
+
+func TestQueryKeys_CreateListDelete(t *testing.T) {
+	r := setupRouter(t)
+
+	rec := doRequest(t, r, http.MethodPost, "/querykeys/frontend", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create: status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var created struct{ Name, Key string }
+	_ = json.Unmarshal(rec.Body.Bytes(), &created)
+	if created.Name != "frontend" || created.Key == "" {
+		t.Fatalf("created = %+v", created)
+	}
+
+	rec = doRequest(t, r, http.MethodGet, "/querykeys", "")
+	var list struct {
+		Value []struct{ Name, Key string } `json:"value"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &list)
+	if len(list.Value) != 2 {
+		t.Errorf("list = %s, want the configured and the created key", rec.Body.String())
+	}
+
+	if rec := doKeyRequest(t, r, created.Key, http.MethodGet, "/indexes/movies/docs/$count", ""); rec.Code == http.StatusUnauthorized {
+		t.Error("a created query key should authenticate")
+	}
+	if rec := doRequest(t, r, http.MethodDelete, "/querykeys/"+created.Key, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: status = %d", rec.Code)
+	}
+	if rec := doKeyRequest(t, r, created.Key, http.MethodGet, "/indexes/movies/docs/$count", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("deleted key: status = %d, want 401", rec.Code)
+	}
+	if rec := doRequest(t, r, http.MethodDelete, "/querykeys/"+created.Key, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("delete twice: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestAdminKeys_Regenerate(t *testing.T) {
+	r := setupRouter(t)
+
+	rec := doRequest(t, r, http.MethodGet, "/adminkeys", "")
+	var keys struct {
+		PrimaryKey   string `json:"primaryKey"`
+		SecondaryKey string `json:"secondaryKey"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &keys)
+	if keys.PrimaryKey != apiTestKey {
+		t.Fatalf("adminkeys = %s", rec.Body.String())
+	}
+
+	rec = doRequest(t, r, http.MethodPost, "/adminkeys/secondary/regenerate", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("regenerate secondary: status = %d", rec.Code)
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &keys)
+	if keys.SecondaryKey == "" {
+		t.Fatal("a secondary key should have been generated")
+	}
+
+	// Rotate: switch to the secondary key, then regenerate the primary.
+	if rec := doKeyRequest(t, r, keys.SecondaryKey, http.MethodPost, "/adminkeys/primary/regenerate", ""); rec.Code != http.StatusOK {
+		t.Fatalf("regenerate primary: status = %d", rec.Code)
+	}
+	if rec := doRequest(t, r, http.MethodGet, "/indexes", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("old primary key: status = %d, want 401", rec.Code)
+	}
+	if rec := doKeyRequest(t, r, keys.SecondaryKey, http.MethodPost, "/adminkeys/tertiary/regenerate", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown key kind: status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"encoding/xml"
+	"strings"
+
+	"ai-search-emulator/internal/application"
+)
+
+// CSDL (OData Common Schema Definition Language) documents served by the
+// $metadata endpoints.
+
+const (
+	edmxNamespace = "http://docs.oasis-open.org/odata/ns/edmx"
+	edmNamespace  = "http://docs.oasis-open.org/odata/ns/edm"
+)
+
+type csdlEdmx struct {
+	XMLName      xml.Name         `xml:"edmx:Edmx"`
+	Version      string           `xml:"Version,attr"`
+	XMLNS        string           `xml:"xmlns:edmx,attr"`
+	DataServices csdlDataServices `xml:"edmx:DataServices"`
+}
+
+type csdlDataServices struct {
+	Schema csdlSchema `xml:"Schema"`
+}
+
+type csdlSchema struct {
+	Namespace    string              `xml:"Namespace,attr"`
+	XMLNS        string              `xml:"xmlns,attr"`
+	EntityTypes  []csdlStructured    `xml:"EntityType"`
+	ComplexTypes []csdlStructured    `xml:"ComplexType"`
+	Container    csdlEntityContainer `xml:"EntityContainer"`
+}
+
+type csdlStructured struct {
+	Name       string         `xml:"Name,attr"`
+	Key        *csdlKey       `xml:"Key,omitempty"`
+	Properties []csdlProperty `xml:"Property"`
+}
+
+type csdlKey struct {
+	PropertyRef csdlPropertyRef `xml:"PropertyRef"`
+}
+
+type csdlPropertyRef struct {
+	Name string `xml:"Name,attr"`
+}
+
+type csdlProperty struct {
+	Name     string `xml:"Name,attr"`
+	Type     string `xml:"Type,attr"`
+	Nullable string `xml:"Nullable,attr,omitempty"`
+}
+
+type csdlEntityContainer struct {
+	Name       string          `xml:"Name,attr"`
+	EntitySets []csdlEntitySet `xml:"EntitySet"`
+}
+
+type csdlEntitySet struct {
+	Name       string `xml:"Name,attr"`
+	EntityType string `xml:"EntityType,attr"`
+}
+
+// metadataNamespace derives the CSDL namespace from the api-version, as Azure
+// does (e.g. Microsoft.Azure.Search.V2024_07_01).
+func metadataNamespace(apiVersion string) string {
+	if apiVersion == "" {
+		return "Microsoft.Azure.Search"
+	}
+	return "Microsoft.Azure.Search.V" + strings.NewReplacer("-", "_").Replace(apiVersion)
+}
+
+// renderCSDL marshals a schema into a complete CSDL document.
+func renderCSDL(schema csdlSchema) ([]byte, error) {
+	schema.XMLNS = edmNamespace
+	doc := csdlEdmx{
+		Version:      "4.0",
+		XMLNS:        edmxNamespace,
+		DataServices: csdlDataServices{Schema: schema},
+	}
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// serviceMetadata describes the service-level entity model: index
+// definitions and their statistics.
+func serviceMetadata(apiVersion string) ([]byte, error) {
+	ns := metadataNamespace(apiVersion)
+	prop := func(name, typ string) csdlProperty { return csdlProperty{Name: name, Type: typ} }
+	return renderCSDL(csdlSchema{
+		Namespace: ns,
+		EntityTypes: []csdlStructured{{
+			Name: "Index",
+			Key:  &csdlKey{PropertyRef: csdlPropertyRef{Name: "name"}},
+			Properties: []csdlProperty{
+				{Name: "name", Type: "Edm.String", Nullable: "false"},
+				prop("fields", "Collection("+ns+".Field)"),
+				prop("suggesters", "Collection("+ns+".Suggester)"),
+				prop("analyzers", "Collection(Edm.Untyped)"),
+				prop("vectorSearch", "Edm.Untyped"),
+				prop("semantic", "Edm.Untyped"),
+			},
+		}},
+		ComplexTypes: []csdlStructured{
+			{
+				Name: "Field",
+				Properties: []csdlProperty{
+					{Name: "name", Type: "Edm.String", Nullable: "false"},
+					{Name: "type", Type: "Edm.String", Nullable: "false"},
+					prop("key", "Edm.Boolean"),
+					prop("retrievable", "Edm.Boolean"),
+					prop("stored", "Edm.Boolean"),
+					prop("searchable", "Edm.Boolean"),
+					prop("filterable", "Edm.Boolean"),
+					prop("sortable", "Edm.Boolean"),
+					prop("facetable", "Edm.Boolean"),
+					prop("analyzer", "Edm.String"),
+					prop("indexAnalyzer", "Edm.String"),
+					prop("searchAnalyzer", "Edm.String"),
+					prop("normalizer", "Edm.String"),
+					prop("dimensions", "Edm.Int32"),
+					prop("vectorSearchProfile", "Edm.String"),
+					prop("fields", "Collection("+ns+".Field)"),
+				},
+			},
+			{
+				Name: "Suggester",
+				Properties: []csdlProperty{
+					{Name: "name", Type: "Edm.String", Nullable: "false"},
+					prop("searchMode", "Edm.String"),
+					prop("sourceFields", "Collection(Edm.String)"),
+				},
+			},
+			{
+				Name: "IndexStatistics",
+				Properties: []csdlProperty{
+					{Name: "documentCount", Type: "Edm.Int64", Nullable: "false"},
+					{Name: "storageSize", Type: "Edm.Int64", Nullable: "false"},
+				},
+			},
+		},
+		Container: csdlEntityContainer{
+			Name:       "SearchServiceContainer",
+			EntitySets: []csdlEntitySet{{Name: "indexes", EntityType: ns + ".Index"}},
+		},
+	})
+}
+
+// indexMetadata describes the documents of one index. Complex fields become
+// complex types named after their field path.
+func indexMetadata(apiVersion string, model *application.DocumentModel) ([]byte, error) {
+	ns := metadataNamespace(apiVersion)
+	var complexTypes []csdlStructured
+	doc := csdlStructured{
+		Name:       "Document",
+		Key:        &csdlKey{PropertyRef: csdlPropertyRef{Name: model.Key}},
+		Properties: csdlProperties(ns, "", model.Key, model.Fields, &complexTypes),
+	}
+	return renderCSDL(csdlSchema{
+		Namespace:    ns,
+		EntityTypes:  []csdlStructured{doc},
+		ComplexTypes: complexTypes,
+		Container: csdlEntityContainer{
+			Name:       "SearchIndexContainer",
+			EntitySets: []csdlEntitySet{{Name: "docs", EntityType: ns + ".Document"}},
+		},
+	})
+}
+
+// csdlProperties converts fields to CSDL properties, appending a complex type
+// to complexTypes for every complex field.
+func csdlProperties(ns, prefix, key string, fields []application.ModelField, complexTypes *[]csdlStructured) []csdlProperty {
+	props := make([]csdlProperty, 0, len(fields))
+	for _, f := range fields {
+		typ := f.Type
+		if elem := strings.TrimSuffix(strings.TrimPrefix(typ, "Collection("), ")"); elem == "Edm.ComplexType" {
+			typeName := prefix + f.Name
+			*complexTypes = append(*complexTypes, csdlStructured{
+				Name:       typeName,
+				Properties: csdlProperties(ns, typeName+"_", "", f.Fields, complexTypes),
+			})
+			typ = strings.Replace(typ, "Edm.ComplexType", ns+"."+typeName, 1)
+		}
+		p := csdlProperty{Name: f.Name, Type: typ}
+		if f.Name == key {
+			p.Nullable = "false"
+		}
+		props = append(props, p)
+	}
+	return props
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestServiceMetadata_ServesCSDL(t *testing.T) {
+	r := setupRouter(t)
+	rec := doRequest(t, r, http.MethodGet, "/$metadata?api-version=2024-07-01", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/xml") {
+		t.Errorf("Content-Type = %q, want application/xml", ct)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`Namespace="Microsoft.Azure.Search.V2024_07_01"`,
+		`<EntityType Name="Index">`,
+		`<EntitySet Name="indexes" EntityType="Microsoft.Azure.Search.V2024_07_01.Index">`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metadata should contain %s", want)
+		}
+	}
+	if err := xml.Unmarshal(rec.Body.Bytes(), new(struct{})); err != nil {
+		t.Errorf("metadata is not well-formed XML: %v", err)
+	}
+}
+
+func TestIndexMetadata_DescribesDocuments(t *testing.T) {
+	r := setupRouter(t)
+	schema := `{"name":"hotels","fields":[
+		{"name":"id","type":"Edm.String","key":true},
+		{"name":"rating","type":"Edm.Double"},
+		{"name":"tags","type":"Collection(Edm.String)"},
+		{"name":"address","type":"Edm.ComplexType","fields":[{"name":"city","type":"Edm.String"}]},
+		{"name":"rooms","type":"Collection(Edm.ComplexType)","fields":[{"name":"beds","type":"Edm.Int32"}]}
+	]}`
+	if rec := doRequest(t, r, http.MethodPost, "/indexes", schema); rec.Code != http.StatusCreated {
+		t.Fatalf("create index: status = %d", rec.Code)
+	}
+
+	h := ODataPathRewriter(r)
+	for _, path := range []string{"/indexes/hotels/$metadata", "/indexes('hotels')/$metadata"} {
+		rec := doRequest(t, h, http.MethodGet, path, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body=%s", path, rec.Code, rec.Body.String())
+		}
+		body := rec.Body.String()
+		for _, want := range []string{
+			`<PropertyRef Name="id"></PropertyRef>`,
+			`<Property Name="id" Type="Edm.String" Nullable="false"></Property>`,
+			`<Property Name="tags" Type="Collection(Edm.String)"></Property>`,
+			`<Property Name="address" Type="Microsoft.Azure.Search.V2024_07_01.address"></Property>`,
+			`<Property Name="rooms" Type="Collection(Microsoft.Azure.Search.V2024_07_01.rooms)"></Property>`,
+			`<ComplexType Name="rooms">`,
+			`<EntitySet Name="docs" EntityType="Microsoft.Azure.Search.V2024_07_01.Document">`,
+		} {
+			if !strings.Contains(body, want) {
+				t.Errorf("%s: metadata should contain %s", path, want)
+			}
+		}
+	}
+}
+
+func TestIndexMetadata_UnknownIndexReturns404(t *testing.T) {
+	r := setupRouter(t)
+	if rec := doRequest(t, r, http.MethodGet, "/indexes/missing/$metadata", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+}
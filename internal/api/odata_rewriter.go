@@ -2,19 +2,26 @@ package api
 
 import (
 	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
 )
 
-var (
-	indexODataRe = regexp.MustCompile(`/indexes\('([^']+)'\)`)
-	docODataRe   = regexp.MustCompile(`/docs\('([^']+)'\)`)
-)
+// resourceODataRe matches OData key segments of named resources, e.g.
+// /indexes('name'), /datasources('name'), /indexers('name') or
+// /skillsets('name').
+var resourceODataRe = regexp.MustCompile(`/(indexes|datasources|indexers|skillsets)\('([^']+)'\)`)
+
+// indexerActionRe matches the OData action names of indexer operations,
+// e.g. /indexers/name/search.run.
+var indexerActionRe = regexp.MustCompile(`^(/indexers/[^/]+)/search\.(run|reset|resetdocs|status)$`)
+
+const docKeyPrefix = "/docs('"
 
 func rewriteODataPath(path string) string {
-	path = indexODataRe.ReplaceAllString(path, "/indexes/$1")
-	path = docODataRe.ReplaceAllString(path, "/docs/$1")
+	path = resourceODataRe.ReplaceAllString(path, "/$1/$2")
 	path = strings.ReplaceAll(path, "/search.stats", "/stats")
+	path = indexerActionRe.ReplaceAllString(path, "$1/$2")
 	path = strings.ReplaceAll(path, "/docs/search.index", "/docs/index")
 	path = strings.ReplaceAll(path, "/docs/search.post.search", "/docs/search")
 	return path
@@ -27,7 +34,99 @@ func rewriteODataPath(path string) string {
 // while the emulator routes use /indexes/name and /docs/search.
 func ODataPathRewriter(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		r.URL.Path = rewriteODataPath(r.URL.Path)
+		rewriteODataURL(r.URL)
 		next.ServeHTTP(w, r)
 	})
 }
+
+// rewriteODataURL rewrites u in place. Document lookups of the form
+// /docs('key') become /docs/<escaped key> with RawPath set, so keys that
+// contain '/', '(' or quotes survive routing as a single path segment.
+func rewriteODataURL(u *url.URL) {
+	u.RawPath = normalizeRawPath(u.RawPath)
+	if path, raw, ok := rewriteDocKeyPath(u.Path); ok {
+		u.Path, u.RawPath = path, raw
+		return
+	}
+	if path := rewriteODataPath(u.Path); path != u.Path {
+		u.Path = path
+		if u.RawPath != "" {
+			u.RawPath = rewriteODataPath(u.RawPath)
+		}
+	}
+}
+
+// rewriteDocKeyPath translates a decoded path containing /docs('key') and
+// returns the new decoded and escaped paths. Inside the quotes, OData escapes
+// a single quote by doubling it.
+func rewriteDocKeyPath(path string) (string, string, bool) {
+	path = resourceODataRe.ReplaceAllString(path, "/$1/$2")
+	start := strings.Index(path, docKeyPrefix)
+	if start < 0 {
+		return "", "", false
+	}
+	var key strings.Builder
+	i := start + len(docKeyPrefix)
+	for {
+		if i >= len(path) {
+			return "", "", false
+		}
+		if path[i] == '\'' {
+			if i+1 < len(path) && path[i+1] == '\'' {
+				key.WriteByte('\'')
+				i += 2
+				continue
+			}
+			if i+1 < len(path) && path[i+1] == ')' {
+				break
+			}
+			return "", "", false
+		}
+		key.WriteByte(path[i])
+		i++
+	}
+	prefix, rest := path[:start], path[i+2:]
+	decoded := prefix + "/docs/" + key.String() + rest
+	escaped := escapePathSegments(prefix) + "/docs/" + url.PathEscape(key.String()) + escapePathSegments(rest)
+	return decoded, escaped, true
+}
+
+// normalizeRawPath decodes escapes of characters that may appear literally
+// in a path segment, e.g. %24 for '$', so that routing on the escaped path
+// still reaches literal routes such as /docs/$count. Escapes that matter to
+// routing, like %2F and %25, are kept.
+func normalizeRawPath(raw string) string {
+	if !strings.Contains(raw, "%") {
+		return raw
+	}
+	var b strings.Builder
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == '%' && i+2 < len(raw) {
+			if c, err := url.PathUnescape(raw[i : i+3]); err == nil && isSegmentLiteral(c[0]) {
+				b.WriteString(c)
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(raw[i])
+	}
+	return b.String()
+}
+
+// isSegmentLiteral reports whether RFC 3986 allows c unescaped in a path
+// segment.
+func isSegmentLiteral(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("-._~!$&'()*+,;=:@", c) >= 0
+}
+
+func escapePathSegments(path string) string {
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	return strings.Join(segments, "/")
+}
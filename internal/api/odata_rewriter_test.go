@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestRewriteODataURL(t *testing.T) {
+	tests := []struct {
+		in       string
+		wantPath string
+		wantRaw  string
+	}{
+		{"/indexes('movies')/docs/search.post.search", "/indexes/movies/docs/search", "/indexes/movies/docs/search"},
+		{"/indexes('movies')/search.stats", "/indexes/movies/stats", "/indexes/movies/stats"},
+		{"/indexes/movies/docs/search.index", "/indexes/movies/docs/index", ""},
+		{"/indexes('movies')/docs('1')", "/indexes/movies/docs/1", "/indexes/movies/docs/1"},
+		{"/indexes('movies')/docs('it''s')", "/indexes/movies/docs/it's", "/indexes/movies/docs/it%27s"},
+		{"/indexes('movies')/docs('a)b')", "/indexes/movies/docs/a)b", "/indexes/movies/docs/a%29b"},
+		{"/indexes('movies')/docs('a%2Fb')", "/indexes/movies/docs/a/b", "/indexes/movies/docs/a%2Fb"},
+		{"/indexes/movies/docs('x%20y')", "/indexes/movies/docs/x y", "/indexes/movies/docs/x%20y"},
+		{"/indexes/movies/docs/a%2Fb", "/indexes/movies/docs/a/b", "/indexes/movies/docs/a%2Fb"},
+		{"/datasources('blobs')", "/datasources/blobs", "/datasources/blobs"},
+		{"/skillsets('enrich')", "/skillsets/enrich", "/skillsets/enrich"},
+		{"/indexers('nightly')/search.run", "/indexers/nightly/run", "/indexers/nightly/run"},
+		{"/indexers/nightly/search.status", "/indexers/nightly/status", ""},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.in)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tt.in, err)
+		}
+		rewriteODataURL(u)
+		if u.Path != tt.wantPath {
+			t.Errorf("%s: path = %q, want %q", tt.in, u.Path, tt.wantPath)
+		}
+		if u.RawPath != tt.wantRaw {
+			t.Errorf("%s: raw path = %q, want %q", tt.in, u.RawPath, tt.wantRaw)
+		}
+	}
+}
+
+func TestRewriteODataURL_UnterminatedKeyIsLeftAlone(t *testing.T) {
+	u, _ := url.Parse("/indexes/movies/docs('abc")
+	rewriteODataURL(u)
+	if u.Path != "/indexes/movies/docs('abc" {
+		t.Errorf("path = %q", u.Path)
+	}
+}
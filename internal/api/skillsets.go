@@ -0,0 +1,100 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"ai-search-emulator/internal/application"
+	"ai-search-emulator/internal/domain"
+)
+
+func registerSkillsetRoutes(r *gin.Engine, svc *application.SkillsetService) {
+	// スキルセット作成API
+	r.POST("/skillsets", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			err400(c, "Failed to read request body")
+			return
+		}
+		ss, err := svc.CreateSkillset(c.Request.Context(), body)
+		if err != nil {
+			handleSkillsetError(c, err)
+			return
+		}
+		respondSkillset(c, http.StatusCreated, svc, ss.Name)
+	})
+	// スキルセット一覧API
+	r.GET("/skillsets", func(c *gin.Context) {
+		list, err := svc.ListSkillsets(c.Request.Context())
+		if err != nil {
+			err500(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"@odata.context": requestBaseURL(c.Request) + "/$metadata#skillsets",
+			"value":          list,
+		})
+	})
+	// スキルセット取得API
+	r.GET("/skillsets/:name", func(c *gin.Context) {
+		respondSkillset(c, http.StatusOK, svc, c.Param("name"))
+	})
+	// スキルセット更新API（create-or-update）
+	r.PUT("/skillsets/:name", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			err400(c, "Failed to read request body")
+			return
+		}
+		ss, created, err := svc.CreateOrUpdateSkillset(c.Request.Context(), c.Param("name"), body, accessCondition(c))
+		if err != nil {
+			handleSkillsetError(c, err)
+			return
+		}
+		status := http.StatusOK
+		if created {
+			status = http.StatusCreated
+		}
+		respondSkillset(c, status, svc, ss.Name)
+	})
+	// スキルセット削除API
+	r.DELETE("/skillsets/:name", func(c *gin.Context) {
+		if err := svc.DeleteSkillset(c.Request.Context(), c.Param("name"), accessCondition(c)); err != nil {
+			handleSkillsetError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
+
+// respondSkillset writes the stored skillset.
+func respondSkillset(c *gin.Context, status int, svc *application.SkillsetService, name string) {
+	ss, err := svc.GetSkillset(c.Request.Context(), name)
+	if err != nil {
+		handleSkillsetError(c, err)
+		return
+	}
+	if etag, ok := ss["@odata.etag"].(string); ok {
+		c.Header("ETag", etag)
+	}
+	c.JSON(status, ss)
+}
+
+func handleSkillsetError(c *gin.Context, err error) {
+	if errors.Is(err, domain.ErrSkillsetNotFound) {
+		err404(c, "Skillset not found")
+	} else if errors.Is(err, domain.ErrSkillsetAlreadyExists) {
+		err409(c, "Skillset already exists")
+	} else if errors.Is(err, domain.ErrPreconditionFailed) {
+		err412(c, "The skillset was modified or does not match the If-Match/If-None-Match condition")
+	} else if errors.Is(err, domain.ErrInvalidName) {
+		err400InvalidName(c, err.Error())
+	} else if errors.Is(err, domain.ErrInvalidSkillset) {
+		err400InvalidParam(c, err.Error())
+	} else {
+		err500(c, err)
+	}
+}
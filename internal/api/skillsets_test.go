@@ -0,0 +1,223 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const apiTestSkillset = `{
+	"name": "enrich",
+	"skills": [
+		{
+			"@odata.type": "#Microsoft.Skills.Text.LanguageDetectionSkill",
+			"inputs": [{"name": "text", "source": "/document/content"}],
+			"outputs": [{"name": "languageCode", "targetName": "language"}]
+		},
+		{
+			"@odata.type": "#Microsoft.Skills.Text.SplitSkill",
+			"name": "split",
+			"textSplitMode": "sentences",
+			"inputs": [{"name": "text", "source": "/document/content"}],
+			"outputs": [{"name": "textItems", "targetName": "pages"}]
+		}
+	]
+}`
+
+func TestSkillsets_CRUD(t *testing.T) {
+	r := setupRouter(t)
+
+	rec := doRequest(t, r, http.MethodPost, "/skillsets", apiTestSkillset)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("ETag") == "" {
+		t.Errorf("create response should carry an ETag header")
+	}
+
+	rec = doRequest(t, r, http.MethodGet, "/skillsets/enrich", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var got struct {
+		Skills []map[string]interface{} `json:"skills"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &got)
+	if len(got.Skills) != 2 || got.Skills[0]["name"] != "#1" {
+		t.Errorf("skills = %v, want the unnamed skill named #1", got.Skills)
+	}
+
+	rec = doRequest(t, r, http.MethodGet, "/skillsets", "")
+	var list struct {
+		Value []map[string]interface{} `json:"value"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &list)
+	if rec.Code != http.StatusOK || len(list.Value) != 1 {
+		t.Errorf("list status = %d, value = %v", rec.Code, list.Value)
+	}
+
+	if rec = doRequest(t, r, http.MethodPut, "/skillsets/enrich", apiTestSkillset); rec.Code != http.StatusOK {
+		t.Errorf("update status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	if rec = doRequest(t, ODataPathRewriter(r), http.MethodGet, "/skillsets('enrich')", ""); rec.Code != http.StatusOK {
+		t.Errorf("OData path status = %d", rec.Code)
+	}
+	if rec = doRequest(t, r, http.MethodDelete, "/skillsets/enrich", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("delete status = %d", rec.Code)
+	}
+	if rec = doRequest(t, r, http.MethodGet, "/skillsets/enrich", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("get after delete status = %d, want 404", rec.Code)
+	}
+}
+
+func TestSkillsets_Errors(t *testing.T) {
+	r := setupRouter(t)
+	doRequest(t, r, http.MethodPost, "/skillsets", apiTestSkillset)
+
+	if rec := doRequest(t, r, http.MethodPost, "/skillsets", apiTestSkillset); rec.Code != http.StatusConflict {
+		t.Errorf("duplicate status = %d, want 409", rec.Code)
+	}
+	bad := strings.Replace(apiTestSkillset, `"textSplitMode": "sentences"`, `"textSplitMode": "words"`, 1)
+	bad = strings.Replace(bad, `"enrich"`, `"bad"`, 1)
+	if rec := doRequest(t, r, http.MethodPost, "/skillsets", bad); rec.Code != http.StatusBadRequest || errCode(t, rec) != "InvalidRequestParameter" {
+		t.Errorf("invalid skill: status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	if rec := doRequest(t, r, http.MethodDelete, "/skillsets/missing", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("delete missing status = %d, want 404", rec.Code)
+	}
+}
+
+func TestSkillsets_IndexerEnrichment(t *testing.T) {
+	h := setupIndexerRouter(t)
+	doRequest(t, h, http.MethodPost, "/indexes", `{"name":"enriched","fields":[
+		{"name":"metadata_storage_name","type":"Edm.String","key":true},
+		{"name":"language","type":"Edm.String","filterable":true},
+		{"name":"pages","type":"Collection(Edm.String)","searchable":true}]}`)
+	if rec := doRequest(t, h, http.MethodPost, "/skillsets", apiTestSkillset); rec.Code != http.StatusCreated {
+		t.Fatalf("create skillset: %d %s", rec.Code, rec.Body.String())
+	}
+
+	ix := `{"name":"enrich-ix","dataSourceName":"films","targetIndexName":"enriched","skillsetName":"enrich",
+		"outputFieldMappings":[
+			{"sourceFieldName":"/document/language","targetFieldName":"language"},
+			{"sourceFieldName":"/document/pages","targetFieldName":"pages"}]}`
+	if rec := doRequest(t, h, http.MethodPost, "/indexers", ix); rec.Code != http.StatusCreated {
+		t.Fatalf("create indexer: %d %s", rec.Code, rec.Body.String())
+	}
+	waitForIndexer(t, h, "enrich-ix")
+	rec := doRequest(t, h, http.MethodGet, "/indexes/enriched/docs/a", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get document: %d %s", rec.Code, rec.Body.String())
+	}
+	var doc map[string]interface{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &doc)
+	pages, _ := doc["pages"].([]interface{})
+	if len(pages) != 1 || pages[0] != "film a" {
+		t.Errorf("pages = %v, want [film a]", doc["pages"])
+	}
+	if _, ok := doc["language"].(string); !ok {
+		t.Errorf("language = %v, want a detected language code", doc["language"])
+	}
+
+	missing := strings.Replace(ix, `"enrich-ix"`, `"other-ix"`, 1)
+	missing = strings.Replace(missing, `"skillsetName":"enrich"`, `"skillsetName":"missing"`, 1)
+	if rec := doRequest(t, h, http.MethodPost, "/indexers", missing); rec.Code != http.StatusBadRequest {
+		t.Errorf("missing skillset: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestSkillsets_WebApiSkill(t *testing.T) {
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Values []struct {
+				RecordID string            `json:"recordId"`
+				Data     map[string]string `json:"data"`
+			} `json:"values"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		out := []map[string]interface{}{}
+		for _, v := range req.Values {
+			rec := map[string]interface{}{"recordId": v.RecordID, "data": map[string]string{"shout": strings.ToUpper(v.Data["text"])}}
+			if v.Data["text"] == "film b" {
+				rec["errors"] = []map[string]string{{"message": "rejected by the service"}}
+			}
+			out = append(out, rec)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"values": out})
+	}))
+	defer mock.Close()
+
+	h := setupIndexerRouter(t)
+	ss, _ := json.Marshal(map[string]interface{}{
+		"name": "custom",
+		"skills": []map[string]interface{}{{
+			"@odata.type": "#Microsoft.Skills.Custom.WebApiSkill",
+			"uri":         mock.URL,
+			"batchSize":   1,
+			"httpHeaders": map[string]string{"x-functions-key": "local"},
+			"inputs":      []map[string]string{{"name": "text", "source": "/document/content"}},
+			"outputs":     []map[string]string{{"name": "shout"}},
+		}},
+	})
+	if rec := doRequest(t, h, http.MethodPost, "/skillsets", string(ss)); rec.Code != http.StatusCreated {
+		t.Fatalf("create skillset: %d %s", rec.Code, rec.Body.String())
+	}
+	ix := `{"name":"custom-ix","dataSourceName":"films","targetIndexName":"films","skillsetName":"custom",
+		"parameters":{"maxFailedItems":-1},
+		"outputFieldMappings":[{"sourceFieldName":"/document/shout","targetFieldName":"content"}]}`
+	if rec := doRequest(t, h, http.MethodPost, "/indexers", ix); rec.Code != http.StatusCreated {
+		t.Fatalf("create indexer: %d %s", rec.Code, rec.Body.String())
+	}
+	waitForIndexer(t, h, "custom-ix")
+	if rec := doRequest(t, h, http.MethodGet, "/indexes/films/docs/a", ""); !strings.Contains(rec.Body.String(), `"content":"FILM A"`) {
+		t.Errorf("document a = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := doRequest(t, h, http.MethodGet, "/indexes/films/docs/b", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("document b failed enrichment and should not be indexed: %d %s", rec.Code, rec.Body.String())
+	}
+	rec := doRequest(t, h, http.MethodGet, "/indexers/custom-ix/search.status", "")
+	if !strings.Contains(rec.Body.String(), "rejected by the service") {
+		t.Errorf("status should report the skill error: %s", rec.Body.String())
+	}
+}
+
+func TestSkillsets_IndexProjections(t *testing.T) {
+	h := setupIndexerRouter(t)
+	doRequest(t, h, http.MethodPost, "/indexes", `{"name":"chunks","fields":[
+		{"name":"chunk_id","type":"Edm.String","key":true},
+		{"name":"parent_id","type":"Edm.String","filterable":true},
+		{"name":"chunk","type":"Edm.String","searchable":true}]}`)
+	ss := `{"name":"chunker","skills":[{"@odata.type":"#Microsoft.Skills.Text.SplitSkill","textSplitMode":"sentences",
+		"inputs":[{"name":"text","source":"/document/content"}],"outputs":[{"name":"textItems","targetName":"pages"}]}],
+		"indexProjections":{"selectors":[{"targetIndexName":"chunks","parentKeyFieldName":"parent_id","sourceContext":"/document/pages/*",
+			"mappings":[{"name":"chunk","source":"/document/pages/*"}]}],
+		"parameters":{"projectionMode":"skipIndexingParentDocuments"}}}`
+	if rec := doRequest(t, h, http.MethodPost, "/skillsets", ss); rec.Code != http.StatusCreated {
+		t.Fatalf("create skillset: %d %s", rec.Code, rec.Body.String())
+	}
+	// The indexer targets the chunk index itself, as integrated chunking
+	// setups do; parents are keyed by their encoded blob path.
+	ix := `{"name":"chunk-ix","dataSourceName":"films","targetIndexName":"chunks","skillsetName":"chunker"}`
+	if rec := doRequest(t, h, http.MethodPost, "/indexers", ix); rec.Code != http.StatusCreated {
+		t.Fatalf("create indexer: %d %s", rec.Code, rec.Body.String())
+	}
+	waitForIndexer(t, h, "chunk-ix")
+	rec := doRequest(t, h, http.MethodGet, "/indexes/chunks/docs?search=*&$orderby=chunk", "")
+	var body struct {
+		Value []map[string]interface{} `json:"value"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	if len(body.Value) != 2 || body.Value[0]["chunk"] != "film a" {
+		t.Fatalf("chunks = %s", rec.Body.String())
+	}
+	parent, _ := body.Value[0]["parent_id"].(string)
+	key, _ := body.Value[0]["chunk_id"].(string)
+	if parent == "" || !strings.HasSuffix(key, "_"+parent+"_pages_0") {
+		t.Errorf("chunk_id = %s, parent_id = %s; want <hash>_<parent>_pages_0", key, parent)
+	}
+	if rec := doRequest(t, h, http.MethodGet, "/indexers/chunk-ix/search.status", ""); !strings.Contains(rec.Body.String(), `"itemsFailed":0`) {
+		t.Errorf("status = %s", rec.Body.String())
+	}
+}
@@ -1,6 +1,10 @@
 package application
 
 type AppServices struct {
-	IndexService    *IndexService
-	DocumentService *DocumentService
+	IndexService      *IndexService
+	DocumentService   *DocumentService
+	KeyService        *KeyService
+	DataSourceService *DataSourceService
+	IndexerService    *IndexerService
+	SkillsetService   *SkillsetService
 }
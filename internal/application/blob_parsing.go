@@ -0,0 +1,465 @@
+package application
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"ai-search-emulator/internal/domain"
+)
+
+// Values of parameters.configuration.parsingMode.
+const (
+	parsingModeDefault       = "default"
+	parsingModeText          = "text"
+	parsingModeJSON          = "json"
+	parsingModeJSONArray     = "jsonArray"
+	parsingModeJSONLines     = "jsonLines"
+	parsingModeDelimitedText = "delimitedText"
+	parsingModeMarkdown      = "markdown"
+)
+
+// documentKeyField is the source field Azure generates for the documents of
+// one-to-many parsing modes; it maps to the index key unless fieldMappings
+// supply the key.
+const documentKeyField = "AzureSearch_DocumentKey"
+
+// indexerConfiguration is parameters.configuration: how blob content is
+// cracked into source documents.
+type indexerConfiguration struct {
+	ParsingMode              string `json:"parsingMode"`
+	DataToExtract            string `json:"dataToExtract"`
+	FirstLineContainsHeaders *bool  `json:"firstLineContainsHeaders"`
+	DelimitedTextDelimiter   string `json:"delimitedTextDelimiter"`
+	DelimitedTextHeaders     string `json:"delimitedTextHeaders"`
+	DocumentRoot             string `json:"documentRoot"`
+	MarkdownParsingSubmode   string `json:"markdownParsingSubmode"`
+	MarkdownHeaderDepth      string `json:"markdownHeaderDepth"`
+}
+
+func (c *indexerConfiguration) parsingMode() string {
+	if c.ParsingMode == "" {
+		return parsingModeDefault
+	}
+	return c.ParsingMode
+}
+
+// validate checks the configuration; blob reports whether the data source
+// holds blobs, the only sources whose content can be parsed.
+func (c *indexerConfiguration) validate(blob bool) error {
+	switch c.parsingMode() {
+	case parsingModeDefault:
+	case parsingModeText, parsingModeJSON, parsingModeJSONArray, parsingModeJSONLines, parsingModeDelimitedText, parsingModeMarkdown:
+		if !blob {
+			return fmt.Errorf("%w: parsingMode '%s' is only supported for blob data sources", domain.ErrInvalidIndexer, c.ParsingMode)
+		}
+	default:
+		return fmt.Errorf("%w: parsingMode '%s' is not supported", domain.ErrInvalidIndexer, c.ParsingMode)
+	}
+	switch c.DataToExtract {
+	case "", "contentAndMetadata", "allMetadata", "storageMetadata":
+	default:
+		return fmt.Errorf("%w: dataToExtract '%s' is not supported", domain.ErrInvalidIndexer, c.DataToExtract)
+	}
+	if c.DelimitedTextDelimiter != "" && utf8.RuneCountInString(c.DelimitedTextDelimiter) != 1 {
+		return fmt.Errorf("%w: delimitedTextDelimiter must be a single character", domain.ErrInvalidIndexer)
+	}
+	if c.parsingMode() == parsingModeDelimitedText && !c.firstLineContainsHeaders() && c.DelimitedTextHeaders == "" {
+		return fmt.Errorf("%w: delimitedTextHeaders must be set when firstLineContainsHeaders is false", domain.ErrInvalidIndexer)
+	}
+	if c.DocumentRoot != "" && !strings.HasPrefix(c.DocumentRoot, "/") {
+		return fmt.Errorf("%w: documentRoot must be a JSON pointer starting with '/'", domain.ErrInvalidIndexer)
+	}
+	switch c.MarkdownParsingSubmode {
+	case "", "oneToMany", "oneToOne":
+	default:
+		return fmt.Errorf("%w: markdownParsingSubmode '%s' is not supported", domain.ErrInvalidIndexer, c.MarkdownParsingSubmode)
+	}
+	if _, err := c.markdownHeaderDepth(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *indexerConfiguration) firstLineContainsHeaders() bool {
+	return c.FirstLineContainsHeaders == nil || *c.FirstLineContainsHeaders
+}
+
+func (c *indexerConfiguration) markdownHeaderDepth() (int, error) {
+	if c.MarkdownHeaderDepth == "" {
+		return 6, nil
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(c.MarkdownHeaderDepth, "h"))
+	if err != nil || !strings.HasPrefix(c.MarkdownHeaderDepth, "h") || n < 1 || n > 6 {
+		return 0, fmt.Errorf("%w: markdownHeaderDepth must be one of h1 to h6", domain.ErrInvalidIndexer)
+	}
+	return n, nil
+}
+
+// crack turns a source item into the source documents it yields. Rows and
+// metadata-only extraction yield the item's fields; blob content is parsed
+// according to parsingMode. warning is set when content could not be
+// extracted but the item is still indexed.
+func (c *indexerConfiguration) crack(item domain.SourceItem) (docs []map[string]interface{}, warning string, err error) {
+	if item.Content == nil {
+		return []map[string]interface{}{copyFields(item.Fields)}, "", nil
+	}
+	switch c.DataToExtract {
+	case "storageMetadata":
+		return []map[string]interface{}{copyFields(item.Fields)}, "", nil
+	case "allMetadata":
+		doc := copyFields(item.Fields)
+		doc["metadata_content_type"] = contentType(item)
+		return []map[string]interface{}{doc}, "", nil
+	}
+
+	switch c.parsingMode() {
+	case parsingModeText:
+		doc := copyFields(item.Fields)
+		doc["content"] = string(item.Content)
+		return []map[string]interface{}{doc}, "", nil
+	case parsingModeJSON:
+		root, err := c.jsonRoot(item.Content)
+		if err != nil {
+			return nil, "", err
+		}
+		obj, ok := root.(map[string]interface{})
+		if !ok {
+			return nil, "", fmt.Errorf("the JSON document is not an object")
+		}
+		return []map[string]interface{}{withFields(item.Fields, obj)}, "", nil
+	case parsingModeJSONArray:
+		root, err := c.jsonRoot(item.Content)
+		if err != nil {
+			return nil, "", err
+		}
+		elems, ok := root.([]interface{})
+		if !ok {
+			return nil, "", fmt.Errorf("the JSON document is not an array")
+		}
+		for _, e := range elems {
+			obj, ok := e.(map[string]interface{})
+			if !ok {
+				return nil, "", fmt.Errorf("the JSON array element %d is not an object", len(docs)+1)
+			}
+			docs = append(docs, withFields(item.Fields, obj))
+		}
+		return numberDocuments(item, docs), "", nil
+	case parsingModeJSONLines:
+		docs, err := parseJSONLines(item)
+		if err != nil {
+			return nil, "", err
+		}
+		return numberDocuments(item, docs), "", nil
+	case parsingModeDelimitedText:
+		docs, err := c.parseDelimitedText(item)
+		if err != nil {
+			return nil, "", err
+		}
+		return numberDocuments(item, docs), "", nil
+	case parsingModeMarkdown:
+		depth, _ := c.markdownHeaderDepth()
+		if c.MarkdownParsingSubmode == "oneToOne" {
+			doc := copyFields(item.Fields)
+			doc["content"] = string(item.Content)
+			doc["sections"] = markdownSectionTree(markdownSections(string(item.Content), depth))
+			return []map[string]interface{}{doc}, "", nil
+		}
+		for _, sec := range markdownSections(string(item.Content), depth) {
+			if sec.content == "" {
+				continue
+			}
+			doc := copyFields(item.Fields)
+			doc["content"] = sec.content
+			headers := map[string]interface{}{}
+			for i, h := range sec.headers {
+				if h != "" {
+					headers["h"+strconv.Itoa(i+1)] = h
+				}
+			}
+			doc["sections"] = headers
+			doc["ordinal_position"] = int64(len(docs))
+			docs = append(docs, doc)
+		}
+		return numberDocuments(item, docs), "", nil
+	}
+	return crackDefault(item)
+}
+
+// crackDefault extracts text content the way the default parsing mode does
+// for the formats that can be read without a document cracker: HTML is
+// reduced to its text, and other text formats are taken as they are.
+func crackDefault(item domain.SourceItem) ([]map[string]interface{}, string, error) {
+	doc := copyFields(item.Fields)
+	ct := contentType(item)
+	doc["metadata_content_type"] = ct
+	if !utf8.Valid(item.Content) {
+		doc["content"] = ""
+		return []map[string]interface{}{doc}, fmt.Sprintf("could not extract content from a blob of type '%s'; only its metadata was indexed", ct), nil
+	}
+	text := string(bytes.TrimPrefix(item.Content, []byte("\xef\xbb\xbf")))
+	if strings.HasPrefix(ct, "text/html") {
+		if m := htmlTitleRe.FindStringSubmatch(text); m != nil {
+			doc["metadata_title"] = strings.TrimSpace(html.UnescapeString(m[1]))
+		}
+		text = htmlText(text)
+	}
+	doc["content"] = text
+	return []map[string]interface{}{doc}, "", nil
+}
+
+var (
+	htmlTitleRe    = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	htmlHiddenRe   = regexp.MustCompile(`(?is)<(script|style|head)[^>]*>.*?</(script|style|head)>|<!--.*?-->`)
+	htmlBlockRe    = regexp.MustCompile(`(?i)<(br|/p|/div|/h[1-6]|/li|/tr|/title)[^>]*>`)
+	htmlTagRe      = regexp.MustCompile(`<[^>]*>`)
+	blankSpaceRe   = regexp.MustCompile(`[ \t\r\f\v]+`)
+	blankLinesRe   = regexp.MustCompile(`\n\s*\n+`)
+	markdownHeadRe = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+)
+
+// htmlText returns the visible text of an HTML document with one line per
+// block element.
+func htmlText(s string) string {
+	s = htmlHiddenRe.ReplaceAllString(s, "")
+	s = htmlBlockRe.ReplaceAllString(s, "\n")
+	s = htmlTagRe.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	s = blankSpaceRe.ReplaceAllString(s, " ")
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(l)
+	}
+	return strings.TrimSpace(blankLinesRe.ReplaceAllString(strings.Join(lines, "\n"), "\n"))
+}
+
+// contentType returns the blob's content type, derived from its extension
+// when the source does not report one.
+func contentType(item domain.SourceItem) string {
+	if ct, ok := item.Fields["metadata_storage_content_type"].(string); ok && ct != "" && ct != "application/octet-stream" {
+		return ct
+	}
+	name, _ := item.Fields["metadata_storage_name"].(string)
+	if name == "" {
+		name = item.Key
+	}
+	if ct := mime.TypeByExtension(path.Ext(name)); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+// jsonRoot parses a JSON blob and returns the value documentRoot points to.
+func (c *indexerConfiguration) jsonRoot(content []byte) (interface{}, error) {
+	var v interface{}
+	if err := json.Unmarshal(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")), &v); err != nil {
+		return nil, fmt.Errorf("the blob is not valid JSON: %v", err)
+	}
+	if c.DocumentRoot == "" || c.DocumentRoot == "/" {
+		return v, nil
+	}
+	for _, token := range strings.Split(c.DocumentRoot[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch node := v.(type) {
+		case map[string]interface{}:
+			next, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("the documentRoot '%s' does not exist in the blob", c.DocumentRoot)
+			}
+			v = next
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("the documentRoot '%s' does not exist in the blob", c.DocumentRoot)
+			}
+			v = node[i]
+		default:
+			return nil, fmt.Errorf("the documentRoot '%s' does not exist in the blob", c.DocumentRoot)
+		}
+	}
+	return v, nil
+}
+
+func parseJSONLines(item domain.SourceItem) ([]map[string]interface{}, error) {
+	var docs []map[string]interface{}
+	scanner := bufio.NewScanner(bytes.NewReader(item.Content))
+	scanner.Buffer(make([]byte, 64*1024), len(item.Content)+1)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if text == "" {
+			continue
+		}
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(text), &obj); err != nil {
+			return nil, fmt.Errorf("line %d is not a JSON object", line)
+		}
+		docs = append(docs, withFields(item.Fields, obj))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// parseDelimitedText yields a document per row whose fields are named by
+// the header line or delimitedTextHeaders. Values are strings.
+func (c *indexerConfiguration) parseDelimitedText(item domain.SourceItem) ([]map[string]interface{}, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(item.Content, []byte("\xef\xbb\xbf"))))
+	if c.DelimitedTextDelimiter != "" {
+		r.Comma, _ = utf8.DecodeRuneInString(c.DelimitedTextDelimiter)
+	}
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	var headers []string
+	if !c.firstLineContainsHeaders() {
+		for _, h := range strings.Split(c.DelimitedTextHeaders, ",") {
+			headers = append(headers, strings.TrimSpace(h))
+		}
+	}
+	var docs []map[string]interface{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("the blob is not valid delimited text: %v", err)
+		}
+		if headers == nil {
+			headers = record
+			continue
+		}
+		if len(record) > len(headers) {
+			return nil, fmt.Errorf("row %d has more values than there are headers", len(docs)+1)
+		}
+		row := make(map[string]interface{}, len(record))
+		for i, v := range record {
+			row[headers[i]] = v
+		}
+		docs = append(docs, withFields(item.Fields, row))
+	}
+	return docs, nil
+}
+
+// markdownSection is the text below a header. headers holds the enclosing
+// h1..hN headers, with "" for levels that are not present.
+type markdownSection struct {
+	level   int
+	headers []string
+	content string
+}
+
+// markdownSections splits a Markdown document at headers up to depth; deeper
+// headers are part of the section content. Text before the first header is a
+// section of level 0.
+func markdownSections(text string, depth int) []markdownSection {
+	var sections []markdownSection
+	current := markdownSection{headers: make([]string, depth)}
+	var body []string
+	inFence := false
+	flush := func() {
+		current.content = strings.TrimSpace(strings.Join(body, "\n"))
+		if current.content != "" || current.level > 0 {
+			sections = append(sections, current)
+		}
+		body = nil
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+		}
+		m := markdownHeadRe.FindStringSubmatch(line)
+		if inFence || m == nil || len(m[1]) > depth {
+			body = append(body, line)
+			continue
+		}
+		flush()
+		level := len(m[1])
+		headers := make([]string, depth)
+		copy(headers, current.headers[:level-1])
+		headers[level-1] = m[2]
+		current = markdownSection{level: level, headers: headers}
+	}
+	flush()
+	return sections
+}
+
+// markdownSectionTree nests sections under their parent headers for the
+// oneToOne submode.
+func markdownSectionTree(sections []markdownSection) []interface{} {
+	type node struct {
+		level    int
+		value    map[string]interface{}
+		children []interface{}
+	}
+	root := &node{}
+	stack := []*node{root}
+	for _, sec := range sections {
+		if sec.level == 0 {
+			continue
+		}
+		n := &node{level: sec.level, value: map[string]interface{}{
+			"header_level": int64(sec.level),
+			"header_name":  sec.headers[sec.level-1],
+			"content":      sec.content,
+		}}
+		for len(stack) > 1 && stack[len(stack)-1].level >= sec.level {
+			stack = stack[:len(stack)-1]
+		}
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, n)
+		stack = append(stack, n)
+	}
+	var build func(children []interface{}) []interface{}
+	build = func(children []interface{}) []interface{} {
+		out := make([]interface{}, 0, len(children))
+		for _, c := range children {
+			n := c.(*node)
+			n.value["sections"] = build(n.children)
+			out = append(out, n.value)
+		}
+		return out
+	}
+	return build(root.children)
+}
+
+// numberDocuments sets AzureSearch_DocumentKey on the documents of a
+// one-to-many parsing mode: the URL token encoding of the blob path and the
+// document's 1-based ordinal, as Azure generates it.
+func numberDocuments(item domain.SourceItem, docs []map[string]interface{}) []map[string]interface{} {
+	blobPath, _ := item.Fields["metadata_storage_path"].(string)
+	if blobPath == "" {
+		blobPath = item.Key
+	}
+	for i, doc := range docs {
+		doc[documentKeyField] = urlTokenEncode([]byte(blobPath + ";" + strconv.Itoa(i+1)))
+	}
+	return docs
+}
+
+func copyFields(fields map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(fields)+2)
+	for k, v := range fields {
+		out[k] = v
+	}
+	return out
+}
+
+// withFields returns the parsed fields on top of the blob's metadata.
+func withFields(metadata, parsed map[string]interface{}) map[string]interface{} {
+	out := copyFields(metadata)
+	for k, v := range parsed {
+		out[k] = v
+	}
+	return out
+}
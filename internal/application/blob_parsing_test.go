@@ -0,0 +1,182 @@
+package application
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"ai-search-emulator/internal/domain"
+)
+
+func blobItem(name, content string) domain.SourceItem {
+	return domain.SourceItem{
+		Key: name,
+		Fields: map[string]interface{}{
+			"metadata_storage_name": name,
+			"metadata_storage_path": "https://acct.blob.core.windows.net/docs/" + name,
+		},
+		Content: []byte(content),
+	}
+}
+
+func crackForTest(t *testing.T, cfg indexerConfiguration, item domain.SourceItem) []map[string]interface{} {
+	t.Helper()
+	if err := cfg.validate(true); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	docs, warning, err := cfg.crack(item)
+	if err != nil {
+		t.Fatalf("crack: %v", err)
+	}
+	if warning != "" {
+		t.Fatalf("unexpected warning: %s", warning)
+	}
+	return docs
+}
+
+func TestCrack_Default(t *testing.T) {
+	t.Parallel()
+	page := `<html><head><title>Hotels &amp; Inns</title><style>p{}</style></head>
+<body><h1>Welcome</h1><p>Rooms <b>available</b>.</p><script>alert(1)</script></body></html>`
+	docs := crackForTest(t, indexerConfiguration{}, blobItem("index.html", page))
+	if len(docs) != 1 {
+		t.Fatalf("docs = %v, want one document", docs)
+	}
+	doc := docs[0]
+	if doc["content"] != "Welcome\nRooms available." {
+		t.Errorf("content = %q", doc["content"])
+	}
+	if doc["metadata_title"] != "Hotels & Inns" || doc["metadata_content_type"] != "text/html; charset=utf-8" {
+		t.Errorf("metadata = %v", doc)
+	}
+	if doc["metadata_storage_name"] != "index.html" {
+		t.Errorf("storage metadata should be kept: %v", doc)
+	}
+
+	text := crackForTest(t, indexerConfiguration{ParsingMode: "text"}, blobItem("index.html", page))
+	if text[0]["content"] != page {
+		t.Errorf("text mode content = %q, want the raw text", text[0]["content"])
+	}
+
+	_, warning, err := (&indexerConfiguration{}).crack(blobItem("a.bin", "\xff\xfe\x00"))
+	if err != nil || warning == "" {
+		t.Errorf("binary content: warning = %q, err = %v, want a warning", warning, err)
+	}
+
+	meta := crackForTest(t, indexerConfiguration{DataToExtract: "storageMetadata"}, blobItem("a.txt", "hi"))
+	if _, ok := meta[0]["content"]; ok {
+		t.Errorf("storageMetadata should not extract content: %v", meta[0])
+	}
+}
+
+func TestCrack_JSON(t *testing.T) {
+	t.Parallel()
+	blob := `{"hotel":{"id":"1","name":"Grand"},"rooms":[{"id":"r1"},{"id":"r2"}]}`
+
+	docs := crackForTest(t, indexerConfiguration{ParsingMode: "json", DocumentRoot: "/hotel"}, blobItem("h.json", blob))
+	if len(docs) != 1 || docs[0]["id"] != "1" || docs[0]["name"] != "Grand" {
+		t.Errorf("json docs = %v", docs)
+	}
+	if _, ok := docs[0][documentKeyField]; ok {
+		t.Error("json mode yields one document per blob and needs no generated key")
+	}
+
+	docs = crackForTest(t, indexerConfiguration{ParsingMode: "jsonArray", DocumentRoot: "/rooms"}, blobItem("h.json", blob))
+	if len(docs) != 2 || docs[0]["id"] != "r1" || docs[1]["id"] != "r2" {
+		t.Fatalf("jsonArray docs = %v", docs)
+	}
+	// base64 of "<path>;1" in HttpServerUtility.UrlTokenEncode form.
+	want := urlTokenEncode([]byte("https://acct.blob.core.windows.net/docs/h.json;1"))
+	if docs[0][documentKeyField] != want || docs[0][documentKeyField] == docs[1][documentKeyField] {
+		t.Errorf("%s = %v and %v", documentKeyField, docs[0][documentKeyField], docs[1][documentKeyField])
+	}
+
+	docs = crackForTest(t, indexerConfiguration{ParsingMode: "jsonLines"}, blobItem("h.jsonl", "{\"id\":\"a\"}\n\n{\"id\":\"b\"}\n"))
+	if len(docs) != 2 || docs[1]["id"] != "b" {
+		t.Errorf("jsonLines docs = %v", docs)
+	}
+
+	for _, tc := range []struct {
+		cfg  indexerConfiguration
+		blob string
+	}{
+		{indexerConfiguration{ParsingMode: "json"}, `[1]`},
+		{indexerConfiguration{ParsingMode: "jsonArray"}, `{"a":1}`},
+		{indexerConfiguration{ParsingMode: "jsonArray", DocumentRoot: "/missing"}, `{"a":[]}`},
+		{indexerConfiguration{ParsingMode: "jsonLines"}, "{\"id\":\"a\"}\nnot json"},
+	} {
+		if _, _, err := tc.cfg.crack(blobItem("x.json", tc.blob)); err == nil {
+			t.Errorf("%s of %s should fail", tc.cfg.ParsingMode, tc.blob)
+		}
+	}
+}
+
+func TestCrack_DelimitedText(t *testing.T) {
+	t.Parallel()
+	docs := crackForTest(t, indexerConfiguration{ParsingMode: "delimitedText"}, blobItem("h.csv", "id,name\n1,\"Grand, Hotel\"\n2,Inn\n"))
+	if len(docs) != 2 || docs[0]["id"] != "1" || docs[0]["name"] != "Grand, Hotel" || docs[1]["name"] != "Inn" {
+		t.Errorf("docs = %v", docs)
+	}
+
+	noHeaders := false
+	cfg := indexerConfiguration{ParsingMode: "delimitedText", FirstLineContainsHeaders: &noHeaders, DelimitedTextHeaders: "id,name", DelimitedTextDelimiter: "|"}
+	docs = crackForTest(t, cfg, blobItem("h.txt", "1|Grand\n2|Inn"))
+	if len(docs) != 2 || docs[0]["name"] != "Grand" || docs[1][documentKeyField] == nil {
+		t.Errorf("docs = %v", docs)
+	}
+}
+
+func TestCrack_Markdown(t *testing.T) {
+	t.Parallel()
+	md := "Intro\n# Hotels\nAbout.\n## Rooms\nTwo rooms.\n### Beds\nKing.\n```\n# not a header\n```\n"
+
+	docs := crackForTest(t, indexerConfiguration{ParsingMode: "markdown", MarkdownHeaderDepth: "h2"}, blobItem("h.md", md))
+	if len(docs) != 3 {
+		t.Fatalf("docs = %v, want 3 sections", docs)
+	}
+	if docs[0]["content"] != "Intro" || !reflect.DeepEqual(docs[0]["sections"], map[string]interface{}{}) {
+		t.Errorf("section 0 = %v", docs[0])
+	}
+	want := map[string]interface{}{"h1": "Hotels", "h2": "Rooms"}
+	if docs[2]["content"] != "Two rooms.\n### Beds\nKing.\n```\n# not a header\n```" || !reflect.DeepEqual(docs[2]["sections"], want) {
+		t.Errorf("section 2 = %v", docs[2])
+	}
+	if docs[2]["ordinal_position"] != int64(2) {
+		t.Errorf("ordinal_position = %v, want 2", docs[2]["ordinal_position"])
+	}
+
+	docs = crackForTest(t, indexerConfiguration{ParsingMode: "markdown", MarkdownParsingSubmode: "oneToOne"}, blobItem("h.md", md))
+	sections, _ := docs[0]["sections"].([]interface{})
+	if len(docs) != 1 || len(sections) != 1 {
+		t.Fatalf("oneToOne docs = %v", docs)
+	}
+	hotels := sections[0].(map[string]interface{})
+	rooms := hotels["sections"].([]interface{})[0].(map[string]interface{})
+	if hotels["header_name"] != "Hotels" || rooms["header_level"] != int64(2) || len(rooms["sections"].([]interface{})) != 1 {
+		t.Errorf("section tree = %v", sections)
+	}
+}
+
+func TestIndexerConfiguration_Validate(t *testing.T) {
+	t.Parallel()
+	noHeaders := false
+	cases := []struct {
+		name string
+		cfg  indexerConfiguration
+		blob bool
+	}{
+		{"unknown mode", indexerConfiguration{ParsingMode: "xml"}, true},
+		{"mode on rows", indexerConfiguration{ParsingMode: "json"}, false},
+		{"long delimiter", indexerConfiguration{ParsingMode: "delimitedText", DelimitedTextDelimiter: "||"}, true},
+		{"missing headers", indexerConfiguration{ParsingMode: "delimitedText", FirstLineContainsHeaders: &noHeaders}, true},
+		{"relative root", indexerConfiguration{ParsingMode: "json", DocumentRoot: "hotel"}, true},
+		{"bad depth", indexerConfiguration{ParsingMode: "markdown", MarkdownHeaderDepth: "h7"}, true},
+		{"bad submode", indexerConfiguration{ParsingMode: "markdown", MarkdownParsingSubmode: "manyToMany"}, true},
+		{"bad extraction", indexerConfiguration{DataToExtract: "everything"}, true},
+	}
+	for _, tc := range cases {
+		if err := tc.cfg.validate(tc.blob); !errors.Is(err, domain.ErrInvalidIndexer) {
+			t.Errorf("%s: err = %v, want ErrInvalidIndexer", tc.name, err)
+		}
+	}
+}
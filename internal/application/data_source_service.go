@@ -0,0 +1,315 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"ai-search-emulator/internal/domain"
+)
+
+// unchangedCredentials is the placeholder Azure accepts on update to keep the
+// stored connection string.
+const unchangedCredentials = "<unchanged>"
+
+// dataSourceTypes lists the data source types Azure defines. Definitions of
+// any of them can be stored; indexers can only run against types with a
+// registered connector.
+var dataSourceTypes = map[string]struct{}{
+	"azureblob": {}, "adlsgen2": {}, "azurefile": {}, "azuresql": {}, "cosmosdb": {},
+	"azuretable": {}, "mysql": {}, "onelake": {}, "sharepoint": {},
+}
+
+// dataSourceDefinition is the parsed form of domain.DataSource.Definition.
+type dataSourceDefinition struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Credentials struct {
+		ConnectionString *string `json:"connectionString"`
+	} `json:"credentials"`
+	Container struct {
+		Name  string `json:"name"`
+		Query string `json:"query"`
+	} `json:"container"`
+	DataChangeDetectionPolicy   *changeDetectionPolicy   `json:"dataChangeDetectionPolicy"`
+	DataDeletionDetectionPolicy *deletionDetectionPolicy `json:"dataDeletionDetectionPolicy"`
+}
+
+// Policy types supported by indexers.
+const (
+	highWaterMarkPolicyType = "#Microsoft.Azure.Search.HighWaterMarkChangeDetectionPolicy"
+	softDeletePolicyType    = "#Microsoft.Azure.Search.SoftDeleteColumnDeletionDetectionPolicy"
+)
+
+type changeDetectionPolicy struct {
+	ODataType               string `json:"@odata.type"`
+	HighWaterMarkColumnName string `json:"highWaterMarkColumnName"`
+}
+
+type deletionDetectionPolicy struct {
+	ODataType             string `json:"@odata.type"`
+	SoftDeleteColumnName  string `json:"softDeleteColumnName"`
+	SoftDeleteMarkerValue string `json:"softDeleteMarkerValue"`
+}
+
+func parseDataSourceDefinition(definition string) (*dataSourceDefinition, error) {
+	var d dataSourceDefinition
+	if err := json.Unmarshal([]byte(definition), &d); err != nil {
+		return nil, fmt.Errorf("%w: the request body is not valid JSON", domain.ErrInvalidDataSource)
+	}
+	return &d, nil
+}
+
+func (d *dataSourceDefinition) config() domain.DataSourceConfig {
+	cfg := domain.DataSourceConfig{Type: d.Type, Container: d.Container.Name, Query: d.Container.Query}
+	if d.Credentials.ConnectionString != nil {
+		cfg.ConnectionString = *d.Credentials.ConnectionString
+	}
+	return cfg
+}
+
+type DataSourceService struct {
+	Repo domain.DataSourceRepository
+	// Connectors maps data source types to the connectors that read them.
+	Connectors map[string]domain.SourceConnector
+}
+
+func NewDataSourceService(repo domain.DataSourceRepository, connectors map[string]domain.SourceConnector) *DataSourceService {
+	return &DataSourceService{Repo: repo, Connectors: connectors}
+}
+
+// CreateDataSource stores a new data source definition.
+func (s *DataSourceService) CreateDataSource(ctx context.Context, body []byte) (*domain.DataSource, error) {
+	def, err := parseDataSourceDefinition(string(body))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validate(def.Name, def, nil); err != nil {
+		return nil, err
+	}
+	if _, err := s.Repo.FindByName(def.Name); err == nil {
+		return nil, domain.ErrDataSourceAlreadyExists
+	} else if !errors.Is(err, domain.ErrDataSourceNotFound) {
+		return nil, err
+	}
+	ds := &domain.DataSource{Name: def.Name, Definition: string(body), ETag: newETag()}
+	if err := s.Repo.Create(ds); err != nil {
+		return nil, err
+	}
+	return ds, nil
+}
+
+// CreateOrUpdateDataSource upserts a data source definition. On update, a
+// missing, null or "<unchanged>" connection string keeps the stored one.
+// Returns true if the data source was newly created.
+func (s *DataSourceService) CreateOrUpdateDataSource(ctx context.Context, name string, body []byte, cond AccessCondition) (*domain.DataSource, bool, error) {
+	def, err := parseDataSourceDefinition(string(body))
+	if err != nil {
+		return nil, false, err
+	}
+	if def.Name != "" && def.Name != name {
+		return nil, false, fmt.Errorf("%w: the data source name '%s' in the request body does not match the name '%s' in the request URL", domain.ErrInvalidDataSource, def.Name, name)
+	}
+
+	ds, err := s.Repo.FindByName(name)
+	if err != nil && !errors.Is(err, domain.ErrDataSourceNotFound) {
+		return nil, false, err
+	}
+	exists := err == nil
+	current := ""
+	var stored *dataSourceDefinition
+	if exists {
+		current = ds.ETag
+		if stored, err = parseDataSourceDefinition(ds.Definition); err != nil {
+			return nil, false, err
+		}
+	}
+	if err := cond.check(exists, current); err != nil {
+		return nil, false, err
+	}
+
+	definition := body
+	if exists && keepsCredentials(def) {
+		def.Credentials.ConnectionString = stored.Credentials.ConnectionString
+		if definition, err = withConnectionString(body, def.Credentials.ConnectionString); err != nil {
+			return nil, false, err
+		}
+	}
+	if err := s.validate(name, def, stored); err != nil {
+		return nil, false, err
+	}
+
+	if exists {
+		ds.Definition = string(definition)
+		ds.ETag = newETag()
+		if cond != (AccessCondition{}) {
+			return ds, false, s.Repo.UpdateIfMatch(ds, current)
+		}
+		return ds, false, s.Repo.Update(ds)
+	}
+	ds = &domain.DataSource{Name: name, Definition: string(definition), ETag: newETag()}
+	return ds, true, s.Repo.Create(ds)
+}
+
+// GetDataSource returns a data source definition with its credentials
+// removed, as Azure never returns connection strings.
+func (s *DataSourceService) GetDataSource(ctx context.Context, name string) (map[string]interface{}, error) {
+	ds, err := s.Repo.FindByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return dataSourceResponse(ds)
+}
+
+func (s *DataSourceService) ListDataSources(ctx context.Context) ([]map[string]interface{}, error) {
+	list, err := s.Repo.List()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]map[string]interface{}, 0, len(list))
+	for _, ds := range list {
+		m, err := dataSourceResponse(ds)
+		if err != nil {
+			continue // 定義不正はスキップ
+		}
+		result = append(result, m)
+	}
+	return result, nil
+}
+
+// DeleteDataSource deletes a data source. When cond is set, the current ETag
+// must satisfy it or domain.ErrPreconditionFailed is returned.
+func (s *DataSourceService) DeleteDataSource(ctx context.Context, name string, cond AccessCondition) error {
+	if cond != (AccessCondition{}) {
+		ds, err := s.Repo.FindByName(name)
+		if err != nil && !errors.Is(err, domain.ErrDataSourceNotFound) {
+			return err
+		}
+		current := ""
+		if err == nil {
+			current = ds.ETag
+		}
+		if err := cond.check(err == nil, current); err != nil {
+			return err
+		}
+		if err == nil {
+			return s.Repo.DeleteIfMatch(name, current)
+		}
+	}
+	return s.Repo.Delete(name)
+}
+
+// dataSourceResponse renders a stored data source for a response: the
+// connection string is nulled and @odata.etag added.
+func dataSourceResponse(ds *domain.DataSource) (map[string]interface{}, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(ds.Definition), &m); err != nil {
+		return nil, fmt.Errorf("definition parse error")
+	}
+	m["name"] = ds.Name
+	if creds, ok := m["credentials"].(map[string]interface{}); ok {
+		creds["connectionString"] = nil
+	} else {
+		m["credentials"] = map[string]interface{}{"connectionString": nil}
+	}
+	m["@odata.etag"] = ds.ETag
+	return m, nil
+}
+
+// validate checks a definition addressed by name. stored is the current
+// definition on update, or nil.
+func (s *DataSourceService) validate(name string, def *dataSourceDefinition, stored *dataSourceDefinition) error {
+	if err := validateResourceName("data source", name); err != nil {
+		return err
+	}
+	if _, ok := dataSourceTypes[def.Type]; !ok {
+		return fmt.Errorf("%w: the data source type '%s' is not supported", domain.ErrInvalidDataSource, def.Type)
+	}
+	if stored != nil && stored.Type != def.Type {
+		return fmt.Errorf("%w: the type of an existing data source cannot be changed from '%s' to '%s'", domain.ErrInvalidDataSource, stored.Type, def.Type)
+	}
+	if def.Container.Name == "" {
+		return fmt.Errorf("%w: the data source must specify container.name", domain.ErrInvalidDataSource)
+	}
+	cfg := def.config()
+	if cfg.ConnectionString == "" || cfg.ConnectionString == unchangedCredentials {
+		return fmt.Errorf("%w: the data source must specify credentials.connectionString", domain.ErrInvalidDataSource)
+	}
+	if p := def.DataChangeDetectionPolicy; p != nil {
+		if p.ODataType != highWaterMarkPolicyType {
+			return fmt.Errorf("%w: the change detection policy '%s' is not supported", domain.ErrInvalidDataSource, p.ODataType)
+		}
+		if p.HighWaterMarkColumnName == "" {
+			return fmt.Errorf("%w: the change detection policy must specify highWaterMarkColumnName", domain.ErrInvalidDataSource)
+		}
+	}
+	if p := def.DataDeletionDetectionPolicy; p != nil {
+		if p.ODataType != softDeletePolicyType {
+			return fmt.Errorf("%w: the deletion detection policy '%s' is not supported", domain.ErrInvalidDataSource, p.ODataType)
+		}
+		if p.SoftDeleteColumnName == "" || p.SoftDeleteMarkerValue == "" {
+			return fmt.Errorf("%w: the deletion detection policy must specify softDeleteColumnName and softDeleteMarkerValue", domain.ErrInvalidDataSource)
+		}
+	}
+	if conn, ok := s.Connectors[def.Type]; ok {
+		return conn.ValidateCredentials(cfg)
+	}
+	return nil
+}
+
+// highWaterMarkColumn returns the column tracking changes, or "" if items
+// are not tracked. Blob data sources track the last modified time without
+// a policy, as Azure's blob indexers do.
+func (d *dataSourceDefinition) highWaterMarkColumn() string {
+	if p := d.DataChangeDetectionPolicy; p != nil {
+		return p.HighWaterMarkColumnName
+	}
+	if d.isBlob() {
+		return "metadata_storage_last_modified"
+	}
+	return ""
+}
+
+// isBlob reports whether the data source holds blobs rather than rows.
+func (d *dataSourceDefinition) isBlob() bool {
+	return d.Type == "azureblob" || d.Type == "adlsgen2"
+}
+
+func keepsCredentials(def *dataSourceDefinition) bool {
+	cs := def.Credentials.ConnectionString
+	return cs == nil || *cs == "" || *cs == unchangedCredentials
+}
+
+// withConnectionString returns body with credentials.connectionString set.
+func withConnectionString(body []byte, cs *string) ([]byte, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, fmt.Errorf("%w: the request body is not valid JSON", domain.ErrInvalidDataSource)
+	}
+	creds, _ := m["credentials"].(map[string]interface{})
+	if creds == nil {
+		creds = map[string]interface{}{}
+		m["credentials"] = creds
+	}
+	creds["connectionString"] = cs
+	return json.Marshal(m)
+}
+
+// source returns the stored definition of a data source, credentials
+// included, together with the connector that reads it.
+func (s *DataSourceService) source(name string) (*dataSourceDefinition, domain.SourceConnector, error) {
+	ds, err := s.Repo.FindByName(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	def, err := parseDataSourceDefinition(ds.Definition)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, ok := s.Connectors[def.Type]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: data sources of type '%s' cannot be indexed by the emulator", domain.ErrInvalidDataSource, def.Type)
+	}
+	return def, conn, nil
+}
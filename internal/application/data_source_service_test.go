@@ -0,0 +1,223 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"ai-search-emulator/internal/domain"
+)
+
+func newDataSourceServiceForTest() (*DataSourceService, *mockDataSourceRepository, *mockConnector) {
+	repo := newMockDataSourceRepository()
+	conn := &mockConnector{invalidCredentials: "bad"}
+	return NewDataSourceService(repo, map[string]domain.SourceConnector{"azureblob": conn}), repo, conn
+}
+
+const validDataSourceJSON = `{
+	"name": "docs",
+	"type": "azureblob",
+	"credentials": {"connectionString": "LocalPath=/data"},
+	"container": {"name": "files", "query": "reports"}
+}`
+
+func TestDataSourceService_CreateDataSource_Success(t *testing.T) {
+	t.Parallel()
+	svc, repo, _ := newDataSourceServiceForTest()
+
+	ds, err := svc.CreateDataSource(context.Background(), []byte(validDataSourceJSON))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ds.Name != "docs" || ds.ETag == "" {
+		t.Errorf("created = %+v, want name docs with an ETag", ds)
+	}
+	if _, err := repo.FindByName("docs"); err != nil {
+		t.Errorf("data source should be stored: %v", err)
+	}
+}
+
+func TestDataSourceService_CreateDataSource_AlreadyExists(t *testing.T) {
+	t.Parallel()
+	svc, _, _ := newDataSourceServiceForTest()
+	if _, err := svc.CreateDataSource(context.Background(), []byte(validDataSourceJSON)); err != nil {
+		t.Fatalf("first create: %v", err)
+	}
+	_, err := svc.CreateDataSource(context.Background(), []byte(validDataSourceJSON))
+	if !errors.Is(err, domain.ErrDataSourceAlreadyExists) {
+		t.Errorf("err = %v, want ErrDataSourceAlreadyExists", err)
+	}
+}
+
+func TestDataSourceService_CreateDataSource_Invalid(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name string
+		body string
+		want error
+	}{
+		{"bad json", `{`, domain.ErrInvalidDataSource},
+		{"bad name", `{"name":"Docs!","type":"azureblob","credentials":{"connectionString":"x"},"container":{"name":"c"}}`, domain.ErrInvalidName},
+		{"unknown type", `{"name":"docs","type":"ftp","credentials":{"connectionString":"x"},"container":{"name":"c"}}`, domain.ErrInvalidDataSource},
+		{"no container", `{"name":"docs","type":"azureblob","credentials":{"connectionString":"x"}}`, domain.ErrInvalidDataSource},
+		{"no credentials", `{"name":"docs","type":"azureblob","container":{"name":"c"}}`, domain.ErrInvalidDataSource},
+		{"rejected credentials", `{"name":"docs","type":"azureblob","credentials":{"connectionString":"bad"},"container":{"name":"c"}}`, domain.ErrInvalidDataSource},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			svc, _, _ := newDataSourceServiceForTest()
+			_, err := svc.CreateDataSource(context.Background(), []byte(tc.body))
+			if !errors.Is(err, tc.want) {
+				t.Errorf("err = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestDataSourceService_CreateDataSource_TypeWithoutConnector(t *testing.T) {
+	t.Parallel()
+	svc, _, _ := newDataSourceServiceForTest()
+	body := `{"name":"cosmos","type":"cosmosdb","credentials":{"connectionString":"AccountEndpoint=x"},"container":{"name":"c"}}`
+
+	if _, err := svc.CreateDataSource(context.Background(), []byte(body)); err != nil {
+		t.Errorf("definitions of known types without a connector should be stored: %v", err)
+	}
+}
+
+func TestDataSourceService_GetDataSource_HidesConnectionString(t *testing.T) {
+	t.Parallel()
+	svc, _, _ := newDataSourceServiceForTest()
+	ds, _ := svc.CreateDataSource(context.Background(), []byte(validDataSourceJSON))
+
+	got, err := svc.GetDataSource(context.Background(), "docs")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	creds, _ := got["credentials"].(map[string]interface{})
+	if cs, ok := creds["connectionString"]; !ok || cs != nil {
+		t.Errorf("connectionString = %v, want null", creds["connectionString"])
+	}
+	if got["@odata.etag"] != ds.ETag {
+		t.Errorf("@odata.etag = %v, want %s", got["@odata.etag"], ds.ETag)
+	}
+}
+
+func TestDataSourceService_GetDataSource_NotFound(t *testing.T) {
+	t.Parallel()
+	svc, _, _ := newDataSourceServiceForTest()
+	if _, err := svc.GetDataSource(context.Background(), "missing"); !errors.Is(err, domain.ErrDataSourceNotFound) {
+		t.Errorf("err = %v, want ErrDataSourceNotFound", err)
+	}
+}
+
+func TestDataSourceService_CreateOrUpdate_KeepsUnchangedCredentials(t *testing.T) {
+	t.Parallel()
+	svc, repo, _ := newDataSourceServiceForTest()
+	ctx := context.Background()
+	if _, created, err := svc.CreateOrUpdateDataSource(ctx, "docs", []byte(validDataSourceJSON), AccessCondition{}); err != nil || !created {
+		t.Fatalf("create: created=%v err=%v", created, err)
+	}
+
+	for _, creds := range []string{`{"connectionString":"<unchanged>"}`, `{"connectionString":null}`, `{}`} {
+		update := `{"name":"docs","type":"azureblob","credentials":` + creds + `,"container":{"name":"other"}}`
+		if _, created, err := svc.CreateOrUpdateDataSource(ctx, "docs", []byte(update), AccessCondition{}); err != nil || created {
+			t.Fatalf("update with %s: created=%v err=%v", creds, created, err)
+		}
+		stored, _ := repo.FindByName("docs")
+		if !strings.Contains(stored.Definition, "LocalPath=/data") {
+			t.Errorf("update with %s dropped the connection string: %s", creds, stored.Definition)
+		}
+		if !strings.Contains(stored.Definition, `"other"`) {
+			t.Errorf("update with %s was not applied: %s", creds, stored.Definition)
+		}
+	}
+}
+
+func TestDataSourceService_CreateOrUpdate_RejectsTypeChange(t *testing.T) {
+	t.Parallel()
+	svc, _, _ := newDataSourceServiceForTest()
+	ctx := context.Background()
+	_, _, _ = svc.CreateOrUpdateDataSource(ctx, "docs", []byte(validDataSourceJSON), AccessCondition{})
+
+	update := `{"name":"docs","type":"azuresql","credentials":{"connectionString":"Data Source=x.db"},"container":{"name":"t"}}`
+	_, _, err := svc.CreateOrUpdateDataSource(ctx, "docs", []byte(update), AccessCondition{})
+	if !errors.Is(err, domain.ErrInvalidDataSource) {
+		t.Errorf("err = %v, want ErrInvalidDataSource", err)
+	}
+}
+
+func TestDataSourceService_CreateOrUpdate_NameMismatch(t *testing.T) {
+	t.Parallel()
+	svc, _, _ := newDataSourceServiceForTest()
+	_, _, err := svc.CreateOrUpdateDataSource(context.Background(), "other", []byte(validDataSourceJSON), AccessCondition{})
+	if !errors.Is(err, domain.ErrInvalidDataSource) {
+		t.Errorf("err = %v, want ErrInvalidDataSource", err)
+	}
+}
+
+func TestDataSourceService_AccessConditions(t *testing.T) {
+	t.Parallel()
+	svc, _, _ := newDataSourceServiceForTest()
+	ctx := context.Background()
+	ds, _, _ := svc.CreateOrUpdateDataSource(ctx, "docs", []byte(validDataSourceJSON), AccessCondition{})
+
+	if _, _, err := svc.CreateOrUpdateDataSource(ctx, "docs", []byte(validDataSourceJSON), AccessCondition{IfNoneMatch: "*"}); !errors.Is(err, domain.ErrPreconditionFailed) {
+		t.Errorf("If-None-Match: * on existing: err = %v, want ErrPreconditionFailed", err)
+	}
+	if _, _, err := svc.CreateOrUpdateDataSource(ctx, "docs", []byte(validDataSourceJSON), AccessCondition{IfMatch: `"stale"`}); !errors.Is(err, domain.ErrPreconditionFailed) {
+		t.Errorf("stale If-Match: err = %v, want ErrPreconditionFailed", err)
+	}
+	if err := svc.DeleteDataSource(ctx, "docs", AccessCondition{IfMatch: `"stale"`}); !errors.Is(err, domain.ErrPreconditionFailed) {
+		t.Errorf("stale If-Match on delete: err = %v, want ErrPreconditionFailed", err)
+	}
+	if err := svc.DeleteDataSource(ctx, "docs", AccessCondition{IfMatch: ds.ETag}); err != nil {
+		t.Errorf("matching If-Match on delete: %v", err)
+	}
+	if err := svc.DeleteDataSource(ctx, "docs", AccessCondition{}); !errors.Is(err, domain.ErrDataSourceNotFound) {
+		t.Errorf("second delete: err = %v, want ErrDataSourceNotFound", err)
+	}
+}
+
+func TestDataSourceService_ListDataSources(t *testing.T) {
+	t.Parallel()
+	svc, _, _ := newDataSourceServiceForTest()
+	ctx := context.Background()
+	_, _ = svc.CreateDataSource(ctx, []byte(validDataSourceJSON))
+
+	list, err := svc.ListDataSources(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list) != 1 || list[0]["name"] != "docs" {
+		t.Errorf("list = %v, want [docs]", list)
+	}
+}
+
+func TestDataSourceService_DetectionPolicies(t *testing.T) {
+	t.Parallel()
+	base := `{"name":"docs","type":"azureblob","credentials":{"connectionString":"x"},"container":{"name":"c"},`
+	cases := []struct {
+		name   string
+		policy string
+		ok     bool
+	}{
+		{"high water mark", `"dataChangeDetectionPolicy":{"@odata.type":"#Microsoft.Azure.Search.HighWaterMarkChangeDetectionPolicy","highWaterMarkColumnName":"ts"}`, true},
+		{"soft delete", `"dataDeletionDetectionPolicy":{"@odata.type":"#Microsoft.Azure.Search.SoftDeleteColumnDeletionDetectionPolicy","softDeleteColumnName":"gone","softDeleteMarkerValue":"true"}`, true},
+		{"no column", `"dataChangeDetectionPolicy":{"@odata.type":"#Microsoft.Azure.Search.HighWaterMarkChangeDetectionPolicy"}`, false},
+		{"no marker", `"dataDeletionDetectionPolicy":{"@odata.type":"#Microsoft.Azure.Search.SoftDeleteColumnDeletionDetectionPolicy","softDeleteColumnName":"gone"}`, false},
+		{"unsupported", `"dataChangeDetectionPolicy":{"@odata.type":"#Microsoft.Azure.Search.SqlIntegratedChangeTrackingPolicy"}`, false},
+	}
+	for _, tc := range cases {
+		svc, _, _ := newDataSourceServiceForTest()
+		_, err := svc.CreateDataSource(context.Background(), []byte(base+tc.policy+`}`))
+		if tc.ok && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+		if !tc.ok && !errors.Is(err, domain.ErrInvalidDataSource) {
+			t.Errorf("%s: err = %v, want ErrInvalidDataSource", tc.name, err)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package application
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"ai-search-emulator/internal/domain"
+)
+
+// maxKeyLength is Azure's limit on the length of a document key.
+const maxKeyLength = 1024
+
+// validateDocumentKey applies Azure's document key rules and wraps
+// domain.ErrInvalidDocumentKey on failure.
+func validateDocumentKey(key string, allowUnsafe bool) error {
+	if reason := invalidKeyReason(key, allowUnsafe); reason != "" {
+		return fmt.Errorf("%w: %s", domain.ErrInvalidDocumentKey, reason)
+	}
+	return nil
+}
+
+// invalidKeyReason explains why key is rejected, or returns "" if it is
+// valid. Keys may contain only letters, digits, '_', '-' and '='; allowUnsafe
+// lifts the character restriction but keys must still be non-empty and at
+// most 1024 characters.
+func invalidKeyReason(key string, allowUnsafe bool) string {
+	if key == "" {
+		return "the document key must not be empty"
+	}
+	if utf8.RuneCountInString(key) > maxKeyLength {
+		return fmt.Sprintf("the document key exceeds the maximum length of %d characters", maxKeyLength)
+	}
+	if allowUnsafe {
+		return ""
+	}
+	for _, r := range key {
+		if !isSafeKeyRune(r) {
+			return fmt.Sprintf("'%s'. Keys can only contain letters, digits, underscore (_), dash (-), or equal sign (=)", key)
+		}
+	}
+	return ""
+}
+
+func isSafeKeyRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' || r == '='
+}
@@ -0,0 +1,48 @@
+package application
+
+import "context"
+
+// DocumentModel describes the shape of an index's documents, as advertised by
+// the per-index OData $metadata document.
+type DocumentModel struct {
+	Index  string
+	Key    string
+	Fields []ModelField
+}
+
+// ModelField is one field of a DocumentModel. Type is the EDM type from the
+// index definition, e.g. "Edm.String" or "Collection(Edm.ComplexType)";
+// Fields holds the sub-fields of complex types.
+type ModelField struct {
+	Name   string
+	Type   string
+	Fields []ModelField
+}
+
+// GetDocumentModel returns the document model of the named index.
+func (s *IndexService) GetDocumentModel(ctx context.Context, name string) (*DocumentModel, error) {
+	idx, err := s.Repo.FindByName(name)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := parseIndexSchema(idx.Schema)
+	if err != nil {
+		return nil, err
+	}
+	return &DocumentModel{
+		Index:  idx.Name,
+		Key:    schema.keyField(),
+		Fields: modelFields(schema.Fields),
+	}, nil
+}
+
+func modelFields(fields []indexField) []ModelField {
+	if len(fields) == 0 {
+		return nil
+	}
+	out := make([]ModelField, len(fields))
+	for i, f := range fields {
+		out[i] = ModelField{Name: f.Name, Type: f.Type, Fields: modelFields(f.Fields)}
+	}
+	return out
+}
@@ -0,0 +1,149 @@
+package application
+
+import (
+	"fmt"
+
+	"ai-search-emulator/internal/domain"
+)
+
+// isReturnable reports whether a field's value may appear in responses.
+// Fields marked retrievable: false or stored: false (vector fields) are
+// never returned.
+func (f *indexField) isReturnable() bool {
+	return (f.Retrievable == nil || *f.Retrievable) && (f.Stored == nil || *f.Stored)
+}
+
+// validateSelect checks that every $select path names an existing field that
+// can be returned.
+func validateSelect(schema *indexSchema, paths []string) error {
+	for _, p := range paths {
+		if p == "*" {
+			continue
+		}
+		fields := schema.Fields
+		for _, seg := range splitFieldPath(p) {
+			f := findField(fields, seg)
+			if f == nil {
+				return fmt.Errorf("%w: could not find a property named '%s' on type 'search.document'", domain.ErrInvalidSelect, p)
+			}
+			if !f.isReturnable() {
+				return fmt.Errorf("%w: the field '%s' is not retrievable", domain.ErrInvalidSelect, p)
+			}
+			fields = f.Fields
+		}
+	}
+	return nil
+}
+
+func findField(fields []indexField, name string) *indexField {
+	for i := range fields {
+		if fields[i].Name == name {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+// projectDocument shapes a stored document for a response: fields that are
+// not returnable are removed and, when paths is non-empty, only the selected
+// (possibly nested, e.g. "Address/City") fields are kept. Fields the schema
+// does not declare are treated as returnable.
+func projectDocument(doc map[string]interface{}, schema *indexSchema, paths []string) map[string]interface{} {
+	if schema != nil {
+		doc = stripUnreturnable(doc, schema.Fields)
+	}
+	if len(paths) == 0 {
+		return doc
+	}
+	out := make(map[string]interface{}, len(paths))
+	for _, p := range paths {
+		if p == "*" {
+			return doc
+		}
+		selectPath(out, doc, splitFieldPath(p))
+	}
+	return out
+}
+
+// stripUnreturnable returns a copy of doc without non-returnable fields,
+// recursing into complex fields and collections of complex fields.
+func stripUnreturnable(doc map[string]interface{}, fields []indexField) map[string]interface{} {
+	out := make(map[string]interface{}, len(doc))
+	for k, v := range doc {
+		f := findField(fields, k)
+		if f == nil {
+			out[k] = v
+			continue
+		}
+		if !f.isReturnable() {
+			continue
+		}
+		if len(f.Fields) == 0 {
+			out[k] = v
+			continue
+		}
+		switch val := v.(type) {
+		case map[string]interface{}:
+			out[k] = stripUnreturnable(val, f.Fields)
+		case []interface{}:
+			items := make([]interface{}, len(val))
+			for i, item := range val {
+				if m, ok := item.(map[string]interface{}); ok {
+					items[i] = stripUnreturnable(m, f.Fields)
+				} else {
+					items[i] = item
+				}
+			}
+			out[k] = items
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// selectPath copies the value at segs from src into dst, creating the
+// enclosing objects. Selecting a sub-field of a complex collection projects
+// every element.
+func selectPath(dst, src map[string]interface{}, segs []string) {
+	if len(segs) == 0 {
+		return
+	}
+	v, ok := src[segs[0]]
+	if !ok {
+		return
+	}
+	if len(segs) == 1 {
+		dst[segs[0]] = v
+		return
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		child, _ := dst[segs[0]].(map[string]interface{})
+		if child == nil {
+			child = map[string]interface{}{}
+		}
+		selectPath(child, val, segs[1:])
+		dst[segs[0]] = child
+	case []interface{}:
+		items, _ := dst[segs[0]].([]interface{})
+		if len(items) != len(val) {
+			items = make([]interface{}, len(val))
+		}
+		for i, item := range val {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			child, _ := items[i].(map[string]interface{})
+			if child == nil {
+				child = map[string]interface{}{}
+			}
+			selectPath(child, m, segs[1:])
+			items[i] = child
+		}
+		dst[segs[0]] = items
+	case nil:
+		dst[segs[0]] = nil
+	}
+}
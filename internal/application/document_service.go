@@ -10,16 +10,27 @@ import (
 	"strings"
 )
 
+// Azure's per-request limits for POST /indexes/{index}/docs/index.
+const (
+	MaxBatchActions      = 1000
+	MaxBatchPayloadBytes = 16 << 20
+)
+
 type DocumentService struct {
 	DocRepo domain.DocumentRepository
 	IdxRepo domain.IndexRepository
+	// Availability rejects requests against indexes that are offline after
+	// an update. Nil treats every index as available.
+	Availability *IndexAvailability
 }
 
 func NewDocumentService(docRepo domain.DocumentRepository, idxRepo domain.IndexRepository) *DocumentService {
 	return &DocumentService{DocRepo: docRepo, IdxRepo: idxRepo}
 }
 
-func (s *DocumentService) AddOrUpdateSingleDoc(ctx context.Context, indexName string, doc map[string]interface{}) error {
+// AddOrUpdateSingleDoc uploads one document. Unless allowUnsafeKeys is set,
+// its key must satisfy Azure's key character rules.
+func (s *DocumentService) AddOrUpdateSingleDoc(ctx context.Context, indexName string, doc map[string]interface{}, allowUnsafeKeys bool) error {
 	exists, err := s.IdxRepo.Exists(indexName)
 	if err != nil {
 		return err
@@ -27,6 +38,9 @@ func (s *DocumentService) AddOrUpdateSingleDoc(ctx context.Context, indexName st
 	if !exists {
 		return domain.ErrIndexNotFound
 	}
+	if err := s.Availability.check(indexName); err != nil {
+		return err
+	}
 	keyField, err := s.keyField(indexName)
 	if err != nil {
 		return err
@@ -39,6 +53,9 @@ func (s *DocumentService) AddOrUpdateSingleDoc(ctx context.Context, indexName st
 	if !ok {
 		return fmt.Errorf("key field must be string")
 	}
+	if err := validateDocumentKey(keyStr, allowUnsafeKeys); err != nil {
+		return err
+	}
 	docJSON, _ := json.Marshal(doc)
 	return s.DocRepo.Upsert(&domain.Document{
 		IndexName: indexName,
@@ -47,7 +64,15 @@ func (s *DocumentService) AddOrUpdateSingleDoc(ctx context.Context, indexName st
 	})
 }
 
-func (s *DocumentService) BatchOperation(ctx context.Context, indexName string, docs []map[string]interface{}) ([]map[string]interface{}, error) {
+// BatchOperation applies a /docs/index batch. The whole batch runs in one
+// repository transaction: per-document problems (missing key, merge of an
+// absent document, invalid key, ...) are reported in the results, while a
+// storage failure rolls back every action and is returned as an error.
+// allowUnsafeKeys lifts the key character restriction.
+func (s *DocumentService) BatchOperation(ctx context.Context, indexName string, docs []map[string]interface{}, allowUnsafeKeys bool) ([]map[string]interface{}, error) {
+	if len(docs) > MaxBatchActions {
+		return nil, fmt.Errorf("%w: the batch contains %d actions; the maximum is %d", domain.ErrBatchTooLarge, len(docs), MaxBatchActions)
+	}
 	exists, err := s.IdxRepo.Exists(indexName)
 	if err != nil {
 		return nil, err
@@ -55,81 +80,126 @@ func (s *DocumentService) BatchOperation(ctx context.Context, indexName string,
 	if !exists {
 		return nil, domain.ErrIndexNotFound
 	}
+	if err := s.Availability.check(indexName); err != nil {
+		return nil, err
+	}
 	keyField, err := s.keyField(indexName)
 	if err != nil {
 		return nil, err
 	}
 
-	results := make([]map[string]interface{}, 0, len(docs))
-	for _, d := range docs {
-		action, ok := d["@search.action"].(string)
-		if !ok {
-			results = append(results, batchError("", http.StatusBadRequest, "Missing @search.action"))
-			continue
+	var results []map[string]interface{}
+	err = s.DocRepo.Batch(indexName, func(b domain.DocumentBatch) error {
+		results = make([]map[string]interface{}, 0, len(docs))
+		for _, d := range docs {
+			result, err := applyBatchAction(b, keyField, d, allowUnsafeKeys)
+			if err != nil {
+				return err
+			}
+			results = append(results, result)
 		}
-		keyVal, ok := d[keyField]
-		if !ok {
-			results = append(results, batchError("", http.StatusBadRequest, "Missing key field"))
-			continue
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// applyBatchAction executes one indexing action and returns its result entry.
+// Only storage errors are returned as err.
+func applyBatchAction(b domain.DocumentBatch, keyField string, d map[string]interface{}, allowUnsafeKeys bool) (map[string]interface{}, error) {
+	action, ok := d["@search.action"].(string)
+	if !ok {
+		return batchError("", http.StatusBadRequest, "Missing @search.action"), nil
+	}
+	keyVal, ok := d[keyField]
+	if !ok {
+		return batchError("", http.StatusBadRequest, "Missing key field"), nil
+	}
+	keyStr, ok := keyVal.(string)
+	if !ok {
+		return batchError("", http.StatusBadRequest, "Key field must be a string"), nil
+	}
+	if reason := invalidKeyReason(keyStr, allowUnsafeKeys); reason != "" {
+		return batchError(keyStr, http.StatusBadRequest, "Invalid document key: "+reason), nil
+	}
+	fields := documentFields(d)
+
+	switch action {
+	case "upload":
+		_, findErr := b.Find(keyStr)
+		if findErr != nil && !errors.Is(findErr, domain.ErrDocumentNotFound) {
+			return nil, findErr
+		}
+		docJSON, _ := json.Marshal(fields)
+		if err := b.Upsert(keyStr, string(docJSON)); err != nil {
+			return nil, err
+		}
+		if findErr != nil {
+			return batchSuccess(keyStr, http.StatusCreated), nil
+		}
+		return batchSuccess(keyStr, http.StatusOK), nil
+	case "merge", "mergeOrUpload":
+		var content map[string]interface{}
+		statusCode := http.StatusOK
+		old, err := b.Find(keyStr)
+		switch {
+		case err == nil:
+			_ = json.Unmarshal([]byte(old.Content), &content)
+		case !errors.Is(err, domain.ErrDocumentNotFound):
+			return nil, err
+		case action == "merge":
+			return batchError(keyStr, http.StatusNotFound, "Document not found for merge"), nil
+		default:
+			// mergeOrUpload of a new document behaves like upload.
+			statusCode = http.StatusCreated
+		}
+		mergedJSON, _ := json.Marshal(mergeDocument(content, fields))
+		if err := b.Upsert(keyStr, string(mergedJSON)); err != nil {
+			return nil, err
 		}
-		keyStr, ok := keyVal.(string)
-		if !ok {
-			results = append(results, batchError("", http.StatusBadRequest, "Key field must be a string"))
-			continue
+		return batchSuccess(keyStr, statusCode), nil
+	case "delete":
+		// Azure reports 200 for deletes even when the key does not exist.
+		if err := b.Delete(keyStr); err != nil {
+			return nil, err
 		}
-		docJSON, _ := json.Marshal(d)
+		return batchSuccess(keyStr, http.StatusOK), nil
+	default:
+		return batchError(keyStr, http.StatusBadRequest, "Unknown action: "+action), nil
+	}
+}
 
-		switch action {
-		case "upload":
-			_, findErr := s.DocRepo.Find(indexName, keyStr)
-			isNew := errors.Is(findErr, domain.ErrDocumentNotFound)
-			if err := s.DocRepo.Upsert(&domain.Document{IndexName: indexName, Key: keyStr, Content: string(docJSON)}); err != nil {
-				results = append(results, batchError(keyStr, http.StatusInternalServerError, err.Error()))
-			} else if isNew {
-				results = append(results, batchSuccess(keyStr, http.StatusCreated))
-			} else {
-				results = append(results, batchSuccess(keyStr, http.StatusOK))
-			}
-		case "mergeOrUpload":
-			_, findErr := s.DocRepo.Find(indexName, keyStr)
-			isNew := errors.Is(findErr, domain.ErrDocumentNotFound)
-			if err := s.DocRepo.Upsert(&domain.Document{IndexName: indexName, Key: keyStr, Content: string(docJSON)}); err != nil {
-				results = append(results, batchError(keyStr, http.StatusInternalServerError, err.Error()))
-			} else if isNew {
-				results = append(results, batchSuccess(keyStr, http.StatusCreated))
-			} else {
-				results = append(results, batchSuccess(keyStr, http.StatusOK))
-			}
-		case "merge":
-			old, err := s.DocRepo.Find(indexName, keyStr)
-			if err != nil {
-				results = append(results, batchError(keyStr, http.StatusNotFound, "Document not found for merge"))
-				continue
-			}
-			var oldDoc map[string]interface{}
-			_ = json.Unmarshal([]byte(old.Content), &oldDoc)
-			for k, v := range d {
-				if k != "@search.action" && k != keyField {
-					oldDoc[k] = v
-				}
-			}
-			mergedJSON, _ := json.Marshal(oldDoc)
-			if err := s.DocRepo.Upsert(&domain.Document{IndexName: indexName, Key: keyStr, Content: string(mergedJSON)}); err != nil {
-				results = append(results, batchError(keyStr, http.StatusInternalServerError, err.Error()))
-			} else {
-				results = append(results, batchSuccess(keyStr, http.StatusOK))
-			}
-		case "delete":
-			if err := s.DocRepo.Delete(indexName, keyStr); err != nil {
-				results = append(results, batchError(keyStr, http.StatusInternalServerError, err.Error()))
-			} else {
-				results = append(results, batchSuccess(keyStr, http.StatusOK))
-			}
+// documentFields returns the document without its "@search.action"
+// annotation, which is not part of the stored content.
+func documentFields(d map[string]interface{}) map[string]interface{} {
+	fields := make(map[string]interface{}, len(d))
+	for k, v := range d {
+		if k != "@search.action" {
+			fields[k] = v
+		}
+	}
+	return fields
+}
+
+// mergeDocument applies Azure's merge semantics to old in place and returns
+// it: null sets a field to null, collections are replaced wholesale and
+// complex (object) fields are merged recursively.
+func mergeDocument(old, patch map[string]interface{}) map[string]interface{} {
+	if old == nil {
+		old = map[string]interface{}{}
+	}
+	for k, v := range patch {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			prev, _ := old[k].(map[string]interface{})
+			old[k] = mergeDocument(prev, val)
 		default:
-			results = append(results, batchError(keyStr, http.StatusBadRequest, "Unknown action: "+action))
+			old[k] = val
 		}
 	}
-	return results, nil
+	return old
 }
 
 func batchSuccess(key string, statusCode int) map[string]interface{} {
@@ -161,32 +231,57 @@ func (s *DocumentService) SearchDocuments(ctx context.Context, indexName string,
 	if !exists {
 		return nil, domain.ErrIndexNotFound
 	}
+	if err := s.Availability.check(indexName); err != nil {
+		return nil, err
+	}
+
+	if err := validateSearchParams(params); err != nil {
+		return nil, err
+	}
+	schema, err := s.schema(indexName)
+	if err != nil {
+		return nil, err
+	}
 
 	opts := domain.SearchOptions{
 		TextSearch:       params.Search,
 		TextSearchFields: params.SearchFields,
-		Top:              params.Top,
+		Top:              defaultTop,
 		Skip:             params.Skip,
 	}
-	if opts.Top <= 0 {
-		opts.Top = defaultTop
+	if params.Top != nil {
+		// $top=0 asks only for the count; the repository still needs a
+		// positive page size, and the page is dropped below.
+		opts.Top = max(*params.Top, 1)
+	}
+	if opts.Top > MaxTop {
+		opts.Top = MaxTop
 	}
 
+	var scoring []*fullTextQuery
 	if params.Filter != "" {
-		whereSQL, whereArgs, err := ParseODataFilter(params.Filter)
+		filter, err := compileODataFilter(params.Filter, schema)
 		if err != nil {
 			return nil, fmt.Errorf("invalid $filter: %w", err)
 		}
-		opts.WhereSQL = whereSQL
-		opts.WhereArgs = whereArgs
+		opts.WhereSQL = filter.sql
+		opts.WhereArgs = filter.args
+		scoring = filter.scoring
 	}
 
+	// Without $orderby, Azure ranks by descending score; the key breaks ties.
+	clauses := []sortClause{{kind: sortScore, desc: true}}
 	if params.OrderBy != "" {
-		orderSQL, err := ParseODataOrderBy(params.OrderBy)
+		clauses, err = parseOrderBy(params.OrderBy, schema)
 		if err != nil {
 			return nil, fmt.Errorf("invalid $orderby: %w", err)
 		}
-		opts.OrderSQL = orderSQL
+	}
+	inMemory := needsInMemorySort(clauses, scoring)
+	if inMemory {
+		opts.Unpaged = true
+	} else {
+		opts.OrderSQL = orderBySQL(clauses)
 	}
 
 	docs, total, err := s.DocRepo.Search(indexName, opts)
@@ -194,21 +289,59 @@ func (s *DocumentService) SearchDocuments(ctx context.Context, indexName string,
 		return nil, err
 	}
 
-	results := make([]map[string]interface{}, 0, len(docs))
+	hits := make([]searchHit, 0, len(docs))
 	for _, doc := range docs {
 		var m map[string]interface{}
 		if err := json.Unmarshal([]byte(doc.Content), &m); err == nil {
-			if len(params.Select) > 0 {
-				m = selectFields(m, params.Select)
-			}
-			results = append(results, m)
+			hits = append(hits, searchHit{key: doc.Key, doc: m, score: documentScore(doc.Content, m, scoring)})
 		}
 	}
+	if inMemory {
+		sortHits(hits, clauses)
+		hits = pageHits(hits, opts.Skip, opts.Top)
+	}
+	if params.Top != nil && *params.Top == 0 {
+		hits = nil
+	}
 
-	return &SearchResult{Value: results, Total: total}, nil
+	results := make([]map[string]interface{}, 0, len(hits))
+	scores := make([]float64, 0, len(hits))
+	for _, h := range hits {
+		results = append(results, projectDocument(h.doc, schema, params.Select))
+		scores = append(scores, h.score)
+	}
+
+	return &SearchResult{Value: results, Scores: scores, Total: total}, nil
+}
+
+// pageHits applies $skip and $top to hits that were sorted in memory.
+func pageHits(hits []searchHit, skip, top int) []searchHit {
+	if skip >= len(hits) {
+		return nil
+	}
+	hits = hits[skip:]
+	if top < len(hits) {
+		hits = hits[:top]
+	}
+	return hits
 }
 
-func (s *DocumentService) GetDocument(ctx context.Context, indexName, key string) (map[string]interface{}, error) {
+// documentScore computes @search.score for a hit. Every hit starts at 1.0 and
+// each search.ismatchscoring clause that matches the document adds its
+// term-frequency score.
+func documentScore(content string, doc map[string]interface{}, scoring []*fullTextQuery) float64 {
+	score := 1.0
+	for _, q := range scoring {
+		if q.matches(content, doc) {
+			score += q.score(content, doc)
+		}
+	}
+	return score
+}
+
+// GetDocument looks up a document by key. Non-retrievable fields are never
+// returned; selectPaths ($select, nested paths allowed) narrows the result.
+func (s *DocumentService) GetDocument(ctx context.Context, indexName, key string, selectPaths []string) (map[string]interface{}, error) {
 	exists, err := s.IdxRepo.Exists(indexName)
 	if err != nil {
 		return nil, err
@@ -216,6 +349,16 @@ func (s *DocumentService) GetDocument(ctx context.Context, indexName, key string
 	if !exists {
 		return nil, domain.ErrIndexNotFound
 	}
+	if err := s.Availability.check(indexName); err != nil {
+		return nil, err
+	}
+	schema, err := s.schema(indexName)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSelect(schema, selectPaths); err != nil {
+		return nil, err
+	}
 	doc, err := s.DocRepo.Find(indexName, key)
 	if err != nil {
 		return nil, err
@@ -224,7 +367,7 @@ func (s *DocumentService) GetDocument(ctx context.Context, indexName, key string
 	if err := json.Unmarshal([]byte(doc.Content), &m); err != nil {
 		return nil, fmt.Errorf("invalid document json")
 	}
-	return m, nil
+	return projectDocument(m, schema, selectPaths), nil
 }
 
 func (s *DocumentService) CountDocuments(ctx context.Context, indexName string) (int, error) {
@@ -235,43 +378,33 @@ func (s *DocumentService) CountDocuments(ctx context.Context, indexName string)
 	if !exists {
 		return 0, domain.ErrIndexNotFound
 	}
+	if err := s.Availability.check(indexName); err != nil {
+		return 0, err
+	}
 	return s.DocRepo.Count(indexName)
 }
 
+// schema loads and parses the schema of the named index.
+func (s *DocumentService) schema(indexName string) (*indexSchema, error) {
+	idx, err := s.IdxRepo.FindByName(indexName)
+	if err != nil {
+		return nil, err
+	}
+	return parseIndexSchema(idx.Schema)
+}
+
 // keyField extracts the name of the key field from the index schema.
 func (s *DocumentService) keyField(indexName string) (string, error) {
-	idx, err := s.IdxRepo.FindByName(indexName)
+	schema, err := s.schema(indexName)
 	if err != nil {
 		return "", err
 	}
-	var schema struct {
-		Fields []struct {
-			Name string `json:"name"`
-			Key  bool   `json:"key"`
-		}
-	}
-	if err := json.Unmarshal([]byte(idx.Schema), &schema); err != nil {
-		return "", fmt.Errorf("schema parse error")
-	}
-	for _, f := range schema.Fields {
-		if f.Key {
-			return f.Name, nil
-		}
+	if key := schema.keyField(); key != "" {
+		return key, nil
 	}
 	return "", domain.ErrMissingKeyField
 }
 
-// selectFields returns a new map containing only the requested fields.
-func selectFields(m map[string]interface{}, fields []string) map[string]interface{} {
-	result := make(map[string]interface{}, len(fields))
-	for _, f := range fields {
-		if v, ok := m[f]; ok {
-			result[f] = v
-		}
-	}
-	return result
-}
-
 // contains reports whether content includes the search term (case-insensitive).
 func contains(content string, search string) bool {
 	if search == "" {
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -34,7 +35,7 @@ func TestDocumentService_AddOrUpdateSingleDoc_Success(t *testing.T) {
 	seedIndex(t, idxRepo, "idx")
 
 	doc := map[string]interface{}{"id": "doc-1", "title": "hello"}
-	if err := svc.AddOrUpdateSingleDoc(context.Background(), "idx", doc); err != nil {
+	if err := svc.AddOrUpdateSingleDoc(context.Background(), "idx", doc, false); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	got, err := docRepo.Find("idx", "doc-1")
@@ -51,7 +52,7 @@ func TestDocumentService_AddOrUpdateSingleDoc_Success(t *testing.T) {
 func TestDocumentService_AddOrUpdateSingleDoc_IndexNotFound(t *testing.T) {
 	t.Parallel()
 	svc, _, _ := newDocumentServiceForTest()
-	err := svc.AddOrUpdateSingleDoc(context.Background(), "missing", map[string]interface{}{"id": "1"})
+	err := svc.AddOrUpdateSingleDoc(context.Background(), "missing", map[string]interface{}{"id": "1"}, false)
 	if err == nil || err.Error() != "index not found" {
 		t.Fatalf("expected 'index not found', got %v", err)
 	}
@@ -61,7 +62,7 @@ func TestDocumentService_AddOrUpdateSingleDoc_MissingKeyField(t *testing.T) {
 	t.Parallel()
 	svc, idxRepo, _ := newDocumentServiceForTest()
 	seedIndex(t, idxRepo, "idx")
-	err := svc.AddOrUpdateSingleDoc(context.Background(), "idx", map[string]interface{}{"title": "no key"})
+	err := svc.AddOrUpdateSingleDoc(context.Background(), "idx", map[string]interface{}{"title": "no key"}, false)
 	if err == nil || err.Error() != "missing key field" {
 		t.Fatalf("expected 'missing key field', got %v", err)
 	}
@@ -71,12 +72,61 @@ func TestDocumentService_AddOrUpdateSingleDoc_NonStringKey(t *testing.T) {
 	t.Parallel()
 	svc, idxRepo, _ := newDocumentServiceForTest()
 	seedIndex(t, idxRepo, "idx")
-	err := svc.AddOrUpdateSingleDoc(context.Background(), "idx", map[string]interface{}{"id": 42, "title": "n"})
+	err := svc.AddOrUpdateSingleDoc(context.Background(), "idx", map[string]interface{}{"id": 42, "title": "n"}, false)
 	if err == nil || err.Error() != "key field must be string" {
 		t.Fatalf("expected 'key field must be string', got %v", err)
 	}
 }
 
+func TestValidateDocumentKey(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		key         string
+		allowUnsafe bool
+		wantErr     bool
+	}{
+		{"abc_DEF-123=", false, false},
+		{"", false, true},
+		{"", true, true},
+		{"has space", false, true},
+		{"a/b", false, true},
+		{"日本", false, true},
+		{"a/b", true, false},
+		{strings.Repeat("k", maxKeyLength), false, false},
+		{strings.Repeat("k", maxKeyLength+1), true, true},
+	}
+	for _, tt := range tests {
+		err := validateDocumentKey(tt.key, tt.allowUnsafe)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validateDocumentKey(%q, %v) = %v, wantErr %v", tt.key, tt.allowUnsafe, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, domain.ErrInvalidDocumentKey) {
+			t.Errorf("error should wrap ErrInvalidDocumentKey, got %v", err)
+		}
+	}
+}
+
+func TestDocumentService_BatchOperation_InvalidKey(t *testing.T) {
+	t.Parallel()
+	svc, idxRepo, docRepo := newDocumentServiceForTest()
+	seedIndex(t, idxRepo, "idx")
+
+	docs := []map[string]interface{}{{"@search.action": "upload", "id": "a b"}}
+	results, err := svc.BatchOperation(context.Background(), "idx", docs, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if results[0]["statusCode"] != http.StatusBadRequest || results[0]["key"] != "a b" {
+		t.Errorf("unexpected result: %v", results[0])
+	}
+	if _, err := svc.BatchOperation(context.Background(), "idx", docs, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := docRepo.Find("idx", "a b"); err != nil {
+		t.Errorf("allowUnsafeKeys should store the document: %v", err)
+	}
+}
+
 func TestDocumentService_AddOrUpdateSingleDoc_NoKeyInSchema(t *testing.T) {
 	t.Parallel()
 	svc, idxRepo, _ := newDocumentServiceForTest()
@@ -84,7 +134,7 @@ func TestDocumentService_AddOrUpdateSingleDoc_NoKeyInSchema(t *testing.T) {
 		Name:   "no-key",
 		Schema: `{"fields":[{"name":"id","key":false}]}`,
 	})
-	err := svc.AddOrUpdateSingleDoc(context.Background(), "no-key", map[string]interface{}{"id": "1"})
+	err := svc.AddOrUpdateSingleDoc(context.Background(), "no-key", map[string]interface{}{"id": "1"}, false)
 	if err == nil || err.Error() != "missing key field" {
 		t.Fatalf("expected 'missing key field', got %v", err)
 	}
@@ -94,7 +144,7 @@ func TestDocumentService_AddOrUpdateSingleDoc_BadSchema(t *testing.T) {
 	t.Parallel()
 	svc, idxRepo, _ := newDocumentServiceForTest()
 	idxRepo.store["bad"] = &domain.Index{Name: "bad", Schema: "not-json"}
-	err := svc.AddOrUpdateSingleDoc(context.Background(), "bad", map[string]interface{}{"id": "1"})
+	err := svc.AddOrUpdateSingleDoc(context.Background(), "bad", map[string]interface{}{"id": "1"}, false)
 	if err == nil || !strings.Contains(err.Error(), "schema parse error") {
 		t.Fatalf("expected schema parse error, got %v", err)
 	}
@@ -104,7 +154,7 @@ func TestDocumentService_AddOrUpdateSingleDoc_ExistsRepoError(t *testing.T) {
 	t.Parallel()
 	svc, idxRepo, _ := newDocumentServiceForTest()
 	idxRepo.existsErr = errors.New("db down")
-	err := svc.AddOrUpdateSingleDoc(context.Background(), "x", map[string]interface{}{"id": "1"})
+	err := svc.AddOrUpdateSingleDoc(context.Background(), "x", map[string]interface{}{"id": "1"}, false)
 	if err == nil || err.Error() != "db down" {
 		t.Fatalf("expected db down, got %v", err)
 	}
@@ -121,7 +171,7 @@ func TestDocumentService_BatchOperation_Upload(t *testing.T) {
 		{"@search.action": "upload", "id": "1", "title": "a"},
 		{"@search.action": "upload", "id": "2", "title": "b"},
 	}
-	results, err := svc.BatchOperation(context.Background(), "idx", docs)
+	results, err := svc.BatchOperation(context.Background(), "idx", docs, false)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -146,7 +196,7 @@ func TestDocumentService_BatchOperation_MergeOrUpload(t *testing.T) {
 	docs := []map[string]interface{}{
 		{"@search.action": "mergeOrUpload", "id": "1", "title": "x"},
 	}
-	results, err := svc.BatchOperation(context.Background(), "idx", docs)
+	results, err := svc.BatchOperation(context.Background(), "idx", docs, false)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -170,7 +220,7 @@ func TestDocumentService_BatchOperation_MergeExisting(t *testing.T) {
 	docs := []map[string]interface{}{
 		{"@search.action": "merge", "id": "1", "title": "new"},
 	}
-	results, err := svc.BatchOperation(context.Background(), "idx", docs)
+	results, err := svc.BatchOperation(context.Background(), "idx", docs, false)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -200,7 +250,7 @@ func TestDocumentService_BatchOperation_MergeMissingDoc(t *testing.T) {
 	docs := []map[string]interface{}{
 		{"@search.action": "merge", "id": "ghost", "title": "n"},
 	}
-	results, err := svc.BatchOperation(context.Background(), "idx", docs)
+	results, err := svc.BatchOperation(context.Background(), "idx", docs, false)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -212,6 +262,112 @@ func TestDocumentService_BatchOperation_MergeMissingDoc(t *testing.T) {
 	}
 }
 
+func TestDocumentService_BatchOperation_MergeSemantics(t *testing.T) {
+	t.Parallel()
+	svc, idxRepo, docRepo := newDocumentServiceForTest()
+	seedIndex(t, idxRepo, "idx")
+	_ = docRepo.Upsert(&domain.Document{
+		IndexName: "idx", Key: "1",
+		Content: `{"id":"1","title":"old","author":"alice","tags":["a","b"],"address":{"city":"Tokyo","zip":"100"}}`,
+	})
+
+	for _, action := range []string{"merge", "mergeOrUpload"} {
+		docs := []map[string]interface{}{{
+			"@search.action": action, "id": "1",
+			"author":  nil,
+			"tags":    []interface{}{"c"},
+			"address": map[string]interface{}{"city": "Osaka"},
+		}}
+		results, err := svc.BatchOperation(context.Background(), "idx", docs, false)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", action, err)
+		}
+		if results[0]["statusCode"] != http.StatusOK {
+			t.Errorf("%s: statusCode = %v, want 200", action, results[0]["statusCode"])
+		}
+
+		got, _ := docRepo.Find("idx", "1")
+		var content map[string]interface{}
+		_ = json.Unmarshal([]byte(got.Content), &content)
+		if v, ok := content["author"]; !ok || v != nil {
+			t.Errorf("%s: null should set author to null, got %v (present=%v)", action, v, ok)
+		}
+		if tags, _ := content["tags"].([]interface{}); len(tags) != 1 || tags[0] != "c" {
+			t.Errorf("%s: collection should be replaced, got %v", action, content["tags"])
+		}
+		addr, _ := content["address"].(map[string]interface{})
+		if addr["city"] != "Osaka" || addr["zip"] != "100" {
+			t.Errorf("%s: complex field should merge recursively, got %v", action, addr)
+		}
+		if content["title"] != "old" {
+			t.Errorf("%s: unspecified fields must be retained, got %v", action, content["title"])
+		}
+		if _, ok := content["@search.action"]; ok {
+			t.Errorf("%s: @search.action must not be stored", action)
+		}
+	}
+}
+
+func TestDocumentService_BatchOperation_MergeOrUploadStatusCodes(t *testing.T) {
+	t.Parallel()
+	svc, idxRepo, docRepo := newDocumentServiceForTest()
+	seedIndex(t, idxRepo, "idx")
+	_ = docRepo.Upsert(&domain.Document{IndexName: "idx", Key: "1", Content: `{"id":"1","title":"old"}`})
+
+	docs := []map[string]interface{}{
+		{"@search.action": "mergeOrUpload", "id": "1", "title": "new"},
+		{"@search.action": "mergeOrUpload", "id": "2", "title": "fresh", "author": nil},
+		{"@search.action": "merge", "id": "3", "title": "ghost"},
+	}
+	results, err := svc.BatchOperation(context.Background(), "idx", docs, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []int{http.StatusOK, http.StatusCreated, http.StatusNotFound}
+	for i, code := range want {
+		if results[i]["statusCode"] != code {
+			t.Errorf("results[%d].statusCode = %v, want %d", i, results[i]["statusCode"], code)
+		}
+	}
+	got, _ := docRepo.Find("idx", "2")
+	if !strings.Contains(got.Content, `"author":null`) {
+		t.Errorf("null fields should be stored as null on upload path, got %s", got.Content)
+	}
+}
+
+func TestDocumentService_BatchOperation_TooManyActions(t *testing.T) {
+	t.Parallel()
+	svc, idxRepo, _ := newDocumentServiceForTest()
+	seedIndex(t, idxRepo, "idx")
+
+	docs := make([]map[string]interface{}, MaxBatchActions+1)
+	for i := range docs {
+		docs[i] = map[string]interface{}{"@search.action": "upload", "id": strconv.Itoa(i)}
+	}
+	if _, err := svc.BatchOperation(context.Background(), "idx", docs, false); !errors.Is(err, domain.ErrBatchTooLarge) {
+		t.Fatalf("expected ErrBatchTooLarge, got %v", err)
+	}
+}
+
+func TestDocumentService_BatchOperation_StorageErrorRollsBack(t *testing.T) {
+	t.Parallel()
+	svc, idxRepo, docRepo := newDocumentServiceForTest()
+	seedIndex(t, idxRepo, "idx")
+	_ = docRepo.Upsert(&domain.Document{IndexName: "idx", Key: "1", Content: `{"id":"1"}`})
+	docRepo.upsertErr = errors.New("disk full")
+
+	docs := []map[string]interface{}{
+		{"@search.action": "delete", "id": "1"},
+		{"@search.action": "upload", "id": "2"},
+	}
+	if _, err := svc.BatchOperation(context.Background(), "idx", docs, false); err == nil {
+		t.Fatalf("expected storage error")
+	}
+	if _, err := docRepo.Find("idx", "1"); err != nil {
+		t.Errorf("delete must be rolled back with the failed batch: %v", err)
+	}
+}
+
 func TestDocumentService_BatchOperation_Delete(t *testing.T) {
 	t.Parallel()
 	svc, idxRepo, docRepo := newDocumentServiceForTest()
@@ -221,7 +377,7 @@ func TestDocumentService_BatchOperation_Delete(t *testing.T) {
 	docs := []map[string]interface{}{
 		{"@search.action": "delete", "id": "1"},
 	}
-	results, err := svc.BatchOperation(context.Background(), "idx", docs)
+	results, err := svc.BatchOperation(context.Background(), "idx", docs, false)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -240,7 +396,7 @@ func TestDocumentService_BatchOperation_MissingAction(t *testing.T) {
 
 	results, err := svc.BatchOperation(context.Background(), "idx", []map[string]interface{}{
 		{"id": "1"},
-	})
+	}, false)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -256,7 +412,7 @@ func TestDocumentService_BatchOperation_MissingKey(t *testing.T) {
 
 	results, err := svc.BatchOperation(context.Background(), "idx", []map[string]interface{}{
 		{"@search.action": "upload", "title": "no key"},
-	})
+	}, false)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -272,7 +428,7 @@ func TestDocumentService_BatchOperation_NonStringKey(t *testing.T) {
 
 	results, err := svc.BatchOperation(context.Background(), "idx", []map[string]interface{}{
 		{"@search.action": "upload", "id": 99},
-	})
+	}, false)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -288,7 +444,7 @@ func TestDocumentService_BatchOperation_UnknownAction(t *testing.T) {
 
 	results, err := svc.BatchOperation(context.Background(), "idx", []map[string]interface{}{
 		{"@search.action": "frobnicate", "id": "1"},
-	})
+	}, false)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -304,7 +460,7 @@ func TestDocumentService_BatchOperation_Upload_NewDoc_Returns201(t *testing.T) {
 
 	results, err := svc.BatchOperation(context.Background(), "idx", []map[string]interface{}{
 		{"@search.action": "upload", "id": "new", "title": "x"},
-	})
+	}, false)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -321,7 +477,7 @@ func TestDocumentService_BatchOperation_Upload_ExistingDoc_Returns200(t *testing
 
 	results, err := svc.BatchOperation(context.Background(), "idx", []map[string]interface{}{
 		{"@search.action": "upload", "id": "1", "title": "updated"},
-	})
+	}, false)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -333,7 +489,7 @@ func TestDocumentService_BatchOperation_Upload_ExistingDoc_Returns200(t *testing
 func TestDocumentService_BatchOperation_IndexNotFound(t *testing.T) {
 	t.Parallel()
 	svc, _, _ := newDocumentServiceForTest()
-	_, err := svc.BatchOperation(context.Background(), "missing", []map[string]interface{}{})
+	_, err := svc.BatchOperation(context.Background(), "missing", []map[string]interface{}{}, false)
 	if err == nil || err.Error() != "index not found" {
 		t.Fatalf("expected 'index not found', got %v", err)
 	}
@@ -344,7 +500,7 @@ func TestDocumentService_BatchOperation_EmptyDocs(t *testing.T) {
 	svc, idxRepo, _ := newDocumentServiceForTest()
 	seedIndex(t, idxRepo, "idx")
 
-	results, err := svc.BatchOperation(context.Background(), "idx", []map[string]interface{}{})
+	results, err := svc.BatchOperation(context.Background(), "idx", []map[string]interface{}{}, false)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -358,7 +514,7 @@ func TestDocumentService_BatchOperation_BadSchema(t *testing.T) {
 	svc, idxRepo, _ := newDocumentServiceForTest()
 	idxRepo.store["bad"] = &domain.Index{Name: "bad", Schema: "garbage"}
 
-	_, err := svc.BatchOperation(context.Background(), "bad", []map[string]interface{}{})
+	_, err := svc.BatchOperation(context.Background(), "bad", []map[string]interface{}{}, false)
 	if err == nil || !strings.Contains(err.Error(), "schema parse error") {
 		t.Fatalf("expected schema parse error, got %v", err)
 	}
@@ -498,7 +654,7 @@ func TestDocumentService_SearchDocuments_TopSkip(t *testing.T) {
 		})
 	}
 
-	res, err := svc.SearchDocuments(context.Background(), "idx", SearchParams{Top: 2, Skip: 1})
+	res, err := svc.SearchDocuments(context.Background(), "idx", SearchParams{Top: intPtr(2), Skip: 1})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -508,6 +664,14 @@ func TestDocumentService_SearchDocuments_TopSkip(t *testing.T) {
 	if len(res.Value) != 2 {
 		t.Errorf("len(value) = %d, want 2", len(res.Value))
 	}
+
+	res, err = svc.SearchDocuments(context.Background(), "idx", SearchParams{Top: intPtr(0)})
+	if err != nil {
+		t.Fatalf("$top=0: %v", err)
+	}
+	if res.Total != 5 || len(res.Value) != 0 {
+		t.Errorf("$top=0: total = %d, value = %v; want the count and no documents", res.Total, res.Value)
+	}
 }
 
 func TestDocumentService_SearchDocuments_InvalidFilter(t *testing.T) {
@@ -532,6 +696,73 @@ func TestDocumentService_SearchDocuments_InvalidOrderBy(t *testing.T) {
 	}
 }
 
+func TestDocumentService_SearchDocuments_IsMatchScoring(t *testing.T) {
+	t.Parallel()
+	svc, idxRepo, docRepo := newDocumentServiceForTest()
+	seedIndex(t, idxRepo, "idx")
+	_ = docRepo.Upsert(&domain.Document{IndexName: "idx", Key: "1", Content: `{"id":"1","title":"luxury luxury suite"}`})
+	_ = docRepo.Upsert(&domain.Document{IndexName: "idx", Key: "2", Content: `{"id":"2","title":"budget room"}`})
+
+	res, err := svc.SearchDocuments(context.Background(), "idx", SearchParams{
+		Filter: "search.ismatchscoring('luxury', 'title') or search.ismatch('budget')",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res.Scores) != len(res.Value) {
+		t.Fatalf("expected one score per result, got %d scores for %d results", len(res.Scores), len(res.Value))
+	}
+	for i, doc := range res.Value {
+		switch doc["id"] {
+		case "1":
+			if res.Scores[i] <= 1.0 {
+				t.Errorf("expected ismatchscoring to raise score of doc 1, got %v", res.Scores[i])
+			}
+		case "2":
+			if res.Scores[i] != 1.0 {
+				t.Errorf("search.ismatch must not affect score, got %v", res.Scores[i])
+			}
+		}
+	}
+}
+
+func TestDocumentService_SearchDocuments_OrderByScoreThenKey(t *testing.T) {
+	t.Parallel()
+	svc, idxRepo, docRepo := newDocumentServiceForTest()
+	seedIndex(t, idxRepo, "idx")
+	_ = docRepo.Upsert(&domain.Document{IndexName: "idx", Key: "a", Content: `{"id":"a","title":"plain"}`})
+	_ = docRepo.Upsert(&domain.Document{IndexName: "idx", Key: "b", Content: `{"id":"b","title":"luxury"}`})
+	_ = docRepo.Upsert(&domain.Document{IndexName: "idx", Key: "c", Content: `{"id":"c","title":"luxury luxury"}`})
+	_ = docRepo.Upsert(&domain.Document{IndexName: "idx", Key: "d", Content: `{"id":"d","title":"plain"}`})
+	filter := "search.ismatchscoring('luxury', 'title') or title eq 'plain'"
+
+	// Default ordering: score descending, then key ascending.
+	res, err := svc.SearchDocuments(context.Background(), "idx", SearchParams{Filter: filter})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, v := range res.Value {
+		got = append(got, v["id"].(string))
+	}
+	if strings.Join(got, ",") != "c,b,a,d" {
+		t.Errorf("default order = %v, want [c b a d]", got)
+	}
+
+	// Ascending score with paging applied after the in-memory sort.
+	res, err = svc.SearchDocuments(context.Background(), "idx", SearchParams{Filter: filter, OrderBy: "search.score() asc", Top: intPtr(2), Skip: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got = got[:0]
+	for _, v := range res.Value {
+		got = append(got, v["id"].(string))
+	}
+	if strings.Join(got, ",") != "d,b" || res.Total != 4 {
+		t.Errorf("page = %v (total %d), want [d b] (total 4)", got, res.Total)
+	}
+}
+
 // --- contains helper ---
 
 func TestContainsHelper(t *testing.T) {
@@ -554,6 +785,45 @@ func TestContainsHelper(t *testing.T) {
 	}
 }
 
+func TestDocumentService_SearchDocuments_TopCappedAtMaxTop(t *testing.T) {
+	t.Parallel()
+	svc, idxRepo, docRepo := newDocumentServiceForTest()
+	seedIndex(t, idxRepo, "idx")
+	for i := 0; i < MaxTop+5; i++ {
+		key := strconv.Itoa(i)
+		_ = docRepo.Upsert(&domain.Document{IndexName: "idx", Key: key, Content: `{"id":"` + key + `"}`})
+	}
+
+	res, err := svc.SearchDocuments(context.Background(), "idx", SearchParams{Top: intPtr(2000)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res.Value) != MaxTop {
+		t.Errorf("page size = %d, want %d", len(res.Value), MaxTop)
+	}
+}
+
+func TestDocumentService_SearchDocuments_ParameterLimits(t *testing.T) {
+	t.Parallel()
+	svc, idxRepo, _ := newDocumentServiceForTest()
+	seedIndex(t, idxRepo, "idx")
+
+	for _, params := range []SearchParams{
+		{Top: intPtr(-1)},
+		{Skip: -1},
+		{Skip: MaxSkip + 1},
+		{Search: strings.Repeat("x", maxSearchTextLength+1)},
+		{Search: strings.Repeat("x ", maxSearchTerms+1)},
+	} {
+		if _, err := svc.SearchDocuments(context.Background(), "idx", params); !errors.Is(err, domain.ErrInvalidSearchParameter) {
+			t.Errorf("params %+.60v: err = %v, want ErrInvalidSearchParameter", params, err)
+		}
+	}
+	if _, err := svc.SearchDocuments(context.Background(), "idx", SearchParams{Skip: MaxSkip}); err != nil {
+		t.Errorf("$skip=%d should be accepted: %v", MaxSkip, err)
+	}
+}
+
 // --- GetDocument ---
 
 func TestDocumentService_GetDocument_Success(t *testing.T) {
@@ -562,7 +832,7 @@ func TestDocumentService_GetDocument_Success(t *testing.T) {
 	seedIndex(t, idxRepo, "idx")
 	_ = docRepo.Upsert(&domain.Document{IndexName: "idx", Key: "1", Content: `{"id":"1","title":"hi"}`})
 
-	got, err := svc.GetDocument(context.Background(), "idx", "1")
+	got, err := svc.GetDocument(context.Background(), "idx", "1", nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -571,10 +841,103 @@ func TestDocumentService_GetDocument_Success(t *testing.T) {
 	}
 }
 
+const projectionSchemaJSON = `{
+	"name": "hotels",
+	"fields": [
+		{"name": "id", "type": "Edm.String", "key": true},
+		{"name": "name", "type": "Edm.String"},
+		{"name": "secret", "type": "Edm.String", "retrievable": false},
+		{"name": "vector", "type": "Collection(Edm.Single)", "stored": false},
+		{"name": "address", "type": "Edm.ComplexType", "fields": [
+			{"name": "city", "type": "Edm.String"},
+			{"name": "code", "type": "Edm.String", "retrievable": false}
+		]},
+		{"name": "rooms", "type": "Collection(Edm.ComplexType)", "fields": [
+			{"name": "type", "type": "Edm.String"},
+			{"name": "rate", "type": "Edm.Double"}
+		]}
+	]
+}`
+
+const projectionDocJSON = `{"id":"1","name":"Grand","secret":"s","vector":[0.1,0.2],` +
+	`"address":{"city":"Kyoto","code":"X"},"rooms":[{"type":"single","rate":1},{"type":"double","rate":2}]}`
+
+func TestDocumentService_GetDocument_StripsNonRetrievable(t *testing.T) {
+	t.Parallel()
+	svc, idxRepo, docRepo := newDocumentServiceForTest()
+	_ = idxRepo.Create(&domain.Index{Name: "hotels", Schema: projectionSchemaJSON})
+	_ = docRepo.Upsert(&domain.Document{IndexName: "hotels", Key: "1", Content: projectionDocJSON})
+
+	got, err := svc.GetDocument(context.Background(), "hotels", "1", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, f := range []string{"secret", "vector"} {
+		if _, ok := got[f]; ok {
+			t.Errorf("%s must not be returned", f)
+		}
+	}
+	addr, _ := got["address"].(map[string]interface{})
+	if addr["city"] != "Kyoto" {
+		t.Errorf("address/city = %v", addr["city"])
+	}
+	if _, ok := addr["code"]; ok {
+		t.Errorf("non-retrievable sub-field address/code must not be returned")
+	}
+}
+
+func TestDocumentService_GetDocument_NestedSelect(t *testing.T) {
+	t.Parallel()
+	svc, idxRepo, docRepo := newDocumentServiceForTest()
+	_ = idxRepo.Create(&domain.Index{Name: "hotels", Schema: projectionSchemaJSON})
+	_ = docRepo.Upsert(&domain.Document{IndexName: "hotels", Key: "1", Content: projectionDocJSON})
+
+	got, err := svc.GetDocument(context.Background(), "hotels", "1", []string{"name", "address/city", "rooms/type"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, _ := json.Marshal(got)
+	want := `{"address":{"city":"Kyoto"},"name":"Grand","rooms":[{"type":"single"},{"type":"double"}]}`
+	if string(b) != want {
+		t.Errorf("got %s, want %s", b, want)
+	}
+
+	for _, sel := range []string{"missing", "secret", "address/code", "vector"} {
+		if _, err := svc.GetDocument(context.Background(), "hotels", "1", []string{sel}); !errors.Is(err, domain.ErrInvalidSelect) {
+			t.Errorf("$select=%s: expected invalid $select error, got %v", sel, err)
+		}
+	}
+}
+
+func TestDocumentService_GetDocument_SelectMergedNull(t *testing.T) {
+	t.Parallel()
+	svc, idxRepo, docRepo := newDocumentServiceForTest()
+	_ = idxRepo.Create(&domain.Index{Name: "hotels", Schema: projectionSchemaJSON})
+	_ = docRepo.Upsert(&domain.Document{IndexName: "hotels", Key: "1", Content: projectionDocJSON})
+
+	docs := []map[string]interface{}{{
+		"@search.action": "merge", "id": "1",
+		"name":    nil,
+		"address": map[string]interface{}{"city": nil},
+	}}
+	if _, err := svc.BatchOperation(context.Background(), "hotels", docs, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := svc.GetDocument(context.Background(), "hotels", "1", []string{"name", "address/city"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, _ := json.Marshal(got)
+	want := `{"address":{"city":null},"name":null}`
+	if string(b) != want {
+		t.Errorf("got %s, want %s", b, want)
+	}
+}
+
 func TestDocumentService_GetDocument_IndexNotFound(t *testing.T) {
 	t.Parallel()
 	svc, _, _ := newDocumentServiceForTest()
-	_, err := svc.GetDocument(context.Background(), "missing", "1")
+	_, err := svc.GetDocument(context.Background(), "missing", "1", nil)
 	if err == nil || err.Error() != "index not found" {
 		t.Fatalf("expected 'index not found', got %v", err)
 	}
@@ -584,7 +947,7 @@ func TestDocumentService_GetDocument_DocNotFound(t *testing.T) {
 	t.Parallel()
 	svc, idxRepo, _ := newDocumentServiceForTest()
 	seedIndex(t, idxRepo, "idx")
-	_, err := svc.GetDocument(context.Background(), "idx", "ghost")
+	_, err := svc.GetDocument(context.Background(), "idx", "ghost", nil)
 	if err == nil || err.Error() != "document not found" {
 		t.Fatalf("expected 'document not found', got %v", err)
 	}
@@ -597,7 +960,7 @@ func TestDocumentService_GetDocument_BadStoredJSON(t *testing.T) {
 	docRepo.store["idx"] = map[string]*domain.Document{
 		"1": {IndexName: "idx", Key: "1", Content: "not-json"},
 	}
-	_, err := svc.GetDocument(context.Background(), "idx", "1")
+	_, err := svc.GetDocument(context.Background(), "idx", "1", nil)
 	if err == nil || !strings.Contains(err.Error(), "invalid document json") {
 		t.Fatalf("expected 'invalid document json', got %v", err)
 	}
@@ -0,0 +1,187 @@
+package application
+
+import (
+	"strconv"
+	"strings"
+)
+
+// enrichmentNode is a node of the enrichment tree an indexer builds for each
+// source document. Besides its value a node carries named children, the
+// annotations skills add below it, and, for collections, a node per element,
+// so that /document/pages/*/language can annotate the strings of a
+// collection.
+type enrichmentNode struct {
+	value    interface{}
+	children map[string]*enrichmentNode
+	items    []*enrichmentNode
+}
+
+// newEnrichmentNode wraps a value, exposing the members of objects as
+// children and the elements of collections as items.
+func newEnrichmentNode(v interface{}) *enrichmentNode {
+	n := &enrichmentNode{value: v, children: map[string]*enrichmentNode{}}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, e := range val {
+			n.children[k] = newEnrichmentNode(e)
+		}
+	case []interface{}:
+		n.items = make([]*enrichmentNode, len(val))
+		for i, e := range val {
+			n.items[i] = newEnrichmentNode(e)
+		}
+	case []string:
+		n.items = make([]*enrichmentNode, len(val))
+		for i, e := range val {
+			n.items[i] = newEnrichmentNode(e)
+		}
+	}
+	return n
+}
+
+// resolved returns the node's value. Objects, and nodes that only hold
+// annotations, include the annotations added below them; collections hold
+// the current values of their elements.
+func (n *enrichmentNode) resolved() interface{} {
+	if n.value == nil && len(n.children) > 0 {
+		return n.objectValue()
+	}
+	switch n.value.(type) {
+	case map[string]interface{}:
+		return n.objectValue()
+	case []interface{}, []string:
+		out := make([]interface{}, len(n.items))
+		for i, item := range n.items {
+			out[i] = item.resolved()
+		}
+		return out
+	}
+	return n.value
+}
+
+func (n *enrichmentNode) objectValue() map[string]interface{} {
+	out := make(map[string]interface{}, len(n.children))
+	for k, c := range n.children {
+		out[k] = c.resolved()
+	}
+	return out
+}
+
+// enrichmentTree is the enrichment tree of one document, rooted at
+// /document.
+type enrichmentTree struct {
+	root *enrichmentNode
+}
+
+func newEnrichmentTree(source map[string]interface{}) *enrichmentTree {
+	doc := make(map[string]interface{}, len(source))
+	for k, v := range source {
+		doc[k] = v
+	}
+	return &enrichmentTree{root: newEnrichmentNode(map[string]interface{}{"document": doc})}
+}
+
+// splitEnrichmentPath splits /document/pages/* into its segments.
+func splitEnrichmentPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+// resolve returns the value at a path. A "*" segment iterates a collection
+// and yields a collection of the values below it; a numeric segment selects
+// one element, as in the concrete paths of context instances.
+func (t *enrichmentTree) resolve(path string) (interface{}, bool) {
+	return resolveSegments(t.root, splitEnrichmentPath(path))
+}
+
+func resolveSegments(n *enrichmentNode, segments []string) (interface{}, bool) {
+	if len(segments) == 0 {
+		return n.resolved(), true
+	}
+	if segments[0] == "*" {
+		if n.items == nil {
+			return nil, false
+		}
+		out := make([]interface{}, 0, len(n.items))
+		for _, item := range n.items {
+			if v, ok := resolveSegments(item, segments[1:]); ok {
+				out = append(out, v)
+			}
+		}
+		return out, true
+	}
+	next := n.child(segments[0])
+	if next == nil {
+		return nil, false
+	}
+	return resolveSegments(next, segments[1:])
+}
+
+// child returns the named child or, for a numeric name on a collection, the
+// element at that index.
+func (n *enrichmentNode) child(name string) *enrichmentNode {
+	if c, ok := n.children[name]; ok {
+		return c
+	}
+	if n.items != nil {
+		if i, err := strconv.Atoi(name); err == nil && i >= 0 && i < len(n.items) {
+			return n.items[i]
+		}
+	}
+	return nil
+}
+
+// instances expands a skill context such as /document/pages/* into the
+// concrete paths it runs at, e.g. document/pages/0 and document/pages/1.
+func (t *enrichmentTree) instances(context string) [][]string {
+	var out [][]string
+	var walk func(n *enrichmentNode, segments, prefix []string)
+	walk = func(n *enrichmentNode, segments, prefix []string) {
+		if len(segments) == 0 {
+			out = append(out, append([]string(nil), prefix...))
+			return
+		}
+		if segments[0] == "*" {
+			for i, item := range n.items {
+				walk(item, segments[1:], append(prefix, strconv.Itoa(i)))
+			}
+			return
+		}
+		if next := n.child(segments[0]); next != nil {
+			walk(next, segments[1:], append(prefix, segments[0]))
+		}
+	}
+	walk(t.root, splitEnrichmentPath(context), nil)
+	return out
+}
+
+// bind rewrites a path relative to a context instance: each "*" the path
+// shares with the context is replaced by the instance's index, so that
+// /document/pages/* read at the instance document/pages/1 is that page.
+// Wildcards beyond the context are kept and yield collections.
+func bind(path string, context []string, instance []string) string {
+	segments := splitEnrichmentPath(path)
+	for i, s := range segments {
+		if i >= len(context) || s != context[i] {
+			break
+		}
+		if s == "*" {
+			segments[i] = instance[i]
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// set stores a value below a concrete instance path, replacing any previous
+// value of the same name.
+func (t *enrichmentTree) set(instance []string, name string, v interface{}) {
+	n := t.root
+	for _, s := range instance {
+		next := n.child(s)
+		if next == nil {
+			next = &enrichmentNode{children: map[string]*enrichmentNode{}}
+			n.children[s] = next
+		}
+		n = next
+	}
+	n.children[name] = newEnrichmentNode(v)
+}
@@ -0,0 +1,68 @@
+package application
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-search-emulator/internal/domain"
+)
+
+// AccessCondition carries the If-Match / If-None-Match request headers used
+// for optimistic concurrency on resource definitions.
+type AccessCondition struct {
+	IfMatch     string
+	IfNoneMatch string
+}
+
+// check evaluates the condition against the current state of a resource.
+// exists reports whether the resource is present and etag is its current ETag.
+func (c AccessCondition) check(exists bool, etag string) error {
+	if c.IfMatch != "" {
+		if !exists || !etagListMatches(c.IfMatch, etag) {
+			return domain.ErrPreconditionFailed
+		}
+	}
+	if c.IfNoneMatch != "" && exists && etagListMatches(c.IfNoneMatch, etag) {
+		return domain.ErrPreconditionFailed
+	}
+	return nil
+}
+
+// etagListMatches reports whether header ("*" or a comma-separated list of
+// entity tags) matches etag. Weak validators are compared by their opaque tag.
+func etagListMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if normalizeETag(candidate) == normalizeETag(etag) && etag != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeETag(etag string) string {
+	return strings.Trim(strings.TrimPrefix(etag, "W/"), `"`)
+}
+
+var (
+	etagMu   sync.Mutex
+	lastETag int64
+)
+
+// newETag returns a new, strictly increasing entity tag in the quoted
+// "0x..." form Azure uses.
+func newETag() string {
+	etagMu.Lock()
+	defer etagMu.Unlock()
+	n := time.Now().UnixNano()
+	if n <= lastETag {
+		n = lastETag + 1
+	}
+	lastETag = n
+	return fmt.Sprintf(`"0x%X"`, n)
+}
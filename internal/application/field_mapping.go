@@ -0,0 +1,183 @@
+package application
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"ai-search-emulator/internal/domain"
+)
+
+// fieldMapping is an entry of an indexer's fieldMappings or
+// outputFieldMappings. For field mappings the source is a field of the
+// source document; for output field mappings it is an enrichment tree path
+// such as /document/content.
+type fieldMapping struct {
+	SourceFieldName string           `json:"sourceFieldName"`
+	TargetFieldName string           `json:"targetFieldName"`
+	MappingFunction *mappingFunction `json:"mappingFunction"`
+}
+
+type mappingFunction struct {
+	Name       string                 `json:"name"`
+	Parameters map[string]interface{} `json:"parameters"`
+}
+
+// target returns the index field the mapping writes, which defaults to the
+// source field name.
+func (m fieldMapping) target() string {
+	if m.TargetFieldName != "" {
+		return m.TargetFieldName
+	}
+	return m.SourceFieldName
+}
+
+// validate checks a mapping against the target index. output selects the
+// rules of outputFieldMappings, whose target must be named explicitly.
+func (m fieldMapping) validate(schema *indexSchema, output bool) error {
+	kind := "fieldMappings"
+	if output {
+		kind = "outputFieldMappings"
+	}
+	if m.SourceFieldName == "" {
+		return fmt.Errorf("%w: %s entries must specify sourceFieldName", domain.ErrInvalidIndexer, kind)
+	}
+	if output && m.TargetFieldName == "" {
+		return fmt.Errorf("%w: %s entries must specify targetFieldName", domain.ErrInvalidIndexer, kind)
+	}
+	if output && !strings.HasPrefix(m.SourceFieldName, "/document") {
+		return fmt.Errorf("%w: the output field mapping source '%s' must be a path starting with /document", domain.ErrInvalidIndexer, m.SourceFieldName)
+	}
+	if _, ok := schema.field(m.target()); !ok {
+		return fmt.Errorf("%w: the %s target field '%s' does not exist in the index", domain.ErrInvalidIndexer, kind, m.target())
+	}
+	if f := m.MappingFunction; f != nil {
+		if _, ok := mappingFunctions[f.Name]; !ok {
+			return fmt.Errorf("%w: the mapping function '%s' is not supported", domain.ErrInvalidIndexer, f.Name)
+		}
+		if f.Name == "extractTokenAtPosition" {
+			if _, ok := f.Parameters["delimiter"].(string); !ok {
+				return fmt.Errorf("%w: extractTokenAtPosition requires the delimiter parameter", domain.ErrInvalidIndexer)
+			}
+			if _, ok := f.Parameters["position"].(float64); !ok {
+				return fmt.Errorf("%w: extractTokenAtPosition requires the position parameter", domain.ErrInvalidIndexer)
+			}
+		}
+	}
+	return nil
+}
+
+// apply transforms a source value with the mapping function, if any. Null
+// values are passed through.
+func (m fieldMapping) apply(v interface{}) (interface{}, error) {
+	f := m.MappingFunction
+	if f == nil || v == nil {
+		return v, nil
+	}
+	out, err := mappingFunctions[f.Name](v, f.Parameters)
+	if err != nil {
+		return nil, fmt.Errorf("could not apply mapping function '%s' to field '%s': %v", f.Name, m.SourceFieldName, err)
+	}
+	return out, nil
+}
+
+// mappingFunctions implements Azure's field mapping functions.
+var mappingFunctions = map[string]func(v interface{}, params map[string]interface{}) (interface{}, error){
+	"base64Encode": func(v interface{}, params map[string]interface{}) (interface{}, error) {
+		data := []byte(mappingString(v))
+		if boolParam(params, "useHttpServerUtilityUrlTokenEncode", true) {
+			return urlTokenEncode(data), nil
+		}
+		return base64.RawURLEncoding.EncodeToString(data), nil
+	},
+	"base64Decode": func(v interface{}, params map[string]interface{}) (interface{}, error) {
+		s := mappingString(v)
+		if boolParam(params, "useHttpServerUtilityUrlTokenDecode", true) {
+			data, err := urlTokenDecode(s)
+			if err != nil {
+				return nil, err
+			}
+			return string(data), nil
+		}
+		for _, enc := range []*base64.Encoding{base64.RawURLEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.StdEncoding} {
+			if data, err := enc.DecodeString(s); err == nil {
+				return string(data), nil
+			}
+		}
+		return nil, fmt.Errorf("the value is not valid base64")
+	},
+	"extractTokenAtPosition": func(v interface{}, params map[string]interface{}) (interface{}, error) {
+		delimiter, _ := params["delimiter"].(string)
+		position, _ := params["position"].(float64)
+		tokens := strings.Split(mappingString(v), delimiter)
+		if position < 0 || int(position) >= len(tokens) {
+			return nil, nil
+		}
+		return tokens[int(position)], nil
+	},
+	"jsonArrayToStringCollection": func(v interface{}, params map[string]interface{}) (interface{}, error) {
+		var values []interface{}
+		if err := json.Unmarshal([]byte(mappingString(v)), &values); err != nil {
+			return nil, fmt.Errorf("the value is not a JSON array")
+		}
+		out := make([]string, 0, len(values))
+		for _, e := range values {
+			out = append(out, mappingString(e))
+		}
+		return out, nil
+	},
+	"urlEncode": func(v interface{}, params map[string]interface{}) (interface{}, error) {
+		return url.QueryEscape(mappingString(v)), nil
+	},
+	"urlDecode": func(v interface{}, params map[string]interface{}) (interface{}, error) {
+		s, err := url.QueryUnescape(mappingString(v))
+		if err != nil {
+			return nil, fmt.Errorf("the value is not URL encoded")
+		}
+		return s, nil
+	},
+}
+
+// mappingString returns the string form of a scalar source value.
+func mappingString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+func boolParam(params map[string]interface{}, name string, def bool) bool {
+	if b, ok := params[name].(bool); ok {
+		return b
+	}
+	return def
+}
+
+// urlTokenEncode encodes like .NET's HttpServerUtility.UrlTokenEncode: URL-safe
+// base64 whose padding is replaced by a trailing digit counting the removed
+// '=' characters.
+func urlTokenEncode(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	s := base64.URLEncoding.EncodeToString(data)
+	trimmed := strings.TrimRight(s, "=")
+	return trimmed + fmt.Sprint(len(s)-len(trimmed))
+}
+
+func urlTokenDecode(s string) ([]byte, error) {
+	if s == "" {
+		return []byte{}, nil
+	}
+	pad := s[len(s)-1]
+	if pad < '0' || pad > '2' {
+		return nil, fmt.Errorf("the value is not a valid URL token")
+	}
+	data, err := base64.URLEncoding.DecodeString(s[:len(s)-1] + strings.Repeat("=", int(pad-'0')))
+	if err != nil {
+		return nil, fmt.Errorf("the value is not a valid URL token")
+	}
+	return data, nil
+}
@@ -0,0 +1,141 @@
+package application
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"ai-search-emulator/internal/domain"
+)
+
+func applyMapping(t *testing.T, name string, params map[string]interface{}, v interface{}) interface{} {
+	t.Helper()
+	m := fieldMapping{SourceFieldName: "f", MappingFunction: &mappingFunction{Name: name, Parameters: params}}
+	out, err := m.apply(v)
+	if err != nil {
+		t.Fatalf("%s(%v): %v", name, v, err)
+	}
+	return out
+}
+
+func TestMappingFunctions_Base64(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		in, want string
+	}{
+		{"abc", "YWJj0"},
+		{"ab", "YWI1"},
+		{"a", "YQ2"},
+		{"https://x/a?b", "aHR0cHM6Ly94L2E_Yg2"},
+	}
+	for _, tc := range cases {
+		if got := applyMapping(t, "base64Encode", nil, tc.in); got != tc.want {
+			t.Errorf("base64Encode(%q) = %v, want %s", tc.in, got, tc.want)
+		}
+		if got := applyMapping(t, "base64Decode", nil, tc.want); got != tc.in {
+			t.Errorf("base64Decode(%q) = %v, want %s", tc.want, got, tc.in)
+		}
+	}
+
+	plain := map[string]interface{}{"useHttpServerUtilityUrlTokenEncode": false}
+	if got := applyMapping(t, "base64Encode", plain, "ab"); got != "YWI" {
+		t.Errorf("base64Encode without UrlTokenEncode = %v, want YWI", got)
+	}
+	plainDecode := map[string]interface{}{"useHttpServerUtilityUrlTokenDecode": false}
+	for _, in := range []string{"YWI", "YWI=", "aHR0cHM6Ly94L2E/Yg=="} {
+		if got := applyMapping(t, "base64Decode", plainDecode, in); got != "ab" && got != "https://x/a?b" {
+			t.Errorf("base64Decode(%q) without UrlTokenDecode = %v", in, got)
+		}
+	}
+
+	m := fieldMapping{SourceFieldName: "f", MappingFunction: &mappingFunction{Name: "base64Decode"}}
+	if _, err := m.apply("YWI9"); err == nil {
+		t.Error("an invalid padding digit should fail")
+	}
+}
+
+func TestMappingFunctions_ExtractTokenAtPosition(t *testing.T) {
+	t.Parallel()
+	params := map[string]interface{}{"delimiter": " ", "position": float64(1)}
+	if got := applyMapping(t, "extractTokenAtPosition", params, "Jane Doe"); got != "Doe" {
+		t.Errorf("extractTokenAtPosition = %v, want Doe", got)
+	}
+	params["position"] = float64(5)
+	if got := applyMapping(t, "extractTokenAtPosition", params, "Jane Doe"); got != nil {
+		t.Errorf("out of range position = %v, want nil", got)
+	}
+}
+
+func TestMappingFunctions_JSONArrayAndURL(t *testing.T) {
+	t.Parallel()
+	got := applyMapping(t, "jsonArrayToStringCollection", nil, `["red","white",3]`)
+	if !reflect.DeepEqual(got, []string{"red", "white", "3"}) {
+		t.Errorf("jsonArrayToStringCollection = %v", got)
+	}
+	m := fieldMapping{SourceFieldName: "f", MappingFunction: &mappingFunction{Name: "jsonArrayToStringCollection"}}
+	if _, err := m.apply("red"); err == nil {
+		t.Error("a non-array value should fail")
+	}
+
+	if got := applyMapping(t, "urlEncode", nil, "a b&c"); got != "a+b%26c" {
+		t.Errorf("urlEncode = %v, want a+b%%26c", got)
+	}
+	if got := applyMapping(t, "urlDecode", nil, "a+b%26c"); got != "a b&c" {
+		t.Errorf("urlDecode = %v, want a b&c", got)
+	}
+	if got := applyMapping(t, "urlEncode", nil, nil); got != nil {
+		t.Errorf("null values should pass through, got %v", got)
+	}
+}
+
+func TestFieldMapping_Validate(t *testing.T) {
+	t.Parallel()
+	schema, err := parseIndexSchema(indexerTestSchema)
+	if err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	cases := []struct {
+		name   string
+		m      fieldMapping
+		output bool
+		ok     bool
+	}{
+		{"implicit target", fieldMapping{SourceFieldName: "name"}, false, true},
+		{"missing source", fieldMapping{TargetFieldName: "name"}, false, false},
+		{"unknown target", fieldMapping{SourceFieldName: "x", TargetFieldName: "nope"}, false, false},
+		{"unknown function", fieldMapping{SourceFieldName: "x", TargetFieldName: "name", MappingFunction: &mappingFunction{Name: "rot13"}}, false, false},
+		{"token without delimiter", fieldMapping{SourceFieldName: "x", TargetFieldName: "name", MappingFunction: &mappingFunction{Name: "extractTokenAtPosition", Parameters: map[string]interface{}{"position": float64(0)}}}, false, false},
+		{"output path", fieldMapping{SourceFieldName: "/document/x", TargetFieldName: "name"}, true, true},
+		{"output without target", fieldMapping{SourceFieldName: "/document/name"}, true, false},
+		{"output not a path", fieldMapping{SourceFieldName: "x", TargetFieldName: "name"}, true, false},
+	}
+	for _, tc := range cases {
+		err := tc.m.validate(schema, tc.output)
+		if tc.ok && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+		if !tc.ok && !errors.Is(err, domain.ErrInvalidIndexer) {
+			t.Errorf("%s: err = %v, want ErrInvalidIndexer", tc.name, err)
+		}
+	}
+}
+
+func TestEnrichmentTree_Resolve(t *testing.T) {
+	t.Parallel()
+	tree := newEnrichmentTree(map[string]interface{}{
+		"title": "t",
+		"pages": []interface{}{
+			map[string]interface{}{"text": "one"},
+			map[string]interface{}{"text": "two"},
+		},
+	})
+	if v, ok := tree.resolve("/document/title"); !ok || v != "t" {
+		t.Errorf("/document/title = %v, %v", v, ok)
+	}
+	if v, ok := tree.resolve("/document/pages/*/text"); !ok || !reflect.DeepEqual(v, []interface{}{"one", "two"}) {
+		t.Errorf("/document/pages/*/text = %v, %v", v, ok)
+	}
+	if _, ok := tree.resolve("/document/missing"); ok {
+		t.Error("a missing path should not resolve")
+	}
+}
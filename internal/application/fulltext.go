@@ -0,0 +1,240 @@
+package application
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// fullTextQuery is a parsed query in the Lucene "simple" syntax subset used by
+// the search.ismatch and search.ismatchscoring filter functions.
+type fullTextQuery struct {
+	terms     []queryTerm
+	fields    []string // restrict matching to these fields; empty = full content
+	matchAll  bool     // searchMode=all: every optional term must match
+	matchNone bool     // query is "*": matches every document
+}
+
+// queryTerm is a single word or quoted phrase of a fullTextQuery.
+type queryTerm struct {
+	text     string // lower-cased
+	required bool   // prefixed with '+'
+	excluded bool   // prefixed with '-'
+}
+
+// parseFullTextQuery tokenizes search text using the simple query syntax
+// and the given searchMode ("any" or "all"). The full Lucene syntax is not
+// supported and is rejected rather than silently run as simple.
+func parseFullTextQuery(search string, fields []string, queryType, searchMode string) (*fullTextQuery, error) {
+	switch strings.ToLower(queryType) {
+	case "", "simple":
+	case "full":
+		return nil, fmt.Errorf("queryType 'full' is not supported by the emulator: use 'simple'")
+	default:
+		return nil, fmt.Errorf("invalid queryType %q: expected 'simple' or 'full'", queryType)
+	}
+	q := &fullTextQuery{fields: fields}
+	switch strings.ToLower(searchMode) {
+	case "", "any":
+	case "all":
+		q.matchAll = true
+	default:
+		return nil, fmt.Errorf("invalid searchMode %q: expected 'any' or 'all'", searchMode)
+	}
+
+	search = strings.TrimSpace(search)
+	if search == "" || search == "*" {
+		q.matchNone = true
+		return q, nil
+	}
+	for _, tok := range tokenizeQuery(search) {
+		t := queryTerm{}
+		switch {
+		case strings.HasPrefix(tok, "+"):
+			t.required = true
+			tok = tok[1:]
+		case strings.HasPrefix(tok, "-"):
+			t.excluded = true
+			tok = tok[1:]
+		}
+		// Prefix queries ("lux*") are already covered by substring matching.
+		tok = strings.TrimSuffix(strings.Trim(tok, `"`), "*")
+		if tok == "" {
+			continue
+		}
+		t.text = strings.ToLower(tok)
+		q.terms = append(q.terms, t)
+	}
+	if len(q.terms) == 0 {
+		q.matchNone = true
+	}
+	return q, nil
+}
+
+// tokenizeQuery splits search text on whitespace, keeping double-quoted
+// phrases (optionally prefixed with '+' or '-') together as one token.
+func tokenizeQuery(s string) []string {
+	var tokens []string
+	var sb strings.Builder
+	inQuote := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			sb.WriteRune(r)
+		case !inQuote && (r == ' ' || r == '\t' || r == '\n'):
+			if sb.Len() > 0 {
+				tokens = append(tokens, sb.String())
+				sb.Reset()
+			}
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	if sb.Len() > 0 {
+		tokens = append(tokens, sb.String())
+	}
+	return tokens
+}
+
+// toSQL compiles the query into a SQL boolean fragment over the documents table.
+func (q *fullTextQuery) toSQL() (string, []interface{}) {
+	if q.matchNone {
+		return "1 = 1", nil
+	}
+	var must, should []string
+	var args []interface{}
+	var shouldArgs []interface{}
+	for _, t := range q.terms {
+		termSQL, termArgs := q.termSQL(t.text)
+		switch {
+		case t.excluded:
+			must = append(must, "NOT "+termSQL)
+			args = append(args, termArgs...)
+		case t.required || q.matchAll:
+			must = append(must, termSQL)
+			args = append(args, termArgs...)
+		default:
+			should = append(should, termSQL)
+			shouldArgs = append(shouldArgs, termArgs...)
+		}
+	}
+	if len(should) > 0 {
+		must = append(must, "("+strings.Join(should, " OR ")+")")
+		args = append(args, shouldArgs...)
+	}
+	return "(" + strings.Join(must, " AND ") + ")", args
+}
+
+func (q *fullTextQuery) termSQL(term string) (string, []interface{}) {
+	pattern := "%" + escapeLike(term) + "%"
+	if len(q.fields) == 0 {
+		return `LOWER(content) LIKE ? ESCAPE '\'`, []interface{}{pattern}
+	}
+	parts := make([]string, len(q.fields))
+	args := make([]interface{}, len(q.fields))
+	for i, f := range q.fields {
+		parts[i] = "LOWER(" + jsonExtract(f) + `) LIKE ? ESCAPE '\'`
+		args[i] = pattern
+	}
+	return "(" + strings.Join(parts, " OR ") + ")", args
+}
+
+// matches evaluates the query against a stored document in memory, mirroring
+// the semantics of toSQL.
+func (q *fullTextQuery) matches(content string, doc map[string]interface{}) bool {
+	if q.matchNone {
+		return true
+	}
+	texts := q.searchableTexts(content, doc)
+	anyShould, hasShould := false, false
+	for _, t := range q.terms {
+		found := termFrequency(texts, t.text) > 0
+		switch {
+		case t.excluded:
+			if found {
+				return false
+			}
+		case t.required || q.matchAll:
+			if !found {
+				return false
+			}
+		default:
+			hasShould = true
+			anyShould = anyShould || found
+		}
+	}
+	return !hasShould || anyShould
+}
+
+// score returns a term-frequency based relevance score for the document.
+// Each matching term contributes 1 + ln(tf).
+func (q *fullTextQuery) score(content string, doc map[string]interface{}) float64 {
+	if q.matchNone {
+		return 1.0
+	}
+	texts := q.searchableTexts(content, doc)
+	var s float64
+	for _, t := range q.terms {
+		if t.excluded {
+			continue
+		}
+		if tf := termFrequency(texts, t.text); tf > 0 {
+			s += 1 + math.Log(float64(tf))
+		}
+	}
+	return s
+}
+
+// searchableTexts returns the lower-cased text the query is evaluated against.
+func (q *fullTextQuery) searchableTexts(content string, doc map[string]interface{}) []string {
+	if len(q.fields) == 0 {
+		return []string{strings.ToLower(content)}
+	}
+	texts := make([]string, 0, len(q.fields))
+	for _, f := range q.fields {
+		v, ok := lookupPath(doc, f)
+		if !ok || v == nil {
+			continue
+		}
+		if s, ok := v.(string); ok {
+			texts = append(texts, strings.ToLower(s))
+			continue
+		}
+		b, _ := json.Marshal(v)
+		texts = append(texts, strings.ToLower(string(b)))
+	}
+	return texts
+}
+
+func termFrequency(texts []string, term string) int {
+	n := 0
+	for _, t := range texts {
+		n += strings.Count(t, term)
+	}
+	return n
+}
+
+// lookupPath resolves a field path (e.g. "Address/City") in a document.
+func lookupPath(doc map[string]interface{}, path string) (interface{}, bool) {
+	var cur interface{} = doc
+	for _, seg := range splitFieldPath(path) {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[seg]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// escapeLike escapes LIKE metacharacters so the value matches literally when
+// used with ESCAPE '\'.
+func escapeLike(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "%", `\%`)
+	return strings.ReplaceAll(s, "_", `\_`)
+}
@@ -0,0 +1,64 @@
+package application
+
+import (
+	"sync"
+	"time"
+
+	"ai-search-emulator/internal/domain"
+)
+
+// DefaultIndexDowntime is how long an index stays offline after an update
+// applied with allowIndexDowntime=true.
+const DefaultIndexDowntime = 2 * time.Second
+
+// IndexAvailability tracks indexes that are temporarily offline. It is shared
+// by IndexService, which takes indexes offline, and DocumentService, which
+// rejects indexing and query requests against them. A nil *IndexAvailability
+// reports every index as available.
+type IndexAvailability struct {
+	downtime time.Duration
+	mu       sync.Mutex
+	until    map[string]time.Time
+}
+
+func NewIndexAvailability(downtime time.Duration) *IndexAvailability {
+	return &IndexAvailability{downtime: downtime, until: map[string]time.Time{}}
+}
+
+// takeOffline marks the index unavailable for the configured downtime.
+func (a *IndexAvailability) takeOffline(name string) {
+	if a == nil || a.downtime <= 0 {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.until[name] = time.Now().Add(a.downtime)
+}
+
+// forget clears any downtime recorded for a deleted index.
+func (a *IndexAvailability) forget(name string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.until, name)
+}
+
+// check returns domain.ErrIndexUnavailable while the index is offline.
+func (a *IndexAvailability) check(name string) error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	until, ok := a.until[name]
+	if !ok {
+		return nil
+	}
+	if time.Now().Before(until) {
+		return domain.ErrIndexUnavailable
+	}
+	delete(a.until, name)
+	return nil
+}
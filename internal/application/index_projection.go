@@ -0,0 +1,281 @@
+package application
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"ai-search-emulator/internal/domain"
+)
+
+// Projection modes of indexProjections.parameters.
+const (
+	projectionModeSkipParents    = "skipIndexingParentDocuments"
+	projectionModeIncludeParents = "includeIndexingParentDocuments"
+)
+
+// indexProjections is a skillset's indexProjections: each selector writes
+// one document per instance of its sourceContext into a secondary index,
+// linked to the parent document through parentKeyFieldName.
+type indexProjections struct {
+	Selectors  []*indexProjectionSelector `json:"selectors"`
+	Parameters struct {
+		ProjectionMode string `json:"projectionMode"`
+	} `json:"parameters"`
+}
+
+// indexProjectionSelector maps an enrichment context to the documents of
+// targetIndexName. Mappings take the same form as skill inputs.
+type indexProjectionSelector struct {
+	TargetIndexName    string       `json:"targetIndexName"`
+	ParentKeyFieldName string       `json:"parentKeyFieldName"`
+	SourceContext      string       `json:"sourceContext"`
+	Mappings           []skillInput `json:"mappings"`
+}
+
+func (p *indexProjections) skipParents() bool {
+	return p.Parameters.ProjectionMode == projectionModeSkipParents
+}
+
+func (p *indexProjections) validate() error {
+	if len(p.Selectors) == 0 {
+		return fmt.Errorf("%w: indexProjections must contain at least one selector", domain.ErrInvalidSkillset)
+	}
+	switch p.Parameters.ProjectionMode {
+	case "", projectionModeSkipParents, projectionModeIncludeParents:
+	default:
+		return fmt.Errorf("%w: projectionMode must be '%s' or '%s'", domain.ErrInvalidSkillset, projectionModeSkipParents, projectionModeIncludeParents)
+	}
+	for i, sel := range p.Selectors {
+		if sel == nil {
+			return fmt.Errorf("%w: index projection selectors must not contain null entries", domain.ErrInvalidSkillset)
+		}
+		if err := sel.validate(); err != nil {
+			return fmt.Errorf("%w: index projection selector %d: %v", domain.ErrInvalidSkillset, i+1, err)
+		}
+	}
+	return nil
+}
+
+func (sel *indexProjectionSelector) validate() error {
+	if sel.TargetIndexName == "" {
+		return fmt.Errorf("targetIndexName is required")
+	}
+	if sel.ParentKeyFieldName == "" {
+		return fmt.Errorf("parentKeyFieldName is required")
+	}
+	if !isDocumentPath(sel.SourceContext) || sel.SourceContext == "/document" {
+		return fmt.Errorf("sourceContext must be a path below /document")
+	}
+	if len(sel.Mappings) == 0 {
+		return fmt.Errorf("at least one mapping is required")
+	}
+	names := map[string]bool{}
+	for _, m := range sel.Mappings {
+		if err := m.validate(); err != nil {
+			return err
+		}
+		if names[m.Name] || m.Name == sel.ParentKeyFieldName {
+			return fmt.Errorf("the field '%s' is mapped more than once", m.Name)
+		}
+		names[m.Name] = true
+	}
+	return nil
+}
+
+// validateTarget checks a selector against the schema of its target index:
+// the parent key field must be a string field and every mapping must name
+// a top-level field other than the key, which projections generate.
+func (sel *indexProjectionSelector) validateTarget(schema *indexSchema) error {
+	key := schema.keyField()
+	parent, ok := schema.field(sel.ParentKeyFieldName)
+	if !ok || strings.Contains(sel.ParentKeyFieldName, "/") || parent.Type != "Edm.String" || parent.Key {
+		return fmt.Errorf("parentKeyFieldName '%s' must be a non-key Edm.String field of the index '%s'", sel.ParentKeyFieldName, sel.TargetIndexName)
+	}
+	for _, m := range sel.Mappings {
+		if _, ok := schema.field(m.Name); !ok || strings.Contains(m.Name, "/") {
+			return fmt.Errorf("the mapping '%s' does not name a field of the index '%s'", m.Name, sel.TargetIndexName)
+		}
+		if m.Name == key {
+			return fmt.Errorf("the mapping '%s' targets the key field, which index projections generate", m.Name)
+		}
+	}
+	return nil
+}
+
+// keyPrefix identifies the selector in the keys of its documents, so that
+// selectors sharing a target index keep their documents apart.
+func (sel *indexProjectionSelector) keyPrefix() string {
+	sum := sha256.Sum256([]byte(sel.TargetIndexName + "\x00" + sel.SourceContext))
+	return hex.EncodeToString(sum[:6]) + "_"
+}
+
+// projectionKey generates the key of a projected document the way Azure
+// does: the selector hash, the parent key and the path of the context
+// instance below /document, joined by underscores, e.g.
+// 1a2b3c4d5e6f_aHR0cHM6Ly9...0_pages_3.
+func projectionKey(prefix, parentKey string, instance []string) string {
+	return prefix + parentKey + "_" + strings.Join(instance[1:], "_")
+}
+
+// documents builds the projected documents of a parent document.
+func (sel *indexProjectionSelector) documents(tree *enrichmentTree, parentKey string, schema *indexSchema) ([]map[string]interface{}, error) {
+	keyField := schema.keyField()
+	contextSegments := splitEnrichmentPath(sel.SourceContext)
+	var docs []map[string]interface{}
+	for _, inst := range tree.instances(sel.SourceContext) {
+		in, err := skillInputs(tree, sel.Mappings, contextSegments, inst)
+		if err != nil {
+			return nil, err
+		}
+		doc := map[string]interface{}{"@search.action": "upload"}
+		for _, f := range schema.Fields {
+			if v, ok := in[f.Name]; ok {
+				doc[f.Name] = convertFieldValue(v, f.Type)
+			}
+		}
+		doc[sel.ParentKeyFieldName] = parentKey
+		doc[keyField] = projectionKey(sel.keyPrefix(), parentKey, inst)
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// projectionRun writes the index projections of one indexer run. The keys
+// of the documents each selector projected for a parent are read from the
+// target index on first use and kept up to date as parents are indexed.
+type projectionRun struct {
+	projections *indexProjections
+	docs        *DocumentService
+	targets     []*projectionTarget
+}
+
+type projectionTarget struct {
+	schema   *indexSchema
+	children map[string][]string // parent key -> projected document keys
+}
+
+func (r *projectionRun) skipParents() bool {
+	return r.projections.skipParents()
+}
+
+// parentDocumentKey is the key of a parent document whose key cannot be
+// built for the target index: the generated AzureSearch_DocumentKey of
+// one-to-many parsing modes, the URL token encoding of a blob's path, or
+// the source key of other items.
+func parentDocumentKey(src map[string]interface{}, item domain.SourceItem) string {
+	if k, ok := src[documentKeyField].(string); ok && k != "" {
+		return k
+	}
+	if p, ok := item.Fields["metadata_storage_path"].(string); ok && p != "" {
+		return urlTokenEncode([]byte(p))
+	}
+	return item.Key
+}
+
+func newProjectionRun(p *indexProjections, docs *DocumentService) *projectionRun {
+	return &projectionRun{projections: p, docs: docs, targets: make([]*projectionTarget, len(p.Selectors))}
+}
+
+func (r *projectionRun) target(i int) (*projectionTarget, error) {
+	if r.targets[i] != nil {
+		return r.targets[i], nil
+	}
+	sel := r.projections.Selectors[i]
+	schema, err := r.docs.schema(sel.TargetIndexName)
+	if err != nil {
+		return nil, fmt.Errorf("the index '%s' cannot be loaded: %w", sel.TargetIndexName, err)
+	}
+	stored, err := r.docs.DocRepo.List(sel.TargetIndexName)
+	if err != nil {
+		return nil, err
+	}
+	t := &projectionTarget{schema: schema, children: map[string][]string{}}
+	prefix := sel.keyPrefix()
+	for _, d := range stored {
+		if !strings.HasPrefix(d.Key, prefix) {
+			continue
+		}
+		var content map[string]interface{}
+		if err := json.Unmarshal([]byte(d.Content), &content); err != nil {
+			continue
+		}
+		if parent, ok := content[sel.ParentKeyFieldName].(string); ok {
+			t.children[parent] = append(t.children[parent], d.Key)
+		}
+	}
+	r.targets[i] = t
+	return t, nil
+}
+
+// write projects a parent document into every selector's index and deletes
+// the documents previously projected for the parent that no longer have a
+// context instance. When the parent is deleted, all of them are deleted.
+// Documents the target indexes reject fail the parent item once, with every
+// rejection listed in the error message; the returned error fails the run.
+func (r *projectionRun) write(ctx context.Context, tree *enrichmentTree, parentKey string, deleted bool, run *indexerRun) error {
+	var rejected []string
+	statusCode := 0
+	reject := func(code int, msg string) {
+		if statusCode == 0 {
+			statusCode = code
+		}
+		rejected = append(rejected, msg)
+	}
+	for i, sel := range r.projections.Selectors {
+		t, err := r.target(i)
+		if err != nil {
+			return err
+		}
+		var actions []map[string]interface{}
+		current := map[string]bool{}
+		if !deleted {
+			docs, err := sel.documents(tree, parentKey, t.schema)
+			if err != nil {
+				reject(http.StatusBadRequest, fmt.Sprintf("index '%s': %v", sel.TargetIndexName, err))
+				continue
+			}
+			for _, d := range docs {
+				current[d[t.schema.keyField()].(string)] = true
+			}
+			actions = docs
+		}
+		var keys []string
+		for k := range current {
+			keys = append(keys, k)
+		}
+		for _, k := range t.children[parentKey] {
+			if !current[k] {
+				actions = append(actions, map[string]interface{}{"@search.action": "delete", t.schema.keyField(): k})
+			}
+		}
+		t.children[parentKey] = keys
+
+		for start := 0; start < len(actions); start += MaxBatchActions {
+			chunk := actions[start:min(start+MaxBatchActions, len(actions))]
+			results, err := r.docs.BatchOperation(ctx, sel.TargetIndexName, chunk, false)
+			if errors.Is(err, domain.ErrIndexNotFound) {
+				return fmt.Errorf("the index '%s' of an index projection does not exist", sel.TargetIndexName)
+			} else if err != nil {
+				return err
+			}
+			for _, res := range results {
+				if ok, _ := res["status"].(bool); !ok {
+					key, _ := res["key"].(string)
+					code, _ := res["statusCode"].(int)
+					msg, _ := res["errorMessage"].(string)
+					reject(code, fmt.Sprintf("index '%s', key '%s': %s", sel.TargetIndexName, key, msg))
+				}
+			}
+		}
+	}
+	if len(rejected) > 0 {
+		run.fail(parentKey, statusCode, fmt.Sprintf("%d index projection document(s) could not be written: %s", len(rejected), strings.Join(rejected, "; ")))
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"ai-search-emulator/internal/domain"
+)
+
+const projectionTestSchema = `{
+	"name": "chunks",
+	"fields": [
+		{"name": "chunk_id", "type": "Edm.String", "key": true},
+		{"name": "parent_id", "type": "Edm.String", "filterable": true},
+		{"name": "chunk", "type": "Edm.String"},
+		{"name": "rating", "type": "Edm.String"}
+	]
+}`
+
+func projectionSkillset(mode string) string {
+	return `{"name":"chunker","skills":[{"@odata.type":"#Microsoft.Skills.Text.SplitSkill","textSplitMode":"sentences",
+		"inputs":[{"name":"text","source":"/document/name"}],"outputs":[{"name":"textItems","targetName":"pages"}]}],
+		"indexProjections":{"selectors":[{"targetIndexName":"chunks","parentKeyFieldName":"parent_id","sourceContext":"/document/pages/*",
+			"mappings":[{"name":"chunk","source":"/document/pages/*"},{"name":"rating","source":"/document/rating"}]}],
+		"parameters":{"projectionMode":"` + mode + `"}}}`
+}
+
+// newProjectionIndexerForTest runs an indexer over the tracked data source
+// whose skillset projects the sentences of name into the chunks index.
+func newProjectionIndexerForTest(t *testing.T, mode string, items []domain.SourceItem) *indexerTestEnv {
+	t.Helper()
+	env := newIndexerServiceForTest(t, items)
+	ctx := context.Background()
+	_ = env.svc.Documents.IdxRepo.Create(&domain.Index{Name: "chunks", Schema: projectionTestSchema})
+	if _, err := env.svc.Skillsets.CreateSkillset(ctx, []byte(projectionSkillset(mode))); err != nil {
+		t.Fatalf("create skillset: %v", err)
+	}
+	src := `{"name":"tracked","type":"azuresql","credentials":{"connectionString":"Data Source=x.db"},"container":{"name":"t"},
+		"dataDeletionDetectionPolicy":{"@odata.type":"#Microsoft.Azure.Search.SoftDeleteColumnDeletionDetectionPolicy","softDeleteColumnName":"deleted","softDeleteMarkerValue":"true"}}`
+	if _, err := env.svc.DataSources.CreateDataSource(ctx, []byte(src)); err != nil {
+		t.Fatalf("create data source: %v", err)
+	}
+	if _, err := env.svc.CreateIndexer(ctx, []byte(`{"name":"ix","dataSourceName":"tracked","targetIndexName":"hotels","skillsetName":"chunker"}`)); err != nil {
+		t.Fatalf("create indexer: %v", err)
+	}
+	if st := env.status(t, "ix"); st.LastResult == nil || st.LastResult.Status != "success" {
+		t.Fatalf("lastResult = %+v, want success", st.LastResult)
+	}
+	return env
+}
+
+func sentenceItems(texts ...string) []domain.SourceItem {
+	items := trackedItems(make([]int64, len(texts))...)
+	for i, text := range texts {
+		items[i].Fields["name"] = text
+	}
+	return items
+}
+
+// chunkKeys returns the sorted keys of the chunks projected for parent.
+func (e *indexerTestEnv) chunkKeys(t *testing.T, parent string) []string {
+	t.Helper()
+	docs, err := e.docs.List("chunks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, d := range docs {
+		if strings.Contains(d.Content, `"parent_id":"`+parent+`"`) {
+			keys = append(keys, d.Key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestIndexProjections_SkipParents(t *testing.T) {
+	t.Parallel()
+	env := newProjectionIndexerForTest(t, projectionModeSkipParents, sentenceItems("One. Two. Three.", "Single."))
+
+	keys := env.chunkKeys(t, "1")
+	if len(keys) != 3 {
+		t.Fatalf("chunks of 1 = %v, want 3", keys)
+	}
+	prefix := (&indexProjectionSelector{TargetIndexName: "chunks", SourceContext: "/document/pages/*"}).keyPrefix()
+	if keys[0] != prefix+"1_pages_0" || len(prefix) != 13 {
+		t.Errorf("key = %s, want %s1_pages_0", keys[0], prefix)
+	}
+	doc, err := env.docs.Find("chunks", prefix+"1_pages_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(doc.Content, `"chunk":"Two."`) || !strings.Contains(doc.Content, `"rating":"0"`) {
+		t.Errorf("chunk = %s", doc.Content)
+	}
+	if _, err := env.docs.Find("hotels", "1"); !errors.Is(err, domain.ErrDocumentNotFound) {
+		t.Errorf("parents should not be indexed in skipIndexingParentDocuments mode, err = %v", err)
+	}
+
+	// A parent with fewer chunks loses the stale ones; a deleted parent
+	// loses them all.
+	items := sentenceItems("One. Two.", "Single.")
+	items[1].Fields["deleted"] = int64(1)
+	env.setItems(items)
+	if err := env.svc.RunIndexer(context.Background(), "ix"); err != nil {
+		t.Fatal(err)
+	}
+	if keys := env.chunkKeys(t, "1"); len(keys) != 2 {
+		t.Errorf("chunks of 1 after update = %v, want 2", keys)
+	}
+	if keys := env.chunkKeys(t, "2"); len(keys) != 0 {
+		t.Errorf("chunks of deleted parent 2 = %v, want none", keys)
+	}
+}
+
+func TestIndexProjections_OneFailurePerParent(t *testing.T) {
+	t.Parallel()
+	env := newProjectionIndexerForTest(t, projectionModeSkipParents, sentenceItems("Single."))
+
+	// The parent key fits the index, but the keys of its three projected
+	// documents exceed the maximum key length.
+	long := strings.Repeat("k", maxKeyLength-5)
+	items := sentenceItems("One. Two. Three.")
+	items[0].Key, items[0].Fields["id"] = long, long
+	env.setItems(items)
+	if err := env.svc.RunIndexer(context.Background(), "ix"); err != nil {
+		t.Fatal(err)
+	}
+	last := env.status(t, "ix").LastResult
+	if last.ItemsFailed != 1 || len(last.Errors) != 1 {
+		t.Fatalf("itemsFailed = %d, errors = %+v; want one failure for the parent", last.ItemsFailed, last.Errors)
+	}
+	if e := last.Errors[0]; e.Key != long || !strings.Contains(e.ErrorMessage, "3 index projection document(s)") || e.StatusCode != http.StatusBadRequest {
+		t.Errorf("error = %+v", e)
+	}
+}
+
+func TestIndexProjections_IncludeParents(t *testing.T) {
+	t.Parallel()
+	env := newProjectionIndexerForTest(t, projectionModeIncludeParents, sentenceItems("One. Two."))
+	if _, err := env.docs.Find("hotels", "1"); err != nil {
+		t.Errorf("the parent should be indexed: %v", err)
+	}
+	if keys := env.chunkKeys(t, "1"); len(keys) != 2 {
+		t.Errorf("chunks = %v, want 2", keys)
+	}
+}
+
+func TestIndexProjections_Validation(t *testing.T) {
+	t.Parallel()
+	svc := NewSkillsetService(newMockSkillsetRepository())
+	skillsets := []string{
+		strings.Replace(projectionSkillset(projectionModeSkipParents), `"sourceContext":"/document/pages/*"`, `"sourceContext":"/document"`, 1),
+		strings.Replace(projectionSkillset(projectionModeSkipParents), `"parentKeyFieldName":"parent_id",`, ``, 1),
+		projectionSkillset("everything"),
+	}
+	for _, body := range skillsets {
+		if _, err := svc.CreateSkillset(context.Background(), []byte(body)); !errors.Is(err, domain.ErrInvalidSkillset) {
+			t.Errorf("err = %v, want ErrInvalidSkillset for %s", err, body)
+		}
+	}
+
+	env := newIndexerServiceForTest(t, nil)
+	_ = env.svc.Documents.IdxRepo.Create(&domain.Index{Name: "chunks", Schema: projectionTestSchema})
+	for name, body := range map[string]string{
+		"missing-index": strings.Replace(projectionSkillset(projectionModeSkipParents), `"targetIndexName":"chunks"`, `"targetIndexName":"nope"`, 1),
+		"bad-parent":    strings.Replace(projectionSkillset(projectionModeSkipParents), `"parentKeyFieldName":"parent_id"`, `"parentKeyFieldName":"rating2"`, 1),
+		"key-mapping":   strings.Replace(projectionSkillset(projectionModeSkipParents), `{"name":"chunk",`, `{"name":"chunk_id",`, 1),
+	} {
+		body = strings.Replace(body, `"name":"chunker"`, `"name":"`+name+`"`, 1)
+		if _, err := env.svc.Skillsets.CreateSkillset(context.Background(), []byte(body)); err != nil {
+			t.Fatalf("%s: create skillset: %v", name, err)
+		}
+		ix := `{"name":"ix","dataSourceName":"src","targetIndexName":"hotels","skillsetName":"` + name + `"}`
+		if _, err := env.svc.CreateIndexer(context.Background(), []byte(ix)); !errors.Is(err, domain.ErrInvalidIndexer) {
+			t.Errorf("%s: err = %v, want ErrInvalidIndexer", name, err)
+		}
+	}
+}
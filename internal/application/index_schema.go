@@ -0,0 +1,89 @@
+package application
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// indexField is the subset of an Azure field definition the emulator
+// interprets. Attribute pointers are nil when the attribute was omitted so
+// that Azure's defaults can be applied.
+type indexField struct {
+	Name          string       `json:"name"`
+	Type          string       `json:"type"`
+	Key           bool         `json:"key"`
+	Searchable    *bool        `json:"searchable"`
+	Filterable    *bool        `json:"filterable"`
+	Sortable      *bool        `json:"sortable"`
+	Facetable     *bool        `json:"facetable"`
+	Retrievable   *bool        `json:"retrievable"`
+	Stored        *bool        `json:"stored"`
+	Analyzer      string       `json:"analyzer"`
+	IndexAnalyzer string       `json:"indexAnalyzer"`
+	Normalizer    string       `json:"normalizer"`
+	Fields        []indexField `json:"fields"` // sub-fields of Edm.ComplexType
+}
+
+// indexSchema is the parsed form of domain.Index.Schema.
+type indexSchema struct {
+	Name   string       `json:"name"`
+	Fields []indexField `json:"fields"`
+}
+
+func parseIndexSchema(schema string) (*indexSchema, error) {
+	var s indexSchema
+	if err := json.Unmarshal([]byte(schema), &s); err != nil {
+		return nil, fmt.Errorf("schema parse error")
+	}
+	return &s, nil
+}
+
+// keyField returns the name of the key field, or "" if none is declared.
+func (s *indexSchema) keyField() string {
+	for _, f := range s.Fields {
+		if f.Key {
+			return f.Name
+		}
+	}
+	return ""
+}
+
+// field resolves a field path such as "Address/City" or "Address.City".
+func (s *indexSchema) field(path string) (*indexField, bool) {
+	fields := s.Fields
+	var found *indexField
+	for _, seg := range splitFieldPath(path) {
+		found = nil
+		for i := range fields {
+			if fields[i].Name == seg {
+				found = &fields[i]
+				break
+			}
+		}
+		if found == nil {
+			return nil, false
+		}
+		fields = found.Fields
+	}
+	return found, found != nil
+}
+
+// splitFieldPath splits an OData field path on '/' (or the '.' separator used
+// internally for json_extract paths).
+func splitFieldPath(path string) []string {
+	return strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '.' })
+}
+
+// isCollection reports whether the field type is Collection(...).
+func (f *indexField) isCollection() bool {
+	return strings.HasPrefix(f.Type, "Collection(")
+}
+
+// elementType returns the element type of a collection, or the type itself.
+func (f *indexField) elementType() string {
+	if f.isCollection() {
+		return strings.TrimSuffix(strings.TrimPrefix(f.Type, "Collection("), ")")
+	}
+	return f.Type
+}
@@ -3,6 +3,7 @@ package application
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -13,48 +14,57 @@ import (
 type IndexService struct {
 	Repo    domain.IndexRepository
 	DocRepo domain.DocumentRepository
+	// Availability records indexes taken offline by updates that require
+	// downtime. Nil disables the downtime window.
+	Availability *IndexAvailability
 }
 
 func NewIndexService(repo domain.IndexRepository, docRepo domain.DocumentRepository) *IndexService {
 	return &IndexService{Repo: repo, DocRepo: docRepo}
 }
 
-func (s *IndexService) CreateIndex(ctx context.Context, name string, body io.ReadCloser) error {
+// CreateIndex creates a new index and returns the stored entity, including
+// its newly assigned ETag.
+func (s *IndexService) CreateIndex(ctx context.Context, name string, body io.ReadCloser) (*domain.Index, error) {
 	defer body.Close()
 
 	exists, err := s.Repo.Exists(name)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if exists {
-		return domain.ErrIndexAlreadyExists
+		return nil, domain.ErrIndexAlreadyExists
 	}
 
 	// bodyからスキーマJSONを読み込む
 	schemaBytes, err := io.ReadAll(body)
 	if err != nil {
-		return fmt.Errorf("failed to read body: %w", err)
+		return nil, fmt.Errorf("failed to read body: %w", err)
 	}
 	// バリデーション: fields配列が存在するか最低限チェック
 	var tmp struct {
 		Fields []interface{} `json:"fields"`
 	}
 	if err := json.Unmarshal(schemaBytes, &tmp); err != nil {
-		return fmt.Errorf("invalid schema json: %w", err)
+		return nil, fmt.Errorf("invalid schema json: %w", err)
 	}
 	if len(tmp.Fields) == 0 {
-		return fmt.Errorf("fields required in schema")
+		return nil, fmt.Errorf("fields required in schema")
+	}
+	if err := validateIndexDefinition(name, schemaBytes); err != nil {
+		return nil, err
 	}
 
 	// domain.Indexエンティティを生成し保存
 	index := &domain.Index{
 		Name:   name,
 		Schema: string(schemaBytes),
+		ETag:   newETag(),
 	}
 	if err := s.Repo.Create(index); err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	return index, nil
 }
 
 func (s *IndexService) ListIndexes(ctx context.Context, selectFields string) ([]map[string]interface{}, error) {
@@ -68,12 +78,13 @@ func (s *IndexService) ListIndexes(ctx context.Context, selectFields string) ([]
 		if err := json.Unmarshal([]byte(idx.Schema), &schema); err != nil {
 			continue // スキーマ不正はスキップ
 		}
+		schema["@odata.etag"] = idx.ETag
 		if selectFields != "" && selectFields != "*" {
 			fields := map[string]struct{}{}
 			for _, f := range strings.Split(selectFields, ",") {
 				fields[strings.TrimSpace(f)] = struct{}{}
 			}
-			filtered := map[string]interface{}{}
+			filtered := map[string]interface{}{"@odata.etag": idx.ETag}
 			for k, v := range schema {
 				if _, ok := fields[k]; ok {
 					filtered[k] = v
@@ -96,40 +107,71 @@ func (s *IndexService) GetIndex(ctx context.Context, name string) (map[string]in
 	if err := json.Unmarshal([]byte(idx.Schema), &schema); err != nil {
 		return nil, fmt.Errorf("schema parse error")
 	}
+	schema["@odata.etag"] = idx.ETag
 	return schema, nil
 }
 
 // CreateOrUpdateIndex upserts an index: creates it if absent, updates it if present.
-// Returns true if the index was newly created, false if it was updated.
-func (s *IndexService) CreateOrUpdateIndex(ctx context.Context, name string, body io.ReadCloser) (bool, error) {
+// cond is evaluated against the current ETag; a mismatch returns
+// domain.ErrPreconditionFailed. Updates must follow Azure's update rules (see
+// checkIndexUpdate); allowDowntime permits changes that take the index offline
+// briefly. Returns the stored index and true if the index was newly created,
+// false if it was updated.
+func (s *IndexService) CreateOrUpdateIndex(ctx context.Context, name string, body io.ReadCloser, cond AccessCondition, allowDowntime bool) (*domain.Index, bool, error) {
 	defer body.Close()
 	schemaBytes, err := io.ReadAll(body)
 	if err != nil {
-		return false, fmt.Errorf("failed to read body: %w", err)
+		return nil, false, fmt.Errorf("failed to read body: %w", err)
 	}
 	var tmp struct {
 		Fields []interface{} `json:"fields"`
 	}
 	if err := json.Unmarshal(schemaBytes, &tmp); err != nil {
-		return false, fmt.Errorf("invalid schema json: %w", err)
+		return nil, false, fmt.Errorf("invalid schema json: %w", err)
 	}
 	if len(tmp.Fields) == 0 {
-		return false, fmt.Errorf("fields required in schema")
+		return nil, false, fmt.Errorf("fields required in schema")
+	}
+	if err := validateIndexDefinition(name, schemaBytes); err != nil {
+		return nil, false, err
 	}
 
-	exists, err := s.Repo.Exists(name)
-	if err != nil {
-		return false, err
+	idx, err := s.Repo.FindByName(name)
+	if err != nil && !errors.Is(err, domain.ErrIndexNotFound) {
+		return nil, false, err
 	}
+	exists := err == nil
+	current := ""
 	if exists {
-		idx, err := s.Repo.FindByName(name)
+		current = idx.ETag
+	}
+	if err := cond.check(exists, current); err != nil {
+		return nil, false, err
+	}
+	if exists {
+		downtime, err := checkIndexUpdate(idx.Schema, string(schemaBytes), allowDowntime)
 		if err != nil {
-			return false, err
+			return nil, false, err
 		}
 		idx.Schema = string(schemaBytes)
-		return false, s.Repo.Update(idx)
+		idx.ETag = newETag()
+		if cond != (AccessCondition{}) {
+			// The condition is checked again by the update itself, which
+			// fails if the index changed since it was read.
+			err = s.Repo.UpdateIfMatch(idx, current)
+		} else {
+			err = s.Repo.Update(idx)
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		if downtime {
+			s.Availability.takeOffline(name)
+		}
+		return idx, false, nil
 	}
-	return true, s.Repo.Create(&domain.Index{Name: name, Schema: string(schemaBytes)})
+	idx = &domain.Index{Name: name, Schema: string(schemaBytes), ETag: newETag()}
+	return idx, true, s.Repo.Create(idx)
 }
 
 func (s *IndexService) UpdateIndex(ctx context.Context, name string, body io.ReadCloser) error {
@@ -152,12 +194,45 @@ func (s *IndexService) UpdateIndex(ctx context.Context, name string, body io.Rea
 	if len(tmp.Fields) == 0 {
 		return fmt.Errorf("fields required in schema")
 	}
+	if err := validateIndexDefinition(name, schemaBytes); err != nil {
+		return err
+	}
+	if _, err := checkIndexUpdate(idx.Schema, string(schemaBytes), false); err != nil {
+		return err
+	}
 	idx.Schema = string(schemaBytes)
+	idx.ETag = newETag()
 	return s.Repo.Update(idx)
 }
 
-func (s *IndexService) DeleteIndex(ctx context.Context, name string) error {
-	return s.Repo.Delete(name)
+// DeleteIndex deletes an index. When cond is set, the index's current ETag
+// must satisfy it or domain.ErrPreconditionFailed is returned.
+func (s *IndexService) DeleteIndex(ctx context.Context, name string, cond AccessCondition) error {
+	if cond != (AccessCondition{}) {
+		idx, err := s.Repo.FindByName(name)
+		if err != nil && !errors.Is(err, domain.ErrIndexNotFound) {
+			return err
+		}
+		current := ""
+		if err == nil {
+			current = idx.ETag
+		}
+		if err := cond.check(err == nil, current); err != nil {
+			return err
+		}
+		if err == nil {
+			if err := s.Repo.DeleteIfMatch(name, current); err != nil {
+				return err
+			}
+			s.Availability.forget(name)
+			return nil
+		}
+	}
+	if err := s.Repo.Delete(name); err != nil {
+		return err
+	}
+	s.Availability.forget(name)
+	return nil
 }
 
 func (s *IndexService) GetIndexStats(ctx context.Context, name string) (map[string]interface{}, error) {
@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"io"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"ai-search-emulator/internal/domain"
 )
@@ -34,7 +36,7 @@ func TestIndexService_CreateIndex_Success(t *testing.T) {
 	t.Parallel()
 	svc, idxRepo, _ := newIndexServiceForTest()
 
-	if err := svc.CreateIndex(context.Background(), "my-index", body(validSchemaJSON)); err != nil {
+	if _, err := svc.CreateIndex(context.Background(), "my-index", body(validSchemaJSON)); err != nil {
 		t.Fatalf("expected success, got error: %v", err)
 	}
 
@@ -55,7 +57,7 @@ func TestIndexService_CreateIndex_AlreadyExists(t *testing.T) {
 	svc, idxRepo, _ := newIndexServiceForTest()
 	_ = idxRepo.Create(&domain.Index{Name: "my-index", Schema: validSchemaJSON})
 
-	err := svc.CreateIndex(context.Background(), "my-index", body(validSchemaJSON))
+	_, err := svc.CreateIndex(context.Background(), "my-index", body(validSchemaJSON))
 	if !errors.Is(err, domain.ErrIndexAlreadyExists) {
 		t.Fatalf("expected ErrIndexAlreadyExists, got %v", err)
 	}
@@ -65,7 +67,7 @@ func TestIndexService_CreateIndex_InvalidJSON(t *testing.T) {
 	t.Parallel()
 	svc, _, _ := newIndexServiceForTest()
 
-	err := svc.CreateIndex(context.Background(), "bad", body("{not-json"))
+	_, err := svc.CreateIndex(context.Background(), "bad", body("{not-json"))
 	if err == nil || !strings.Contains(err.Error(), "invalid schema json") {
 		t.Fatalf("expected invalid schema error, got %v", err)
 	}
@@ -75,7 +77,7 @@ func TestIndexService_CreateIndex_EmptyFields(t *testing.T) {
 	t.Parallel()
 	svc, _, _ := newIndexServiceForTest()
 
-	err := svc.CreateIndex(context.Background(), "empty", body(`{"fields": []}`))
+	_, err := svc.CreateIndex(context.Background(), "empty", body(`{"fields": []}`))
 	if err == nil || !strings.Contains(err.Error(), "fields required") {
 		t.Fatalf("expected fields-required error, got %v", err)
 	}
@@ -86,7 +88,7 @@ func TestIndexService_CreateIndex_RepositoryExistsError(t *testing.T) {
 	svc, idxRepo, _ := newIndexServiceForTest()
 	idxRepo.existsErr = errors.New("db down")
 
-	err := svc.CreateIndex(context.Background(), "x", body(validSchemaJSON))
+	_, err := svc.CreateIndex(context.Background(), "x", body(validSchemaJSON))
 	if err == nil || err.Error() != "db down" {
 		t.Fatalf("expected db down error, got %v", err)
 	}
@@ -96,7 +98,7 @@ func TestIndexService_CreateIndex_BodyClosed(t *testing.T) {
 	t.Parallel()
 	svc, _, _ := newIndexServiceForTest()
 	tc := &trackingCloser{ReadCloser: body(validSchemaJSON)}
-	if err := svc.CreateIndex(context.Background(), "my-index", tc); err != nil {
+	if _, err := svc.CreateIndex(context.Background(), "my-index", tc); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if !tc.closed {
@@ -240,6 +242,29 @@ func TestIndexService_GetIndex_InvalidStoredSchema(t *testing.T) {
 	}
 }
 
+func TestIndexService_GetDocumentModel(t *testing.T) {
+	t.Parallel()
+	svc, idxRepo, _ := newIndexServiceForTest()
+	idxRepo.store["hotels"] = &domain.Index{Name: "hotels", Schema: `{"name":"hotels","fields":[
+		{"name":"id","type":"Edm.String","key":true},
+		{"name":"address","type":"Edm.ComplexType","fields":[{"name":"city","type":"Edm.String"}]}
+	]}`}
+
+	model, err := svc.GetDocumentModel(context.Background(), "hotels")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if model.Index != "hotels" || model.Key != "id" || len(model.Fields) != 2 {
+		t.Fatalf("model = %+v", model)
+	}
+	if sub := model.Fields[1].Fields; len(sub) != 1 || sub[0].Name != "city" || sub[0].Type != "Edm.String" {
+		t.Errorf("complex sub-fields = %+v", sub)
+	}
+	if _, err := svc.GetDocumentModel(context.Background(), "missing"); !errors.Is(err, domain.ErrIndexNotFound) {
+		t.Errorf("missing index: err = %v, want ErrIndexNotFound", err)
+	}
+}
+
 func TestIndexService_GetIndex_RepoError(t *testing.T) {
 	t.Parallel()
 	svc, idxRepo, _ := newIndexServiceForTest()
@@ -255,7 +280,7 @@ func TestIndexService_UpdateIndex_Success(t *testing.T) {
 	svc, idxRepo, _ := newIndexServiceForTest()
 	_ = idxRepo.Create(&domain.Index{Name: "my-index", Schema: validSchemaJSON})
 
-	updated := `{"name":"my-index","fields":[{"name":"id","key":true}]}`
+	updated := `{"name":"my-index","fields":[{"name":"id","type":"Edm.String","key":true},{"name":"title","type":"Edm.String"},{"name":"year","type":"Edm.Int32"}]}`
 	if err := svc.UpdateIndex(context.Background(), "my-index", body(updated)); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -301,7 +326,7 @@ func TestIndexService_DeleteIndex_Success(t *testing.T) {
 	svc, idxRepo, _ := newIndexServiceForTest()
 	_ = idxRepo.Create(&domain.Index{Name: "a", Schema: validSchemaJSON})
 
-	if err := svc.DeleteIndex(context.Background(), "a"); err != nil {
+	if err := svc.DeleteIndex(context.Background(), "a", AccessCondition{}); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if _, err := idxRepo.FindByName("a"); err == nil {
@@ -312,7 +337,7 @@ func TestIndexService_DeleteIndex_Success(t *testing.T) {
 func TestIndexService_DeleteIndex_NotFound(t *testing.T) {
 	t.Parallel()
 	svc, _, _ := newIndexServiceForTest()
-	err := svc.DeleteIndex(context.Background(), "missing")
+	err := svc.DeleteIndex(context.Background(), "missing", AccessCondition{})
 	if err == nil || err.Error() != "index not found" {
 		t.Fatalf("expected 'index not found', got %v", err)
 	}
@@ -322,7 +347,7 @@ func TestIndexService_DeleteIndex_RepoError(t *testing.T) {
 	t.Parallel()
 	svc, idxRepo, _ := newIndexServiceForTest()
 	idxRepo.deleteErr = errors.New("db error")
-	err := svc.DeleteIndex(context.Background(), "anything")
+	err := svc.DeleteIndex(context.Background(), "anything", AccessCondition{})
 	if err == nil || errors.Is(err, domain.ErrIndexNotFound) {
 		t.Fatalf("expected db error to bubble up, got %v", err)
 	}
@@ -366,3 +391,220 @@ func TestIndexService_GetIndexStats_CountError(t *testing.T) {
 		t.Fatalf("expected count error, got nil")
 	}
 }
+
+// --- ETags / access conditions ---
+
+func TestIndexService_CreateIndex_AssignsETag(t *testing.T) {
+	t.Parallel()
+	svc, _, _ := newIndexServiceForTest()
+	idx, err := svc.CreateIndex(context.Background(), "my-index", body(validSchemaJSON))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(idx.ETag, `"0x`) {
+		t.Errorf("etag = %q, want quoted 0x... value", idx.ETag)
+	}
+	got, _ := svc.GetIndex(context.Background(), "my-index")
+	if got["@odata.etag"] != idx.ETag {
+		t.Errorf("@odata.etag = %v, want %v", got["@odata.etag"], idx.ETag)
+	}
+}
+
+func TestIndexService_CreateOrUpdateIndex_AccessConditions(t *testing.T) {
+	t.Parallel()
+	svc, _, _ := newIndexServiceForTest()
+	ctx := context.Background()
+
+	// If-Match on a missing index fails.
+	if _, _, err := svc.CreateOrUpdateIndex(ctx, "my-index", body(validSchemaJSON), AccessCondition{IfMatch: "*"}, false); !errors.Is(err, domain.ErrPreconditionFailed) {
+		t.Fatalf("expected precondition failure, got %v", err)
+	}
+	idx, created, err := svc.CreateOrUpdateIndex(ctx, "my-index", body(validSchemaJSON), AccessCondition{IfNoneMatch: "*"}, false)
+	if err != nil || !created {
+		t.Fatalf("expected creation, got created=%v err=%v", created, err)
+	}
+	// If-None-Match: * on an existing index fails.
+	if _, _, err := svc.CreateOrUpdateIndex(ctx, "my-index", body(validSchemaJSON), AccessCondition{IfNoneMatch: "*"}, false); !errors.Is(err, domain.ErrPreconditionFailed) {
+		t.Fatalf("expected precondition failure, got %v", err)
+	}
+	updated, created, err := svc.CreateOrUpdateIndex(ctx, "my-index", body(validSchemaJSON), AccessCondition{IfMatch: idx.ETag}, false)
+	if err != nil || created {
+		t.Fatalf("expected update, got created=%v err=%v", created, err)
+	}
+	if updated.ETag == idx.ETag {
+		t.Errorf("etag must change on update")
+	}
+	// The old ETag is now stale.
+	if _, _, err := svc.CreateOrUpdateIndex(ctx, "my-index", body(validSchemaJSON), AccessCondition{IfMatch: idx.ETag}, false); !errors.Is(err, domain.ErrPreconditionFailed) {
+		t.Fatalf("expected precondition failure for stale etag, got %v", err)
+	}
+}
+
+func TestIndexService_DeleteIndex_IfMatch(t *testing.T) {
+	t.Parallel()
+	svc, idxRepo, _ := newIndexServiceForTest()
+	_ = idxRepo.Create(&domain.Index{Name: "a", Schema: validSchemaJSON, ETag: `"0x1"`})
+
+	err := svc.DeleteIndex(context.Background(), "a", AccessCondition{IfMatch: `"0x2"`})
+	if !errors.Is(err, domain.ErrPreconditionFailed) {
+		t.Fatalf("expected precondition failure, got %v", err)
+	}
+	if err := svc.DeleteIndex(context.Background(), "a", AccessCondition{IfMatch: `W/"0x1"`}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// --- update rules / allowIndexDowntime ---
+
+func TestCheckIndexUpdate(t *testing.T) {
+	t.Parallel()
+	const base = `{"name":"a","fields":[{"name":"id","type":"Edm.String","key":true},` +
+		`{"name":"title","type":"Edm.String","analyzer":"en.lucene"},` +
+		`{"name":"addr","type":"Edm.ComplexType","fields":[{"name":"city","type":"Edm.String"}]}],` +
+		`"suggesters":[{"name":"sg","searchMode":"analyzingInfixMatching","sourceFields":["title"]}]}`
+	const fields = `{"name":"id","type":"Edm.String","key":true},{"name":"title","type":"Edm.String","analyzer":"en.lucene"},` +
+		`{"name":"addr","type":"Edm.ComplexType","fields":[{"name":"city","type":"Edm.String"}]}`
+	const sugg = `"suggesters":[{"name":"sg","searchMode":"analyzingInfixMatching","sourceFields":["title"]}]`
+	tests := []struct {
+		name     string
+		updated  string
+		allow    bool
+		wantErr  error
+		downtime bool
+	}{
+		{"unchanged", base, false, nil, false},
+		{"add field", `{"fields":[` + fields + `,{"name":"year","type":"Edm.Int32"}],` + sugg + `}`, false, nil, false},
+		{"add sub-field", `{"fields":[{"name":"id","type":"Edm.String","key":true},{"name":"title","type":"Edm.String","analyzer":"en.lucene"},` +
+			`{"name":"addr","type":"Edm.ComplexType","fields":[{"name":"city","type":"Edm.String"},{"name":"zip","type":"Edm.String"}]}],` + sugg + `}`, false, nil, false},
+		{"retrievable may change", `{"fields":[{"name":"id","type":"Edm.String","key":true},{"name":"title","type":"Edm.String","analyzer":"en.lucene","retrievable":false},` +
+			`{"name":"addr","type":"Edm.ComplexType","fields":[{"name":"city","type":"Edm.String"}]}],` + sugg + `}`, false, nil, false},
+		{"remove field", `{"fields":[{"name":"id","type":"Edm.String","key":true},{"name":"title","type":"Edm.String","analyzer":"en.lucene"}],` + sugg + `}`, false, domain.ErrCannotChangeExistingField, false},
+		{"change type", `{"fields":[{"name":"id","type":"Edm.String","key":true},{"name":"title","type":"Edm.Int32","analyzer":"en.lucene"},` +
+			`{"name":"addr","type":"Edm.ComplexType","fields":[{"name":"city","type":"Edm.String"}]}],` + sugg + `}`, false, domain.ErrCannotChangeExistingField, false},
+		{"change analyzer", `{"fields":[{"name":"id","type":"Edm.String","key":true},{"name":"title","type":"Edm.String","analyzer":"standard.lucene"},` +
+			`{"name":"addr","type":"Edm.ComplexType","fields":[{"name":"city","type":"Edm.String"}]}],` + sugg + `}`, false, domain.ErrCannotChangeExistingField, false},
+		{"change filterable", `{"fields":[{"name":"id","type":"Edm.String","key":true,"filterable":false},{"name":"title","type":"Edm.String","analyzer":"en.lucene"},` +
+			`{"name":"addr","type":"Edm.ComplexType","fields":[{"name":"city","type":"Edm.String"}]}],` + sugg + `}`, false, domain.ErrCannotChangeExistingField, false},
+		{"remove sub-field", `{"fields":[{"name":"id","type":"Edm.String","key":true},{"name":"title","type":"Edm.String","analyzer":"en.lucene"},` +
+			`{"name":"addr","type":"Edm.ComplexType","fields":[]}],` + sugg + `}`, false, domain.ErrCannotChangeExistingField, false},
+		{"add analyzer without downtime", `{"fields":[` + fields + `],` + sugg + `,"analyzers":[{"name":"my","@odata.type":"#Microsoft.Azure.Search.CustomAnalyzer","tokenizer":"standard_v2"}]}`, false, domain.ErrIndexDowntimeRequired, true},
+		{"add analyzer with downtime", `{"fields":[` + fields + `],` + sugg + `,"analyzers":[{"name":"my","@odata.type":"#Microsoft.Azure.Search.CustomAnalyzer","tokenizer":"standard_v2"}]}`, true, nil, true},
+		{"empty analyzers equal absent", `{"fields":[` + fields + `],` + sugg + `,"analyzers":[]}`, false, nil, false},
+		{"change suggester", `{"fields":[` + fields + `],"suggesters":[{"name":"sg","searchMode":"analyzingInfixMatching","sourceFields":["title","addr/city"]}]}`, false, domain.ErrIndexDowntimeRequired, true},
+		{"new suggester on new field", `{"fields":[` + fields + `,{"name":"tag","type":"Edm.String"}],` +
+			`"suggesters":[{"name":"sg","searchMode":"analyzingInfixMatching","sourceFields":["title"]},{"name":"sg2","searchMode":"analyzingInfixMatching","sourceFields":["tag"]}]}`, false, nil, false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			downtime, err := checkIndexUpdate(base, tt.updated, tt.allow)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if downtime != tt.downtime {
+				t.Errorf("downtime = %v, want %v", downtime, tt.downtime)
+			}
+		})
+	}
+}
+
+func TestIndexService_CreateOrUpdateIndex_DowntimeTakesIndexOffline(t *testing.T) {
+	t.Parallel()
+	svc, idxRepo, docRepo := newIndexServiceForTest()
+	availability := NewIndexAvailability(time.Hour)
+	svc.Availability = availability
+	docs := NewDocumentService(docRepo, idxRepo)
+	docs.Availability = availability
+	ctx := context.Background()
+
+	if _, err := svc.CreateIndex(ctx, "my-index", body(validSchemaJSON)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	withAnalyzer := `{"name":"my-index","fields":[{"name":"id","type":"Edm.String","key":true},{"name":"title","type":"Edm.String"}],` +
+		`"analyzers":[{"name":"my","@odata.type":"#Microsoft.Azure.Search.CustomAnalyzer","tokenizer":"standard_v2"}]}`
+	if _, _, err := svc.CreateOrUpdateIndex(ctx, "my-index", body(withAnalyzer), AccessCondition{}, false); !errors.Is(err, domain.ErrIndexDowntimeRequired) {
+		t.Fatalf("expected downtime error, got %v", err)
+	}
+	if _, err := docs.CountDocuments(ctx, "my-index"); err != nil {
+		t.Fatalf("rejected update must not take the index offline: %v", err)
+	}
+	if _, _, err := svc.CreateOrUpdateIndex(ctx, "my-index", body(withAnalyzer), AccessCondition{}, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := docs.SearchDocuments(ctx, "my-index", SearchParams{Search: "*"}); !errors.Is(err, domain.ErrIndexUnavailable) {
+		t.Errorf("search during downtime: err = %v, want ErrIndexUnavailable", err)
+	}
+	if _, err := svc.GetIndex(ctx, "my-index"); err != nil {
+		t.Errorf("index definition should stay readable during downtime: %v", err)
+	}
+
+	if err := svc.DeleteIndex(ctx, "my-index", AccessCondition{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := availability.check("my-index"); err != nil {
+		t.Errorf("deleting the index should clear its downtime, got %v", err)
+	}
+}
+
+// --- index definition validation ---
+
+func TestValidateIndexName(t *testing.T) {
+	t.Parallel()
+	valid := []string{"ab", "my-index", "idx2", "2idx", strings.Repeat("a", 128)}
+	invalid := []string{"a", "", "My-Index", "-idx", "idx-", "my_index", "my index", strings.Repeat("a", 129)}
+	for _, name := range valid {
+		if err := validateIndexName(name); err != nil {
+			t.Errorf("validateIndexName(%q) = %v, want nil", name, err)
+		}
+	}
+	for _, name := range invalid {
+		if err := validateIndexName(name); !errors.Is(err, domain.ErrInvalidName) {
+			t.Errorf("validateIndexName(%q) = %v, want ErrInvalidName", name, err)
+		}
+	}
+}
+
+func TestValidateIndexDefinition(t *testing.T) {
+	t.Parallel()
+	manyFields := make([]string, 0, maxFieldsPerIndex+1)
+	manyFields = append(manyFields, `{"name":"id","type":"Edm.String","key":true}`)
+	for i := 0; i < maxFieldsPerIndex; i++ {
+		manyFields = append(manyFields, `{"name":"f`+strconv.Itoa(i)+`","type":"Edm.String"}`)
+	}
+	tests := []struct {
+		name    string
+		body    string
+		wantErr error
+	}{
+		{"valid", `{"name":"idx","fields":[{"name":"id","type":"Edm.String","key":true},{"name":"addr","type":"Edm.ComplexType","fields":[{"name":"city","type":"Edm.String"}]}]}`, nil},
+		{"name omitted", `{"fields":[{"name":"id","type":"Edm.String","key":true}]}`, nil},
+		{"name mismatch", `{"name":"other","fields":[{"name":"id","type":"Edm.String","key":true}]}`, domain.ErrInvalidIndexDefinition},
+		{"no key", `{"name":"idx","fields":[{"name":"id","type":"Edm.String"}]}`, domain.ErrInvalidIndexDefinition},
+		{"two keys", `{"name":"idx","fields":[{"name":"id","type":"Edm.String","key":true},{"name":"id2","type":"Edm.String","key":true}]}`, domain.ErrInvalidIndexDefinition},
+		{"non-string key", `{"name":"idx","fields":[{"name":"id","type":"Edm.Int32","key":true}]}`, domain.ErrInvalidIndexDefinition},
+		{"nested key", `{"name":"idx","fields":[{"name":"id","type":"Edm.String","key":true},{"name":"addr","type":"Edm.ComplexType","fields":[{"name":"k","type":"Edm.String","key":true}]}]}`, domain.ErrInvalidIndexDefinition},
+		{"duplicate field", `{"name":"idx","fields":[{"name":"id","type":"Edm.String","key":true},{"name":"id","type":"Edm.String"}]}`, domain.ErrInvalidIndexDefinition},
+		{"field starts with digit", `{"name":"idx","fields":[{"name":"id","type":"Edm.String","key":true},{"name":"1st","type":"Edm.String"}]}`, domain.ErrInvalidName},
+		{"field with dash", `{"name":"idx","fields":[{"name":"id","type":"Edm.String","key":true},{"name":"a-b","type":"Edm.String"}]}`, domain.ErrInvalidName},
+		{"reserved prefix", `{"name":"idx","fields":[{"name":"id","type":"Edm.String","key":true},{"name":"azureSearchScore","type":"Edm.Double"}]}`, domain.ErrInvalidName},
+		{"invalid sub-field", `{"name":"idx","fields":[{"name":"id","type":"Edm.String","key":true},{"name":"addr","type":"Edm.ComplexType","fields":[{"name":"_city","type":"Edm.String"}]}]}`, domain.ErrInvalidName},
+		{"too many fields", `{"name":"idx","fields":[` + strings.Join(manyFields, ",") + `]}`, domain.ErrInvalidIndexDefinition},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := validateIndexDefinition("idx", []byte(tt.body))
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
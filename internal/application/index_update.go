@@ -0,0 +1,166 @@
+package application
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"ai-search-emulator/internal/domain"
+)
+
+// analysisComponents are the index-level analysis definitions Azure can only
+// change by taking the index offline.
+var analysisComponents = []string{"analyzers", "tokenizers", "tokenFilters", "charFilters", "normalizers"}
+
+// checkIndexUpdate validates an update of oldSchema to newSchema against
+// Azure's rules. New fields may be added freely; existing fields cannot be
+// removed or have their type, key, analyzer or indexing attributes changed.
+// It reports whether the update requires downtime (changed analysis
+// components or suggesters over existing fields); such updates fail with
+// domain.ErrIndexDowntimeRequired unless allowDowntime is set.
+func checkIndexUpdate(oldSchema, newSchema string, allowDowntime bool) (bool, error) {
+	oldIdx, err := parseIndexSchema(oldSchema)
+	if err != nil {
+		return false, err
+	}
+	newIdx, err := parseIndexSchema(newSchema)
+	if err != nil {
+		return false, err
+	}
+	if err := compareFields("", oldIdx.Fields, newIdx.Fields); err != nil {
+		return false, err
+	}
+
+	var oldDef, newDef map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(oldSchema), &oldDef); err != nil {
+		return false, fmt.Errorf("schema parse error")
+	}
+	if err := json.Unmarshal([]byte(newSchema), &newDef); err != nil {
+		return false, fmt.Errorf("schema parse error")
+	}
+
+	var reason string
+	for _, name := range analysisComponents {
+		if !sameJSON(oldDef[name], newDef[name]) {
+			reason = fmt.Sprintf("the '%s' of the index changed", name)
+			break
+		}
+	}
+	if reason == "" {
+		reason = suggesterChange(oldDef["suggesters"], newDef["suggesters"], oldIdx)
+	}
+	if reason == "" {
+		return false, nil
+	}
+	if !allowDowntime {
+		return true, fmt.Errorf("%w: %s; set the 'allowIndexDowntime' query parameter to 'true' to apply it", domain.ErrIndexDowntimeRequired, reason)
+	}
+	return true, nil
+}
+
+// compareFields checks every field of old is still present in updated with
+// the same immutable attributes, recursing into complex sub-fields.
+func compareFields(prefix string, old, updated []indexField) error {
+	byName := make(map[string]*indexField, len(updated))
+	for i := range updated {
+		byName[updated[i].Name] = &updated[i]
+	}
+	for i := range old {
+		o := &old[i]
+		path := prefix + o.Name
+		n, ok := byName[o.Name]
+		if !ok {
+			return fmt.Errorf("%w: field '%s' cannot be removed", domain.ErrCannotChangeExistingField, path)
+		}
+		if attr, ok := changedAttribute(o, n); ok {
+			return fmt.Errorf("%w: the '%s' attribute of field '%s' cannot be changed", domain.ErrCannotChangeExistingField, attr, path)
+		}
+		if err := compareFields(path+"/", o.Fields, n.Fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// changedAttribute returns the first immutable attribute that differs between
+// old and updated. retrievable, searchAnalyzer and synonymMaps may change.
+func changedAttribute(old, updated *indexField) (string, bool) {
+	switch {
+	case old.Type != updated.Type:
+		return "type", true
+	case old.Key != updated.Key:
+		return "key", true
+	case boolAttr(old.Searchable) != boolAttr(updated.Searchable):
+		return "searchable", true
+	case boolAttr(old.Filterable) != boolAttr(updated.Filterable):
+		return "filterable", true
+	case boolAttr(old.Sortable) != boolAttr(updated.Sortable):
+		return "sortable", true
+	case boolAttr(old.Facetable) != boolAttr(updated.Facetable):
+		return "facetable", true
+	case old.Analyzer != updated.Analyzer:
+		return "analyzer", true
+	case old.IndexAnalyzer != updated.IndexAnalyzer:
+		return "indexAnalyzer", true
+	case old.Normalizer != updated.Normalizer:
+		return "normalizer", true
+	}
+	return "", false
+}
+
+// boolAttr resolves an omitted attribute to Azure's default of true.
+func boolAttr(b *bool) bool {
+	return b == nil || *b
+}
+
+type suggesterDef struct {
+	Name         string   `json:"name"`
+	SourceFields []string `json:"sourceFields"`
+}
+
+// suggesterChange describes a suggester change that needs downtime: an
+// existing suggester was modified, or a new one covers fields that already
+// held data. It returns "" when no such change was made.
+func suggesterChange(oldRaw, newRaw json.RawMessage, oldIdx *indexSchema) string {
+	var oldSugg, newSugg []suggesterDef
+	_ = json.Unmarshal(oldRaw, &oldSugg)
+	_ = json.Unmarshal(newRaw, &newSugg)
+	existing := make(map[string]suggesterDef, len(oldSugg))
+	for _, s := range oldSugg {
+		existing[s.Name] = s
+	}
+	for _, s := range newSugg {
+		if prev, ok := existing[s.Name]; ok {
+			if !reflect.DeepEqual(prev.SourceFields, s.SourceFields) {
+				return fmt.Sprintf("the source fields of suggester '%s' changed", s.Name)
+			}
+			continue
+		}
+		for _, f := range s.SourceFields {
+			if _, ok := oldIdx.field(f); ok {
+				return fmt.Sprintf("suggester '%s' includes existing field '%s'", s.Name, f)
+			}
+		}
+	}
+	return ""
+}
+
+// sameJSON reports whether two raw JSON values are semantically equal.
+// An absent value equals null and an empty array.
+func sameJSON(a, b json.RawMessage) bool {
+	var va, vb interface{}
+	_ = json.Unmarshal(a, &va)
+	_ = json.Unmarshal(b, &vb)
+	if isEmptyJSON(va) && isEmptyJSON(vb) {
+		return true
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+func isEmptyJSON(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	arr, ok := v.([]interface{})
+	return ok && len(arr) == 0
+}
@@ -0,0 +1,112 @@
+package application
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"ai-search-emulator/internal/domain"
+)
+
+// Azure's limits on index definitions.
+const (
+	maxIndexNameLength = 128
+	maxFieldNameLength = 128
+	maxFieldsPerIndex  = 1000
+)
+
+var (
+	indexNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*[a-z0-9]$`)
+	fieldNameRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+)
+
+// validateIndexName applies Azure's index naming rules: lowercase letters,
+// digits and dashes, 2-128 characters, no leading or trailing dash.
+func validateIndexName(name string) error {
+	return validateResourceName("index", name)
+}
+
+// validateResourceName applies the index naming rules to other resources
+// (data sources, indexers, skillsets); kind names the resource in the error.
+func validateResourceName(kind, name string) error {
+	if len(name) < 2 || len(name) > maxIndexNameLength || !indexNameRe.MatchString(name) {
+		return fmt.Errorf("%w: invalid %s name '%s'. Names must contain only lowercase letters, digits or dashes, cannot start or end with dashes and must be between 2 and %d characters", domain.ErrInvalidName, kind, name, maxIndexNameLength)
+	}
+	return nil
+}
+
+// validateIndexDefinition checks an index definition body against Azure's
+// rules. urlName is the index name addressed by the request; the body name
+// must equal it when present.
+func validateIndexDefinition(urlName string, schemaBytes []byte) error {
+	schema, err := parseIndexSchema(string(schemaBytes))
+	if err != nil {
+		return fmt.Errorf("invalid schema json: %w", err)
+	}
+	if schema.Name != "" && schema.Name != urlName {
+		return fmt.Errorf("%w: the index name '%s' in the request body does not match the index name '%s' in the request URL", domain.ErrInvalidIndexDefinition, schema.Name, urlName)
+	}
+	if err := validateIndexName(urlName); err != nil {
+		return err
+	}
+
+	total, err := validateFields("", schema.Fields)
+	if err != nil {
+		return err
+	}
+	if total > maxFieldsPerIndex {
+		return fmt.Errorf("%w: the index has %d fields, which exceeds the maximum of %d", domain.ErrInvalidIndexDefinition, total, maxFieldsPerIndex)
+	}
+
+	var keys []*indexField
+	for i := range schema.Fields {
+		if schema.Fields[i].Key {
+			keys = append(keys, &schema.Fields[i])
+		}
+	}
+	if len(keys) != 1 {
+		return fmt.Errorf("%w: the index must contain exactly one key field, found %d", domain.ErrInvalidIndexDefinition, len(keys))
+	}
+	if keys[0].Type != "Edm.String" {
+		return fmt.Errorf("%w: the key field '%s' must be of type 'Edm.String', not '%s'", domain.ErrInvalidIndexDefinition, keys[0].Name, keys[0].Type)
+	}
+	return nil
+}
+
+// validateFields checks names, uniqueness and key placement of fields at one
+// level, recursing into complex sub-fields. It returns the total field count.
+func validateFields(prefix string, fields []indexField) (int, error) {
+	seen := make(map[string]struct{}, len(fields))
+	total := len(fields)
+	for i := range fields {
+		f := &fields[i]
+		path := prefix + f.Name
+		if err := validateFieldName(f.Name); err != nil {
+			return 0, err
+		}
+		if _, dup := seen[f.Name]; dup {
+			return 0, fmt.Errorf("%w: the field name '%s' is used more than once", domain.ErrInvalidIndexDefinition, path)
+		}
+		seen[f.Name] = struct{}{}
+		if prefix != "" && f.Key {
+			return 0, fmt.Errorf("%w: the sub-field '%s' cannot be a key field; the key must be a top-level field", domain.ErrInvalidIndexDefinition, path)
+		}
+		n, err := validateFields(path+"/", f.Fields)
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// validateFieldName applies Azure's field naming rules.
+func validateFieldName(name string) error {
+	switch {
+	case len(name) > maxFieldNameLength || !fieldNameRe.MatchString(name):
+		return fmt.Errorf("%w: invalid field name '%s'. Field names must start with a letter, contain only letters, digits or underscores and be at most %d characters", domain.ErrInvalidName, name, maxFieldNameLength)
+	case strings.HasPrefix(strings.ToLower(name), "azuresearch"):
+		return fmt.Errorf("%w: invalid field name '%s'. Field names starting with 'azureSearch' are reserved", domain.ErrInvalidName, name)
+	}
+	return nil
+}
@@ -0,0 +1,560 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ai-search-emulator/internal/domain"
+)
+
+// Default batch sizes Azure applies when parameters.batchSize is not set.
+const (
+	defaultBlobBatchSize = 10
+	defaultBatchSize     = 1000
+)
+
+// RunIndexer runs an indexer to completion and records the result in its
+// execution history. Failures of the run itself (a missing container, too
+// many failed items, ...) are recorded rather than returned; only problems
+// loading or saving the indexer are returned.
+func (s *IndexerService) RunIndexer(ctx context.Context, name string) error {
+	def, err := s.loadDefinition(name)
+	if err != nil {
+		return err
+	}
+	if !s.begin(name) {
+		return domain.ErrIndexerRunning
+	}
+	defer s.end(name)
+	return s.run(ctx, name, def)
+}
+
+// StartIndexer starts a run in the background and returns once the indexer
+// is marked as running, as Azure accepts a run request before it completes.
+// The run uses the service's context rather than ctx, so it outlives the
+// request that started it; Wait blocks until background runs finish.
+func (s *IndexerService) StartIndexer(ctx context.Context, name string) error {
+	def, err := s.loadDefinition(name)
+	if err != nil {
+		return err
+	}
+	if !s.begin(name) {
+		return domain.ErrIndexerRunning
+	}
+	s.runs.Add(1)
+	go func() {
+		defer s.runs.Done()
+		defer s.end(name)
+		if err := s.run(s.ctx, name, def); err != nil {
+			log.Printf("indexer %s: %v", name, err)
+		}
+	}()
+	return nil
+}
+
+// Wait blocks until the runs started by StartIndexer have finished.
+func (s *IndexerService) Wait() {
+	s.runs.Wait()
+}
+
+func (s *IndexerService) loadDefinition(name string) (*indexerDefinition, error) {
+	ix, err := s.Repo.FindByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return parseIndexerDefinition(ix.Definition)
+}
+
+// run executes a run of an indexer the caller has marked as running.
+func (s *IndexerService) run(ctx context.Context, name string, def *indexerDefinition) error {
+	st, err := s.loadState(name)
+	if err != nil {
+		return err
+	}
+	run := s.execute(ctx, def, st)
+	if run.result.Status == "success" {
+		if run.tracked {
+			st.HighWaterMark = run.highWaterMark
+		}
+		st.ResetDocumentKeys, st.ResetDatasourceDocumentIds = nil, nil
+	}
+	st.record(run.result)
+	return s.saveState(name, st)
+}
+
+// indexerRun accumulates the outcome of one execution.
+type indexerRun struct {
+	result         IndexerExecutionResult
+	maxFailedItems int
+	// tracked is set when the run advances the high-water mark; highWaterMark
+	// is then the mark to keep if the run succeeds.
+	tracked       bool
+	highWaterMark interface{}
+}
+
+func (r *indexerRun) fail(key string, statusCode int, message string) {
+	r.result.ItemsFailed++
+	r.result.Errors = append(r.result.Errors, IndexerItemError{Key: key, ErrorMessage: message, StatusCode: statusCode})
+}
+
+// enriched records the messages of enriching a document and reports
+// whether it can be indexed; a document with enrichment errors counts as a
+// failed item.
+func (r *indexerRun) enriched(key string, warnings, errs []enrichmentMessage) bool {
+	for _, w := range warnings {
+		r.result.Warnings = append(r.result.Warnings, IndexerItemWarning{Key: key, Name: enrichmentName(w.skill), Message: w.message})
+	}
+	if len(errs) == 0 {
+		return true
+	}
+	r.result.ItemsProcessed++
+	r.result.ItemsFailed++
+	for _, e := range errs {
+		r.result.Errors = append(r.result.Errors, IndexerItemError{Key: key, Name: enrichmentName(e.skill), ErrorMessage: e.message, StatusCode: http.StatusBadRequest})
+	}
+	return false
+}
+
+// tooManyFailures reports whether itemsFailed exceeds maxFailedItems; -1
+// allows any number of failures.
+func (r *indexerRun) tooManyFailures() bool {
+	return r.maxFailedItems >= 0 && r.result.ItemsFailed > r.maxFailedItems
+}
+
+func (s *IndexerService) execute(ctx context.Context, def *indexerDefinition, st *indexerState) *indexerRun {
+	run := &indexerRun{result: IndexerExecutionResult{
+		StartTime: s.Clock().UTC(),
+		Errors:    []IndexerItemError{},
+		Warnings:  []IndexerItemWarning{},
+	}}
+	if m := def.Parameters.MaxFailedItems; m != nil {
+		run.maxFailedItems = *m
+	}
+	if err := s.pull(ctx, def, st, run); err != nil {
+		msg := err.Error()
+		run.result.Status = "transientFailure"
+		run.result.ErrorMessage = &msg
+	} else {
+		run.result.Status = "success"
+	}
+	end := s.Clock().UTC()
+	run.result.EndTime = &end
+	if run.tracked {
+		run.result.InitialTrackingState = trackingState(st.HighWaterMark)
+		run.result.FinalTrackingState = trackingState(run.highWaterMark)
+	}
+	return run
+}
+
+// trackingState renders a high-water mark the way status reports it.
+func trackingState(mark interface{}) *string {
+	data, _ := json.Marshal(map[string]interface{}{"highWaterMark": mark})
+	s := string(data)
+	return &s
+}
+
+// pull reads the data source and writes its items into the target index in
+// batches. Items at or below the high-water mark are skipped, and items
+// carrying the soft delete marker are deleted from the index. The returned
+// error fails the whole run.
+func (s *IndexerService) pull(ctx context.Context, def *indexerDefinition, st *indexerState, run *indexerRun) error {
+	source, conn, err := s.DataSources.source(def.DataSourceName)
+	if err != nil {
+		return err
+	}
+	cfg := source.config()
+	schema, err := s.Documents.schema(def.TargetIndexName)
+	if err != nil {
+		return fmt.Errorf("the index '%s' cannot be loaded: %w", def.TargetIndexName, err)
+	}
+	keyField := schema.keyField()
+	if keyField == "" {
+		return domain.ErrMissingKeyField
+	}
+	var skillset *skillsetDefinition
+	var projections *projectionRun
+	if def.SkillsetName != "" {
+		if skillset, err = s.Skillsets.load(def.SkillsetName); err != nil {
+			return fmt.Errorf("the skillset '%s' cannot be loaded: %w", def.SkillsetName, err)
+		}
+		if skillset.IndexProjections != nil {
+			projections = newProjectionRun(skillset.IndexProjections, s.Documents)
+		}
+	}
+	items, err := conn.Read(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	batchSize := defaultBatchSize
+	if source.isBlob() {
+		batchSize = defaultBlobBatchSize
+	}
+	if b := def.Parameters.BatchSize; b != nil {
+		batchSize = *b
+	}
+	selected := resetSelection(st)
+	// Reset documents are reindexed regardless of the high-water mark, and
+	// do not move it.
+	markColumn := ""
+	if selected == nil {
+		markColumn = source.highWaterMarkColumn()
+	}
+	run.tracked = markColumn != ""
+	run.highWaterMark = st.HighWaterMark
+
+	batch := make([]map[string]interface{}, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		results, err := s.Documents.BatchOperation(ctx, def.TargetIndexName, batch, false)
+		if err != nil {
+			return err
+		}
+		for _, r := range results {
+			if ok, _ := r["status"].(bool); !ok {
+				key, _ := r["key"].(string)
+				code, _ := r["statusCode"].(int)
+				msg, _ := r["errorMessage"].(string)
+				run.fail(key, code, msg)
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	// Documents are enriched a batch at a time, so skills see the records of
+	// every document in the batch together.
+	var pending []pendingDocument
+	index := func() error {
+		defer func() { pending = pending[:0] }()
+		var trees []*enrichmentTree
+		for _, p := range pending {
+			if p.err == nil && skillset != nil && !p.deleted {
+				trees = append(trees, p.tree)
+			}
+		}
+		var outcomes []enrichment
+		if len(trees) > 0 {
+			outcomes = skillset.enrich(ctx, trees)
+		}
+		for _, p := range pending {
+			doc, key, err := p.doc, p.key, p.err
+			if err == nil && skillset != nil && !p.deleted {
+				// Enrichment runs before outputFieldMappings read the tree.
+				o := outcomes[0]
+				outcomes = outcomes[1:]
+				if !run.enriched(p.sourceKey, o.warnings, o.errs) {
+					if run.tooManyFailures() {
+						return tooManyFailuresError(run)
+					}
+					continue
+				}
+				doc, key, err = p.document()
+			}
+			if err == nil && projections != nil {
+				if err := projections.write(ctx, p.tree, key, p.deleted, run); err != nil {
+					return err
+				}
+				if projections.skipParents() && !p.deleted {
+					doc = nil
+				}
+			}
+			if err == nil && p.deleted {
+				doc = map[string]interface{}{"@search.action": "delete", keyField: key}
+			}
+			run.result.ItemsProcessed++
+			if err != nil {
+				run.fail(p.sourceKey, http.StatusBadRequest, err.Error())
+			} else if doc != nil {
+				batch = append(batch, doc)
+				if len(batch) >= batchSize {
+					if err := flush(); err != nil {
+						return err
+					}
+				}
+			}
+			if run.tooManyFailures() {
+				return tooManyFailuresError(run)
+			}
+		}
+		return nil
+	}
+
+	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if markColumn != "" {
+			mark, ok := item.Fields[markColumn]
+			mark = markValue(mark)
+			if !ok || mark == nil {
+				return fmt.Errorf("the high water mark column '%s' is missing from item '%s'", markColumn, item.Key)
+			}
+			if !markAfter(mark, st.HighWaterMark) {
+				continue
+			}
+			if markAfter(mark, run.highWaterMark) {
+				run.highWaterMark = mark
+			}
+		}
+		sources, warning, err := def.Parameters.Configuration.crack(item)
+		if err != nil {
+			if selected == nil || selected(item.Key, "") {
+				run.result.ItemsProcessed++
+				run.fail(item.Key, http.StatusBadRequest, err.Error())
+				if run.tooManyFailures() {
+					return tooManyFailuresError(run)
+				}
+			}
+			continue
+		}
+		if warning != "" {
+			run.result.Warnings = append(run.result.Warnings, IndexerItemWarning{Key: item.Key, Message: warning})
+		}
+		deleted := source.softDeleted(item)
+		for _, src := range sources {
+			tree := newEnrichmentTree(src)
+			document := func() (map[string]interface{}, string, error) {
+				doc, key, err := itemDocument(src, tree, def, schema, keyField)
+				if err != nil && projections != nil && projections.skipParents() {
+					// The parent is not indexed, so its key need not fit the target index.
+					return nil, parentDocumentKey(src, item), nil
+				}
+				return doc, key, err
+			}
+			doc, key, err := document()
+			if selected != nil && !selected(item.Key, key) {
+				continue
+			}
+			pending = append(pending, pendingDocument{item.Key, tree, document, doc, key, err, deleted})
+			if len(pending) < batchSize {
+				continue
+			}
+			if err := index(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := index(); err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	if run.tooManyFailures() {
+		return tooManyFailuresError(run)
+	}
+	return nil
+}
+
+// pendingDocument is a document waiting to be enriched and indexed with the
+// rest of its batch.
+type pendingDocument struct {
+	sourceKey string
+	tree      *enrichmentTree
+	// document rebuilds the document from the enriched tree.
+	document func() (map[string]interface{}, string, error)
+	doc      map[string]interface{}
+	key      string
+	err      error
+	deleted  bool
+}
+
+func tooManyFailuresError(run *indexerRun) error {
+	return fmt.Errorf("the number of failed items (%d) exceeded the limit set by the maxFailedItems parameter (%d)", run.result.ItemsFailed, run.maxFailedItems)
+}
+
+// resetSelection returns a filter accepting only the items queued by
+// resetdocs, or nil when every item is to be processed.
+func resetSelection(st *indexerState) func(sourceID, documentKey string) bool {
+	if !st.hasResetDocs() {
+		return nil
+	}
+	keys := make(map[string]bool, len(st.ResetDocumentKeys))
+	for _, k := range st.ResetDocumentKeys {
+		keys[k] = true
+	}
+	ids := make(map[string]bool, len(st.ResetDatasourceDocumentIds))
+	for _, id := range st.ResetDatasourceDocumentIds {
+		ids[id] = true
+	}
+	return func(sourceID, documentKey string) bool {
+		return ids[sourceID] || documentKey != "" && keys[documentKey]
+	}
+}
+
+// itemDocument turns a source document into a mergeOrUpload action and
+// returns it with its key. Source fields map to index fields of the same
+// name, then fieldMappings and outputFieldMappings are applied, each
+// overriding earlier values of its target field. Documents of one-to-many
+// parsing modes are keyed by AzureSearch_DocumentKey unless a mapping
+// supplies the key.
+func itemDocument(source map[string]interface{}, tree *enrichmentTree, def *indexerDefinition, schema *indexSchema, keyField string) (map[string]interface{}, string, error) {
+	doc := map[string]interface{}{"@search.action": "mergeOrUpload"}
+	for _, f := range schema.Fields {
+		if v, ok := source[f.Name]; ok {
+			doc[f.Name] = v
+		}
+	}
+	for _, m := range def.FieldMappings {
+		v, ok := source[m.SourceFieldName]
+		if !ok {
+			continue
+		}
+		if err := setMappedField(doc, m, v); err != nil {
+			return nil, "", err
+		}
+	}
+	if _, ok := doc[keyField]; !ok {
+		if v, ok := source[documentKeyField]; ok {
+			doc[keyField] = v
+		}
+	}
+	for _, m := range def.OutputFieldMappings {
+		v, ok := tree.resolve(m.SourceFieldName)
+		if !ok {
+			continue
+		}
+		if err := setMappedField(doc, m, v); err != nil {
+			return nil, "", err
+		}
+	}
+	for _, f := range schema.Fields {
+		if v, ok := doc[f.Name]; ok {
+			doc[f.Name] = convertFieldValue(v, f.Type)
+		}
+	}
+	key, _ := doc[keyField].(string)
+	if key == "" {
+		return nil, "", fmt.Errorf("the document key field '%s' is missing or empty", keyField)
+	}
+	return doc, key, nil
+}
+
+// enrichmentName names the skill an enrichment error or warning came from,
+// as Azure reports it.
+func enrichmentName(skill string) string {
+	if skill == "" {
+		return ""
+	}
+	return "Enrichment." + skill
+}
+
+func setMappedField(doc map[string]interface{}, m fieldMapping, v interface{}) error {
+	out, err := m.apply(v)
+	if err != nil {
+		return err
+	}
+	doc[m.target()] = out
+	return nil
+}
+
+// convertFieldValue applies the conversions Azure performs when a source
+// column does not have the field's type: numbers and booleans become
+// strings, and 0/1 integers become booleans.
+func convertFieldValue(v interface{}, fieldType string) interface{} {
+	switch fieldType {
+	case "Edm.String":
+		switch val := v.(type) {
+		case int64, float64, bool:
+			return fmt.Sprint(val)
+		}
+	case "Edm.Boolean":
+		if n, ok := v.(int64); ok {
+			return n != 0
+		}
+	}
+	return v
+}
+
+// softDeleted reports whether an item carries the marker value of the data
+// source's soft delete column. Integer columns match "true" and "false" as 1
+// and 0, as SQL bit columns do.
+func (d *dataSourceDefinition) softDeleted(item domain.SourceItem) bool {
+	p := d.DataDeletionDetectionPolicy
+	if p == nil {
+		return false
+	}
+	switch v := item.Fields[p.SoftDeleteColumnName].(type) {
+	case nil:
+		return false
+	case bool:
+		return strings.EqualFold(p.SoftDeleteMarkerValue, strconv.FormatBool(v))
+	case int64:
+		if b, err := strconv.ParseBool(p.SoftDeleteMarkerValue); err == nil {
+			return (v != 0) == b
+		}
+		return strconv.FormatInt(v, 10) == p.SoftDeleteMarkerValue
+	default:
+		return strings.EqualFold(fmt.Sprint(v), p.SoftDeleteMarkerValue)
+	}
+}
+
+// markAfter reports whether the change tracking value v is beyond mark. A
+// nil mark precedes everything. Integers compare exactly, other numbers
+// numerically and timestamps chronologically; other values compare as
+// strings.
+func markAfter(v, mark interface{}) bool {
+	if mark == nil {
+		return true
+	}
+	v, mark = markValue(v), markValue(mark)
+	if a, ok := v.(int64); ok {
+		if b, ok := mark.(int64); ok {
+			return a > b
+		}
+	}
+	if a, ok := markNumber(v); ok {
+		if b, ok := markNumber(mark); ok {
+			return a > b
+		}
+	}
+	as, bs := fmt.Sprint(v), fmt.Sprint(mark)
+	if a, err := time.Parse(time.RFC3339Nano, as); err == nil {
+		if b, err := time.Parse(time.RFC3339Nano, bs); err == nil {
+			return a.After(b)
+		}
+	}
+	return as > bs
+}
+
+// markValue normalizes a change tracking value for comparison and storage:
+// timestamps become RFC 3339 strings in UTC, as they are saved in the
+// indexer state, and JSON numbers become int64 where they are integers.
+func markValue(v interface{}) interface{} {
+	switch n := v.(type) {
+	case time.Time:
+		return n.UTC().Format(time.RFC3339Nano)
+	case int:
+		return int64(n)
+	case json.Number:
+		if i, err := n.Int64(); err == nil {
+			return i
+		}
+		if f, err := n.Float64(); err == nil {
+			return f
+		}
+		return n.String()
+	}
+	return v
+}
+
+func markNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
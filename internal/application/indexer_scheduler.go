@@ -0,0 +1,138 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Bounds Azure places on schedule.interval.
+const (
+	minScheduleInterval = 5 * time.Minute
+	maxScheduleInterval = 24 * time.Hour
+)
+
+// isoDurationRe matches the ISO 8601 durations used by schedule.interval,
+// e.g. PT5M, PT1H30M or P1D.
+var isoDurationRe = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+func parseISODuration(s string) (time.Duration, error) {
+	m := isoDurationRe.FindStringSubmatch(s)
+	if m == nil || s == "P" || s == "PT" {
+		return 0, fmt.Errorf("'%s' is not an ISO 8601 duration", s)
+	}
+	var d time.Duration
+	for i, unit := range []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if m[i+1] == "" {
+			continue
+		}
+		n, err := strconv.ParseFloat(m[i+1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("'%s' is not an ISO 8601 duration", s)
+		}
+		d += time.Duration(n * float64(unit))
+	}
+	return d, nil
+}
+
+// NewAcceleratedClock returns a clock that starts at the current time and
+// advances factor times faster than real time, so that scheduled indexers
+// can be exercised without waiting: with a factor of 60 a PT5M schedule
+// fires every five seconds. A factor of 1 or less returns time.Now.
+//
+// Run timestamps are taken from this clock and persisted in the execution
+// history, so they drift ahead of real time. After a restart the clock
+// starts again from real time, and a schedule may wait until it catches up
+// with the last recorded run; running the indexer on demand records a run
+// at the current time and resumes the schedule from there.
+func NewAcceleratedClock(factor float64) func() time.Time {
+	if factor <= 1 {
+		return time.Now
+	}
+	origin := time.Now()
+	return func() time.Time {
+		elapsed := time.Since(origin)
+		return origin.Add(time.Duration(float64(elapsed) * factor))
+	}
+}
+
+// IndexerScheduler runs indexers on their schedule.interval, measured with
+// the indexer service's clock. Due times derive from the execution history,
+// so schedules survive restarts.
+type IndexerScheduler struct {
+	Indexers *IndexerService
+	// PollInterval is how often, in real time, schedules are checked.
+	PollInterval time.Duration
+}
+
+func NewIndexerScheduler(indexers *IndexerService, pollInterval time.Duration) *IndexerScheduler {
+	return &IndexerScheduler{Indexers: indexers, PollInterval: pollInterval}
+}
+
+// Run checks schedules every PollInterval until ctx is done.
+func (s *IndexerScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runDue(ctx)
+		}
+	}
+}
+
+// runDue runs every enabled, scheduled indexer whose next run is due.
+func (s *IndexerScheduler) runDue(ctx context.Context) {
+	list, err := s.Indexers.Repo.List()
+	if err != nil {
+		log.Printf("indexer scheduler: %v", err)
+		return
+	}
+	now := s.Indexers.Clock()
+	for _, ix := range list {
+		def, err := parseIndexerDefinition(ix.Definition)
+		if err != nil || def.Schedule == nil || def.disabled() {
+			continue
+		}
+		due, err := s.nextRun(ix.Name, def.Schedule)
+		if err != nil {
+			log.Printf("indexer scheduler: %s: %v", ix.Name, err)
+			continue
+		}
+		if now.Before(due) {
+			continue
+		}
+		if err := s.Indexers.RunIndexer(ctx, ix.Name); err != nil {
+			log.Printf("indexer scheduler: %s: %v", ix.Name, err)
+		}
+	}
+}
+
+// nextRun returns when the indexer is next due: interval after its last
+// run, and not before schedule.startTime. Resets do not count as runs.
+func (s *IndexerScheduler) nextRun(name string, schedule *indexerSchedule) (time.Time, error) {
+	interval, err := parseISODuration(schedule.Interval)
+	if err != nil {
+		return time.Time{}, err
+	}
+	st, err := s.Indexers.loadState(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var due time.Time
+	for _, r := range st.ExecutionHistory {
+		if r.Status != "reset" {
+			due = r.StartTime.Add(interval)
+			break
+		}
+	}
+	if schedule.StartTime != nil && schedule.StartTime.After(due) {
+		due = *schedule.StartTime
+	}
+	return due, nil
+}
@@ -0,0 +1,107 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseISODuration(t *testing.T) {
+	t.Parallel()
+	valid := map[string]time.Duration{
+		"PT5M":    5 * time.Minute,
+		"PT1H30M": 90 * time.Minute,
+		"P1D":     24 * time.Hour,
+		"PT45S":   45 * time.Second,
+		"P1DT2H":  26 * time.Hour,
+		"PT0.5S":  500 * time.Millisecond,
+	}
+	for in, want := range valid {
+		if got, err := parseISODuration(in); err != nil || got != want {
+			t.Errorf("parseISODuration(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "P", "PT", "5M", "PT5X", "P1W"} {
+		if _, err := parseISODuration(in); err == nil {
+			t.Errorf("parseISODuration(%q) should fail", in)
+		}
+	}
+}
+
+func TestNewAcceleratedClock(t *testing.T) {
+	t.Parallel()
+	clock := NewAcceleratedClock(1000)
+	start := clock()
+	time.Sleep(10 * time.Millisecond)
+	if elapsed := clock().Sub(start); elapsed < 5*time.Second {
+		t.Errorf("10ms at 1000x elapsed %v, want at least 5s", elapsed)
+	}
+}
+
+// fakeClock is a settable clock for schedule tests.
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func TestIndexerScheduler_RunsDueIndexers(t *testing.T) {
+	t.Parallel()
+	env := newIndexerServiceForTest(t, hotelItems(1))
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	env.svc.Clock = clock.Now
+	ctx := context.Background()
+	future := clock.now.Add(time.Hour).Format(time.RFC3339)
+	for _, body := range []string{
+		`{"name":"every5","dataSourceName":"src","targetIndexName":"hotels","schedule":{"interval":"PT5M"}}`,
+		`{"name":"manual","dataSourceName":"src","targetIndexName":"hotels"}`,
+		`{"name":"off","dataSourceName":"src","targetIndexName":"hotels","disabled":true,"schedule":{"interval":"PT5M"}}`,
+		`{"name":"later","dataSourceName":"src","targetIndexName":"hotels","disabled":true,"schedule":{"interval":"PT5M","startTime":"` + future + `"}}`,
+	} {
+		if _, err := env.svc.CreateIndexer(ctx, []byte(body)); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		env.svc.Wait()
+	}
+	// "later" is enabled without running so that only its start time applies.
+	later, _ := env.svc.Repo.FindByName("later")
+	later.Definition = `{"name":"later","dataSourceName":"src","targetIndexName":"hotels","schedule":{"interval":"PT5M","startTime":"` + future + `"}}`
+	_ = env.svc.Repo.Update(later)
+
+	sched := NewIndexerScheduler(env.svc, time.Second)
+	runs := func(name string) int { return len(env.status(t, name).ExecutionHistory) }
+
+	clock.now = clock.now.Add(4 * time.Minute)
+	sched.runDue(ctx)
+	if runs("every5") != 1 {
+		t.Errorf("every5 ran before its interval elapsed")
+	}
+	clock.now = clock.now.Add(time.Minute)
+	sched.runDue(ctx)
+	if runs("every5") != 2 {
+		t.Errorf("every5 runs = %d, want 2 after 5 minutes", runs("every5"))
+	}
+	if runs("manual") != 1 || runs("off") != 0 || runs("later") != 0 {
+		t.Errorf("runs: manual=%d off=%d later=%d, want 1, 0, 0", runs("manual"), runs("off"), runs("later"))
+	}
+	clock.now = clock.now.Add(time.Hour)
+	sched.runDue(ctx)
+	if runs("later") != 1 {
+		t.Errorf("later should run once its start time has passed")
+	}
+}
+
+func TestIndexerScheduler_ResetDoesNotCountAsRun(t *testing.T) {
+	t.Parallel()
+	env := newIndexerServiceForTest(t, hotelItems(1))
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	env.svc.Clock = clock.Now
+	ctx := context.Background()
+	_, _ = env.svc.CreateIndexer(ctx, []byte(`{"name":"ix","dataSourceName":"src","targetIndexName":"hotels","schedule":{"interval":"PT5M"}}`))
+	env.svc.Wait()
+
+	clock.now = clock.now.Add(5 * time.Minute)
+	_ = env.svc.ResetIndexer(ctx, "ix")
+	NewIndexerScheduler(env.svc, time.Second).runDue(ctx)
+	if st := env.status(t, "ix"); len(st.ExecutionHistory) != 3 || st.LastResult.Status != "success" {
+		t.Errorf("history = %+v, want a scheduled run after the reset", st.ExecutionHistory)
+	}
+}
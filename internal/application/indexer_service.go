@@ -0,0 +1,538 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-search-emulator/internal/domain"
+)
+
+// maxExecutionHistory is the number of runs Azure keeps in executionHistory.
+const maxExecutionHistory = 50
+
+// indexerDefinition is the parsed form of domain.Indexer.Definition.
+type indexerDefinition struct {
+	Name            string            `json:"name"`
+	DataSourceName  string            `json:"dataSourceName"`
+	TargetIndexName string            `json:"targetIndexName"`
+	SkillsetName    string            `json:"skillsetName"`
+	Disabled        *bool             `json:"disabled"`
+	Schedule        *indexerSchedule  `json:"schedule"`
+	Parameters      indexerParameters `json:"parameters"`
+	// FieldMappings apply to the source document before enrichment;
+	// OutputFieldMappings map enrichment tree paths to index fields.
+	FieldMappings       []fieldMapping `json:"fieldMappings"`
+	OutputFieldMappings []fieldMapping `json:"outputFieldMappings"`
+}
+
+type indexerSchedule struct {
+	Interval  string     `json:"interval"`
+	StartTime *time.Time `json:"startTime"`
+}
+
+type indexerParameters struct {
+	BatchSize      *int                 `json:"batchSize"`
+	MaxFailedItems *int                 `json:"maxFailedItems"`
+	Configuration  indexerConfiguration `json:"configuration"`
+}
+
+func (d *indexerDefinition) disabled() bool {
+	return d.Disabled != nil && *d.Disabled
+}
+
+func parseIndexerDefinition(definition string) (*indexerDefinition, error) {
+	var d indexerDefinition
+	if err := json.Unmarshal([]byte(definition), &d); err != nil {
+		return nil, fmt.Errorf("%w: the request body is not valid JSON", domain.ErrInvalidIndexer)
+	}
+	return &d, nil
+}
+
+// IndexerExecutionResult is one entry of an indexer's execution history.
+type IndexerExecutionResult struct {
+	Status               string               `json:"status"`
+	ErrorMessage         *string              `json:"errorMessage"`
+	StartTime            time.Time            `json:"startTime"`
+	EndTime              *time.Time           `json:"endTime"`
+	ItemsProcessed       int                  `json:"itemsProcessed"`
+	ItemsFailed          int                  `json:"itemsFailed"`
+	Errors               []IndexerItemError   `json:"errors"`
+	Warnings             []IndexerItemWarning `json:"warnings"`
+	InitialTrackingState *string              `json:"initialTrackingState"`
+	FinalTrackingState   *string              `json:"finalTrackingState"`
+}
+
+// IndexerItemError reports an item that could not be indexed.
+type IndexerItemError struct {
+	Key          string `json:"key,omitempty"`
+	ErrorMessage string `json:"errorMessage"`
+	StatusCode   int    `json:"statusCode"`
+	Name         string `json:"name,omitempty"`
+	Details      string `json:"details,omitempty"`
+}
+
+// IndexerItemWarning reports an item that was indexed with a problem.
+type IndexerItemWarning struct {
+	Key     string `json:"key,omitempty"`
+	Message string `json:"message"`
+	Name    string `json:"name,omitempty"`
+	Details string `json:"details,omitempty"`
+}
+
+// IndexerCurrentState describes what the next run will process.
+type IndexerCurrentState struct {
+	Mode                       string   `json:"mode"`
+	ResetDocumentKeys          []string `json:"resetDocumentKeys,omitempty"`
+	ResetDatasourceDocumentIds []string `json:"resetDatasourceDocumentIds,omitempty"`
+}
+
+// IndexerStatus is the response of GET /indexers/{name}/search.status.
+type IndexerStatus struct {
+	Name             string                   `json:"name"`
+	Status           string                   `json:"status"`
+	LastResult       *IndexerExecutionResult  `json:"lastResult"`
+	ExecutionHistory []IndexerExecutionResult `json:"executionHistory"`
+	Limits           map[string]interface{}   `json:"limits"`
+	CurrentState     IndexerCurrentState      `json:"currentState"`
+}
+
+// indexerState is the persisted status of an indexer: its history, newest
+// first, and the documents queued by resetdocs.
+type indexerState struct {
+	ExecutionHistory           []IndexerExecutionResult `json:"executionHistory"`
+	ResetDocumentKeys          []string                 `json:"resetDocumentKeys,omitempty"`
+	ResetDatasourceDocumentIds []string                 `json:"resetDatasourceDocumentIds,omitempty"`
+	// HighWaterMark is the highest change tracking value indexed so far.
+	HighWaterMark interface{} `json:"highWaterMark,omitempty"`
+}
+
+func (st *indexerState) record(r IndexerExecutionResult) {
+	st.ExecutionHistory = append([]IndexerExecutionResult{r}, st.ExecutionHistory...)
+	if len(st.ExecutionHistory) > maxExecutionHistory {
+		st.ExecutionHistory = st.ExecutionHistory[:maxExecutionHistory]
+	}
+}
+
+type IndexerService struct {
+	Repo        domain.IndexerRepository
+	DataSources *DataSourceService
+	Documents   *DocumentService
+	// Skillsets supplies the skillsets indexers enrich documents with.
+	Skillsets *SkillsetService
+	// Clock supplies run timestamps and the time schedules are evaluated
+	// against. It defaults to time.Now; see NewAcceleratedClock.
+	Clock func() time.Time
+
+	// ctx is the context of runs started in the background by StartIndexer.
+	ctx     context.Context
+	runs    sync.WaitGroup
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+func NewIndexerService(repo domain.IndexerRepository, dataSources *DataSourceService, documents *DocumentService) *IndexerService {
+	return &IndexerService{
+		Repo:        repo,
+		DataSources: dataSources,
+		Documents:   documents,
+		Clock:       time.Now,
+		ctx:         context.Background(),
+		running:     map[string]bool{},
+	}
+}
+
+// CreateIndexer stores a new indexer and, unless it is disabled, starts a
+// run of it.
+func (s *IndexerService) CreateIndexer(ctx context.Context, body []byte) (*domain.Indexer, error) {
+	def, err := parseIndexerDefinition(string(body))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validate(def.Name, def); err != nil {
+		return nil, err
+	}
+	if _, err := s.Repo.FindByName(def.Name); err == nil {
+		return nil, domain.ErrIndexerAlreadyExists
+	} else if !errors.Is(err, domain.ErrIndexerNotFound) {
+		return nil, err
+	}
+	ix := &domain.Indexer{Name: def.Name, Definition: string(body), ETag: newETag()}
+	if err := s.Repo.Create(ix); err != nil {
+		return nil, err
+	}
+	return ix, s.runIfEnabled(ctx, ix.Name, def)
+}
+
+// CreateOrUpdateIndexer upserts an indexer and, unless it is disabled,
+// starts a run of it. Returns true if the indexer was newly created.
+func (s *IndexerService) CreateOrUpdateIndexer(ctx context.Context, name string, body []byte, cond AccessCondition) (*domain.Indexer, bool, error) {
+	def, err := parseIndexerDefinition(string(body))
+	if err != nil {
+		return nil, false, err
+	}
+	if def.Name != "" && def.Name != name {
+		return nil, false, fmt.Errorf("%w: the indexer name '%s' in the request body does not match the name '%s' in the request URL", domain.ErrInvalidIndexer, def.Name, name)
+	}
+	ix, err := s.Repo.FindByName(name)
+	if err != nil && !errors.Is(err, domain.ErrIndexerNotFound) {
+		return nil, false, err
+	}
+	exists := err == nil
+	current := ""
+	if exists {
+		current = ix.ETag
+	}
+	if err := cond.check(exists, current); err != nil {
+		return nil, false, err
+	}
+	if err := s.validate(name, def); err != nil {
+		return nil, false, err
+	}
+
+	if exists {
+		ix.Definition = string(body)
+		ix.ETag = newETag()
+		if cond != (AccessCondition{}) {
+			err = s.Repo.UpdateIfMatch(ix, current)
+		} else {
+			err = s.Repo.Update(ix)
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		return ix, false, s.runIfEnabled(ctx, name, def)
+	}
+	ix = &domain.Indexer{Name: name, Definition: string(body), ETag: newETag()}
+	if err := s.Repo.Create(ix); err != nil {
+		return nil, false, err
+	}
+	return ix, true, s.runIfEnabled(ctx, name, def)
+}
+
+// runIfEnabled starts a run of a newly saved indexer the way Azure does on
+// create and update. A run already in progress is left alone.
+func (s *IndexerService) runIfEnabled(ctx context.Context, name string, def *indexerDefinition) error {
+	if def.disabled() {
+		return nil
+	}
+	if err := s.StartIndexer(ctx, name); err != nil && !errors.Is(err, domain.ErrIndexerRunning) {
+		return err
+	}
+	return nil
+}
+
+func (s *IndexerService) GetIndexer(ctx context.Context, name string) (map[string]interface{}, error) {
+	ix, err := s.Repo.FindByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return indexerResponse(ix)
+}
+
+func (s *IndexerService) ListIndexers(ctx context.Context) ([]map[string]interface{}, error) {
+	list, err := s.Repo.List()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]map[string]interface{}, 0, len(list))
+	for _, ix := range list {
+		m, err := indexerResponse(ix)
+		if err != nil {
+			continue // 定義不正はスキップ
+		}
+		result = append(result, m)
+	}
+	return result, nil
+}
+
+// DeleteIndexer deletes an indexer and its status. When cond is set, the
+// current ETag must satisfy it or domain.ErrPreconditionFailed is returned.
+// A running indexer cannot be deleted: the end of the run would otherwise
+// save its status again.
+func (s *IndexerService) DeleteIndexer(ctx context.Context, name string, cond AccessCondition) error {
+	if !s.begin(name) {
+		return domain.ErrIndexerRunning
+	}
+	defer s.end(name)
+	if cond != (AccessCondition{}) {
+		ix, err := s.Repo.FindByName(name)
+		if err != nil && !errors.Is(err, domain.ErrIndexerNotFound) {
+			return err
+		}
+		current := ""
+		if err == nil {
+			current = ix.ETag
+		}
+		if err := cond.check(err == nil, current); err != nil {
+			return err
+		}
+		if err == nil {
+			return s.Repo.DeleteIfMatch(name, current)
+		}
+	}
+	return s.Repo.Delete(name)
+}
+
+// GetIndexerStatus returns the execution history of an indexer.
+func (s *IndexerService) GetIndexerStatus(ctx context.Context, name string) (*IndexerStatus, error) {
+	if _, err := s.Repo.FindByName(name); err != nil {
+		return nil, err
+	}
+	st, err := s.loadState(name)
+	if err != nil {
+		return nil, err
+	}
+	status := &IndexerStatus{
+		Name:             name,
+		Status:           "running",
+		ExecutionHistory: st.ExecutionHistory,
+		Limits: map[string]interface{}{
+			"maxRunTime":                            "PT2H",
+			"maxDocumentExtractionSize":             16777216,
+			"maxDocumentContentCharactersToExtract": 32768,
+		},
+		CurrentState: IndexerCurrentState{
+			Mode:                       "indexingAllDocs",
+			ResetDocumentKeys:          st.ResetDocumentKeys,
+			ResetDatasourceDocumentIds: st.ResetDatasourceDocumentIds,
+		},
+	}
+	if status.ExecutionHistory == nil {
+		status.ExecutionHistory = []IndexerExecutionResult{}
+	}
+	if len(st.ExecutionHistory) > 0 {
+		last := st.ExecutionHistory[0]
+		status.LastResult = &last
+	}
+	if st.hasResetDocs() {
+		status.CurrentState.Mode = "indexingResetDocs"
+	}
+	s.mu.Lock()
+	if s.running[name] {
+		status.LastResult = &IndexerExecutionResult{Status: "inProgress", Errors: []IndexerItemError{}, Warnings: []IndexerItemWarning{}}
+	}
+	s.mu.Unlock()
+	return status, nil
+}
+
+// ResetIndexer discards the indexer's change tracking state (its high-water
+// mark), so that the next run processes every item again.
+func (s *IndexerService) ResetIndexer(ctx context.Context, name string) error {
+	if _, err := s.Repo.FindByName(name); err != nil {
+		return err
+	}
+	if !s.begin(name) {
+		return domain.ErrIndexerRunning
+	}
+	defer s.end(name)
+	st, err := s.loadState(name)
+	if err != nil {
+		return err
+	}
+	st.ResetDocumentKeys, st.ResetDatasourceDocumentIds = nil, nil
+	st.HighWaterMark = nil
+	now := s.Clock().UTC()
+	st.record(IndexerExecutionResult{Status: "reset", StartTime: now, EndTime: &now, Errors: []IndexerItemError{}, Warnings: []IndexerItemWarning{}})
+	return s.saveState(name, st)
+}
+
+// ResetDocuments queues documents, by index key or by data source item ID,
+// for the next run, which then processes only those. Unless overwrite is
+// set, the keys are added to the ones already queued.
+func (s *IndexerService) ResetDocuments(ctx context.Context, name string, documentKeys, datasourceDocumentIds []string, overwrite bool) error {
+	if _, err := s.Repo.FindByName(name); err != nil {
+		return err
+	}
+	if len(documentKeys) > 0 && len(datasourceDocumentIds) > 0 {
+		return fmt.Errorf("%w: only one of documentKeys or datasourceDocumentIds can be specified", domain.ErrInvalidIndexer)
+	}
+	if len(documentKeys) == 0 && len(datasourceDocumentIds) == 0 && !overwrite {
+		return fmt.Errorf("%w: documentKeys or datasourceDocumentIds must be specified", domain.ErrInvalidIndexer)
+	}
+	if !s.begin(name) {
+		return domain.ErrIndexerRunning
+	}
+	defer s.end(name)
+	st, err := s.loadState(name)
+	if err != nil {
+		return err
+	}
+	if overwrite {
+		st.ResetDocumentKeys, st.ResetDatasourceDocumentIds = nil, nil
+	}
+	st.ResetDocumentKeys = appendUnique(st.ResetDocumentKeys, documentKeys)
+	st.ResetDatasourceDocumentIds = appendUnique(st.ResetDatasourceDocumentIds, datasourceDocumentIds)
+	return s.saveState(name, st)
+}
+
+func (st *indexerState) hasResetDocs() bool {
+	return len(st.ResetDocumentKeys) > 0 || len(st.ResetDatasourceDocumentIds) > 0
+}
+
+func appendUnique(list, add []string) []string {
+	seen := make(map[string]bool, len(list))
+	for _, v := range list {
+		seen[v] = true
+	}
+	for _, v := range add {
+		if !seen[v] {
+			seen[v] = true
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// begin marks the indexer as running; it returns false if it already is.
+func (s *IndexerService) begin(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[name] {
+		return false
+	}
+	s.running[name] = true
+	return true
+}
+
+func (s *IndexerService) end(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, name)
+}
+
+func (s *IndexerService) loadState(name string) (*indexerState, error) {
+	raw, err := s.Repo.FindStatus(name)
+	if err != nil {
+		return nil, err
+	}
+	st := &indexerState{}
+	if raw == "" {
+		return st, nil
+	}
+	// Decode numbers as json.Number so integer high-water marks beyond
+	// float64 precision survive the round trip.
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(st); err != nil {
+		return nil, fmt.Errorf("indexer status parse error")
+	}
+	st.HighWaterMark = markValue(st.HighWaterMark)
+	return st, nil
+}
+
+func (s *IndexerService) saveState(name string, st *indexerState) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return s.Repo.SaveStatus(name, string(data))
+}
+
+// indexerResponse renders a stored indexer with its @odata.etag.
+func indexerResponse(ix *domain.Indexer) (map[string]interface{}, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(ix.Definition), &m); err != nil {
+		return nil, fmt.Errorf("definition parse error")
+	}
+	m["name"] = ix.Name
+	m["@odata.etag"] = ix.ETag
+	return m, nil
+}
+
+// validateProjections checks the index projections of an indexer's
+// skillset against their target indexes.
+func (s *IndexerService) validateProjections(p *indexProjections) error {
+	if p == nil {
+		return nil
+	}
+	for _, sel := range p.Selectors {
+		schema, err := s.Documents.schema(sel.TargetIndexName)
+		if errors.Is(err, domain.ErrIndexNotFound) {
+			return fmt.Errorf("%w: the index '%s' of an index projection does not exist", domain.ErrInvalidIndexer, sel.TargetIndexName)
+		} else if err != nil {
+			return err
+		}
+		if err := sel.validateTarget(schema); err != nil {
+			return fmt.Errorf("%w: %v", domain.ErrInvalidIndexer, err)
+		}
+	}
+	return nil
+}
+
+// validate checks a definition addressed by name, including that its data
+// source and target index exist.
+func (s *IndexerService) validate(name string, def *indexerDefinition) error {
+	if err := validateResourceName("indexer", name); err != nil {
+		return err
+	}
+	if def.DataSourceName == "" {
+		return fmt.Errorf("%w: the indexer must specify dataSourceName", domain.ErrInvalidIndexer)
+	}
+	if def.TargetIndexName == "" {
+		return fmt.Errorf("%w: the indexer must specify targetIndexName", domain.ErrInvalidIndexer)
+	}
+	ds, err := s.DataSources.Repo.FindByName(def.DataSourceName)
+	if errors.Is(err, domain.ErrDataSourceNotFound) {
+		return fmt.Errorf("%w: the data source '%s' does not exist", domain.ErrInvalidIndexer, def.DataSourceName)
+	} else if err != nil {
+		return err
+	}
+	source, err := parseDataSourceDefinition(ds.Definition)
+	if err != nil {
+		return err
+	}
+	if err := def.Parameters.Configuration.validate(source.isBlob()); err != nil {
+		return err
+	}
+	schema, err := s.Documents.schema(def.TargetIndexName)
+	if errors.Is(err, domain.ErrIndexNotFound) {
+		return fmt.Errorf("%w: the index '%s' does not exist", domain.ErrInvalidIndexer, def.TargetIndexName)
+	} else if err != nil {
+		return err
+	}
+	if def.SkillsetName != "" {
+		if s.Skillsets == nil {
+			return fmt.Errorf("%w: skillsets are not supported", domain.ErrInvalidIndexer)
+		}
+		skillset, err := s.Skillsets.load(def.SkillsetName)
+		if errors.Is(err, domain.ErrSkillsetNotFound) {
+			return fmt.Errorf("%w: the skillset '%s' does not exist", domain.ErrInvalidIndexer, def.SkillsetName)
+		} else if err != nil {
+			return err
+		}
+		if err := s.validateProjections(skillset.IndexProjections); err != nil {
+			return err
+		}
+	}
+	for _, m := range def.FieldMappings {
+		if err := m.validate(schema, false); err != nil {
+			return err
+		}
+	}
+	for _, m := range def.OutputFieldMappings {
+		if err := m.validate(schema, true); err != nil {
+			return err
+		}
+	}
+	if b := def.Parameters.BatchSize; b != nil && (*b < 1 || *b > MaxBatchActions) {
+		return fmt.Errorf("%w: parameters.batchSize must be between 1 and %d", domain.ErrInvalidIndexer, MaxBatchActions)
+	}
+	if m := def.Parameters.MaxFailedItems; m != nil && *m < -1 {
+		return fmt.Errorf("%w: parameters.maxFailedItems must be -1 or greater", domain.ErrInvalidIndexer)
+	}
+	if def.Schedule != nil {
+		interval, err := parseISODuration(def.Schedule.Interval)
+		if err != nil {
+			return fmt.Errorf("%w: schedule.interval: %v", domain.ErrInvalidIndexer, err)
+		}
+		if interval < minScheduleInterval || interval > maxScheduleInterval {
+			return fmt.Errorf("%w: schedule.interval must be between PT5M and P1D", domain.ErrInvalidIndexer)
+		}
+	}
+	return nil
+}
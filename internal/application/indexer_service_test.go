@@ -0,0 +1,557 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"ai-search-emulator/internal/domain"
+)
+
+const indexerTestSchema = `{
+	"name": "hotels",
+	"fields": [
+		{"name": "id", "type": "Edm.String", "key": true},
+		{"name": "name", "type": "Edm.String"},
+		{"name": "rating", "type": "Edm.String"},
+		{"name": "open", "type": "Edm.Boolean"}
+	]
+}`
+
+type indexerTestEnv struct {
+	svc  *IndexerService
+	conn *mockConnector
+	docs *mockDocumentRepository
+}
+
+// newIndexerServiceForTest returns a service with a "hotels" index and an
+// "src" data source served by a mock connector returning items.
+func newIndexerServiceForTest(t *testing.T, items []domain.SourceItem) *indexerTestEnv {
+	t.Helper()
+	idxRepo := newMockIndexRepository()
+	docRepo := newMockDocumentRepository()
+	_ = idxRepo.Create(&domain.Index{Name: "hotels", Schema: indexerTestSchema})
+	conn := &mockConnector{items: items}
+	dataSources := NewDataSourceService(newMockDataSourceRepository(), map[string]domain.SourceConnector{"azuresql": conn})
+	src := `{"name":"src","type":"azuresql","credentials":{"connectionString":"Data Source=x.db"},"container":{"name":"t"}}`
+	if _, err := dataSources.CreateDataSource(context.Background(), []byte(src)); err != nil {
+		t.Fatalf("create data source: %v", err)
+	}
+	svc := NewIndexerService(newMockIndexerRepository(), dataSources, NewDocumentService(docRepo, idxRepo))
+	svc.Skillsets = NewSkillsetService(newMockSkillsetRepository())
+	return &indexerTestEnv{svc: svc, conn: conn, docs: docRepo}
+}
+
+func hotelItems(n int) []domain.SourceItem {
+	items := make([]domain.SourceItem, n)
+	for i := range items {
+		id := fmt.Sprint(i + 1)
+		items[i] = domain.SourceItem{Key: id, Fields: map[string]interface{}{"id": id, "name": "Hotel " + id, "rating": int64(i), "open": int64(i % 2)}}
+	}
+	return items
+}
+
+func indexerJSON(name, params string) []byte {
+	return []byte(`{"name":"` + name + `","dataSourceName":"src","targetIndexName":"hotels","parameters":` + params + `}`)
+}
+
+// status returns the status of an indexer once the runs started in the
+// background have finished.
+func (e *indexerTestEnv) status(t *testing.T, name string) *IndexerStatus {
+	t.Helper()
+	e.svc.Wait()
+	st, err := e.svc.GetIndexerStatus(context.Background(), name)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	return st
+}
+
+func TestIndexerService_CreateRunsIndexer(t *testing.T) {
+	t.Parallel()
+	env := newIndexerServiceForTest(t, hotelItems(3))
+
+	if _, err := env.svc.CreateIndexer(context.Background(), indexerJSON("ix", `{}`)); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	st := env.status(t, "ix")
+	if st.LastResult == nil || st.LastResult.Status != "success" || st.LastResult.ItemsProcessed != 3 || st.LastResult.ItemsFailed != 0 {
+		t.Fatalf("lastResult = %+v, want success with 3 items", st.LastResult)
+	}
+	doc, err := env.docs.Find("hotels", "2")
+	if err != nil {
+		t.Fatalf("document 2 should be indexed: %v", err)
+	}
+	// rating is converted to Edm.String and open to Edm.Boolean.
+	if !strings.Contains(doc.Content, `"rating":"1"`) || !strings.Contains(doc.Content, `"open":true`) {
+		t.Errorf("content = %s", doc.Content)
+	}
+}
+
+func TestIndexerService_CreateDisabledDoesNotRun(t *testing.T) {
+	t.Parallel()
+	env := newIndexerServiceForTest(t, hotelItems(1))
+	body := []byte(`{"name":"ix","dataSourceName":"src","targetIndexName":"hotels","disabled":true}`)
+
+	if _, err := env.svc.CreateIndexer(context.Background(), body); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if st := env.status(t, "ix"); st.LastResult != nil || len(st.ExecutionHistory) != 0 {
+		t.Errorf("disabled indexer should not run, history = %+v", st.ExecutionHistory)
+	}
+}
+
+func TestIndexerService_CreateValidation(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name string
+		body string
+		want error
+	}{
+		{"bad name", `{"name":"IX","dataSourceName":"src","targetIndexName":"hotels"}`, domain.ErrInvalidName},
+		{"missing data source", `{"name":"ix","dataSourceName":"nope","targetIndexName":"hotels"}`, domain.ErrInvalidIndexer},
+		{"missing index", `{"name":"ix","dataSourceName":"src","targetIndexName":"nope"}`, domain.ErrInvalidIndexer},
+		{"bad batch size", `{"name":"ix","dataSourceName":"src","targetIndexName":"hotels","parameters":{"batchSize":0}}`, domain.ErrInvalidIndexer},
+		{"bad max failed", `{"name":"ix","dataSourceName":"src","targetIndexName":"hotels","parameters":{"maxFailedItems":-2}}`, domain.ErrInvalidIndexer},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			env := newIndexerServiceForTest(t, nil)
+			if _, err := env.svc.CreateIndexer(context.Background(), []byte(tc.body)); !errors.Is(err, tc.want) {
+				t.Errorf("err = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestIndexerService_Run_MaxFailedItems(t *testing.T) {
+	t.Parallel()
+	items := hotelItems(4)
+	delete(items[1].Fields, "id")
+	delete(items[2].Fields, "id")
+
+	env := newIndexerServiceForTest(t, items)
+	ctx := context.Background()
+	if _, err := env.svc.CreateIndexer(ctx, indexerJSON("strict", `{"batchSize":1}`)); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	last := env.status(t, "strict").LastResult
+	if last.Status != "transientFailure" || last.ErrorMessage == nil {
+		t.Fatalf("lastResult = %+v, want transientFailure", last)
+	}
+	// The run stops at the first failure beyond the default limit of 0.
+	if last.ItemsProcessed != 2 || last.ItemsFailed != 1 || len(last.Errors) != 1 || last.Errors[0].Key != "2" {
+		t.Errorf("lastResult = %+v", last)
+	}
+
+	if _, err := env.svc.CreateIndexer(ctx, indexerJSON("lenient", `{"maxFailedItems":2}`)); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	last = env.status(t, "lenient").LastResult
+	if last.Status != "success" || last.ItemsProcessed != 4 || last.ItemsFailed != 2 {
+		t.Errorf("lastResult = %+v, want success with 2 failures", last)
+	}
+}
+
+func TestIndexerService_Run_ReportsBatchFailures(t *testing.T) {
+	t.Parallel()
+	items := hotelItems(2)
+	items[1].Fields["id"] = "bad/key"
+	env := newIndexerServiceForTest(t, items)
+
+	if _, err := env.svc.CreateIndexer(context.Background(), indexerJSON("ix", `{"maxFailedItems":-1}`)); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	last := env.status(t, "ix").LastResult
+	if last.ItemsFailed != 1 || last.Errors[0].Key != "bad/key" || last.Errors[0].StatusCode != 400 {
+		t.Errorf("lastResult = %+v, want the invalid key reported", last)
+	}
+}
+
+func TestIndexerService_Run_DataSourceErrorFailsRun(t *testing.T) {
+	t.Parallel()
+	env := newIndexerServiceForTest(t, nil)
+	env.conn.readErr = fmt.Errorf("%w: the container 'x' does not exist", domain.ErrInvalidDataSource)
+
+	if _, err := env.svc.CreateIndexer(context.Background(), indexerJSON("ix", `{}`)); err != nil {
+		t.Fatalf("create should succeed even if the run fails: %v", err)
+	}
+	last := env.status(t, "ix").LastResult
+	if last.Status != "transientFailure" || last.ErrorMessage == nil || !strings.Contains(*last.ErrorMessage, "container") {
+		t.Errorf("lastResult = %+v", last)
+	}
+}
+
+func TestIndexerService_HistoryAndReset(t *testing.T) {
+	t.Parallel()
+	env := newIndexerServiceForTest(t, hotelItems(1))
+	ctx := context.Background()
+	_, _ = env.svc.CreateIndexer(ctx, indexerJSON("ix", `{}`))
+	env.svc.Wait()
+
+	if err := env.svc.RunIndexer(ctx, "ix"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if err := env.svc.ResetIndexer(ctx, "ix"); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	st := env.status(t, "ix")
+	if len(st.ExecutionHistory) != 3 || st.LastResult.Status != "reset" {
+		t.Fatalf("history = %+v, want reset on top of 2 runs", st.ExecutionHistory)
+	}
+	if err := env.svc.RunIndexer(ctx, "missing"); !errors.Is(err, domain.ErrIndexerNotFound) {
+		t.Errorf("run missing: err = %v, want ErrIndexerNotFound", err)
+	}
+}
+
+func TestIndexerService_RunWhileRunning(t *testing.T) {
+	t.Parallel()
+	env := newIndexerServiceForTest(t, nil)
+	ctx := context.Background()
+	_, _ = env.svc.CreateIndexer(ctx, indexerJSON("ix", `{}`))
+	env.svc.Wait()
+
+	env.svc.begin("ix")
+	defer env.svc.end("ix")
+	if err := env.svc.RunIndexer(ctx, "ix"); !errors.Is(err, domain.ErrIndexerRunning) {
+		t.Errorf("err = %v, want ErrIndexerRunning", err)
+	}
+	if st := env.status(t, "ix"); st.LastResult.Status != "inProgress" {
+		t.Errorf("lastResult.status = %s, want inProgress", st.LastResult.Status)
+	}
+	if err := env.svc.DeleteIndexer(ctx, "ix", AccessCondition{}); !errors.Is(err, domain.ErrIndexerRunning) {
+		t.Errorf("delete while running: err = %v, want ErrIndexerRunning", err)
+	}
+}
+
+func TestIndexerService_StartIndexerRunsInBackground(t *testing.T) {
+	t.Parallel()
+	env := newIndexerServiceForTest(t, hotelItems(2))
+	ctx := context.Background()
+	_, _ = env.svc.CreateIndexer(ctx, indexerJSON("ix", `{}`))
+	env.svc.Wait()
+
+	release := make(chan struct{})
+	env.conn.mu.Lock()
+	env.conn.release = release
+	env.conn.mu.Unlock()
+	// The run must outlive the request that started it.
+	reqCtx, cancel := context.WithCancel(ctx)
+	if err := env.svc.StartIndexer(reqCtx, "ix"); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	cancel()
+	for i := 0; i < 3; i++ {
+		st, err := env.svc.GetIndexerStatus(ctx, "ix")
+		if err != nil {
+			t.Fatalf("status: %v", err)
+		}
+		if st.LastResult.Status != "inProgress" {
+			t.Fatalf("lastResult.status during the run = %s, want inProgress", st.LastResult.Status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := env.svc.StartIndexer(ctx, "ix"); !errors.Is(err, domain.ErrIndexerRunning) {
+		t.Errorf("second start: err = %v, want ErrIndexerRunning", err)
+	}
+
+	close(release)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		st, err := env.svc.GetIndexerStatus(ctx, "ix")
+		if err != nil {
+			t.Fatalf("status: %v", err)
+		}
+		if st.LastResult.Status != "inProgress" {
+			if st.LastResult.Status != "success" || st.LastResult.ItemsProcessed != 2 || len(st.ExecutionHistory) != 2 {
+				t.Errorf("lastResult after the run = %+v", st.LastResult)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the run did not finish")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestIndexerService_ResetDocuments(t *testing.T) {
+	t.Parallel()
+	env := newIndexerServiceForTest(t, hotelItems(5))
+	ctx := context.Background()
+	_, _ = env.svc.CreateIndexer(ctx, indexerJSON("ix", `{}`))
+	env.svc.Wait()
+
+	if err := env.svc.ResetDocuments(ctx, "ix", []string{"2"}, nil, false); err != nil {
+		t.Fatalf("resetdocs: %v", err)
+	}
+	if err := env.svc.ResetDocuments(ctx, "ix", []string{"4", "2"}, nil, false); err != nil {
+		t.Fatalf("resetdocs: %v", err)
+	}
+	st := env.status(t, "ix")
+	if st.CurrentState.Mode != "indexingResetDocs" || len(st.CurrentState.ResetDocumentKeys) != 2 {
+		t.Fatalf("currentState = %+v, want keys 2 and 4", st.CurrentState)
+	}
+
+	if err := env.svc.RunIndexer(ctx, "ix"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	st = env.status(t, "ix")
+	if st.LastResult.ItemsProcessed != 2 {
+		t.Errorf("itemsProcessed = %d, want only the 2 reset documents", st.LastResult.ItemsProcessed)
+	}
+	if st.CurrentState.Mode != "indexingAllDocs" || len(st.CurrentState.ResetDocumentKeys) != 0 {
+		t.Errorf("currentState after run = %+v", st.CurrentState)
+	}
+
+	if err := env.svc.ResetDocuments(ctx, "ix", []string{"1"}, []string{"1"}, false); !errors.Is(err, domain.ErrInvalidIndexer) {
+		t.Errorf("mixed keys: err = %v, want ErrInvalidIndexer", err)
+	}
+	_ = env.svc.ResetDocuments(ctx, "ix", nil, []string{"3"}, false)
+	_ = env.svc.ResetDocuments(ctx, "ix", nil, []string{"5"}, true)
+	if st := env.status(t, "ix"); len(st.CurrentState.ResetDatasourceDocumentIds) != 1 || st.CurrentState.ResetDatasourceDocumentIds[0] != "5" {
+		t.Errorf("overwrite: ids = %v, want [5]", st.CurrentState.ResetDatasourceDocumentIds)
+	}
+}
+
+func TestIndexerService_CreateOrUpdateAndDelete(t *testing.T) {
+	t.Parallel()
+	env := newIndexerServiceForTest(t, nil)
+	ctx := context.Background()
+
+	ix, created, err := env.svc.CreateOrUpdateIndexer(ctx, "ix", indexerJSON("ix", `{}`), AccessCondition{})
+	if err != nil || !created {
+		t.Fatalf("create: created=%v err=%v", created, err)
+	}
+	env.svc.Wait()
+	if _, _, err := env.svc.CreateOrUpdateIndexer(ctx, "ix", indexerJSON("ix", `{}`), AccessCondition{IfMatch: `"stale"`}); !errors.Is(err, domain.ErrPreconditionFailed) {
+		t.Errorf("stale If-Match: err = %v, want ErrPreconditionFailed", err)
+	}
+	if _, created, err := env.svc.CreateOrUpdateIndexer(ctx, "ix", indexerJSON("ix", `{"batchSize":5}`), AccessCondition{IfMatch: ix.ETag}); err != nil || created {
+		t.Errorf("update: created=%v err=%v", created, err)
+	}
+	if len(env.status(t, "ix").ExecutionHistory) != 2 {
+		t.Errorf("create and update should each run the indexer")
+	}
+	if err := env.svc.DeleteIndexer(ctx, "ix", AccessCondition{}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := env.svc.GetIndexerStatus(ctx, "ix"); !errors.Is(err, domain.ErrIndexerNotFound) {
+		t.Errorf("status after delete: err = %v, want ErrIndexerNotFound", err)
+	}
+}
+
+// trackedItems returns hotel rows with an "updated" version column and an
+// "deleted" soft delete flag.
+func trackedItems(versions ...int64) []domain.SourceItem {
+	items := hotelItems(len(versions))
+	for i, v := range versions {
+		items[i].Fields["updated"] = v
+		items[i].Fields["deleted"] = int64(0)
+	}
+	return items
+}
+
+func (e *indexerTestEnv) setItems(items []domain.SourceItem) {
+	e.conn.mu.Lock()
+	defer e.conn.mu.Unlock()
+	e.conn.items = items
+}
+
+func newTrackedIndexerForTest(t *testing.T, items []domain.SourceItem) *indexerTestEnv {
+	t.Helper()
+	env := newIndexerServiceForTest(t, items)
+	src := `{"name":"tracked","type":"azuresql","credentials":{"connectionString":"Data Source=x.db"},"container":{"name":"t"},
+		"dataChangeDetectionPolicy":{"@odata.type":"#Microsoft.Azure.Search.HighWaterMarkChangeDetectionPolicy","highWaterMarkColumnName":"updated"},
+		"dataDeletionDetectionPolicy":{"@odata.type":"#Microsoft.Azure.Search.SoftDeleteColumnDeletionDetectionPolicy","softDeleteColumnName":"deleted","softDeleteMarkerValue":"true"}}`
+	if _, err := env.svc.DataSources.CreateDataSource(context.Background(), []byte(src)); err != nil {
+		t.Fatalf("create data source: %v", err)
+	}
+	body := `{"name":"ix","dataSourceName":"tracked","targetIndexName":"hotels"}`
+	if _, err := env.svc.CreateIndexer(context.Background(), []byte(body)); err != nil {
+		t.Fatalf("create indexer: %v", err)
+	}
+	env.svc.Wait()
+	return env
+}
+
+func TestIndexerService_HighWaterMark(t *testing.T) {
+	t.Parallel()
+	env := newTrackedIndexerForTest(t, trackedItems(10, 20, 30))
+	ctx := context.Background()
+	if last := env.status(t, "ix").LastResult; last.ItemsProcessed != 3 || last.FinalTrackingState == nil || *last.FinalTrackingState != `{"highWaterMark":30}` {
+		t.Fatalf("first run = %+v, want 3 items and mark 30", last)
+	}
+
+	_ = env.svc.RunIndexer(ctx, "ix")
+	if last := env.status(t, "ix").LastResult; last.ItemsProcessed != 0 {
+		t.Errorf("unchanged rows should be skipped, itemsProcessed = %d", last.ItemsProcessed)
+	}
+
+	items := trackedItems(10, 40, 30)
+	items[1].Fields["name"] = "Renamed"
+	env.setItems(items)
+	_ = env.svc.RunIndexer(ctx, "ix")
+	if last := env.status(t, "ix").LastResult; last.ItemsProcessed != 1 {
+		t.Errorf("only the changed row should be processed, itemsProcessed = %d", last.ItemsProcessed)
+	}
+	if doc, _ := env.docs.Find("hotels", "2"); doc == nil || !strings.Contains(doc.Content, "Renamed") {
+		t.Errorf("changed row was not reindexed: %+v", doc)
+	}
+
+	_ = env.svc.ResetIndexer(ctx, "ix")
+	_ = env.svc.RunIndexer(ctx, "ix")
+	if last := env.status(t, "ix").LastResult; last.ItemsProcessed != 3 || *last.InitialTrackingState != `{"highWaterMark":null}` {
+		t.Errorf("after reset every row should be processed, got %+v", last)
+	}
+}
+
+func TestIndexerService_HighWaterMarkLargeInteger(t *testing.T) {
+	t.Parallel()
+	// 2^53+1 rounds to 2^53 as a float64.
+	const mark = int64(1 << 53)
+	env := newTrackedIndexerForTest(t, trackedItems(mark))
+	if last := env.status(t, "ix").LastResult; *last.FinalTrackingState != `{"highWaterMark":9007199254740992}` {
+		t.Fatalf("final tracking state = %s", *last.FinalTrackingState)
+	}
+	_ = env.svc.RunIndexer(context.Background(), "ix")
+	if last := env.status(t, "ix").LastResult; last.ItemsProcessed != 0 {
+		t.Errorf("the row at the saved mark should be skipped, itemsProcessed = %d", last.ItemsProcessed)
+	}
+	env.setItems(trackedItems(mark + 1))
+	_ = env.svc.RunIndexer(context.Background(), "ix")
+	if last := env.status(t, "ix").LastResult; last.ItemsProcessed != 1 || *last.FinalTrackingState != `{"highWaterMark":9007199254740993}` {
+		t.Errorf("the row one past the mark should be processed, got %+v", last)
+	}
+}
+
+func TestIndexerService_HighWaterMarkKeptOnFailure(t *testing.T) {
+	t.Parallel()
+	env := newTrackedIndexerForTest(t, trackedItems(10))
+	ctx := context.Background()
+
+	items := trackedItems(10, 20)
+	delete(items[1].Fields, "id")
+	env.setItems(items)
+	_ = env.svc.RunIndexer(ctx, "ix")
+	if last := env.status(t, "ix").LastResult; last.Status != "transientFailure" {
+		t.Fatalf("run should fail, got %+v", last)
+	}
+	env.setItems(trackedItems(10, 20))
+	_ = env.svc.RunIndexer(ctx, "ix")
+	if last := env.status(t, "ix").LastResult; last.Status != "success" || last.ItemsProcessed != 1 {
+		t.Errorf("the failed row should be retried, got %+v", last)
+	}
+}
+
+func TestIndexerService_SoftDelete(t *testing.T) {
+	t.Parallel()
+	env := newTrackedIndexerForTest(t, trackedItems(1, 2))
+	if _, err := env.docs.Find("hotels", "1"); err != nil {
+		t.Fatalf("document 1 should be indexed: %v", err)
+	}
+
+	items := trackedItems(3, 2)
+	items[0].Fields["deleted"] = int64(1)
+	env.setItems(items)
+	_ = env.svc.RunIndexer(context.Background(), "ix")
+	if _, err := env.docs.Find("hotels", "1"); !errors.Is(err, domain.ErrDocumentNotFound) {
+		t.Errorf("soft-deleted row should be removed from the index, err = %v", err)
+	}
+	if _, err := env.docs.Find("hotels", "2"); err != nil {
+		t.Errorf("document 2 should remain: %v", err)
+	}
+}
+
+func TestIndexerService_ScheduleValidation(t *testing.T) {
+	t.Parallel()
+	env := newIndexerServiceForTest(t, nil)
+	for _, interval := range []string{"PT1M", "P2D", "5 minutes"} {
+		body := `{"name":"ix","dataSourceName":"src","targetIndexName":"hotels","schedule":{"interval":"` + interval + `"}}`
+		if _, err := env.svc.CreateIndexer(context.Background(), []byte(body)); !errors.Is(err, domain.ErrInvalidIndexer) {
+			t.Errorf("interval %s: err = %v, want ErrInvalidIndexer", interval, err)
+		}
+	}
+}
+
+func TestIndexerService_FieldMappings(t *testing.T) {
+	t.Parallel()
+	items := []domain.SourceItem{{Key: "1", Fields: map[string]interface{}{
+		"path": "https://x/a?b", "title": "Grand Hotel", "owner": "Jane Doe",
+	}}}
+	env := newIndexerServiceForTest(t, items)
+	body := `{"name":"ix","dataSourceName":"src","targetIndexName":"hotels",
+		"fieldMappings":[
+			{"sourceFieldName":"path","targetFieldName":"id","mappingFunction":{"name":"base64Encode"}},
+			{"sourceFieldName":"owner","targetFieldName":"rating","mappingFunction":{"name":"extractTokenAtPosition","parameters":{"delimiter":" ","position":1}}}
+		],
+		"outputFieldMappings":[{"sourceFieldName":"/document/title","targetFieldName":"name"}]}`
+
+	if _, err := env.svc.CreateIndexer(context.Background(), []byte(body)); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if st := env.status(t, "ix"); st.LastResult == nil || st.LastResult.Status != "success" {
+		t.Fatalf("lastResult = %+v, want success", st.LastResult)
+	}
+	doc, err := env.docs.Find("hotels", "aHR0cHM6Ly94L2E_Yg2")
+	if err != nil {
+		t.Fatalf("document keyed by the encoded path should be indexed: %v", err)
+	}
+	if !strings.Contains(doc.Content, `"name":"Grand Hotel"`) || !strings.Contains(doc.Content, `"rating":"Doe"`) {
+		t.Errorf("content = %s", doc.Content)
+	}
+}
+
+func TestIndexerService_FieldMappingValidation(t *testing.T) {
+	t.Parallel()
+	env := newIndexerServiceForTest(t, nil)
+	bodies := []string{
+		`{"name":"ix","dataSourceName":"src","targetIndexName":"hotels","fieldMappings":[{"sourceFieldName":"a","targetFieldName":"nope"}]}`,
+		`{"name":"ix","dataSourceName":"src","targetIndexName":"hotels","fieldMappings":[{"sourceFieldName":"a","targetFieldName":"name","mappingFunction":{"name":"rot13"}}]}`,
+		`{"name":"ix","dataSourceName":"src","targetIndexName":"hotels","outputFieldMappings":[{"sourceFieldName":"a","targetFieldName":"name"}]}`,
+	}
+	for _, body := range bodies {
+		if _, err := env.svc.CreateIndexer(context.Background(), []byte(body)); !errors.Is(err, domain.ErrInvalidIndexer) {
+			t.Errorf("%s: err = %v, want ErrInvalidIndexer", body, err)
+		}
+	}
+}
+
+func TestIndexerService_Skillset(t *testing.T) {
+	t.Parallel()
+	items := []domain.SourceItem{
+		{Key: "1", Fields: map[string]interface{}{"id": "1", "name": "The room is clean and the staff is friendly."}},
+		{Key: "2", Fields: map[string]interface{}{"id": "2", "name": "La chambre est calme et le lit est grand."}},
+	}
+	env := newIndexerServiceForTest(t, items)
+	ss := `{"name":"enrich","skills":[{"@odata.type":"#Microsoft.Skills.Text.LanguageDetectionSkill",
+		"inputs":[{"name":"text","source":"/document/name"}],"outputs":[{"name":"languageCode","targetName":"lang"}]}]}`
+	if _, err := env.svc.Skillsets.CreateSkillset(context.Background(), []byte(ss)); err != nil {
+		t.Fatalf("create skillset: %v", err)
+	}
+	if _, err := env.svc.CreateIndexer(context.Background(), []byte(`{"name":"ix","dataSourceName":"src","targetIndexName":"hotels","skillsetName":"missing"}`)); !errors.Is(err, domain.ErrInvalidIndexer) {
+		t.Errorf("missing skillset: err = %v, want ErrInvalidIndexer", err)
+	}
+	body := `{"name":"ix","dataSourceName":"src","targetIndexName":"hotels","skillsetName":"enrich",
+		"outputFieldMappings":[{"sourceFieldName":"/document/lang","targetFieldName":"rating"}]}`
+	if _, err := env.svc.CreateIndexer(context.Background(), []byte(body)); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if st := env.status(t, "ix"); st.LastResult == nil || st.LastResult.Status != "success" || st.LastResult.ItemsProcessed != 2 {
+		t.Fatalf("lastResult = %+v, want success with 2 items", st.LastResult)
+	}
+	for key, lang := range map[string]string{"1": "en", "2": "fr"} {
+		doc, err := env.docs.Find("hotels", key)
+		if err != nil {
+			t.Fatalf("find %s: %v", key, err)
+		}
+		if !strings.Contains(doc.Content, `"rating":"`+lang+`"`) {
+			t.Errorf("document %s: content = %s, want rating %s", key, doc.Content, lang)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package application
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"fmt"
+	"strings"
+	"sync"
+
+	"ai-search-emulator/internal/domain"
+)
+
+// MaxQueryKeys is Azure's limit on query keys per service.
+const MaxQueryKeys = 50
+
+// KeyService holds the service's admin and query api-keys. Keys live in
+// memory: the configured keys are restored on restart and regenerated or
+// created keys are lost.
+type KeyService struct {
+	mu        sync.RWMutex
+	admin     domain.AdminKeys
+	queryKeys []domain.QueryKey
+}
+
+// NewKeyService creates a KeyService with the given admin keys and query
+// keys. Empty keys are ignored.
+func NewKeyService(primary, secondary string, queryKeys []string) *KeyService {
+	s := &KeyService{admin: domain.AdminKeys{PrimaryKey: primary, SecondaryKey: secondary}}
+	for _, k := range queryKeys {
+		if k != "" {
+			s.queryKeys = append(s.queryKeys, domain.QueryKey{Key: k})
+		}
+	}
+	return s
+}
+
+// HasAdminKey reports whether any admin key is configured. Without one, no
+// request can be authorized by api-key.
+func (s *KeyService) HasAdminKey() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.admin.PrimaryKey != "" || s.admin.SecondaryKey != ""
+}
+
+// Authenticate returns the kind of the given api-key, or domain.KeyNone.
+func (s *KeyService) Authenticate(key string) domain.KeyKind {
+	if key == "" {
+		return domain.KeyNone
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if keyEqual(key, s.admin.PrimaryKey) || keyEqual(key, s.admin.SecondaryKey) {
+		return domain.KeyAdmin
+	}
+	for _, q := range s.queryKeys {
+		if keyEqual(key, q.Key) {
+			return domain.KeyQuery
+		}
+	}
+	return domain.KeyNone
+}
+
+func (s *KeyService) GetAdminKeys(ctx context.Context) domain.AdminKeys {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.admin
+}
+
+// RegenerateAdminKey replaces the "primary" or "secondary" admin key with a
+// new random key and returns both keys.
+func (s *KeyService) RegenerateAdminKey(ctx context.Context, kind string) (domain.AdminKeys, error) {
+	key, err := generateKey()
+	if err != nil {
+		return domain.AdminKeys{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch strings.ToLower(kind) {
+	case "primary":
+		s.admin.PrimaryKey = key
+	case "secondary":
+		s.admin.SecondaryKey = key
+	default:
+		return domain.AdminKeys{}, fmt.Errorf("%w: '%s'; expected 'primary' or 'secondary'", domain.ErrInvalidKeyKind, kind)
+	}
+	return s.admin, nil
+}
+
+func (s *KeyService) ListQueryKeys(ctx context.Context) []domain.QueryKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]domain.QueryKey{}, s.queryKeys...)
+}
+
+// CreateQueryKey adds a query key with a random value.
+func (s *KeyService) CreateQueryKey(ctx context.Context, name string) (domain.QueryKey, error) {
+	key, err := generateKey()
+	if err != nil {
+		return domain.QueryKey{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queryKeys) >= MaxQueryKeys {
+		return domain.QueryKey{}, fmt.Errorf("%w: a service can have at most %d query keys", domain.ErrQueryKeyLimit, MaxQueryKeys)
+	}
+	q := domain.QueryKey{Name: name, Key: key}
+	s.queryKeys = append(s.queryKeys, q)
+	return q, nil
+}
+
+// DeleteQueryKey removes the query key with the given value.
+func (s *KeyService) DeleteQueryKey(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, q := range s.queryKeys {
+		if keyEqual(key, q.Key) {
+			s.queryKeys = append(s.queryKeys[:i], s.queryKeys[i+1:]...)
+			return nil
+		}
+	}
+	return domain.ErrKeyNotFound
+}
+
+// keyEqual compares keys in constant time. An empty configured key never
+// matches.
+func keyEqual(given, configured string) bool {
+	return configured != "" && subtle.ConstantTimeCompare([]byte(given), []byte(configured)) == 1
+}
+
+// generateKey returns a random 52-character key, the length Azure uses.
+func generateKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b), nil
+}
@@ -0,0 +1,92 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"ai-search-emulator/internal/domain"
+)
+
+func TestKeyService_Authenticate(t *testing.T) {
+	t.Parallel()
+	svc := NewKeyService("primary", "secondary", []string{"query", ""})
+	tests := []struct {
+		key  string
+		want domain.KeyKind
+	}{
+		{"primary", domain.KeyAdmin},
+		{"secondary", domain.KeyAdmin},
+		{"query", domain.KeyQuery},
+		{"", domain.KeyNone},
+		{"other", domain.KeyNone},
+	}
+	for _, tt := range tests {
+		if got := svc.Authenticate(tt.key); got != tt.want {
+			t.Errorf("Authenticate(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+	if NewKeyService("", "", nil).Authenticate("") != domain.KeyNone {
+		t.Error("an empty key must never match an unset admin key")
+	}
+}
+
+func TestKeyService_RegenerateAdminKey(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	svc := NewKeyService("primary", "secondary", nil)
+
+	keys, err := svc.RegenerateAdminKey(ctx, "Primary")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if keys.PrimaryKey == "primary" || len(keys.PrimaryKey) != 52 || keys.SecondaryKey != "secondary" {
+		t.Errorf("keys = %+v", keys)
+	}
+	if svc.Authenticate("primary") != domain.KeyNone || svc.Authenticate(keys.PrimaryKey) != domain.KeyAdmin {
+		t.Error("the old primary key should stop working and the new one should work")
+	}
+	if _, err := svc.RegenerateAdminKey(ctx, "tertiary"); !errors.Is(err, domain.ErrInvalidKeyKind) {
+		t.Errorf("err = %v, want ErrInvalidKeyKind", err)
+	}
+}
+
+func TestKeyService_QueryKeyLifecycle(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	svc := NewKeyService("primary", "", []string{"configured"})
+
+	q, err := svc.CreateQueryKey(ctx, "app")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q.Name != "app" || svc.Authenticate(q.Key) != domain.KeyQuery {
+		t.Fatalf("created key = %+v", q)
+	}
+	if got := svc.ListQueryKeys(ctx); len(got) != 2 {
+		t.Fatalf("ListQueryKeys = %+v", got)
+	}
+	if err := svc.DeleteQueryKey(ctx, q.Key); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if svc.Authenticate(q.Key) != domain.KeyNone {
+		t.Error("a deleted query key should stop working")
+	}
+	if err := svc.DeleteQueryKey(ctx, q.Key); !errors.Is(err, domain.ErrKeyNotFound) {
+		t.Errorf("err = %v, want ErrKeyNotFound", err)
+	}
+}
+
+func TestKeyService_QueryKeyLimit(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	svc := NewKeyService("primary", "", nil)
+	for i := 0; i < MaxQueryKeys; i++ {
+		if _, err := svc.CreateQueryKey(ctx, ""); err != nil {
+			t.Fatalf("key %d: %v", i, err)
+		}
+	}
+	if _, err := svc.CreateQueryKey(ctx, ""); !errors.Is(err, domain.ErrQueryKeyLimit) {
+		t.Errorf("err = %v, want ErrQueryKeyLimit", err)
+	}
+}
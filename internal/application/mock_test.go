@@ -1,6 +1,7 @@
 package application
 
 import (
+	"context"
 	"sync"
 
 	"ai-search-emulator/internal/domain"
@@ -37,7 +38,7 @@ func (m *mockIndexRepository) Create(index *domain.Index) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	// Return a fresh copy to avoid mutation of the caller's pointer.
-	copy := &domain.Index{Name: index.Name, Schema: index.Schema}
+	copy := &domain.Index{Name: index.Name, Schema: index.Schema, ETag: index.ETag}
 	m.store[index.Name] = copy
 	return nil
 }
@@ -51,7 +52,20 @@ func (m *mockIndexRepository) Update(index *domain.Index) error {
 	if _, ok := m.store[index.Name]; !ok {
 		return domain.ErrIndexNotFound
 	}
-	m.store[index.Name] = &domain.Index{Name: index.Name, Schema: index.Schema}
+	m.store[index.Name] = &domain.Index{Name: index.Name, Schema: index.Schema, ETag: index.ETag}
+	return nil
+}
+
+func (m *mockIndexRepository) UpdateIfMatch(index *domain.Index, etag string) error {
+	if m.updateErr != nil {
+		return m.updateErr
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cur, ok := m.store[index.Name]; !ok || cur.ETag != etag {
+		return domain.ErrPreconditionFailed
+	}
+	m.store[index.Name] = &domain.Index{Name: index.Name, Schema: index.Schema, ETag: index.ETag}
 	return nil
 }
 
@@ -66,7 +80,7 @@ func (m *mockIndexRepository) FindByName(name string) (*domain.Index, error) {
 		return nil, domain.ErrIndexNotFound
 	}
 	// Return a copy to prevent the caller from mutating internal state.
-	return &domain.Index{Name: idx.Name, Schema: idx.Schema}, nil
+	return &domain.Index{Name: idx.Name, Schema: idx.Schema, ETag: idx.ETag}, nil
 }
 
 func (m *mockIndexRepository) Exists(name string) (bool, error) {
@@ -87,7 +101,7 @@ func (m *mockIndexRepository) List() ([]*domain.Index, error) {
 	defer m.mu.RUnlock()
 	out := make([]*domain.Index, 0, len(m.store))
 	for _, idx := range m.store {
-		out = append(out, &domain.Index{Name: idx.Name, Schema: idx.Schema})
+		out = append(out, &domain.Index{Name: idx.Name, Schema: idx.Schema, ETag: idx.ETag})
 	}
 	return out, nil
 }
@@ -105,6 +119,19 @@ func (m *mockIndexRepository) Delete(name string) error {
 	return nil
 }
 
+func (m *mockIndexRepository) DeleteIfMatch(name, etag string) error {
+	if m.deleteErr != nil {
+		return m.deleteErr
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cur, ok := m.store[name]; !ok || cur.ETag != etag {
+		return domain.ErrPreconditionFailed
+	}
+	delete(m.store, name)
+	return nil
+}
+
 // mockDocumentRepository is an in-memory implementation of
 // domain.DocumentRepository for application-layer unit tests.
 type mockDocumentRepository struct {
@@ -117,6 +144,7 @@ type mockDocumentRepository struct {
 	listErr   error
 	countErr  error
 	searchErr error
+	batchErr  error
 }
 
 func newMockDocumentRepository() *mockDocumentRepository {
@@ -225,6 +253,9 @@ func (m *mockDocumentRepository) Search(indexName string, opts domain.SearchOpti
 	}
 
 	total := int64(len(all))
+	if opts.Unpaged {
+		return all, total, nil
+	}
 
 	if opts.Skip > 0 {
 		if opts.Skip >= len(all) {
@@ -241,3 +272,332 @@ func (m *mockDocumentRepository) Search(indexName string, opts domain.SearchOpti
 	}
 	return all, total, nil
 }
+
+// Batch implements domain.DocumentRepository for unit tests. Changes are
+// staged on a copy of the index and only published when fn succeeds.
+func (m *mockDocumentRepository) Batch(indexName string, fn func(b domain.DocumentBatch) error) error {
+	if m.batchErr != nil {
+		return m.batchErr
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	staged := map[string]*domain.Document{}
+	for k, doc := range m.store[indexName] {
+		staged[k] = doc
+	}
+	if err := fn(&mockDocumentBatch{indexName: indexName, docs: staged, upsertErr: m.upsertErr}); err != nil {
+		return err
+	}
+	m.store[indexName] = staged
+	return nil
+}
+
+type mockDocumentBatch struct {
+	indexName string
+	docs      map[string]*domain.Document
+	upsertErr error
+}
+
+func (b *mockDocumentBatch) Find(key string) (*domain.Document, error) {
+	doc, ok := b.docs[key]
+	if !ok {
+		return nil, domain.ErrDocumentNotFound
+	}
+	return &domain.Document{IndexName: doc.IndexName, Key: doc.Key, Content: doc.Content}, nil
+}
+
+func (b *mockDocumentBatch) Upsert(key, content string) error {
+	if b.upsertErr != nil {
+		return b.upsertErr
+	}
+	b.docs[key] = &domain.Document{IndexName: b.indexName, Key: key, Content: content}
+	return nil
+}
+
+func (b *mockDocumentBatch) Delete(key string) error {
+	delete(b.docs, key)
+	return nil
+}
+
+// mockDataSourceRepository is an in-memory domain.DataSourceRepository.
+type mockDataSourceRepository struct {
+	mu    sync.RWMutex
+	store map[string]domain.DataSource
+}
+
+func newMockDataSourceRepository() *mockDataSourceRepository {
+	return &mockDataSourceRepository{store: map[string]domain.DataSource{}}
+}
+
+func (m *mockDataSourceRepository) Create(ds *domain.DataSource) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.store[ds.Name] = *ds
+	return nil
+}
+
+func (m *mockDataSourceRepository) Update(ds *domain.DataSource) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.store[ds.Name]; !ok {
+		return domain.ErrDataSourceNotFound
+	}
+	m.store[ds.Name] = *ds
+	return nil
+}
+
+func (m *mockDataSourceRepository) UpdateIfMatch(ds *domain.DataSource, etag string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cur, ok := m.store[ds.Name]; !ok || cur.ETag != etag {
+		return domain.ErrPreconditionFailed
+	}
+	m.store[ds.Name] = *ds
+	return nil
+}
+
+func (m *mockDataSourceRepository) FindByName(name string) (*domain.DataSource, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ds, ok := m.store[name]
+	if !ok {
+		return nil, domain.ErrDataSourceNotFound
+	}
+	return &ds, nil
+}
+
+func (m *mockDataSourceRepository) List() ([]*domain.DataSource, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []*domain.DataSource
+	for _, ds := range m.store {
+		ds := ds
+		out = append(out, &ds)
+	}
+	return out, nil
+}
+
+func (m *mockDataSourceRepository) Delete(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.store[name]; !ok {
+		return domain.ErrDataSourceNotFound
+	}
+	delete(m.store, name)
+	return nil
+}
+
+func (m *mockDataSourceRepository) DeleteIfMatch(name, etag string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cur, ok := m.store[name]; !ok || cur.ETag != etag {
+		return domain.ErrPreconditionFailed
+	}
+	delete(m.store, name)
+	return nil
+}
+
+// mockSkillsetRepository is an in-memory domain.SkillsetRepository.
+type mockSkillsetRepository struct {
+	mu    sync.RWMutex
+	store map[string]domain.Skillset
+}
+
+func newMockSkillsetRepository() *mockSkillsetRepository {
+	return &mockSkillsetRepository{store: map[string]domain.Skillset{}}
+}
+
+func (m *mockSkillsetRepository) Create(ss *domain.Skillset) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.store[ss.Name] = *ss
+	return nil
+}
+
+func (m *mockSkillsetRepository) Update(ss *domain.Skillset) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.store[ss.Name]; !ok {
+		return domain.ErrSkillsetNotFound
+	}
+	m.store[ss.Name] = *ss
+	return nil
+}
+
+func (m *mockSkillsetRepository) UpdateIfMatch(ss *domain.Skillset, etag string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cur, ok := m.store[ss.Name]; !ok || cur.ETag != etag {
+		return domain.ErrPreconditionFailed
+	}
+	m.store[ss.Name] = *ss
+	return nil
+}
+
+func (m *mockSkillsetRepository) FindByName(name string) (*domain.Skillset, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ss, ok := m.store[name]
+	if !ok {
+		return nil, domain.ErrSkillsetNotFound
+	}
+	return &ss, nil
+}
+
+func (m *mockSkillsetRepository) List() ([]*domain.Skillset, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []*domain.Skillset
+	for _, ss := range m.store {
+		ss := ss
+		out = append(out, &ss)
+	}
+	return out, nil
+}
+
+func (m *mockSkillsetRepository) Delete(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.store[name]; !ok {
+		return domain.ErrSkillsetNotFound
+	}
+	delete(m.store, name)
+	return nil
+}
+
+func (m *mockSkillsetRepository) DeleteIfMatch(name, etag string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cur, ok := m.store[name]; !ok || cur.ETag != etag {
+		return domain.ErrPreconditionFailed
+	}
+	delete(m.store, name)
+	return nil
+}
+
+// mockConnector is a domain.SourceConnector returning fixed items.
+type mockConnector struct {
+	mu      sync.Mutex
+	items   []domain.SourceItem
+	readErr error
+	// invalidCredentials is rejected by ValidateCredentials.
+	invalidCredentials string
+	// release, when set, blocks Read until it is closed.
+	release chan struct{}
+}
+
+func (c *mockConnector) ValidateCredentials(cfg domain.DataSourceConfig) error {
+	if c.invalidCredentials != "" && cfg.ConnectionString == c.invalidCredentials {
+		return domain.ErrInvalidDataSource
+	}
+	return nil
+}
+
+func (c *mockConnector) Read(ctx context.Context, cfg domain.DataSourceConfig) ([]domain.SourceItem, error) {
+	c.mu.Lock()
+	release := c.release
+	c.mu.Unlock()
+	if release != nil {
+		<-release
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.readErr != nil {
+		return nil, c.readErr
+	}
+	return append([]domain.SourceItem{}, c.items...), nil
+}
+
+// mockIndexerRepository is an in-memory domain.IndexerRepository.
+type mockIndexerRepository struct {
+	mu     sync.RWMutex
+	store  map[string]domain.Indexer
+	status map[string]string
+}
+
+func newMockIndexerRepository() *mockIndexerRepository {
+	return &mockIndexerRepository{store: map[string]domain.Indexer{}, status: map[string]string{}}
+}
+
+func (m *mockIndexerRepository) Create(ix *domain.Indexer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.store[ix.Name] = *ix
+	return nil
+}
+
+func (m *mockIndexerRepository) Update(ix *domain.Indexer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.store[ix.Name]; !ok {
+		return domain.ErrIndexerNotFound
+	}
+	m.store[ix.Name] = *ix
+	return nil
+}
+
+func (m *mockIndexerRepository) UpdateIfMatch(ix *domain.Indexer, etag string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cur, ok := m.store[ix.Name]; !ok || cur.ETag != etag {
+		return domain.ErrPreconditionFailed
+	}
+	m.store[ix.Name] = *ix
+	return nil
+}
+
+func (m *mockIndexerRepository) FindByName(name string) (*domain.Indexer, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ix, ok := m.store[name]
+	if !ok {
+		return nil, domain.ErrIndexerNotFound
+	}
+	return &ix, nil
+}
+
+func (m *mockIndexerRepository) List() ([]*domain.Indexer, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []*domain.Indexer
+	for _, ix := range m.store {
+		ix := ix
+		out = append(out, &ix)
+	}
+	return out, nil
+}
+
+func (m *mockIndexerRepository) Delete(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.store[name]; !ok {
+		return domain.ErrIndexerNotFound
+	}
+	delete(m.store, name)
+	delete(m.status, name)
+	return nil
+}
+
+func (m *mockIndexerRepository) DeleteIfMatch(name, etag string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cur, ok := m.store[name]; !ok || cur.ETag != etag {
+		return domain.ErrPreconditionFailed
+	}
+	delete(m.store, name)
+	delete(m.status, name)
+	return nil
+}
+
+func (m *mockIndexerRepository) FindStatus(name string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status[name], nil
+}
+
+func (m *mockIndexerRepository) SaveStatus(name string, status string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.status[name] = status
+	return nil
+}
@@ -2,7 +2,6 @@ package application
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
 	"unicode"
 )
@@ -11,54 +10,59 @@ import (
 // fragment (no WHERE keyword) using json_extract() for SQLite document blobs,
 // plus the corresponding bind arguments.
 func ParseODataFilter(filter string) (string, []interface{}, error) {
-	p := &filterParser{input: strings.TrimSpace(filter)}
-	sql, args, err := p.parseOrExpr()
+	f, err := compileODataFilter(filter, nil)
 	if err != nil {
 		return "", nil, err
 	}
+	return f.sql, f.args, nil
+}
+
+// compiledFilter is the result of compiling a $filter expression.
+type compiledFilter struct {
+	sql  string
+	args []interface{}
+	// scoring holds the search.ismatchscoring queries found in the filter;
+	// each one contributes to @search.score of the documents it matches.
+	scoring []*fullTextQuery
+}
+
+// compileODataFilter compiles a $filter expression. When schema is non-nil,
+// field references are resolved against it and comparisons are type-checked
+// using the declared field types.
+func compileODataFilter(filter string, schema *indexSchema) (*compiledFilter, error) {
+	p := &filterParser{input: strings.TrimSpace(filter), schema: schema}
+	sql, args, err := p.parseOrExpr()
+	if err != nil {
+		return nil, err
+	}
 	p.skipSpaces()
 	if p.pos < len(p.input) {
-		return "", nil, fmt.Errorf("unexpected input at position %d: %q", p.pos, p.input[p.pos:])
+		return nil, fmt.Errorf("unexpected input at position %d: %q", p.pos, p.input[p.pos:])
 	}
-	return sql, args, nil
-}
-
-// ParseODataOrderBy parses an OData $orderby expression and returns an SQL
-// ORDER BY fragment (no ORDER BY keyword).
-func ParseODataOrderBy(orderby string) (string, error) {
-	parts := strings.Split(orderby, ",")
-	sqlParts := make([]string, 0, len(parts))
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		if part == "" {
-			continue
-		}
-		fields := strings.Fields(part)
-		fieldSQL := jsonExtract(fields[0])
-		dir := "ASC"
-		if len(fields) >= 2 {
-			switch strings.ToLower(fields[1]) {
-			case "desc":
-				dir = "DESC"
-			case "asc":
-				dir = "ASC"
-			default:
-				return "", fmt.Errorf("invalid sort direction: %q", fields[1])
-			}
-		}
-		sqlParts = append(sqlParts, fieldSQL+" "+dir)
+	if clauses := p.joins + 1; clauses > maxFilterClauses {
+		return nil, fmt.Errorf("the filter has %d clauses, which exceeds the maximum of %d", clauses, maxFilterClauses)
 	}
-	return strings.Join(sqlParts, ", "), nil
+	return &compiledFilter{sql: sql, args: args, scoring: p.scoring}, nil
 }
 
 func jsonExtract(field string) string {
-	return fmt.Sprintf("json_extract(content, '$.%s')", field)
+	return fmt.Sprintf("json_extract(content, '$.%s')", jsonPath(field))
+}
+
+// jsonPath converts an OData field path ("Address/City") to the dotted form
+// used by SQLite JSON paths ("Address.City").
+func jsonPath(field string) string {
+	return strings.ReplaceAll(field, "/", ".")
 }
 
 // filterParser is a recursive-descent parser for OData $filter expressions.
 type filterParser struct {
-	input string
-	pos   int
+	input   string
+	pos     int
+	schema  *indexSchema // nil when field types are unknown
+	scoring []*fullTextQuery
+	joins   int // number of 'and'/'or' operators; clauses = joins + 1
+	negated int // number of enclosing 'not' operators
 }
 
 func (p *filterParser) skipSpaces() {
@@ -79,6 +83,7 @@ func (p *filterParser) parseOrExpr() (string, []interface{}, error) {
 			break
 		}
 		p.consumeKeyword("or")
+		p.joins++
 		right, rightArgs, err := p.parseAndExpr()
 		if err != nil {
 			return "", nil, err
@@ -101,6 +106,7 @@ func (p *filterParser) parseAndExpr() (string, []interface{}, error) {
 			break
 		}
 		p.consumeKeyword("and")
+		p.joins++
 		right, rightArgs, err := p.parseNotExpr()
 		if err != nil {
 			return "", nil, err
@@ -116,7 +122,9 @@ func (p *filterParser) parseNotExpr() (string, []interface{}, error) {
 	p.skipSpaces()
 	if p.peekKeyword("not") {
 		p.consumeKeyword("not")
+		p.negated++
 		inner, args, err := p.parsePrimary()
+		p.negated--
 		if err != nil {
 			return "", nil, err
 		}
@@ -143,15 +151,50 @@ func (p *filterParser) parsePrimary() (string, []interface{}, error) {
 		p.pos++
 		return sql, args, nil
 	}
+	if p.peekFunc("search.ismatchscoring") {
+		return p.parseIsMatch("search.ismatchscoring", true)
+	}
+	if p.peekFunc("search.ismatch") {
+		return p.parseIsMatch("search.ismatch", false)
+	}
 	if p.peekFunc("search.in") {
 		return p.parseSearchIn()
 	}
 	if p.peekFunc("startswith") {
 		return p.parseStartsWith()
 	}
+	if name, ok := p.peekAnyFunc(); ok {
+		return "", nil, unsupportedFunctionError(name)
+	}
 	return p.parseComparison()
 }
 
+// peekAnyFunc reports whether the input at the current position is a function
+// call and returns the function name.
+func (p *filterParser) peekAnyFunc() (string, bool) {
+	p.skipSpaces()
+	end := p.pos
+	for end < len(p.input) && isIdentRune(rune(p.input[end])) {
+		end++
+	}
+	if end == p.pos || end >= len(p.input) || p.input[end] != '(' {
+		return "", false
+	}
+	return p.input[p.pos:end], true
+}
+
+// unsupportedFunctionError explains why a function is rejected. Standard OData
+// string functions are not part of the Azure AI Search filter language.
+func unsupportedFunctionError(name string) error {
+	switch strings.ToLower(name) {
+	case "endswith", "contains", "substringof", "substring", "indexof", "length", "tolower", "toupper", "trim", "concat":
+		return fmt.Errorf("invalid expression: function '%s' is not supported; use search.ismatch for full-text matching or startswith for prefix matching", name)
+	case "geo.distance", "geo.intersects":
+		return fmt.Errorf("invalid expression: function '%s' is not supported by the emulator in $filter", name)
+	}
+	return fmt.Errorf("invalid expression: unknown function '%s'", name)
+}
+
 func (p *filterParser) peekKeyword(kw string) bool {
 	p.skipSpaces()
 	end := p.pos + len(kw)
@@ -185,16 +228,27 @@ func isIdentRune(r rune) bool {
 	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.'
 }
 
+// parseIdentifier reads a field path; sub-fields of complex types are
+// separated by '/' (e.g. Address/City).
 func (p *filterParser) parseIdentifier() (string, error) {
 	p.skipSpaces()
 	start := p.pos
-	for p.pos < len(p.input) && isIdentRune(rune(p.input[p.pos])) {
+	for p.pos < len(p.input) && (isIdentRune(rune(p.input[p.pos])) || p.input[p.pos] == '/') {
 		p.pos++
 	}
 	if p.pos == start {
 		return "", fmt.Errorf("expected identifier at position %d", p.pos)
 	}
-	return p.input[start:p.pos], nil
+	ident := p.input[start:p.pos]
+	if p.pos < len(p.input) && p.input[p.pos] == '(' {
+		if i := strings.LastIndex(ident, "/"); i >= 0 {
+			switch strings.ToLower(ident[i+1:]) {
+			case "any", "all":
+				return "", fmt.Errorf("invalid expression: lambda expressions ('%s') are not supported by the emulator", ident)
+			}
+		}
+	}
+	return ident, nil
 }
 
 func (p *filterParser) parseStringLiteral() (string, error) {
@@ -238,6 +292,12 @@ func (p *filterParser) parseComparison() (string, []interface{}, error) {
 		"eq": "=", "ne": "!=", "gt": ">", "ge": ">=", "lt": "<", "le": "<=",
 	}[op]
 	if !ok {
+		switch op {
+		case "add", "sub", "mul", "div", "mod":
+			return "", nil, fmt.Errorf("invalid expression: arithmetic operator '%s' is not supported in $filter", op)
+		case "has", "in":
+			return "", nil, fmt.Errorf("invalid expression: operator '%s' is not supported in $filter; use search.in instead", op)
+		}
 		return "", nil, fmt.Errorf("unknown comparison operator: %q", op)
 	}
 
@@ -246,40 +306,35 @@ func (p *filterParser) parseComparison() (string, []interface{}, error) {
 }
 
 func (p *filterParser) buildComparison(field, sqlOp string) (string, []interface{}, error) {
-	if p.pos >= len(p.input) {
-		return "", nil, fmt.Errorf("expected value at position %d", p.pos)
-	}
-	fieldExpr := jsonExtract(field)
-	rest := strings.ToLower(p.input[p.pos:])
-
-	// null
-	if strings.HasPrefix(rest, "null") && !isIdentRune(rune(p.peek(4))) {
-		p.pos += 4
-		if sqlOp == "=" {
-			return fieldExpr + " IS NULL", nil, nil
-		}
-		return fieldExpr + " IS NOT NULL", nil, nil
-	}
-	// true
-	if strings.HasPrefix(rest, "true") && !isIdentRune(rune(p.peek(4))) {
-		p.pos += 4
-		return fieldExpr + " " + sqlOp + " ?", []interface{}{true}, nil
-	}
-	// false
-	if strings.HasPrefix(rest, "false") && !isIdentRune(rune(p.peek(5))) {
-		p.pos += 5
-		return fieldExpr + " " + sqlOp + " ?", []interface{}{false}, nil
+	lit, err := p.parseLiteral()
+	if err != nil {
+		return "", nil, err
 	}
-	// string literal
-	if p.input[p.pos] == '\'' {
-		s, err := p.parseStringLiteral()
+	fieldType := ""
+	if p.schema != nil {
+		f, err := p.lookupField(field)
 		if err != nil {
 			return "", nil, err
 		}
-		return fieldExpr + " " + sqlOp + " ?", []interface{}{s}, nil
+		if f.Filterable != nil && !*f.Filterable {
+			return "", nil, fmt.Errorf("invalid expression: the field '%s' is not filterable", field)
+		}
+		if err := checkComparable(f, field, sqlOp, lit); err != nil {
+			return "", nil, err
+		}
+		fieldType = f.Type
 	}
-	// number
-	return p.parseNumber(fieldExpr, sqlOp)
+	sql, args := comparisonSQL(jsonExtract(field), fieldType, sqlOp, lit)
+	return sql, args, nil
+}
+
+// lookupField resolves a field path against the index schema.
+func (p *filterParser) lookupField(path string) (*indexField, error) {
+	f, ok := p.schema.field(path)
+	if !ok {
+		return nil, fmt.Errorf("invalid expression: could not find a property named '%s' on type 'search.document'", path)
+	}
+	return f, nil
 }
 
 // peek returns the rune at pos+offset, or 0 if out of range.
@@ -291,32 +346,6 @@ func (p *filterParser) peek(offset int) byte {
 	return p.input[i]
 }
 
-func (p *filterParser) parseNumber(fieldExpr, sqlOp string) (string, []interface{}, error) {
-	start := p.pos
-	if p.pos < len(p.input) && p.input[p.pos] == '-' {
-		p.pos++
-	}
-	for p.pos < len(p.input) && (unicode.IsDigit(rune(p.input[p.pos])) || p.input[p.pos] == '.') {
-		p.pos++
-	}
-	numStr := p.input[start:p.pos]
-	if numStr == "" || numStr == "-" {
-		return "", nil, fmt.Errorf("expected numeric value at position %d", start)
-	}
-	if strings.Contains(numStr, ".") {
-		f, err := strconv.ParseFloat(numStr, 64)
-		if err != nil {
-			return "", nil, fmt.Errorf("invalid number %q: %w", numStr, err)
-		}
-		return fieldExpr + " " + sqlOp + " ?", []interface{}{f}, nil
-	}
-	n, err := strconv.ParseInt(numStr, 10, 64)
-	if err != nil {
-		return "", nil, fmt.Errorf("invalid number %q: %w", numStr, err)
-	}
-	return fieldExpr + " " + sqlOp + " ?", []interface{}{n}, nil
-}
-
 // parseSearchIn handles: search.in(field, 'val1,val2' [, 'delimiter'])
 func (p *filterParser) parseSearchIn() (string, []interface{}, error) {
 	p.skipSpaces()
@@ -360,7 +389,22 @@ func (p *filterParser) parseSearchIn() (string, []interface{}, error) {
 		placeholders[i] = "?"
 		args[i] = strings.TrimSpace(v)
 	}
-	return fieldExpr + " IN (" + strings.Join(placeholders, ",") + ")", args, nil
+	inList := " IN (" + strings.Join(placeholders, ",") + ")"
+
+	if p.schema != nil {
+		f, err := p.lookupField(field)
+		if err != nil {
+			return "", nil, err
+		}
+		if f.elementType() != "Edm.String" {
+			return "", nil, fmt.Errorf("invalid expression: search.in can only be used with fields of type 'Edm.String' or 'Collection(Edm.String)', but '%s' is of type '%s'", field, f.Type)
+		}
+		if f.isCollection() {
+			// A string collection matches when any of its elements is in the list.
+			return "EXISTS (SELECT 1 FROM json_each(content, '$." + jsonPath(field) + "') WHERE value" + inList + ")", args, nil
+		}
+	}
+	return fieldExpr + inList, args, nil
 }
 
 // parseStartsWith handles: startswith(field, 'prefix')
@@ -384,13 +428,100 @@ func (p *filterParser) parseStartsWith() (string, []interface{}, error) {
 	if err := p.expectCloseParen("startswith"); err != nil {
 		return "", nil, err
 	}
+	if p.schema != nil {
+		f, err := p.lookupField(field)
+		if err != nil {
+			return "", nil, err
+		}
+		if f.Type != "Edm.String" {
+			return "", nil, fmt.Errorf("invalid expression: startswith can only be used with fields of type 'Edm.String', but '%s' is of type '%s'", field, f.Type)
+		}
+	}
 
 	fieldExpr := jsonExtract(field)
-	// Escape LIKE metacharacters in prefix.
-	escaped := strings.ReplaceAll(prefix, `\`, `\\`)
-	escaped = strings.ReplaceAll(escaped, "%", `\%`)
-	escaped = strings.ReplaceAll(escaped, "_", `\_`)
-	return fieldExpr + ` LIKE ? ESCAPE '\'`, []interface{}{escaped + "%"}, nil
+	return fieldExpr + ` LIKE ? ESCAPE '\'`, []interface{}{escapeLike(prefix) + "%"}, nil
+}
+
+// parseIsMatch handles:
+//
+//	search.ismatch('search' [, 'searchFields' [, 'queryType', 'searchMode']])
+//
+// and the search.ismatchscoring variant, which additionally registers the
+// query so that matching documents receive a relevance score.
+func (p *filterParser) parseIsMatch(name string, scoring bool) (string, []interface{}, error) {
+	p.skipSpaces()
+	p.pos += len(name) + 1
+
+	var strArgs []string
+	for {
+		s, err := p.parseStringLiteral()
+		if err != nil {
+			return "", nil, fmt.Errorf("%s: %w", name, err)
+		}
+		strArgs = append(strArgs, s)
+		p.skipSpaces()
+		if p.pos < len(p.input) && p.input[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		break
+	}
+	if err := p.expectCloseParen(name); err != nil {
+		return "", nil, err
+	}
+	if len(strArgs) != 1 && len(strArgs) != 2 && len(strArgs) != 4 {
+		return "", nil, fmt.Errorf("%s: expected 1, 2 or 4 arguments, got %d", name, len(strArgs))
+	}
+
+	var fields []string
+	if len(strArgs) >= 2 && strings.TrimSpace(strArgs[1]) != "" {
+		for _, f := range strings.Split(strArgs[1], ",") {
+			f = strings.TrimSpace(f)
+			if !isValidFieldPath(f) {
+				return "", nil, fmt.Errorf("%s: invalid search field %q", name, f)
+			}
+			if p.schema != nil {
+				sf, err := p.lookupField(f)
+				if err != nil {
+					return "", nil, err
+				}
+				if sf.elementType() != "Edm.String" || (sf.Searchable != nil && !*sf.Searchable) {
+					return "", nil, fmt.Errorf("%s: the field '%s' is not searchable", name, f)
+				}
+			}
+			fields = append(fields, f)
+		}
+	}
+	queryType, searchMode := "", ""
+	if len(strArgs) == 4 {
+		queryType, searchMode = strArgs[2], strArgs[3]
+	}
+
+	q, err := parseFullTextQuery(strArgs[0], fields, queryType, searchMode)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if scoring && p.negated%2 == 0 {
+		// A negated clause matches documents the query does not, so it
+		// has nothing to contribute to their score.
+		p.scoring = append(p.scoring, q)
+	}
+	sql, args := q.toSQL()
+	return sql, args, nil
+}
+
+// isValidFieldPath reports whether s is a (possibly dotted) field name that is
+// safe to embed in a json_extract path.
+func isValidFieldPath(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !isIdentRune(r) && r != '/' {
+			return false
+		}
+	}
+	return true
 }
 
 func (p *filterParser) expectComma(ctx string) error {
@@ -1,6 +1,8 @@
 package application
 
 import (
+	"math"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -218,6 +220,242 @@ func TestParseODataFilter_StartsWith_EscapesMetachars(t *testing.T) {
 	}
 }
 
+func TestParseODataFilter_IsMatch(t *testing.T) {
+	t.Parallel()
+	sql, args, err := ParseODataFilter("search.ismatch('luxury')")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(sql, "LOWER(content) LIKE ?") {
+		t.Errorf("expected full-content LIKE, got: %s", sql)
+	}
+	if len(args) != 1 || args[0] != "%luxury%" {
+		t.Errorf("expected args=[%%luxury%%], got %v", args)
+	}
+}
+
+func TestParseODataFilter_IsMatch_SearchFields(t *testing.T) {
+	t.Parallel()
+	sql, args, err := ParseODataFilter("search.ismatch('Luxury', 'Description, Tags')")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(sql, "$.Description") || !strings.Contains(sql, "$.Tags") {
+		t.Errorf("expected both search fields in sql, got: %s", sql)
+	}
+	if len(args) != 2 || args[0] != "%luxury%" {
+		t.Errorf("expected lower-cased pattern per field, got %v", args)
+	}
+}
+
+func TestParseODataFilter_IsMatch_SearchModeAll(t *testing.T) {
+	t.Parallel()
+	sql, _, err := ParseODataFilter("search.ismatch('pool view', 'Description', 'simple', 'all')")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(sql, " OR ") {
+		t.Errorf("searchMode=all should AND the terms, got: %s", sql)
+	}
+	sql, _, err = ParseODataFilter("search.ismatch('pool view', 'Description', 'simple', 'any')")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(sql, " OR ") {
+		t.Errorf("searchMode=any should OR the terms, got: %s", sql)
+	}
+}
+
+func TestParseODataFilter_IsMatch_FullQueryTypeRejected(t *testing.T) {
+	t.Parallel()
+	if _, _, err := ParseODataFilter("search.ismatch('pool~', 'Description', 'full', 'any')"); err == nil {
+		t.Error("queryType 'full' should be rejected, not run as simple")
+	}
+}
+
+func TestParseODataFilter_IsMatch_CombinedWithComparison(t *testing.T) {
+	t.Parallel()
+	sql, args, err := ParseODataFilter("search.ismatchscoring('luxury') and Rating ge 4 or not search.ismatch('motel')")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(sql, " AND ") || !strings.Contains(sql, "NOT (") {
+		t.Errorf("unexpected sql: %s", sql)
+	}
+	if len(args) != 3 {
+		t.Errorf("expected 3 args, got %v", args)
+	}
+}
+
+func TestParseODataFilter_IsMatch_InvalidArguments(t *testing.T) {
+	t.Parallel()
+	cases := []string{
+		"search.ismatch('a', 'f', 'simple')",
+		"search.ismatch('a', 'f', 'fuzzy', 'any')",
+		"search.ismatch('a', 'f', 'simple', 'most')",
+		"search.ismatch('a', 'f; DROP TABLE documents')",
+		"search.ismatch(Title)",
+	}
+	for _, c := range cases {
+		if _, _, err := ParseODataFilter(c); err == nil {
+			t.Errorf("expected error for %q", c)
+		}
+	}
+}
+
+func TestCompileODataFilter_CollectsScoringQueries(t *testing.T) {
+	t.Parallel()
+	f, err := compileODataFilter("search.ismatchscoring('luxury') or search.ismatch('budget')", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(f.scoring) != 1 {
+		t.Fatalf("expected 1 scoring query, got %d", len(f.scoring))
+	}
+}
+
+func TestCompileODataFilter_NegatedScoringDoesNotScore(t *testing.T) {
+	t.Parallel()
+	f, err := compileODataFilter("not (search.ismatchscoring('motel') or Rating ge 4) and search.ismatchscoring('luxury')", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(f.scoring) != 1 || f.scoring[0].terms[0].text != "luxury" {
+		t.Fatalf("scoring = %+v, want only the non-negated 'luxury' query", f.scoring)
+	}
+	f, err = compileODataFilter("not (not search.ismatchscoring('motel'))", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(f.scoring) != 1 {
+		t.Errorf("a doubly negated query should score, got %d scoring queries", len(f.scoring))
+	}
+}
+
+func TestParseODataFilter_ExponentLiteral(t *testing.T) {
+	t.Parallel()
+	_, args, err := ParseODataFilter("Price lt 1.5e3 and Weight gt 2E-2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if args[0] != 1500.0 || args[1] != 0.02 {
+		t.Errorf("expected [1500 0.02], got %v", args)
+	}
+}
+
+func TestParseODataFilter_DateTimeOffsetLiteral(t *testing.T) {
+	t.Parallel()
+	sql, args, err := ParseODataFilter("LastRenovated ge 2024-01-01T09:00:00+09:00")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(sql, "julianday(json_extract(content, '$.LastRenovated')) >= julianday(?)") {
+		t.Errorf("expected julianday comparison, got: %s", sql)
+	}
+	if len(args) != 1 || args[0] != "2024-01-01T00:00:00Z" {
+		t.Errorf("expected instant normalised to UTC, got %v", args)
+	}
+}
+
+func TestParseODataFilter_NonFiniteDoubles(t *testing.T) {
+	t.Parallel()
+	sql, args, err := ParseODataFilter("Score lt INF")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(sql, "WHEN 'INF'") || len(args) != 1 || !math.IsInf(args[0].(float64), 1) {
+		t.Errorf("unexpected INF comparison: %s %v", sql, args)
+	}
+	if _, args, err = ParseODataFilter("Score gt -INF"); err != nil || !math.IsInf(args[0].(float64), -1) {
+		t.Errorf("expected -INF literal, got %v %v", args, err)
+	}
+	sql, _, err = ParseODataFilter("Score eq NaN")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(sql, "= 'NaN'") {
+		t.Errorf("expected NaN string comparison, got: %s", sql)
+	}
+}
+
+const typedFilterSchema = `{"fields":[
+	{"name":"id","type":"Edm.String","key":true},
+	{"name":"Rating","type":"Edm.Int32"},
+	{"name":"Price","type":"Edm.Double"},
+	{"name":"Opened","type":"Edm.DateTimeOffset"},
+	{"name":"Tags","type":"Collection(Edm.String)"},
+	{"name":"Secret","type":"Edm.String","filterable":false},
+	{"name":"Address","type":"Edm.ComplexType","fields":[{"name":"City","type":"Edm.String"}]}
+]}`
+
+func compileTyped(t *testing.T, filter string) (*compiledFilter, error) {
+	t.Helper()
+	schema, err := parseIndexSchema(typedFilterSchema)
+	if err != nil {
+		t.Fatalf("bad test schema: %v", err)
+	}
+	return compileODataFilter(filter, schema)
+}
+
+func TestCompileODataFilter_TypeMismatch(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"Rating eq 'five'":                  "cannot compare field 'Rating' of type 'Edm.Int32'",
+		"Opened gt '2024-01-01'":            "of type 'Edm.DateTimeOffset'",
+		"Tags eq 'wifi'":                    "is a collection",
+		"Missing eq 1":                      "could not find a property named 'Missing'",
+		"Secret eq 'x'":                     "is not filterable",
+		"search.in(Rating, '1,2')":          "search.in can only be used",
+		"Address/Country eq 'JP'":           "could not find a property named 'Address/Country'",
+		"Rating gt null":                    "null can only be compared",
+		"search.ismatch('x', 'Rating')":     "is not searchable",
+		"startswith(Price, '1')":            "startswith can only be used",
+		"Tags/any(t: t eq 'wifi')":          "lambda expressions",
+		"endswith(id, 'x')":                 "function 'endswith' is not supported",
+		"contains(id, 'x')":                 "function 'contains' is not supported",
+		"Rating add 1 gt 3":                 "arithmetic operator 'add'",
+		"geo.distance(Location, 1) le 10.0": "geo.distance",
+	}
+	for filter, want := range cases {
+		_, err := compileTyped(t, filter)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected error containing %q, got %v", filter, want, err)
+		}
+	}
+}
+
+func TestCompileODataFilter_TypedComparisons(t *testing.T) {
+	t.Parallel()
+	for _, filter := range []string{
+		"Rating ge 4",
+		"Rating lt 4.5",
+		"Price gt 1e2",
+		"Price lt INF",
+		"Opened lt 2024-06-01T00:00:00Z",
+		"Opened eq null",
+		"Address/City eq 'Tokyo'",
+		"search.in(id, 'a,b')",
+	} {
+		if _, err := compileTyped(t, filter); err != nil {
+			t.Errorf("%s: unexpected error: %v", filter, err)
+		}
+	}
+}
+
+func TestCompileODataFilter_SearchInStringCollection(t *testing.T) {
+	t.Parallel()
+	f, err := compileTyped(t, "search.in(Tags, 'wifi|pool', '|')")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(f.sql, "json_each(content, '$.Tags')") {
+		t.Errorf("expected json_each over the collection, got: %s", f.sql)
+	}
+	if len(f.args) != 2 {
+		t.Errorf("expected 2 args, got %v", f.args)
+	}
+}
+
 func TestParseODataFilter_SingleQuoteEscape(t *testing.T) {
 	t.Parallel()
 	_, args, err := ParseODataFilter("Name eq 'O''Brien'")
@@ -310,3 +548,98 @@ func TestParseODataOrderBy_InvalidDirection(t *testing.T) {
 		t.Fatal("expected error for invalid sort direction")
 	}
 }
+
+func TestParseODataOrderBy_SearchScoreNotCompiledAsField(t *testing.T) {
+	t.Parallel()
+	sql, err := ParseODataOrderBy("search.score() desc, Rating asc")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(sql, "search.score") {
+		t.Errorf("search.score() must not be turned into a json path: %s", sql)
+	}
+	if !strings.HasSuffix(sql, "key ASC") {
+		t.Errorf("expected key tie-breaker, got: %s", sql)
+	}
+}
+
+func TestParseODataOrderBy_RejectsMalformedClauses(t *testing.T) {
+	t.Parallel()
+	for _, orderby := range []string{
+		"Rating desc extra",
+		"Rating,",
+		"Rating'); DROP TABLE documents; --",
+		"foo() asc",
+		"geo.distance(Location, 'x')",
+	} {
+		if _, err := ParseODataOrderBy(orderby); err == nil {
+			t.Errorf("expected error for %q", orderby)
+		}
+	}
+}
+
+func TestParseOrderBy_ClauseLimit(t *testing.T) {
+	t.Parallel()
+	fields := make([]string, maxOrderByClauses+1)
+	for i := range fields {
+		fields[i] = "f" + strconv.Itoa(i)
+	}
+	if _, err := parseOrderBy(strings.Join(fields[:maxOrderByClauses], ","), nil); err != nil {
+		t.Errorf("expected %d clauses to be accepted, got %v", maxOrderByClauses, err)
+	}
+	if _, err := parseOrderBy(strings.Join(fields, ","), nil); err == nil {
+		t.Errorf("expected error for %d clauses", len(fields))
+	}
+}
+
+func TestParseOrderBy_GeoDistance(t *testing.T) {
+	t.Parallel()
+	clauses, err := parseOrderBy("geo.distance(Location, geography'POINT(-122.13 47.67)') desc, search.score()", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(clauses) != 2 {
+		t.Fatalf("expected 2 clauses, got %d", len(clauses))
+	}
+	geo := clauses[0]
+	if geo.kind != sortGeoDistance || geo.field != "Location" || !geo.desc || geo.point != [2]float64{-122.13, 47.67} {
+		t.Errorf("unexpected geo clause: %+v", geo)
+	}
+	if clauses[1].kind != sortScore || clauses[1].desc {
+		t.Errorf("unexpected score clause: %+v", clauses[1])
+	}
+}
+
+func TestParseOrderBy_SchemaValidation(t *testing.T) {
+	t.Parallel()
+	schema, _ := parseIndexSchema(`{"fields":[
+		{"name":"id","type":"Edm.String","key":true},
+		{"name":"Tags","type":"Collection(Edm.String)"},
+		{"name":"Rating","type":"Edm.Int32","sortable":false}
+	]}`)
+	for orderby, want := range map[string]string{
+		"Missing": "could not find a property",
+		"Tags":    "not sortable",
+		"Rating":  "not sortable",
+		"geo.distance(id, geography'POINT(0 0)')": "Edm.GeographyPoint",
+	} {
+		if _, err := parseOrderBy(orderby, schema); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected error containing %q, got %v", orderby, want, err)
+		}
+	}
+}
+
+func TestParseODataFilter_ClauseLimit(t *testing.T) {
+	t.Parallel()
+	clauses := make([]string, maxFilterClauses)
+	for i := range clauses {
+		clauses[i] = "rating eq " + strconv.Itoa(i)
+	}
+	if _, _, err := ParseODataFilter(strings.Join(clauses, " or ")); err != nil {
+		t.Fatalf("%d clauses should be accepted: %v", maxFilterClauses, err)
+	}
+	clauses = append(clauses, "rating eq -1")
+	if _, _, err := ParseODataFilter(strings.Join(clauses, " or ")); err == nil {
+		t.Fatalf("expected an error above %d clauses", maxFilterClauses)
+	}
+}
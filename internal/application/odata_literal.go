@@ -0,0 +1,193 @@
+package application
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// literalKind is the EDM type family of an OData literal.
+type literalKind int
+
+const (
+	litNull literalKind = iota
+	litBool
+	litString
+	litInt
+	litDouble
+	litDateTime
+)
+
+// edmName returns the EDM type name used in error messages.
+func (k literalKind) edmName() string {
+	switch k {
+	case litNull:
+		return "null"
+	case litBool:
+		return "Edm.Boolean"
+	case litString:
+		return "Edm.String"
+	case litInt:
+		return "Edm.Int64"
+	case litDouble:
+		return "Edm.Double"
+	case litDateTime:
+		return "Edm.DateTimeOffset"
+	}
+	return "unknown"
+}
+
+// odataLiteral is a typed constant parsed from a $filter expression.
+type odataLiteral struct {
+	kind  literalKind
+	value interface{} // bool, string, int64, float64 or time.Time
+}
+
+var (
+	dateTimeLiteralRe = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}(:\d{2}(\.\d+)?)?(Z|[+-]\d{2}:\d{2})`)
+	numberLiteralRe   = regexp.MustCompile(`^-?\d+(\.\d+)?([eE][+-]?\d+)?`)
+)
+
+// parseLiteral reads a null, boolean, string, numeric (including NaN, INF and
+// -INF) or Edm.DateTimeOffset literal at the current position.
+func (p *filterParser) parseLiteral() (odataLiteral, error) {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return odataLiteral{}, fmt.Errorf("expected value at position %d", p.pos)
+	}
+	rest := p.input[p.pos:]
+	for _, kw := range []struct {
+		text string
+		lit  odataLiteral
+	}{
+		{"null", odataLiteral{kind: litNull}},
+		{"true", odataLiteral{kind: litBool, value: true}},
+		{"false", odataLiteral{kind: litBool, value: false}},
+		{"NaN", odataLiteral{kind: litDouble, value: math.NaN()}},
+		{"INF", odataLiteral{kind: litDouble, value: math.Inf(1)}},
+		{"-INF", odataLiteral{kind: litDouble, value: math.Inf(-1)}},
+	} {
+		caseOK := strings.HasPrefix(rest, kw.text)
+		if kw.lit.kind == litNull || kw.lit.kind == litBool {
+			caseOK = strings.HasPrefix(strings.ToLower(rest), kw.text)
+		}
+		if caseOK && !isIdentRune(rune(p.peek(len(kw.text)))) {
+			p.pos += len(kw.text)
+			return kw.lit, nil
+		}
+	}
+	if rest[0] == '\'' {
+		s, err := p.parseStringLiteral()
+		if err != nil {
+			return odataLiteral{}, err
+		}
+		return odataLiteral{kind: litString, value: s}, nil
+	}
+	if m := dateTimeLiteralRe.FindString(rest); m != "" {
+		t, err := time.Parse(time.RFC3339Nano, m)
+		if err != nil {
+			return odataLiteral{}, fmt.Errorf("invalid Edm.DateTimeOffset literal %q: %w", m, err)
+		}
+		p.pos += len(m)
+		return odataLiteral{kind: litDateTime, value: t.UTC()}, nil
+	}
+	m := numberLiteralRe.FindString(rest)
+	if m == "" {
+		return odataLiteral{}, fmt.Errorf("expected numeric value at position %d", p.pos)
+	}
+	p.pos += len(m)
+	if strings.ContainsAny(m, ".eE") {
+		f, err := strconv.ParseFloat(m, 64)
+		if err != nil {
+			return odataLiteral{}, fmt.Errorf("invalid number %q: %w", m, err)
+		}
+		return odataLiteral{kind: litDouble, value: f}, nil
+	}
+	n, err := strconv.ParseInt(m, 10, 64)
+	if err != nil {
+		return odataLiteral{}, fmt.Errorf("invalid number %q: %w", m, err)
+	}
+	return odataLiteral{kind: litInt, value: n}, nil
+}
+
+// checkComparable validates that a field of the given EDM type may be compared
+// with the literal using op, mirroring the errors Azure returns.
+func checkComparable(field *indexField, path string, op string, lit odataLiteral) error {
+	if field.isCollection() {
+		return fmt.Errorf("invalid expression: the field '%s' is a collection; use a lambda expression (any/all) to filter on it", path)
+	}
+	if lit.kind == litNull {
+		if op != "=" && op != "!=" {
+			return fmt.Errorf("invalid expression: null can only be compared using 'eq' or 'ne'")
+		}
+		return nil
+	}
+	var ok bool
+	switch field.Type {
+	case "Edm.String":
+		ok = lit.kind == litString
+	case "Edm.Boolean":
+		ok = lit.kind == litBool
+		if ok && op != "=" && op != "!=" {
+			return fmt.Errorf("invalid expression: the field '%s' of type 'Edm.Boolean' can only be compared using 'eq' or 'ne'", path)
+		}
+	case "Edm.Int32", "Edm.Int64", "Edm.Double", "Edm.Single":
+		ok = lit.kind == litInt || lit.kind == litDouble
+	case "Edm.DateTimeOffset":
+		ok = lit.kind == litDateTime
+	case "Edm.ComplexType":
+		return fmt.Errorf("invalid expression: the field '%s' is a complex type and cannot be compared directly", path)
+	case "Edm.GeographyPoint":
+		return fmt.Errorf("invalid expression: the field '%s' of type 'Edm.GeographyPoint' cannot be compared; use geo.distance", path)
+	default:
+		ok = true
+	}
+	if !ok {
+		return fmt.Errorf("invalid expression: cannot compare field '%s' of type '%s' with a literal of type '%s'", path, field.Type, lit.kind.edmName())
+	}
+	return nil
+}
+
+// comparisonSQL builds the SQL predicate for "<field> <op> <literal>".
+// fieldType is the declared EDM type of the field, or "" when unknown.
+func comparisonSQL(fieldExpr, fieldType, sqlOp string, lit odataLiteral) (string, []interface{}) {
+	switch lit.kind {
+	case litNull:
+		if sqlOp == "=" {
+			return fieldExpr + " IS NULL", nil
+		}
+		return fieldExpr + " IS NOT NULL", nil
+	case litDateTime:
+		// julianday() normalises time zone offsets and fractional seconds so
+		// that instants compare correctly regardless of their textual form.
+		return "julianday(" + fieldExpr + ") " + sqlOp + " julianday(?)",
+			[]interface{}{lit.value.(time.Time).Format(time.RFC3339Nano)}
+	case litDouble:
+		f := lit.value.(float64)
+		if math.IsNaN(f) {
+			// Azure stores NaN as the string "NaN"; NaN is unordered, so only
+			// eq/ne can ever be true.
+			switch sqlOp {
+			case "=":
+				return fieldExpr + " = 'NaN'", nil
+			case "!=":
+				return fieldExpr + " IS NOT 'NaN'", nil
+			}
+			return "1 = 0", nil
+		}
+		return doubleExpr(fieldExpr) + " " + sqlOp + " ?", []interface{}{f}
+	}
+	if fieldType == "Edm.Double" || fieldType == "Edm.Single" {
+		return doubleExpr(fieldExpr) + " " + sqlOp + " ?", []interface{}{lit.value}
+	}
+	return fieldExpr + " " + sqlOp + " ?", []interface{}{lit.value}
+}
+
+// doubleExpr maps the string encodings Azure uses for non-finite doubles back
+// to numeric values so they order correctly against finite numbers.
+func doubleExpr(fieldExpr string) string {
+	return "(CASE " + fieldExpr + " WHEN 'INF' THEN 9e999 WHEN '-INF' THEN -9e999 WHEN 'NaN' THEN NULL ELSE " + fieldExpr + " END)"
+}
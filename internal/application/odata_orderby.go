@@ -0,0 +1,290 @@
+package application
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxOrderByClauses is Azure's limit on the number of $orderby clauses.
+const maxOrderByClauses = 32
+
+type sortKind int
+
+const (
+	sortField sortKind = iota
+	sortScore
+	sortGeoDistance
+)
+
+// sortClause is one parsed $orderby clause.
+type sortClause struct {
+	kind  sortKind
+	field string     // field path for sortField and sortGeoDistance
+	point [2]float64 // reference point (longitude, latitude) for sortGeoDistance
+	desc  bool
+}
+
+var (
+	scoreSortRe = regexp.MustCompile(`^(?i)search\.score\(\s*\)$`)
+	geoSortRe   = regexp.MustCompile(`^(?i)geo\.distance\(\s*([A-Za-z0-9_/]+)\s*,\s*geography'POINT\(\s*(-?[0-9.]+)\s+(-?[0-9.]+)\s*\)'\s*\)$`)
+)
+
+// ParseODataOrderBy parses an OData $orderby expression and returns an SQL
+// ORDER BY fragment (no ORDER BY keyword). search.score() clauses are omitted
+// because SQL ordering assumes every hit has the same score; geo.distance
+// cannot be expressed in SQL and is rejected.
+func ParseODataOrderBy(orderby string) (string, error) {
+	clauses, err := parseOrderBy(orderby, nil)
+	if err != nil {
+		return "", err
+	}
+	for _, c := range clauses {
+		if c.kind == sortGeoDistance {
+			return "", fmt.Errorf("geo.distance cannot be compiled to SQL")
+		}
+	}
+	return orderBySQL(clauses), nil
+}
+
+// parseOrderBy parses $orderby into sort clauses. When schema is non-nil,
+// field references must exist and be sortable.
+func parseOrderBy(orderby string, schema *indexSchema) ([]sortClause, error) {
+	parts := splitTopLevel(orderby, ',')
+	if len(parts) > maxOrderByClauses {
+		return nil, fmt.Errorf("$orderby may contain at most %d clauses, got %d", maxOrderByClauses, len(parts))
+	}
+	clauses := make([]sortClause, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, fmt.Errorf("empty $orderby clause")
+		}
+		c, err := parseSortClause(part, schema)
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, c)
+	}
+	return clauses, nil
+}
+
+func parseSortClause(part string, schema *indexSchema) (sortClause, error) {
+	expr, dir := part, ""
+	if i := strings.LastIndexAny(part, " \t"); i >= 0 && !strings.HasSuffix(part, ")") {
+		expr, dir = strings.TrimSpace(part[:i]), part[i+1:]
+	}
+	var c sortClause
+	switch strings.ToLower(dir) {
+	case "", "asc":
+	case "desc":
+		c.desc = true
+	default:
+		return c, fmt.Errorf("invalid sort direction: %q", dir)
+	}
+
+	if scoreSortRe.MatchString(expr) {
+		c.kind = sortScore
+		return c, nil
+	}
+	if m := geoSortRe.FindStringSubmatch(expr); m != nil {
+		lon, err1 := strconv.ParseFloat(m[2], 64)
+		lat, err2 := strconv.ParseFloat(m[3], 64)
+		if err1 != nil || err2 != nil {
+			return c, fmt.Errorf("invalid geography point in %q", expr)
+		}
+		c.kind, c.field, c.point = sortGeoDistance, m[1], [2]float64{lon, lat}
+		if schema != nil {
+			f, ok := schema.field(c.field)
+			if !ok {
+				return c, fmt.Errorf("could not find a property named '%s' on type 'search.document'", c.field)
+			}
+			if f.Type != "Edm.GeographyPoint" {
+				return c, fmt.Errorf("geo.distance requires a field of type 'Edm.GeographyPoint', but '%s' is of type '%s'", c.field, f.Type)
+			}
+		}
+		return c, nil
+	}
+	if !isValidFieldPath(expr) {
+		return c, fmt.Errorf("invalid $orderby clause %q", part)
+	}
+	c.kind, c.field = sortField, expr
+	if schema != nil {
+		f, ok := schema.field(expr)
+		if !ok {
+			return c, fmt.Errorf("could not find a property named '%s' on type 'search.document'", expr)
+		}
+		if f.isCollection() || f.Type == "Edm.ComplexType" || f.Type == "Edm.GeographyPoint" || (f.Sortable != nil && !*f.Sortable) {
+			return c, fmt.Errorf("the field '%s' is not sortable", expr)
+		}
+	}
+	return c, nil
+}
+
+// splitTopLevel splits s on sep, ignoring separators inside parentheses or
+// single-quoted literals.
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	depth, inQuote, start := 0, false, 0
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch == '\'':
+			inQuote = !inQuote
+		case inQuote:
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+		case ch == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// orderBySQL compiles field clauses to SQL. Score clauses are skipped (they
+// are only emitted when every hit has the same score) and the document key is
+// appended as the final tie-breaker so paging is deterministic.
+func orderBySQL(clauses []sortClause) string {
+	parts := make([]string, 0, len(clauses)+1)
+	for _, c := range clauses {
+		if c.kind != sortField {
+			continue
+		}
+		dir := "ASC"
+		if c.desc {
+			dir = "DESC"
+		}
+		parts = append(parts, jsonExtract(c.field)+" "+dir)
+	}
+	parts = append(parts, "key ASC")
+	return strings.Join(parts, ", ")
+}
+
+// needsInMemorySort reports whether the clauses depend on values SQLite cannot
+// compute: geo distances, or scores when ismatchscoring makes them vary.
+func needsInMemorySort(clauses []sortClause, scoring []*fullTextQuery) bool {
+	for _, c := range clauses {
+		if c.kind == sortGeoDistance || (c.kind == sortScore && len(scoring) > 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// searchHit is a decoded search result awaiting sorting and projection.
+type searchHit struct {
+	key   string
+	doc   map[string]interface{}
+	score float64
+}
+
+// sortHits orders hits by the clauses, breaking ties by document key.
+func sortHits(hits []searchHit, clauses []sortClause) {
+	sort.SliceStable(hits, func(i, j int) bool {
+		for _, c := range clauses {
+			var cmp int
+			switch c.kind {
+			case sortScore:
+				cmp = compareFloat(hits[i].score, hits[j].score)
+			case sortGeoDistance:
+				cmp = compareValues(geoDistance(hits[i].doc, c), geoDistance(hits[j].doc, c))
+			default:
+				vi, _ := lookupPath(hits[i].doc, c.field)
+				vj, _ := lookupPath(hits[j].doc, c.field)
+				cmp = compareValues(vi, vj)
+			}
+			if c.desc {
+				cmp = -cmp
+			}
+			if cmp != 0 {
+				return cmp < 0
+			}
+		}
+		return hits[i].key < hits[j].key
+	})
+}
+
+// compareValues orders JSON values the way SQLite orders json_extract()
+// results: NULL < booleans/numbers < text.
+func compareValues(a, b interface{}) int {
+	ra, rb := valueRank(a), valueRank(b)
+	if ra != rb {
+		return ra - rb
+	}
+	switch av := a.(type) {
+	case float64:
+		return compareFloat(av, b.(float64))
+	case bool:
+		return compareFloat(boolToFloat(av), boolToFloat(b.(bool)))
+	case string:
+		return strings.Compare(av, b.(string))
+	}
+	return 0
+}
+
+func valueRank(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case float64:
+		return 2
+	case string:
+		return 3
+	}
+	return 4
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// geoDistance returns the distance in kilometers between the document's
+// GeoJSON point and the clause's reference point, or nil if the document has
+// no valid point.
+func geoDistance(doc map[string]interface{}, c sortClause) interface{} {
+	v, _ := lookupPath(doc, c.field)
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	coords, ok := m["coordinates"].([]interface{})
+	if !ok || len(coords) != 2 {
+		return nil
+	}
+	lon, ok1 := coords[0].(float64)
+	lat, ok2 := coords[1].(float64)
+	if !ok1 || !ok2 {
+		return nil
+	}
+	return haversineKm(lat, lon, c.point[1], c.point[0])
+}
+
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusKm = 6371.0
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
@@ -1,5 +1,13 @@
 package application
 
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"ai-search-emulator/internal/domain"
+)
+
 // SearchParams holds all OData query parameters for a search request.
 type SearchParams struct {
 	Search       string
@@ -7,19 +15,50 @@ type SearchParams struct {
 	OrderBy      string   // $orderby
 	Select       []string // $select (empty = all fields)
 	SearchFields []string // searchFields (empty = all fields)
-	Top          int      // $top  (0 = use default)
+	Top          *int     // $top  (nil = use default; 0 returns no documents)
 	Skip         int      // $skip
 	IncludeCount bool     // $count
 }
 
 // SearchResult is returned by DocumentService.SearchDocuments.
 type SearchResult struct {
-	Value []map[string]interface{}
-	Total int64 // total matching docs before TOP/SKIP
+	Value  []map[string]interface{}
+	Scores []float64 // @search.score for each entry of Value
+	Total  int64     // total matching docs before TOP/SKIP
 }
 
 // DefaultTop is the page size used when $top is not specified.
 const DefaultTop = 50
 
+// MaxTop is the largest page Azure serves for one search request. A larger
+// $top is honoured across several pages via the continuation.
+const MaxTop = 1000
+
+// Azure's limits on search request parameters.
+const (
+	MaxSkip             = 100000
+	maxSearchTextLength = 8192
+	maxSearchTerms      = 1024
+	maxFilterClauses    = 1000
+)
+
+// validateSearchParams enforces the numeric bounds and size limits of a
+// search request. Errors wrap domain.ErrInvalidSearchParameter.
+func validateSearchParams(params SearchParams) error {
+	switch {
+	case params.Top != nil && *params.Top < 0:
+		return fmt.Errorf("%w: $top must be a non-negative integer, got %d", domain.ErrInvalidSearchParameter, *params.Top)
+	case params.Skip < 0:
+		return fmt.Errorf("%w: $skip must be a non-negative integer, got %d", domain.ErrInvalidSearchParameter, params.Skip)
+	case params.Skip > MaxSkip:
+		return fmt.Errorf("%w: $skip must be at most %d, got %d; use a $filter range to page further", domain.ErrInvalidSearchParameter, MaxSkip, params.Skip)
+	case utf8.RuneCountInString(params.Search) > maxSearchTextLength:
+		return fmt.Errorf("%w: the search text exceeds the maximum length of %d characters", domain.ErrInvalidSearchParameter, maxSearchTextLength)
+	case len(strings.Fields(params.Search)) > maxSearchTerms:
+		return fmt.Errorf("%w: the search text has more than %d terms", domain.ErrInvalidSearchParameter, maxSearchTerms)
+	}
+	return nil
+}
+
 // unexported alias kept for internal use
 const defaultTop = DefaultTop
@@ -0,0 +1,322 @@
+package application
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// evalSkillExpression evaluates an input source of the form "= expression",
+// as accepted by ConditionalSkill: literals (numbers, 'strings', true,
+// false, null), enrichment paths written $(/document/path), parentheses, and
+// the operators ! - * / % + == != < <= > >= && ||. resolve returns the value
+// at a path, or nil when it does not exist.
+func evalSkillExpression(expr string, resolve func(path string) interface{}) (interface{}, error) {
+	p := &exprParser{src: strings.TrimPrefix(strings.TrimSpace(expr), "="), resolve: resolve}
+	v, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, fmt.Errorf("unexpected '%s' in expression '%s'", p.src[p.pos:], expr)
+	}
+	return v, nil
+}
+
+// expressionPaths returns the enrichment paths an expression reads.
+func expressionPaths(expr string) []string {
+	var paths []string
+	for rest := expr; ; {
+		i := strings.Index(rest, "$(")
+		if i < 0 {
+			return paths
+		}
+		end := strings.Index(rest[i:], ")")
+		if end < 0 {
+			return paths
+		}
+		paths = append(paths, strings.TrimSpace(rest[i+2:i+end]))
+		rest = rest[i+end:]
+	}
+}
+
+type exprParser struct {
+	src     string
+	pos     int
+	resolve func(path string) interface{}
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+// accept consumes op if it comes next.
+func (p *exprParser) accept(op string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.src[p.pos:], op) {
+		p.pos += len(op)
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) or() (interface{}, error) {
+	left, err := p.and()
+	for err == nil && p.accept("||") {
+		var right interface{}
+		if right, err = p.and(); err == nil {
+			left = truthy(left) || truthy(right)
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) and() (interface{}, error) {
+	left, err := p.comparison()
+	for err == nil && p.accept("&&") {
+		var right interface{}
+		if right, err = p.comparison(); err == nil {
+			left = truthy(left) && truthy(right)
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) comparison() (interface{}, error) {
+	left, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if !p.accept(op) {
+			continue
+		}
+		right, err := p.additive()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case "==":
+			return exprEqual(left, right), nil
+		case "!=":
+			return !exprEqual(left, right), nil
+		}
+		c, ok := exprCompare(left, right)
+		if !ok {
+			return false, nil
+		}
+		switch op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *exprParser) additive() (interface{}, error) {
+	left, err := p.multiplicative()
+	for err == nil {
+		var op string
+		switch {
+		case p.accept("+"):
+			op = "+"
+		case p.accept("-"):
+			op = "-"
+		default:
+			return left, nil
+		}
+		var right interface{}
+		if right, err = p.multiplicative(); err != nil {
+			break
+		}
+		a, aok := markNumber(left)
+		b, bok := markNumber(right)
+		switch {
+		case aok && bok && op == "+":
+			left = a + b
+		case aok && bok:
+			left = a - b
+		case op == "+":
+			left = mappingString(left) + mappingString(right)
+		default:
+			err = fmt.Errorf("'-' requires numbers")
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) multiplicative() (interface{}, error) {
+	left, err := p.unary()
+	for err == nil {
+		var op string
+		switch {
+		case p.accept("*"):
+			op = "*"
+		case p.accept("/"):
+			op = "/"
+		case p.accept("%"):
+			op = "%"
+		default:
+			return left, nil
+		}
+		var right interface{}
+		if right, err = p.unary(); err != nil {
+			break
+		}
+		a, aok := markNumber(left)
+		b, bok := markNumber(right)
+		if !aok || !bok {
+			return nil, fmt.Errorf("'%s' requires numbers", op)
+		}
+		switch op {
+		case "*":
+			left = a * b
+		case "/":
+			if b == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			left = a / b
+		default:
+			if int64(b) == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			left = float64(int64(a) % int64(b))
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) unary() (interface{}, error) {
+	if p.accept("!") {
+		v, err := p.unary()
+		return !truthy(v), err
+	}
+	if p.accept("-") {
+		v, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		n, ok := markNumber(v)
+		if !ok {
+			return nil, fmt.Errorf("'-' requires a number")
+		}
+		return -n, nil
+	}
+	return p.primary()
+}
+
+func (p *exprParser) primary() (interface{}, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	rest := p.src[p.pos:]
+	switch c := rest[0]; {
+	case c == '(':
+		p.pos++
+		v, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing ')' in expression")
+		}
+		return v, nil
+	case strings.HasPrefix(rest, "$("):
+		end := strings.Index(rest, ")")
+		if end < 0 {
+			return nil, fmt.Errorf("missing ')' after path")
+		}
+		p.pos += end + 1
+		return p.resolve(strings.TrimSpace(rest[2:end])), nil
+	case c == '\'' || c == '"':
+		end := strings.IndexByte(rest[1:], c)
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated string in expression")
+		}
+		p.pos += end + 2
+		return rest[1 : end+1], nil
+	case c >= '0' && c <= '9' || c == '.':
+		end := 0
+		for end < len(rest) && (rest[end] >= '0' && rest[end] <= '9' || rest[end] == '.') {
+			end++
+		}
+		n, err := strconv.ParseFloat(rest[:end], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s'", rest[:end])
+		}
+		p.pos += end
+		return n, nil
+	}
+	for _, lit := range []struct {
+		word  string
+		value interface{}
+	}{{"true", true}, {"false", false}, {"null", nil}} {
+		if strings.HasPrefix(rest, lit.word) {
+			p.pos += len(lit.word)
+			return lit.value, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected '%s' in expression", rest)
+}
+
+func truthy(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return val
+	case string:
+		return val != ""
+	}
+	if n, ok := markNumber(v); ok {
+		return n != 0
+	}
+	return true
+}
+
+func exprEqual(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if c, ok := exprCompare(a, b); ok {
+		return c == 0
+	}
+	if ab, ok := a.(bool); ok {
+		bb, ok := b.(bool)
+		return ok && ab == bb
+	}
+	return false
+}
+
+// exprCompare orders two numbers or two strings.
+func exprCompare(a, b interface{}) (int, bool) {
+	if x, ok := markNumber(a); ok {
+		if y, ok := markNumber(b); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		}
+		return 0, false
+	}
+	x, aok := a.(string)
+	y, bok := b.(string)
+	if !aok || !bok {
+		return 0, false
+	}
+	return strings.Compare(x, y), true
+}
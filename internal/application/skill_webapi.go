@@ -0,0 +1,200 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults and limits Azure applies to WebApiSkill.
+const (
+	defaultWebAPITimeout     = 30 * time.Second
+	minWebAPITimeout         = time.Second
+	maxWebAPITimeout         = 230 * time.Second
+	defaultWebAPIBatchSize   = 1000
+	defaultWebAPIParallelism = 5
+	maxWebAPIParallelism     = 10
+)
+
+func validateWebAPISkill(d *skillDefinition) error {
+	u, err := url.Parse(d.URI)
+	if d.URI == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("uri must be an absolute http or https URL")
+	}
+	switch strings.ToUpper(d.HTTPMethod) {
+	case "", http.MethodPost, http.MethodPut:
+	default:
+		return fmt.Errorf("httpMethod must be 'POST' or 'PUT'")
+	}
+	if d.Timeout != "" {
+		t, err := parseISODuration(d.Timeout)
+		if err != nil {
+			return fmt.Errorf("timeout: %v", err)
+		}
+		if t < minWebAPITimeout || t > maxWebAPITimeout {
+			return fmt.Errorf("timeout must be between PT1S and PT230S")
+		}
+	}
+	if b := d.BatchSize; b != nil && *b < 1 {
+		return fmt.Errorf("batchSize must be 1 or greater")
+	}
+	if p := d.DegreeOfParallelism; p != nil && (*p < 1 || *p > maxWebAPIParallelism) {
+		return fmt.Errorf("degreeOfParallelism must be between 1 and %d", maxWebAPIParallelism)
+	}
+	return nil
+}
+
+func (d *skillDefinition) webAPITimeout() time.Duration {
+	if d.Timeout == "" {
+		return defaultWebAPITimeout
+	}
+	t, err := parseISODuration(d.Timeout)
+	if err != nil {
+		return defaultWebAPITimeout
+	}
+	return t
+}
+
+// webAPIRecord is an entry of the values array exchanged with a custom Web
+// API: the skill sends recordId and data, the service answers with its
+// outputs in data along with errors and warnings for the record.
+type webAPIRecord struct {
+	RecordID string                 `json:"recordId"`
+	Data     map[string]interface{} `json:"data"`
+	Errors   []webAPIMessage        `json:"errors,omitempty"`
+	Warnings []webAPIMessage        `json:"warnings,omitempty"`
+}
+
+// webAPIMessage accepts both {"message": "..."} and a bare string.
+type webAPIMessage struct {
+	Message string `json:"message"`
+}
+
+func (m *webAPIMessage) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		m.Message = s
+		return nil
+	}
+	type plain webAPIMessage
+	return json.Unmarshal(b, (*plain)(m))
+}
+
+// runWebAPISkill sends the records to the skill's uri in batches of
+// batchSize, with up to degreeOfParallelism requests in flight. A failed
+// request fails every record of its batch.
+func runWebAPISkill(ctx context.Context, d *skillDefinition, records []map[string]interface{}) []skillResult {
+	size := defaultWebAPIBatchSize
+	if d.BatchSize != nil {
+		size = *d.BatchSize
+	}
+	parallelism := defaultWebAPIParallelism
+	if d.DegreeOfParallelism != nil {
+		parallelism = *d.DegreeOfParallelism
+	}
+	results := make([]skillResult, len(records))
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for start := 0; start < len(records); start += size {
+		end := min(start+size, len(records))
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			callWebAPI(ctx, d, start, records[start:end], results[start:end])
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// callWebAPI sends one batch and fills results. Records are identified by
+// their position among all the skill's records.
+func callWebAPI(ctx context.Context, d *skillDefinition, offset int, records []map[string]interface{}, results []skillResult) {
+	fail := func(msg string) {
+		for i := range results {
+			results[i] = skillResult{errors: []string{msg}}
+		}
+	}
+	values := make([]webAPIRecord, len(records))
+	for i, in := range records {
+		values[i] = webAPIRecord{RecordID: strconv.Itoa(offset + i), Data: in}
+	}
+	body, err := json.Marshal(map[string]interface{}{"values": values})
+	if err != nil {
+		fail(fmt.Sprintf("Could not serialize the Web Api request: %v", err))
+		return
+	}
+
+	timeout := d.webAPITimeout()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	method := http.MethodPost
+	if d.HTTPMethod != "" {
+		method = strings.ToUpper(d.HTTPMethod)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, d.URI, bytes.NewReader(body))
+	if err != nil {
+		fail(fmt.Sprintf("Could not execute skill because the Web Api request failed: %v", err))
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range d.HTTPHeaders {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			fail(fmt.Sprintf("Could not execute skill because the Web Api request timed out after %s", timeout))
+		} else {
+			fail(fmt.Sprintf("Could not execute skill because the Web Api request failed: %v", err))
+		}
+		return
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		fail(fmt.Sprintf("Could not execute skill because the Web Api response could not be read: %v", err))
+		return
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		fail(fmt.Sprintf("Web Api response status: '%s', Web Api response details: '%s'", resp.Status, strings.TrimSpace(string(respBody))))
+		return
+	}
+	var parsed struct {
+		Values []webAPIRecord `json:"values"`
+	}
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		fail(fmt.Sprintf("Could not execute skill because the Web Api response is not valid JSON: %v", err))
+		return
+	}
+	byID := make(map[string]webAPIRecord, len(parsed.Values))
+	for _, v := range parsed.Values {
+		byID[v.RecordID] = v
+	}
+	for i, in := range values {
+		v, ok := byID[in.RecordID]
+		if !ok {
+			results[i] = skillResult{errors: []string{fmt.Sprintf("The Web Api response did not contain a record with recordId '%s'", in.RecordID)}}
+			continue
+		}
+		r := skillResult{outputs: v.Data}
+		for _, m := range v.Errors {
+			r.errors = append(r.errors, m.Message)
+		}
+		for _, m := range v.Warnings {
+			r.warnings = append(r.warnings, m.Message)
+		}
+		results[i] = r
+	}
+}
@@ -0,0 +1,166 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// echoWebAPI answers each record with the upper-cased text, an error for
+// the text "fail" and a warning for the text "warn".
+func echoWebAPI(t *testing.T, batches *[]int, mu *sync.Mutex) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-functions-key") != "secret" || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			Values []webAPIRecord `json:"values"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		*batches = append(*batches, len(req.Values))
+		mu.Unlock()
+		out := make([]map[string]interface{}, 0, len(req.Values))
+		for _, v := range req.Values {
+			text, _ := v.Data["text"].(string)
+			rec := map[string]interface{}{"recordId": v.RecordID, "data": map[string]interface{}{"upper": strings.ToUpper(text)}}
+			switch text {
+			case "fail":
+				rec["errors"] = []map[string]string{{"message": "cannot process"}}
+			case "warn":
+				rec["warnings"] = []string{"suspicious"}
+			}
+			out = append(out, rec)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"values": out})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestWebAPISkill_Batches(t *testing.T) {
+	t.Parallel()
+	var batches []int
+	var mu sync.Mutex
+	srv := echoWebAPI(t, &batches, &mu)
+	d := &skillDefinition{URI: srv.URL, HTTPHeaders: map[string]string{"x-functions-key": "secret"}, BatchSize: intPtr(2), DegreeOfParallelism: intPtr(2)}
+
+	records := []map[string]interface{}{{"text": "a"}, {"text": "fail"}, {"text": "warn"}, {"text": "b"}, {"text": "c"}}
+	results := runWebAPISkill(context.Background(), d, records)
+	if len(batches) != 3 {
+		t.Errorf("batches = %v, want 3 requests of at most 2 records", batches)
+	}
+	if results[0].outputs["upper"] != "A" || results[4].outputs["upper"] != "C" {
+		t.Errorf("results = %+v", results)
+	}
+	if len(results[1].errors) != 1 || results[1].errors[0] != "cannot process" {
+		t.Errorf("errors = %v, want the record's error", results[1].errors)
+	}
+	if len(results[2].warnings) != 1 || results[2].warnings[0] != "suspicious" || results[2].outputs["upper"] != "WARN" {
+		t.Errorf("warning record = %+v", results[2])
+	}
+}
+
+func TestWebAPISkill_Failures(t *testing.T) {
+	t.Parallel()
+	var batches []int
+	var mu sync.Mutex
+	srv := echoWebAPI(t, &batches, &mu)
+	records := []map[string]interface{}{{"text": "a"}, {"text": "b"}}
+
+	results := runWebAPISkill(context.Background(), &skillDefinition{URI: srv.URL}, records)
+	for _, r := range results {
+		if len(r.errors) != 1 || !strings.Contains(r.errors[0], "401") {
+			t.Errorf("a failed request should fail every record of the batch: %+v", r)
+		}
+	}
+
+	missing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"values":[{"recordId":"0","data":{"upper":"A"}}]}`))
+	}))
+	defer missing.Close()
+	results = runWebAPISkill(context.Background(), &skillDefinition{URI: missing.URL}, records)
+	if results[0].outputs["upper"] != "A" || len(results[1].errors) != 1 {
+		t.Errorf("a record absent from the response should fail: %+v", results)
+	}
+
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	results = runWebAPISkill(ctx, &skillDefinition{URI: slow.URL, Timeout: "PT1S"}, records[:1])
+	if len(results[0].errors) != 1 || !strings.Contains(results[0].errors[0], "timed out") {
+		t.Errorf("timeout: %+v", results[0])
+	}
+}
+
+func TestWebAPISkill_Validation(t *testing.T) {
+	t.Parallel()
+	bad := []*skillDefinition{
+		{},
+		{URI: "localhost:8080/skill"},
+		{URI: "http://localhost/skill", HTTPMethod: "GET"},
+		{URI: "http://localhost/skill", Timeout: "PT300S"},
+		{URI: "http://localhost/skill", Timeout: "30s"},
+		{URI: "http://localhost/skill", BatchSize: intPtr(0)},
+		{URI: "http://localhost/skill", DegreeOfParallelism: intPtr(11)},
+	}
+	for _, d := range bad {
+		if err := validateWebAPISkill(d); err == nil {
+			t.Errorf("%+v should be rejected", d)
+		}
+	}
+	ok := &skillDefinition{URI: "http://localhost:7071/api/skill", HTTPMethod: "put", Timeout: "PT230S", BatchSize: intPtr(1), DegreeOfParallelism: intPtr(10)}
+	if err := validateWebAPISkill(ok); err != nil {
+		t.Errorf("validate: %v", err)
+	}
+}
+
+func TestWebAPISkill_BatchesAcrossDocuments(t *testing.T) {
+	t.Parallel()
+	var batches []int
+	var mu sync.Mutex
+	srv := echoWebAPI(t, &batches, &mu)
+	items := hotelItems(5)
+	items[2].Fields["name"] = "fail"
+	env := newIndexerServiceForTest(t, items)
+	ss := `{"name":"remote","skills":[{"@odata.type":"#Microsoft.Skills.Custom.WebApiSkill","uri":"` + srv.URL + `",
+		"httpHeaders":{"x-functions-key":"secret"},"batchSize":2,"degreeOfParallelism":1,
+		"inputs":[{"name":"text","source":"/document/name"}],"outputs":[{"name":"upper"}]}]}`
+	if _, err := env.svc.Skillsets.CreateSkillset(context.Background(), []byte(ss)); err != nil {
+		t.Fatalf("create skillset: %v", err)
+	}
+	body := `{"name":"ix","dataSourceName":"src","targetIndexName":"hotels","skillsetName":"remote",
+		"parameters":{"maxFailedItems":-1},
+		"outputFieldMappings":[{"sourceFieldName":"/document/upper","targetFieldName":"name"}]}`
+	if _, err := env.svc.CreateIndexer(context.Background(), []byte(body)); err != nil {
+		t.Fatalf("create indexer: %v", err)
+	}
+	last := env.status(t, "ix").LastResult
+	if last.ItemsProcessed != 5 || last.ItemsFailed != 1 || len(last.Errors) != 1 || last.Errors[0].Key != "3" {
+		t.Fatalf("lastResult = %+v, want 5 items with document 3 failed", last)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(batches, []int{2, 2, 1}) {
+		t.Errorf("records per request = %v, want [2 2 1] across the five documents", batches)
+	}
+	if doc, _ := env.docs.Find("hotels", "5"); doc == nil || !strings.Contains(doc.Content, "HOTEL 5") {
+		t.Errorf("document 5 was not enriched: %+v", doc)
+	}
+}
@@ -0,0 +1,459 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// skillDefinition is one entry of a skillset's skills. Parameters of the
+// individual skill types share the struct; each type reads its own.
+type skillDefinition struct {
+	ODataType   string        `json:"@odata.type"`
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Context     string        `json:"context"`
+	Inputs      []skillInput  `json:"inputs"`
+	Outputs     []skillOutput `json:"outputs"`
+
+	// SplitSkill
+	TextSplitMode      string `json:"textSplitMode"`
+	MaximumPageLength  *int   `json:"maximumPageLength"`
+	PageOverlapLength  *int   `json:"pageOverlapLength"`
+	MaximumPagesToTake *int   `json:"maximumPagesToTake"`
+	// MergeSkill
+	InsertPreTag  *string `json:"insertPreTag"`
+	InsertPostTag *string `json:"insertPostTag"`
+	// TextTranslationSkill
+	DefaultToLanguageCode   string `json:"defaultToLanguageCode"`
+	DefaultFromLanguageCode string `json:"defaultFromLanguageCode"`
+	// SplitSkill, LanguageDetectionSkill
+	DefaultLanguageCode string `json:"defaultLanguageCode"`
+	DefaultCountryHint  string `json:"defaultCountryHint"`
+	// WebApiSkill
+	URI                 string            `json:"uri"`
+	HTTPMethod          string            `json:"httpMethod"`
+	HTTPHeaders         map[string]string `json:"httpHeaders"`
+	Timeout             string            `json:"timeout"`
+	BatchSize           *int              `json:"batchSize"`
+	DegreeOfParallelism *int              `json:"degreeOfParallelism"`
+}
+
+// skillInput is a skill input read from source or, for inputs with nested
+// inputs, shaped into an object; sourceContext shapes one object per
+// instance of a context into a collection.
+type skillInput struct {
+	Name          string       `json:"name"`
+	Source        string       `json:"source"`
+	SourceContext string       `json:"sourceContext"`
+	Inputs        []skillInput `json:"inputs"`
+}
+
+type skillOutput struct {
+	Name       string `json:"name"`
+	TargetName string `json:"targetName"`
+}
+
+func (o skillOutput) target() string {
+	if o.TargetName != "" {
+		return o.TargetName
+	}
+	return o.Name
+}
+
+func (d *skillDefinition) context() string {
+	if d.Context == "" {
+		return "/document"
+	}
+	return d.Context
+}
+
+// skillResult is the outcome of a skill for one record: its outputs by name,
+// and the messages reported for it. A record with errors has no outputs.
+type skillResult struct {
+	outputs  map[string]interface{}
+	errors   []string
+	warnings []string
+}
+
+// skillType describes a skill type: the inputs it requires, the outputs it
+// can produce (nil accepts any) and how it runs. run receives one input map
+// per context instance and returns a result per record.
+type skillType struct {
+	required []string
+	outputs  []string
+	validate func(d *skillDefinition) error
+	run      func(ctx context.Context, d *skillDefinition, records []map[string]interface{}) []skillResult
+}
+
+// perRecord adapts a skill that processes records independently.
+func perRecord(fn func(d *skillDefinition, in map[string]interface{}) skillResult) func(context.Context, *skillDefinition, []map[string]interface{}) []skillResult {
+	return func(_ context.Context, d *skillDefinition, records []map[string]interface{}) []skillResult {
+		out := make([]skillResult, len(records))
+		for i, in := range records {
+			out[i] = fn(d, in)
+		}
+		return out
+	}
+}
+
+// skillTypes lists the supported skills, keyed by @odata.type.
+var skillTypes = map[string]skillType{
+	"#Microsoft.Skills.Text.SplitSkill": {
+		required: []string{"text"},
+		outputs:  []string{"textItems"},
+		validate: validateSplitSkill,
+		run:      perRecord(runSplitSkill),
+	},
+	"#Microsoft.Skills.Text.MergeSkill": {
+		required: []string{"text"},
+		outputs:  []string{"mergedText"},
+		run:      perRecord(runMergeSkill),
+	},
+	"#Microsoft.Skills.Util.ShaperSkill": {
+		outputs: []string{"output"},
+		run: perRecord(func(d *skillDefinition, in map[string]interface{}) skillResult {
+			return skillResult{outputs: map[string]interface{}{"output": in}}
+		}),
+	},
+	"#Microsoft.Skills.Util.ConditionalSkill": {
+		required: []string{"condition", "whenTrue", "whenFalse"},
+		outputs:  []string{"output"},
+		run: perRecord(func(d *skillDefinition, in map[string]interface{}) skillResult {
+			out := in["whenFalse"]
+			if truthy(in["condition"]) {
+				out = in["whenTrue"]
+			}
+			return skillResult{outputs: map[string]interface{}{"output": out}}
+		}),
+	},
+	"#Microsoft.Skills.Text.TranslationSkill": {
+		required: []string{"text"},
+		outputs:  []string{"translatedText", "translatedFromLanguageCode", "translatedToLanguageCode"},
+		validate: func(d *skillDefinition) error {
+			if d.DefaultToLanguageCode == "" {
+				return fmt.Errorf("defaultToLanguageCode is required")
+			}
+			return nil
+		},
+		run: perRecord(runTranslationSkill),
+	},
+	"#Microsoft.Skills.Text.LanguageDetectionSkill": {
+		required: []string{"text"},
+		outputs:  []string{"languageCode", "languageName", "score"},
+		run:      perRecord(runLanguageDetectionSkill),
+	},
+	"#Microsoft.Skills.Custom.WebApiSkill": {
+		validate: validateWebAPISkill,
+		run:      runWebAPISkill,
+	},
+}
+
+// Limits Azure places on SplitSkill pages.
+const (
+	defaultMaximumPageLength = 5000
+	minMaximumPageLength     = 300
+	maxMaximumPageLength     = 50000
+)
+
+func validateSplitSkill(d *skillDefinition) error {
+	switch d.TextSplitMode {
+	case "", "pages", "sentences":
+	default:
+		return fmt.Errorf("textSplitMode must be 'pages' or 'sentences'")
+	}
+	if m := d.MaximumPageLength; m != nil && (*m < minMaximumPageLength || *m > maxMaximumPageLength) {
+		return fmt.Errorf("maximumPageLength must be between %d and %d", minMaximumPageLength, maxMaximumPageLength)
+	}
+	if o := d.PageOverlapLength; o != nil && (*o < 0 || *o >= d.maximumPageLength()/2) {
+		return fmt.Errorf("pageOverlapLength must be at least 0 and less than half of maximumPageLength")
+	}
+	if m := d.MaximumPagesToTake; m != nil && *m < 0 {
+		return fmt.Errorf("maximumPagesToTake must be 0 or greater")
+	}
+	return nil
+}
+
+func (d *skillDefinition) maximumPageLength() int {
+	if d.MaximumPageLength != nil {
+		return *d.MaximumPageLength
+	}
+	return defaultMaximumPageLength
+}
+
+func runSplitSkill(d *skillDefinition, in map[string]interface{}) skillResult {
+	text, _ := in["text"].(string)
+	sentences := splitSentences(text)
+	var items []string
+	if d.TextSplitMode == "sentences" {
+		items = sentences
+	} else {
+		overlap := 0
+		if d.PageOverlapLength != nil {
+			overlap = *d.PageOverlapLength
+		}
+		items = splitPages(sentences, d.maximumPageLength(), overlap)
+	}
+	if m := d.MaximumPagesToTake; m != nil && *m > 0 && len(items) > *m {
+		items = items[:*m]
+	}
+	out := make([]interface{}, len(items))
+	for i, s := range items {
+		out[i] = s
+	}
+	return skillResult{outputs: map[string]interface{}{"textItems": out}}
+}
+
+// sentenceEndRe matches the end of a sentence: terminal punctuation with any
+// closing quotes or brackets followed by white space, CJK full stops, or a
+// paragraph break.
+var sentenceEndRe = regexp.MustCompile(`[.!?]+["'”’)\]]*\s+|[。！？]+\s*|\n\s*\n`)
+
+func splitSentences(text string) []string {
+	var out []string
+	start := 0
+	for _, loc := range sentenceEndRe.FindAllStringIndex(text, -1) {
+		if s := strings.TrimSpace(text[start:loc[1]]); s != "" {
+			out = append(out, s)
+		}
+		start = loc[1]
+	}
+	if s := strings.TrimSpace(text[start:]); s != "" {
+		out = append(out, s)
+	}
+	return out
+}
+
+// splitPages packs sentences into pages of at most max characters. Longer
+// sentences are broken at word boundaries. With overlap, each page after the
+// first starts with up to overlap characters from the end of the previous
+// page.
+func splitPages(sentences []string, max, overlap int) []string {
+	var pieces []string
+	for _, s := range sentences {
+		pieces = append(pieces, splitLong(s, max-overlap)...)
+	}
+	var pages []string
+	var cur strings.Builder
+	for _, p := range pieces {
+		n := utf8.RuneCountInString(cur.String())
+		if n > 0 && n+1+utf8.RuneCountInString(p) > max {
+			page := cur.String()
+			pages = append(pages, page)
+			cur.Reset()
+			// The overlap shrinks when the whole of it, the separating
+			// space and the next piece would not fit in one page.
+			cur.WriteString(tail(page, min(overlap, max-1-utf8.RuneCountInString(p))))
+		}
+		if cur.Len() > 0 {
+			cur.WriteString(" ")
+		}
+		cur.WriteString(p)
+	}
+	if cur.Len() > 0 {
+		pages = append(pages, cur.String())
+	}
+	return pages
+}
+
+// splitLong breaks text longer than max characters at word boundaries, or
+// mid-word when a single word is longer.
+func splitLong(text string, max int) []string {
+	if max < 1 {
+		max = 1
+	}
+	var out []string
+	for utf8.RuneCountInString(text) > max {
+		runes := []rune(text)
+		cut := max
+		for i := max; i > max/2; i-- {
+			if unicode.IsSpace(runes[i]) {
+				cut = i
+				break
+			}
+		}
+		out = append(out, strings.TrimSpace(string(runes[:cut])))
+		text = strings.TrimSpace(string(runes[cut:]))
+	}
+	if text != "" {
+		out = append(out, text)
+	}
+	return out
+}
+
+// tail returns the last n characters of s, starting at a word boundary.
+func tail(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	t := runes[len(runes)-n:]
+	if i := strings.IndexFunc(string(t), unicode.IsSpace); i >= 0 && !unicode.IsSpace(runes[len(runes)-n-1]) {
+		return strings.TrimSpace(string(t)[i:])
+	}
+	return strings.TrimSpace(string(t))
+}
+
+// runMergeSkill inserts itemsToInsert into text at the character offsets,
+// wrapped in insertPreTag and insertPostTag (a space by default). Without
+// offsets the items are appended.
+func runMergeSkill(d *skillDefinition, in map[string]interface{}) skillResult {
+	pre, post := " ", " "
+	if d.InsertPreTag != nil {
+		pre = *d.InsertPreTag
+	}
+	if d.InsertPostTag != nil {
+		post = *d.InsertPostTag
+	}
+	text, _ := in["text"].(string)
+	items, _ := in["itemsToInsert"].([]interface{})
+	offsets, _ := in["offsets"].([]interface{})
+	type insertion struct {
+		at   int
+		text string
+	}
+	runes := []rune(text)
+	var ins []insertion
+	for i, item := range items {
+		at := len(runes)
+		if i < len(offsets) {
+			if n, ok := markNumber(offsets[i]); ok && int(n) >= 0 && int(n) <= len(runes) {
+				at = int(n)
+			}
+		}
+		ins = append(ins, insertion{at: at, text: mappingString(item)})
+	}
+	sort.SliceStable(ins, func(i, j int) bool { return ins[i].at < ins[j].at })
+	var b strings.Builder
+	last := 0
+	for _, x := range ins {
+		b.WriteString(string(runes[last:x.at]))
+		b.WriteString(pre + x.text + post)
+		last = x.at
+	}
+	b.WriteString(string(runes[last:]))
+	return skillResult{outputs: map[string]interface{}{"mergedText": b.String()}}
+}
+
+// runTranslationSkill is an offline stand-in for the Translator service: the
+// text is returned unchanged, with the detected source language.
+func runTranslationSkill(d *skillDefinition, in map[string]interface{}) skillResult {
+	text, _ := in["text"].(string)
+	to, _ := in["toLanguageCode"].(string)
+	if to == "" {
+		to = d.DefaultToLanguageCode
+	}
+	from, _ := in["fromLanguageCode"].(string)
+	if from == "" {
+		from = d.DefaultFromLanguageCode
+	}
+	if from == "" {
+		from, _, _ = detectLanguage(text)
+	}
+	return skillResult{outputs: map[string]interface{}{
+		"translatedText":             text,
+		"translatedFromLanguageCode": from,
+		"translatedToLanguageCode":   to,
+	}}
+}
+
+func runLanguageDetectionSkill(d *skillDefinition, in map[string]interface{}) skillResult {
+	text, _ := in["text"].(string)
+	code, name, score := detectLanguage(text)
+	return skillResult{outputs: map[string]interface{}{"languageCode": code, "languageName": name, "score": score}}
+}
+
+// languageNames are the languages detectLanguage can report.
+var languageNames = map[string]string{
+	"en": "English", "fr": "French", "de": "German", "es": "Spanish", "it": "Italian",
+	"pt": "Portuguese", "nl": "Dutch", "ja": "Japanese", "ko": "Korean", "zh_chs": "Chinese_Simplified",
+	"ru": "Russian", "ar": "Arabic", "el": "Greek", "he": "Hebrew", "th": "Thai", "hi": "Hindi",
+}
+
+// scriptLanguages maps writing systems used by a single language.
+var scriptLanguages = []struct {
+	table *unicode.RangeTable
+	code  string
+}{
+	{unicode.Hiragana, "ja"}, {unicode.Katakana, "ja"}, {unicode.Hangul, "ko"}, {unicode.Cyrillic, "ru"},
+	{unicode.Arabic, "ar"}, {unicode.Greek, "el"}, {unicode.Hebrew, "he"}, {unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"}, {unicode.Han, "zh_chs"},
+}
+
+// stopWords are frequent words that tell Latin-script languages apart.
+var stopWords = map[string][]string{
+	"en": {"the", "and", "is", "of", "to", "in", "it", "that", "with", "for", "this", "are", "was"},
+	"fr": {"le", "la", "les", "et", "est", "des", "une", "un", "du", "dans", "pour", "que", "avec"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "mit", "zu", "den", "von", "ich"},
+	"es": {"el", "la", "los", "las", "y", "es", "de", "que", "en", "un", "una", "por", "con", "para"},
+	"it": {"il", "la", "e", "di", "che", "è", "un", "una", "per", "non", "con", "sono", "gli"},
+	"pt": {"o", "a", "os", "as", "e", "de", "que", "em", "um", "uma", "para", "com", "não", "é"},
+	"nl": {"de", "het", "een", "en", "is", "van", "niet", "dat", "op", "te", "met", "voor", "zijn"},
+}
+
+// detectLanguage guesses the language of text: by writing system for
+// scripts used by one language, otherwise by counting common words. Text
+// without letters is reported as (Unknown).
+func detectLanguage(text string) (code, name string, score float64) {
+	counts := map[string]int{}
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, s := range scriptLanguages {
+			if unicode.Is(s.table, r) {
+				counts[s.code]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return "(Unknown)", "(Unknown)", 0
+	}
+	// Kana marks Japanese even in text dominated by kanji.
+	if counts["ja"] > 0 {
+		counts["ja"] += counts["zh_chs"]
+		delete(counts, "zh_chs")
+	}
+	best, bestCount := "", 0
+	for code, n := range counts {
+		if n > bestCount || n == bestCount && code < best {
+			best, bestCount = code, n
+		}
+	}
+	if bestCount*2 > letters {
+		return best, languageNames[best], 1
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) })
+	hits := map[string]int{}
+	for _, w := range words {
+		for code, list := range stopWords {
+			for _, s := range list {
+				if w == s {
+					hits[code]++
+				}
+			}
+		}
+	}
+	best, bestCount, total := "en", 0, 0
+	for code, n := range hits {
+		total += n
+		if n > bestCount || n == bestCount && code < best {
+			best, bestCount = code, n
+		}
+	}
+	if total == 0 {
+		return "en", languageNames["en"], 0.5
+	}
+	return best, languageNames[best], float64(int(float64(bestCount)/float64(total)*100)) / 100
+}
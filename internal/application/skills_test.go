@@ -0,0 +1,195 @@
+package application
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func intPtr(n int) *int { return &n }
+
+func TestSplitSkill_Sentences(t *testing.T) {
+	t.Parallel()
+	d := &skillDefinition{TextSplitMode: "sentences"}
+	r := runSplitSkill(d, map[string]interface{}{"text": "First one. Second? \"Third!\" Last"})
+	want := []interface{}{"First one.", "Second?", "\"Third!\"", "Last"}
+	if !reflect.DeepEqual(r.outputs["textItems"], want) {
+		t.Errorf("textItems = %q, want %q", r.outputs["textItems"], want)
+	}
+	r = runSplitSkill(d, map[string]interface{}{"text": "今日は晴れ。明日は雨。"})
+	if items := r.outputs["textItems"].([]interface{}); len(items) != 2 || items[1] != "明日は雨。" {
+		t.Errorf("CJK sentences = %q", items)
+	}
+}
+
+func TestSplitSkill_PagesAndOverlap(t *testing.T) {
+	t.Parallel()
+	sentence := strings.Repeat("word ", 19) + "end." // 99 characters
+	text := strings.TrimSpace(strings.Repeat(sentence+" ", 10))
+
+	d := &skillDefinition{MaximumPageLength: intPtr(300)}
+	items := runSplitSkill(d, map[string]interface{}{"text": text}).outputs["textItems"].([]interface{})
+	if len(items) != 4 {
+		t.Fatalf("pages = %d, want 4", len(items))
+	}
+	for _, p := range items {
+		if n := utf8.RuneCountInString(p.(string)); n > 300 {
+			t.Errorf("page of %d characters exceeds maximumPageLength", n)
+		}
+	}
+
+	d.PageOverlapLength = intPtr(20)
+	overlapped := runSplitSkill(d, map[string]interface{}{"text": text}).outputs["textItems"].([]interface{})
+	first, second := overlapped[0].(string), overlapped[1].(string)
+	if !strings.HasPrefix(second, "word word word end.") || !strings.HasSuffix(first, "word word word end.") {
+		t.Errorf("the second page should start with the end of the first:\n%q\n%q", first, second)
+	}
+
+	d.MaximumPagesToTake = intPtr(2)
+	if items := runSplitSkill(d, map[string]interface{}{"text": text}).outputs["textItems"].([]interface{}); len(items) != 2 {
+		t.Errorf("maximumPagesToTake: pages = %d, want 2", len(items))
+	}
+
+	long := strings.Repeat("abcdefghij ", 60)
+	for _, p := range runSplitSkill(&skillDefinition{MaximumPageLength: intPtr(300)}, map[string]interface{}{"text": long}).outputs["textItems"].([]interface{}) {
+		if n := utf8.RuneCountInString(p.(string)); n > 300 {
+			t.Errorf("a long sentence produced a page of %d characters", n)
+		}
+	}
+}
+
+func TestSplitPages_NeverExceedMaximumPageLength(t *testing.T) {
+	t.Parallel()
+	// Pieces of exactly max-overlap characters followed by an overlap tail
+	// of exactly overlap characters used to make pages of max+1.
+	sentences := []string{strings.Repeat("a", 80), strings.Repeat("b", 80), strings.Repeat("c", 80)}
+	for _, p := range splitPages(sentences, 100, 20) {
+		if n := utf8.RuneCountInString(p); n > 100 {
+			t.Errorf("page of %d characters exceeds 100: %q", n, p)
+		}
+	}
+	for max := 10; max <= 60; max++ {
+		for overlap := 0; overlap < max/2; overlap++ {
+			text := strings.Repeat("lorem ipsum dolorsit amet, consectetur adipiscing. ", 5)
+			for _, p := range splitPages(splitSentences(text), max, overlap) {
+				if n := utf8.RuneCountInString(p); n > max {
+					t.Fatalf("max %d, overlap %d: page of %d characters %q", max, overlap, n, p)
+				}
+			}
+		}
+	}
+}
+
+func TestMergeSkill(t *testing.T) {
+	t.Parallel()
+	in := map[string]interface{}{
+		"text":          "The quick fox",
+		"itemsToInsert": []interface{}{"[brown]", "[jumps]"},
+		"offsets":       []interface{}{float64(9), float64(13)},
+	}
+	r := runMergeSkill(&skillDefinition{}, in)
+	if r.outputs["mergedText"] != "The quick [brown]  fox [jumps] " {
+		t.Errorf("mergedText = %q", r.outputs["mergedText"])
+	}
+	pre, post := "<", ">"
+	r = runMergeSkill(&skillDefinition{InsertPreTag: &pre, InsertPostTag: &post}, map[string]interface{}{"text": "a", "itemsToInsert": []interface{}{"b"}})
+	if r.outputs["mergedText"] != "a<b>" {
+		t.Errorf("mergedText = %q, want a<b>", r.outputs["mergedText"])
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"The hotel is close to the beach and the rooms are clean.":        "en",
+		"Le petit déjeuner est inclus et la chambre est calme.":           "fr",
+		"Das Zimmer ist sauber und die Lage ist gut.":                     "de",
+		"El hotel está cerca de la playa y las habitaciones son grandes.": "es",
+		"このホテルは駅から近いです。":                                                  "ja",
+		"호텔은 깨끗합니다":                                                       "ko",
+		"Гостиница находится в центре города":                             "ru",
+		"12345 !!!": "(Unknown)",
+	}
+	for text, want := range cases {
+		if code, _, _ := detectLanguage(text); code != want {
+			t.Errorf("detectLanguage(%q) = %s, want %s", text, code, want)
+		}
+	}
+}
+
+func TestSkillExpression(t *testing.T) {
+	t.Parallel()
+	values := map[string]interface{}{"/document/lang": "fr", "/document/rating": float64(4)}
+	resolve := func(p string) interface{} { return values[p] }
+	cases := map[string]interface{}{
+		"= $(/document/lang) == 'fr'":                                   true,
+		"= $(/document/lang) != 'fr' || $(/document/rating) > 3":        true,
+		"= !($(/document/rating) >= 5) && $(/document/missing) == null": true,
+		"= $(/document/rating) * 2 + 1":                                 float64(9),
+		"= 'a' + 'b'":                                                   "ab",
+		"= 7 % 4":                                                       float64(3),
+	}
+	for expr, want := range cases {
+		got, err := evalSkillExpression(expr, resolve)
+		if err != nil || got != want {
+			t.Errorf("%s = %v, %v; want %v", expr, got, err, want)
+		}
+	}
+	for _, bad := range []string{"= 1 +", "= (1", "= 'x", "= 1 / 0"} {
+		if _, err := evalSkillExpression(bad, resolve); err == nil {
+			t.Errorf("%s should fail", bad)
+		}
+	}
+}
+
+func TestSkillset_Enrich(t *testing.T) {
+	t.Parallel()
+	def, err := parseSkillsetDefinition(`{"name":"ss","skills":[
+		{"@odata.type":"#Microsoft.Skills.Text.LanguageDetectionSkill","name":"lang","context":"/document/pages/*",
+			"inputs":[{"name":"text","source":"/document/pages/*"}],"outputs":[{"name":"languageCode","targetName":"language"}]},
+		{"@odata.type":"#Microsoft.Skills.Text.SplitSkill","name":"split","textSplitMode":"sentences",
+			"inputs":[{"name":"text","source":"/document/content"}],"outputs":[{"name":"textItems","targetName":"pages"}]},
+		{"@odata.type":"#Microsoft.Skills.Util.ConditionalSkill","name":"title",
+			"inputs":[{"name":"condition","source":"= $(/document/title) == null"},
+				{"name":"whenTrue","source":"= 'untitled'"},{"name":"whenFalse","source":"= $(/document/title)"}],
+			"outputs":[{"name":"output","targetName":"displayTitle"}]},
+		{"@odata.type":"#Microsoft.Skills.Util.ShaperSkill","name":"shape",
+			"inputs":[{"name":"title","source":"/document/displayTitle"},
+				{"name":"pages","sourceContext":"/document/pages/*","inputs":[
+					{"name":"text","source":"/document/pages/*"},{"name":"lang","source":"/document/pages/*/language"}]}],
+			"outputs":[{"name":"output","targetName":"summary"}]},
+		{"@odata.type":"#Microsoft.Skills.Text.TranslationSkill","name":"translate","defaultToLanguageCode":"en",
+			"inputs":[{"name":"text","source":"/document/missing"}],"outputs":[{"name":"translatedText"}]}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := def.validate("ss"); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	tree := newEnrichmentTree(map[string]interface{}{"content": "The room is clean and the staff is friendly. Le lit est grand et la chambre est calme."})
+
+	out := def.enrich(context.Background(), []*enrichmentTree{tree})
+	warnings, errs := out[0].warnings, out[0].errs
+	if len(errs) != 0 {
+		t.Fatalf("errors = %v", errs)
+	}
+	if len(warnings) != 1 || warnings[0].skill != "translate" {
+		t.Errorf("warnings = %v, want one for the translation skill's missing input", warnings)
+	}
+	if v, _ := tree.resolve("/document/pages/*/language"); !reflect.DeepEqual(v, []interface{}{"en", "fr"}) {
+		t.Errorf("/document/pages/*/language = %v", v)
+	}
+	summary, _ := tree.resolve("/document/summary")
+	want := map[string]interface{}{
+		"title": "untitled",
+		"pages": []interface{}{
+			map[string]interface{}{"text": "The room is clean and the staff is friendly.", "lang": "en"},
+			map[string]interface{}{"text": "Le lit est grand et la chambre est calme.", "lang": "fr"},
+		},
+	}
+	if !reflect.DeepEqual(summary, want) {
+		t.Errorf("/document/summary = %v, want %v", summary, want)
+	}
+}
@@ -0,0 +1,141 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// enrichmentMessage is an error or warning raised while enriching a
+// document, attributed to the skill that raised it.
+type enrichmentMessage struct {
+	skill   string
+	message string
+}
+
+// enrichment is the outcome of enriching one document.
+type enrichment struct {
+	warnings, errs []enrichmentMessage
+}
+
+// enrich runs the skillset over the enrichment trees of a batch of
+// documents. Each skill runs once per instance of its context, reading its
+// inputs relative to the instance and writing its outputs below it; the
+// records of every document are passed to the skill together, so skills
+// such as WebApiSkill batch them across documents. A skill whose required
+// inputs are missing at an instance is skipped there with a warning. Errors
+// mean the document cannot be indexed.
+func (d *skillsetDefinition) enrich(ctx context.Context, trees []*enrichmentTree) []enrichment {
+	outcomes := make([]enrichment, len(trees))
+	skills, err := d.ordered()
+	if err != nil {
+		for i := range outcomes {
+			outcomes[i].errs = []enrichmentMessage{{message: err.Error()}}
+		}
+		return outcomes
+	}
+	type instanceRef struct {
+		doc      int
+		instance []string
+	}
+	for _, sk := range skills {
+		typ := skillTypes[sk.ODataType]
+		contextSegments := splitEnrichmentPath(sk.context())
+		var refs []instanceRef
+		var records []map[string]interface{}
+		for doc, tree := range trees {
+			for _, inst := range tree.instances(sk.context()) {
+				in, err := skillInputs(tree, sk.Inputs, contextSegments, inst)
+				if err != nil {
+					outcomes[doc].errs = append(outcomes[doc].errs, enrichmentMessage{sk.Name, err.Error()})
+					continue
+				}
+				if missing := missingInputs(typ.required, in); len(missing) > 0 {
+					outcomes[doc].warnings = append(outcomes[doc].warnings, enrichmentMessage{sk.Name, fmt.Sprintf("Could not execute skill because one or more skill input was invalid: required skill input was missing: %s", strings.Join(missing, ", "))})
+					continue
+				}
+				refs = append(refs, instanceRef{doc, inst})
+				records = append(records, in)
+			}
+		}
+		if len(records) == 0 {
+			continue
+		}
+		for i, r := range typ.run(ctx, sk, records) {
+			o := &outcomes[refs[i].doc]
+			for _, m := range r.warnings {
+				o.warnings = append(o.warnings, enrichmentMessage{sk.Name, m})
+			}
+			if len(r.errors) > 0 {
+				for _, m := range r.errors {
+					o.errs = append(o.errs, enrichmentMessage{sk.Name, m})
+				}
+				continue
+			}
+			for _, out := range sk.Outputs {
+				if v, ok := r.outputs[out.Name]; ok {
+					trees[refs[i].doc].set(refs[i].instance, out.target(), v)
+				}
+			}
+		}
+	}
+	return outcomes
+}
+
+// skillInputs evaluates inputs at a context instance. Inputs whose source
+// does not exist are left out.
+func skillInputs(tree *enrichmentTree, inputs []skillInput, context, instance []string) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(inputs))
+	for _, in := range inputs {
+		v, ok, err := skillInputValue(tree, in, context, instance)
+		if err != nil {
+			return nil, fmt.Errorf("input '%s': %v", in.Name, err)
+		}
+		if ok {
+			out[in.Name] = v
+		}
+	}
+	return out, nil
+}
+
+func skillInputValue(tree *enrichmentTree, in skillInput, context, instance []string) (interface{}, bool, error) {
+	resolve := func(path string) (interface{}, bool) {
+		return tree.resolve(bind(path, context, instance))
+	}
+	switch {
+	case len(in.Inputs) > 0 && in.SourceContext != "":
+		// One object per instance of sourceContext below this instance.
+		subContext := splitEnrichmentPath(in.SourceContext)
+		var items []interface{}
+		for _, sub := range tree.instances(bind(in.SourceContext, context, instance)) {
+			obj, err := skillInputs(tree, in.Inputs, subContext, sub)
+			if err != nil {
+				return nil, false, err
+			}
+			items = append(items, obj)
+		}
+		return items, true, nil
+	case len(in.Inputs) > 0:
+		obj, err := skillInputs(tree, in.Inputs, context, instance)
+		return obj, err == nil, err
+	case strings.HasPrefix(in.Source, "="):
+		v, err := evalSkillExpression(in.Source, func(path string) interface{} {
+			v, _ := resolve(path)
+			return v
+		})
+		return v, err == nil, err
+	}
+	v, ok := resolve(in.Source)
+	return v, ok, nil
+}
+
+// missingInputs returns the required inputs whose source does not exist.
+func missingInputs(required []string, in map[string]interface{}) []string {
+	var missing []string
+	for _, r := range required {
+		if _, ok := in[r]; !ok {
+			missing = append(missing, r)
+		}
+	}
+	return missing
+}
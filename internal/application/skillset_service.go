@@ -0,0 +1,389 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"ai-search-emulator/internal/domain"
+)
+
+// skillsetDefinition is the parsed form of domain.Skillset.Definition.
+type skillsetDefinition struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Skills      []*skillDefinition `json:"skills"`
+	// IndexProjections is written after the skills have run.
+	IndexProjections *indexProjections `json:"indexProjections"`
+}
+
+// parseSkillsetDefinition parses a stored or submitted skillset. Skills
+// without a name are named "#1", "#2", ... by position, as Azure does.
+func parseSkillsetDefinition(definition string) (*skillsetDefinition, error) {
+	var d skillsetDefinition
+	if err := json.Unmarshal([]byte(definition), &d); err != nil {
+		return nil, fmt.Errorf("%w: the request body is not valid JSON", domain.ErrInvalidSkillset)
+	}
+	for i, sk := range d.Skills {
+		if sk != nil && sk.Name == "" {
+			sk.Name = fmt.Sprintf("#%d", i+1)
+		}
+	}
+	return &d, nil
+}
+
+type SkillsetService struct {
+	Repo domain.SkillsetRepository
+}
+
+func NewSkillsetService(repo domain.SkillsetRepository) *SkillsetService {
+	return &SkillsetService{Repo: repo}
+}
+
+// CreateSkillset stores a new skillset.
+func (s *SkillsetService) CreateSkillset(ctx context.Context, body []byte) (*domain.Skillset, error) {
+	def, err := parseSkillsetDefinition(string(body))
+	if err != nil {
+		return nil, err
+	}
+	if err := def.validate(def.Name); err != nil {
+		return nil, err
+	}
+	if _, err := s.Repo.FindByName(def.Name); err == nil {
+		return nil, domain.ErrSkillsetAlreadyExists
+	} else if !errors.Is(err, domain.ErrSkillsetNotFound) {
+		return nil, err
+	}
+	ss := &domain.Skillset{Name: def.Name, Definition: string(body), ETag: newETag()}
+	if err := s.Repo.Create(ss); err != nil {
+		return nil, err
+	}
+	return ss, nil
+}
+
+// CreateOrUpdateSkillset upserts a skillset. Returns true if the skillset
+// was newly created.
+func (s *SkillsetService) CreateOrUpdateSkillset(ctx context.Context, name string, body []byte, cond AccessCondition) (*domain.Skillset, bool, error) {
+	def, err := parseSkillsetDefinition(string(body))
+	if err != nil {
+		return nil, false, err
+	}
+	if def.Name != "" && def.Name != name {
+		return nil, false, fmt.Errorf("%w: the skillset name '%s' in the request body does not match the name '%s' in the request URL", domain.ErrInvalidSkillset, def.Name, name)
+	}
+	if err := def.validate(name); err != nil {
+		return nil, false, err
+	}
+
+	ss, err := s.Repo.FindByName(name)
+	if err != nil && !errors.Is(err, domain.ErrSkillsetNotFound) {
+		return nil, false, err
+	}
+	exists := err == nil
+	current := ""
+	if exists {
+		current = ss.ETag
+	}
+	if err := cond.check(exists, current); err != nil {
+		return nil, false, err
+	}
+
+	if exists {
+		ss.Definition = string(body)
+		ss.ETag = newETag()
+		if cond != (AccessCondition{}) {
+			return ss, false, s.Repo.UpdateIfMatch(ss, current)
+		}
+		return ss, false, s.Repo.Update(ss)
+	}
+	ss = &domain.Skillset{Name: name, Definition: string(body), ETag: newETag()}
+	return ss, true, s.Repo.Create(ss)
+}
+
+func (s *SkillsetService) GetSkillset(ctx context.Context, name string) (map[string]interface{}, error) {
+	ss, err := s.Repo.FindByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return skillsetResponse(ss)
+}
+
+func (s *SkillsetService) ListSkillsets(ctx context.Context) ([]map[string]interface{}, error) {
+	list, err := s.Repo.List()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]map[string]interface{}, 0, len(list))
+	for _, ss := range list {
+		m, err := skillsetResponse(ss)
+		if err != nil {
+			continue // 定義不正はスキップ
+		}
+		result = append(result, m)
+	}
+	return result, nil
+}
+
+// DeleteSkillset deletes a skillset. When cond is set, the current ETag must
+// satisfy it or domain.ErrPreconditionFailed is returned.
+func (s *SkillsetService) DeleteSkillset(ctx context.Context, name string, cond AccessCondition) error {
+	if cond != (AccessCondition{}) {
+		ss, err := s.Repo.FindByName(name)
+		if err != nil && !errors.Is(err, domain.ErrSkillsetNotFound) {
+			return err
+		}
+		current := ""
+		if err == nil {
+			current = ss.ETag
+		}
+		if err := cond.check(err == nil, current); err != nil {
+			return err
+		}
+		if err == nil {
+			return s.Repo.DeleteIfMatch(name, current)
+		}
+	}
+	return s.Repo.Delete(name)
+}
+
+// load returns the parsed definition of a stored skillset.
+func (s *SkillsetService) load(name string) (*skillsetDefinition, error) {
+	ss, err := s.Repo.FindByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return parseSkillsetDefinition(ss.Definition)
+}
+
+// skillsetResponse renders a stored skillset with the names assigned to
+// unnamed skills and @odata.etag.
+func skillsetResponse(ss *domain.Skillset) (map[string]interface{}, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(ss.Definition), &m); err != nil {
+		return nil, fmt.Errorf("definition parse error")
+	}
+	m["name"] = ss.Name
+	if skills, ok := m["skills"].([]interface{}); ok {
+		for i, sk := range skills {
+			if obj, ok := sk.(map[string]interface{}); ok {
+				if name, _ := obj["name"].(string); name == "" {
+					obj["name"] = fmt.Sprintf("#%d", i+1)
+				}
+			}
+		}
+	}
+	m["@odata.etag"] = ss.ETag
+	return m, nil
+}
+
+// validate checks a skillset addressed by name, including that its skills
+// can be ordered by their dependencies.
+func (d *skillsetDefinition) validate(name string) error {
+	if err := validateResourceName("skillset", name); err != nil {
+		return err
+	}
+	if len(d.Skills) == 0 {
+		return fmt.Errorf("%w: the skillset must contain at least one skill", domain.ErrInvalidSkillset)
+	}
+	names := map[string]bool{}
+	for _, sk := range d.Skills {
+		if sk == nil {
+			return fmt.Errorf("%w: skills must not contain null entries", domain.ErrInvalidSkillset)
+		}
+		if names[sk.Name] {
+			return fmt.Errorf("%w: the skill name '%s' is used more than once", domain.ErrInvalidSkillset, sk.Name)
+		}
+		names[sk.Name] = true
+		if err := sk.validate(); err != nil {
+			return fmt.Errorf("%w: skill '%s': %v", domain.ErrInvalidSkillset, sk.Name, err)
+		}
+	}
+	if _, err := d.ordered(); err != nil {
+		return err
+	}
+	if d.IndexProjections != nil {
+		return d.IndexProjections.validate()
+	}
+	return nil
+}
+
+func (sk *skillDefinition) validate() error {
+	typ, ok := skillTypes[sk.ODataType]
+	if !ok {
+		return fmt.Errorf("the skill type '%s' is not supported", sk.ODataType)
+	}
+	if !isDocumentPath(sk.context()) {
+		return fmt.Errorf("context '%s' must be a path starting with /document", sk.Context)
+	}
+	given := map[string]bool{}
+	for _, in := range sk.Inputs {
+		if err := in.validate(); err != nil {
+			return err
+		}
+		given[in.Name] = true
+	}
+	for _, r := range typ.required {
+		if !given[r] {
+			return fmt.Errorf("the required input '%s' is missing", r)
+		}
+	}
+	if len(sk.Outputs) == 0 {
+		return fmt.Errorf("the skill must have at least one output")
+	}
+	targets := map[string]bool{}
+	for _, out := range sk.Outputs {
+		if typ.outputs != nil && !containsString(typ.outputs, out.Name) {
+			return fmt.Errorf("the output '%s' is not supported; supported outputs are %s", out.Name, strings.Join(typ.outputs, ", "))
+		}
+		if targets[out.target()] {
+			return fmt.Errorf("the output target '%s' is used more than once", out.target())
+		}
+		targets[out.target()] = true
+	}
+	if typ.validate != nil {
+		return typ.validate(sk)
+	}
+	return nil
+}
+
+func (in skillInput) validate() error {
+	if in.Name == "" {
+		return fmt.Errorf("inputs must specify a name")
+	}
+	if len(in.Inputs) > 0 {
+		if in.SourceContext != "" && !isDocumentPath(in.SourceContext) {
+			return fmt.Errorf("the sourceContext of input '%s' must be a path starting with /document", in.Name)
+		}
+		for _, sub := range in.Inputs {
+			if err := sub.validate(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if in.Source == "" {
+		return fmt.Errorf("the input '%s' must specify a source", in.Name)
+	}
+	if strings.HasPrefix(in.Source, "=") {
+		if _, err := evalSkillExpression(in.Source, func(string) interface{} { return nil }); err != nil {
+			return fmt.Errorf("the input '%s' has an invalid expression: %v", in.Name, err)
+		}
+		return nil
+	}
+	if !isDocumentPath(in.Source) {
+		return fmt.Errorf("the source of input '%s' must be a path starting with /document or an expression starting with '='", in.Name)
+	}
+	return nil
+}
+
+func isDocumentPath(path string) bool {
+	return path == "/document" || strings.HasPrefix(path, "/document/")
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ordered returns the skills in an order where each runs after the skills
+// producing its inputs, keeping definition order otherwise. Circular
+// dependencies are rejected.
+func (d *skillsetDefinition) ordered() ([]*skillDefinition, error) {
+	deps := make([][]int, len(d.Skills))
+	for i, sk := range d.Skills {
+		reads := sk.reads()
+		for j, other := range d.Skills {
+			if i == j {
+				continue
+			}
+			for _, out := range other.Outputs {
+				produced := splitEnrichmentPath(other.context() + "/" + out.target())
+				if readsPath(reads, produced) {
+					deps[i] = append(deps[i], j)
+					break
+				}
+			}
+		}
+	}
+	done := make([]bool, len(d.Skills))
+	out := make([]*skillDefinition, 0, len(d.Skills))
+	for len(out) < len(d.Skills) {
+		progressed := false
+		for i, sk := range d.Skills {
+			if done[i] {
+				continue
+			}
+			ready := true
+			for _, j := range deps[i] {
+				if !done[j] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				done[i] = true
+				out = append(out, sk)
+				progressed = true
+				break
+			}
+		}
+		if !progressed {
+			return nil, fmt.Errorf("%w: the skills have a circular dependency", domain.ErrInvalidSkillset)
+		}
+	}
+	return out, nil
+}
+
+// reads returns the enrichment paths a skill's inputs read.
+func (sk *skillDefinition) reads() [][]string {
+	var paths [][]string
+	var walk func(inputs []skillInput)
+	walk = func(inputs []skillInput) {
+		for _, in := range inputs {
+			switch {
+			case len(in.Inputs) > 0:
+				if in.SourceContext != "" {
+					paths = append(paths, splitEnrichmentPath(in.SourceContext))
+				}
+				walk(in.Inputs)
+			case strings.HasPrefix(in.Source, "="):
+				for _, p := range expressionPaths(in.Source) {
+					paths = append(paths, splitEnrichmentPath(p))
+				}
+			default:
+				paths = append(paths, splitEnrichmentPath(in.Source))
+			}
+		}
+	}
+	walk(sk.Inputs)
+	if strings.Count(sk.context(), "/") > 1 {
+		paths = append(paths, splitEnrichmentPath(sk.context()))
+	}
+	return paths
+}
+
+// readsPath reports whether any of the paths lies at or below produced.
+func readsPath(paths [][]string, produced []string) bool {
+	for _, p := range paths {
+		if len(p) < len(produced) {
+			continue
+		}
+		match := true
+		for i, s := range produced {
+			if p[i] != s {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
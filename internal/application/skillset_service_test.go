@@ -0,0 +1,133 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"ai-search-emulator/internal/domain"
+)
+
+const validSkillsetJSON = `{
+	"name": "enrich",
+	"skills": [
+		{
+			"@odata.type": "#Microsoft.Skills.Text.LanguageDetectionSkill",
+			"inputs": [{"name": "text", "source": "/document/content"}],
+			"outputs": [{"name": "languageCode", "targetName": "language"}]
+		},
+		{
+			"@odata.type": "#Microsoft.Skills.Text.SplitSkill",
+			"name": "split",
+			"textSplitMode": "pages",
+			"maximumPageLength": 300,
+			"inputs": [{"name": "text", "source": "/document/content"}],
+			"outputs": [{"name": "textItems", "targetName": "pages"}]
+		}
+	]
+}`
+
+func TestSkillsetService_CRUD(t *testing.T) {
+	t.Parallel()
+	svc := NewSkillsetService(newMockSkillsetRepository())
+	ctx := context.Background()
+
+	ss, err := svc.CreateSkillset(ctx, []byte(validSkillsetJSON))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := svc.CreateSkillset(ctx, []byte(validSkillsetJSON)); !errors.Is(err, domain.ErrSkillsetAlreadyExists) {
+		t.Errorf("second create: err = %v, want ErrSkillsetAlreadyExists", err)
+	}
+
+	got, err := svc.GetSkillset(ctx, "enrich")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	skills := got["skills"].([]interface{})
+	if skills[0].(map[string]interface{})["name"] != "#1" || skills[1].(map[string]interface{})["name"] != "split" {
+		t.Errorf("unnamed skills should be named by position: %v", skills)
+	}
+	if got["@odata.etag"] != ss.ETag {
+		t.Errorf("@odata.etag = %v, want %s", got["@odata.etag"], ss.ETag)
+	}
+
+	if _, _, err := svc.CreateOrUpdateSkillset(ctx, "enrich", []byte(validSkillsetJSON), AccessCondition{IfMatch: `"stale"`}); !errors.Is(err, domain.ErrPreconditionFailed) {
+		t.Errorf("stale If-Match: err = %v, want ErrPreconditionFailed", err)
+	}
+	if _, created, err := svc.CreateOrUpdateSkillset(ctx, "enrich", []byte(validSkillsetJSON), AccessCondition{IfMatch: ss.ETag}); err != nil || created {
+		t.Errorf("update: created = %v, err = %v", created, err)
+	}
+	if _, _, err := svc.CreateOrUpdateSkillset(ctx, "other", []byte(validSkillsetJSON), AccessCondition{}); !errors.Is(err, domain.ErrInvalidSkillset) {
+		t.Errorf("name mismatch: err = %v, want ErrInvalidSkillset", err)
+	}
+	if list, _ := svc.ListSkillsets(ctx); len(list) != 1 {
+		t.Errorf("list = %v, want one skillset", list)
+	}
+	if err := svc.DeleteSkillset(ctx, "enrich", AccessCondition{}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := svc.GetSkillset(ctx, "enrich"); !errors.Is(err, domain.ErrSkillsetNotFound) {
+		t.Errorf("get after delete: err = %v, want ErrSkillsetNotFound", err)
+	}
+}
+
+func TestSkillsetService_Validation(t *testing.T) {
+	t.Parallel()
+	split := func(extra string) string {
+		return `{"name":"ss","skills":[{"@odata.type":"#Microsoft.Skills.Text.SplitSkill",` + extra +
+			`"inputs":[{"name":"text","source":"/document/content"}],"outputs":[{"name":"textItems"}]}]}`
+	}
+	cases := []struct {
+		name string
+		body string
+		want error
+	}{
+		{"bad name", `{"name":"S!","skills":[]}`, domain.ErrInvalidName},
+		{"no skills", `{"name":"ss","skills":[]}`, domain.ErrInvalidSkillset},
+		{"unknown type", `{"name":"ss","skills":[{"@odata.type":"#Microsoft.Skills.Vision.OcrSkill","inputs":[],"outputs":[{"name":"text"}]}]}`, domain.ErrInvalidSkillset},
+		{"missing input", `{"name":"ss","skills":[{"@odata.type":"#Microsoft.Skills.Text.SplitSkill","inputs":[],"outputs":[{"name":"textItems"}]}]}`, domain.ErrInvalidSkillset},
+		{"unknown output", `{"name":"ss","skills":[{"@odata.type":"#Microsoft.Skills.Text.SplitSkill","inputs":[{"name":"text","source":"/document/content"}],"outputs":[{"name":"chunks"}]}]}`, domain.ErrInvalidSkillset},
+		{"bad context", split(`"context":"/content",`), domain.ErrInvalidSkillset},
+		{"bad split mode", split(`"textSplitMode":"words",`), domain.ErrInvalidSkillset},
+		{"short pages", split(`"maximumPageLength":100,`), domain.ErrInvalidSkillset},
+		{"large overlap", split(`"maximumPageLength":300,"pageOverlapLength":150,`), domain.ErrInvalidSkillset},
+		{"bad source", `{"name":"ss","skills":[{"@odata.type":"#Microsoft.Skills.Text.SplitSkill","inputs":[{"name":"text","source":"content"}],"outputs":[{"name":"textItems"}]}]}`, domain.ErrInvalidSkillset},
+		{"bad expression", `{"name":"ss","skills":[{"@odata.type":"#Microsoft.Skills.Util.ConditionalSkill","inputs":[{"name":"condition","source":"= $(/document/a) =="},{"name":"whenTrue","source":"= 1"},{"name":"whenFalse","source":"= 2"}],"outputs":[{"name":"output"}]}]}`, domain.ErrInvalidSkillset},
+		{"duplicate names", `{"name":"ss","skills":[
+			{"@odata.type":"#Microsoft.Skills.Util.ShaperSkill","name":"a","inputs":[{"name":"x","source":"/document/x"}],"outputs":[{"name":"output","targetName":"o1"}]},
+			{"@odata.type":"#Microsoft.Skills.Util.ShaperSkill","name":"a","inputs":[{"name":"x","source":"/document/x"}],"outputs":[{"name":"output","targetName":"o2"}]}]}`, domain.ErrInvalidSkillset},
+		{"cycle", `{"name":"ss","skills":[
+			{"@odata.type":"#Microsoft.Skills.Util.ShaperSkill","inputs":[{"name":"x","source":"/document/b"}],"outputs":[{"name":"output","targetName":"a"}]},
+			{"@odata.type":"#Microsoft.Skills.Util.ShaperSkill","inputs":[{"name":"x","source":"/document/a"}],"outputs":[{"name":"output","targetName":"b"}]}]}`, domain.ErrInvalidSkillset},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			svc := NewSkillsetService(newMockSkillsetRepository())
+			if _, err := svc.CreateSkillset(context.Background(), []byte(tc.body)); !errors.Is(err, tc.want) {
+				t.Errorf("err = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestSkillsetDefinition_OrderedByDependencies(t *testing.T) {
+	t.Parallel()
+	def, err := parseSkillsetDefinition(`{"name":"ss","skills":[
+		{"@odata.type":"#Microsoft.Skills.Text.LanguageDetectionSkill","name":"lang","context":"/document/pages/*",
+			"inputs":[{"name":"text","source":"/document/pages/*"}],"outputs":[{"name":"languageCode"}]},
+		{"@odata.type":"#Microsoft.Skills.Text.SplitSkill","name":"split","maximumPageLength":300,
+			"inputs":[{"name":"text","source":"/document/content"}],"outputs":[{"name":"textItems","targetName":"pages"}]}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ordered, err := def.ordered()
+	if err != nil {
+		t.Fatalf("ordered: %v", err)
+	}
+	if ordered[0].Name != "split" || ordered[1].Name != "lang" {
+		t.Errorf("order = %s, %s; want split before lang", ordered[0].Name, ordered[1].Name)
+	}
+}
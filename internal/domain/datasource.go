@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrDataSourceNotFound = errors.New("data source not found")
+var ErrDataSourceAlreadyExists = errors.New("data source already exists")
+var ErrInvalidDataSource = errors.New("invalid data source")
+
+type DataSource struct {
+	Name       string
+	Definition string // JSON文字列で保持
+	ETag       string
+}
+
+type DataSourceRepository interface {
+	Create(ds *DataSource) error
+	Update(ds *DataSource) error
+	// UpdateIfMatch updates the data source only if its stored ETag is still
+	// etag and returns ErrPreconditionFailed otherwise.
+	UpdateIfMatch(ds *DataSource, etag string) error
+	FindByName(name string) (*DataSource, error)
+	List() ([]*DataSource, error)
+	Delete(name string) error
+	// DeleteIfMatch is the conditional counterpart of Delete.
+	DeleteIfMatch(name, etag string) error
+}
+
+// DataSourceConfig is the connection information of a data source
+// definition, as consumed by a SourceConnector.
+type DataSourceConfig struct {
+	Type             string
+	ConnectionString string
+	Container        string
+	Query            string
+}
+
+// SourceItem is one item read from a data source: a blob or a table row.
+type SourceItem struct {
+	Key     string                 // blob path or primary key value, unique within the source
+	Fields  map[string]interface{} // row columns, or metadata_* properties of a blob
+	Content []byte                 // blob content; nil for rows
+}
+
+// SourceConnector reads items from one kind of data source.
+type SourceConnector interface {
+	// ValidateCredentials checks the connection string without reading data.
+	// Failures wrap ErrInvalidDataSource.
+	ValidateCredentials(cfg DataSourceConfig) error
+	// Read returns every item in the configured container.
+	Read(ctx context.Context, cfg DataSourceConfig) ([]SourceItem, error)
+}
@@ -4,6 +4,10 @@ import "errors"
 
 var ErrDocumentNotFound = errors.New("document not found")
 var ErrMissingKeyField = errors.New("missing key field")
+var ErrBatchTooLarge = errors.New("batch too large")
+var ErrInvalidDocumentKey = errors.New("invalid document key")
+var ErrInvalidSearchParameter = errors.New("invalid search parameter")
+var ErrInvalidSelect = errors.New("invalid $select")
 
 type Document struct {
 	IndexName string
@@ -22,6 +26,15 @@ type SearchOptions struct {
 	OrderSQL         string        // compiled OData $orderby SQL fragment (no ORDER BY keyword)
 	Top              int           // must be > 0 (caller is responsible for applying the default)
 	Skip             int
+	Unpaged          bool // return every match, ignoring Top/Skip; the caller sorts and pages in memory
+}
+
+// DocumentBatch reads and writes one index's documents inside a
+// DocumentRepository.Batch unit of work.
+type DocumentBatch interface {
+	Find(key string) (*Document, error)
+	Upsert(key, content string) error
+	Delete(key string) error
 }
 
 type DocumentRepository interface {
@@ -32,4 +45,7 @@ type DocumentRepository interface {
 	Count(indexName string) (int, error)
 	// Search returns paginated documents matching opts and the total count before paging.
 	Search(indexName string, opts SearchOptions) ([]*Document, int64, error)
+	// Batch runs fn as a single atomic unit of work against indexName. If fn
+	// returns an error, none of its changes are applied.
+	Batch(indexName string, fn func(b DocumentBatch) error) error
 }
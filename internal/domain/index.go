@@ -4,17 +4,29 @@ import "errors"
 
 var ErrIndexNotFound = errors.New("index not found")
 var ErrIndexAlreadyExists = errors.New("index already exists")
+var ErrPreconditionFailed = errors.New("precondition failed")
+var ErrCannotChangeExistingField = errors.New("cannot change existing field")
+var ErrIndexDowntimeRequired = errors.New("index update requires downtime")
+var ErrIndexUnavailable = errors.New("index is temporarily unavailable")
+var ErrInvalidName = errors.New("invalid name")
+var ErrInvalidIndexDefinition = errors.New("invalid index definition")
 
 type Index struct {
 	Name   string
 	Schema string // JSON文字列で保持
+	ETag   string // 楽観的同時実行制御用 (例: "0x8DC...")
 }
 
 type IndexRepository interface {
 	Create(index *Index) error
 	Update(index *Index) error
+	// UpdateIfMatch updates the index only if its stored ETag is still etag and
+	// returns ErrPreconditionFailed otherwise.
+	UpdateIfMatch(index *Index, etag string) error
 	FindByName(name string) (*Index, error)
 	Exists(name string) (bool, error)
 	List() ([]*Index, error)
 	Delete(name string) error
+	// DeleteIfMatch is the conditional counterpart of Delete.
+	DeleteIfMatch(name, etag string) error
 }
@@ -0,0 +1,35 @@
+package domain
+
+import "errors"
+
+var ErrIndexerNotFound = errors.New("indexer not found")
+var ErrIndexerAlreadyExists = errors.New("indexer already exists")
+var ErrInvalidIndexer = errors.New("invalid indexer")
+var ErrIndexerRunning = errors.New("indexer is already running")
+
+type Indexer struct {
+	Name       string
+	Definition string // JSON文字列で保持
+	ETag       string
+}
+
+// IndexerRepository stores indexer definitions and, separately, their
+// execution status so that redefining an indexer keeps its history.
+type IndexerRepository interface {
+	Create(indexer *Indexer) error
+	Update(indexer *Indexer) error
+	// UpdateIfMatch updates the indexer only if its stored ETag is still etag and
+	// returns ErrPreconditionFailed otherwise.
+	UpdateIfMatch(indexer *Indexer, etag string) error
+	FindByName(name string) (*Indexer, error)
+	List() ([]*Indexer, error)
+	// Delete removes the indexer and its status.
+	Delete(name string) error
+	// DeleteIfMatch deletes the indexer and its status only if its stored
+	// ETag is still etag and returns ErrPreconditionFailed otherwise.
+	DeleteIfMatch(name, etag string) error
+	// FindStatus returns the stored status JSON, or "" if the indexer has
+	// never run.
+	FindStatus(name string) (string, error)
+	SaveStatus(name string, status string) error
+}
@@ -0,0 +1,28 @@
+package domain
+
+import "errors"
+
+var ErrKeyNotFound = errors.New("key not found")
+var ErrQueryKeyLimit = errors.New("query key limit reached")
+var ErrInvalidKeyKind = errors.New("invalid key kind")
+
+// KeyKind identifies the access level granted by an api-key.
+type KeyKind int
+
+const (
+	KeyNone  KeyKind = iota // not a valid key
+	KeyAdmin                // full access to the service
+	KeyQuery                // read-only access to documents
+)
+
+// QueryKey is a named read-only api-key.
+type QueryKey struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+}
+
+// AdminKeys holds the primary and secondary admin api-keys.
+type AdminKeys struct {
+	PrimaryKey   string `json:"primaryKey"`
+	SecondaryKey string `json:"secondaryKey"`
+}
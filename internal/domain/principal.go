@@ -0,0 +1,24 @@
+package domain
+
+import "errors"
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// Azure AI Search built-in roles that can be granted to a bearer token.
+const (
+	RoleSearchServiceContributor   = "Search Service Contributor"
+	RoleSearchIndexDataContributor = "Search Index Data Contributor"
+	RoleSearchIndexDataReader      = "Search Index Data Reader"
+)
+
+// Principal is the identity carried by a validated bearer token.
+type Principal struct {
+	Subject string
+	Roles   []string
+}
+
+// TokenVerifier validates bearer tokens. Invalid, expired or untrusted
+// tokens return an error wrapping ErrInvalidToken.
+type TokenVerifier interface {
+	Verify(token string) (*Principal, error)
+}
@@ -0,0 +1,26 @@
+package domain
+
+import "errors"
+
+var ErrSkillsetNotFound = errors.New("skillset not found")
+var ErrSkillsetAlreadyExists = errors.New("skillset already exists")
+var ErrInvalidSkillset = errors.New("invalid skillset")
+
+type Skillset struct {
+	Name       string
+	Definition string // JSON文字列で保持
+	ETag       string
+}
+
+type SkillsetRepository interface {
+	Create(skillset *Skillset) error
+	Update(skillset *Skillset) error
+	// UpdateIfMatch updates the skillset only if its stored ETag is still etag and
+	// returns ErrPreconditionFailed otherwise.
+	UpdateIfMatch(skillset *Skillset, etag string) error
+	FindByName(name string) (*Skillset, error)
+	List() ([]*Skillset, error)
+	Delete(name string) error
+	// DeleteIfMatch is the conditional counterpart of Delete.
+	DeleteIfMatch(name, etag string) error
+}
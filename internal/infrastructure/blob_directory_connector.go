@@ -0,0 +1,170 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"ai-search-emulator/internal/domain"
+)
+
+// devStorageAccount is the account name of the Azure Storage emulator.
+const devStorageAccount = "devstoreaccount1"
+
+// BlobDirectoryConnector serves azureblob and adlsgen2 data sources from the
+// local file system. A container is a directory and blobs are the files
+// below it; container.query selects a virtual folder prefix.
+//
+// The connection string may name a directory directly with
+// "LocalPath=<dir>". Azure-style connection strings (AccountName/AccountKey,
+// UseDevelopmentStorage=true or ResourceId) resolve to <Root>/<account>.
+type BlobDirectoryConnector struct {
+	Root string
+}
+
+func NewBlobDirectoryConnector(root string) *BlobDirectoryConnector {
+	return &BlobDirectoryConnector{Root: root}
+}
+
+// blobAccount describes where an account's containers live and the URL
+// reported in metadata_storage_path.
+type blobAccount struct {
+	dir      string
+	endpoint string
+}
+
+func (c *BlobDirectoryConnector) account(connectionString string) (blobAccount, error) {
+	cs := parseConnectionString(connectionString)
+	switch {
+	case cs["localpath"] != "":
+		dir, err := filepath.Abs(cs["localpath"])
+		if err != nil {
+			return blobAccount{}, err
+		}
+		return blobAccount{dir: dir, endpoint: "file://" + filepath.ToSlash(dir)}, nil
+	case strings.EqualFold(cs["usedevelopmentstorage"], "true"):
+		return blobAccount{
+			dir:      filepath.Join(c.Root, devStorageAccount),
+			endpoint: "http://127.0.0.1:10000/" + devStorageAccount,
+		}, nil
+	case cs["accountname"] != "":
+		if err := checkAccountName(cs["accountname"]); err != nil {
+			return blobAccount{}, err
+		}
+		if cs["accountkey"] == "" && cs["sharedaccesssignature"] == "" {
+			return blobAccount{}, fmt.Errorf("%w: the connection string for account '%s' must include AccountKey or SharedAccessSignature", domain.ErrInvalidDataSource, cs["accountname"])
+		}
+		endpoint := cs["blobendpoint"]
+		if endpoint == "" {
+			endpoint = "https://" + cs["accountname"] + ".blob.core.windows.net"
+		}
+		return blobAccount{dir: filepath.Join(c.Root, cs["accountname"]), endpoint: strings.TrimSuffix(endpoint, "/")}, nil
+	case cs["resourceid"] != "":
+		// Managed identity: the account is the last segment of the resource ID.
+		id := strings.TrimSuffix(cs["resourceid"], "/")
+		name := id[strings.LastIndex(id, "/")+1:]
+		if err := checkAccountName(name); err != nil {
+			return blobAccount{}, err
+		}
+		return blobAccount{dir: filepath.Join(c.Root, name), endpoint: "https://" + name + ".blob.core.windows.net"}, nil
+	}
+	return blobAccount{}, fmt.Errorf("%w: the connection string must specify LocalPath, AccountName and AccountKey, UseDevelopmentStorage=true or ResourceId", domain.ErrInvalidDataSource)
+}
+
+// checkAccountName rejects account names that would resolve outside Root.
+func checkAccountName(name string) error {
+	if name == "" || strings.Contains(name, "..") || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("%w: invalid storage account name '%s'", domain.ErrInvalidDataSource, name)
+	}
+	return nil
+}
+
+func (c *BlobDirectoryConnector) ValidateCredentials(cfg domain.DataSourceConfig) error {
+	_, err := c.account(cfg.ConnectionString)
+	return err
+}
+
+// Read lists the blobs in the container below the query prefix, in path
+// order, with Azure's metadata_storage_* properties.
+func (c *BlobDirectoryConnector) Read(ctx context.Context, cfg domain.DataSourceConfig) ([]domain.SourceItem, error) {
+	acct, err := c.account(cfg.ConnectionString)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(cfg.Container, "..") || strings.ContainsAny(cfg.Container, `/\`) {
+		return nil, fmt.Errorf("%w: invalid container name '%s'", domain.ErrInvalidDataSource, cfg.Container)
+	}
+	container := filepath.Join(acct.dir, cfg.Container)
+	if info, err := os.Stat(container); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("%w: the container '%s' does not exist", domain.ErrInvalidDataSource, cfg.Container)
+	}
+	prefix := strings.Trim(cfg.Query, "/")
+
+	var items []domain.SourceItem
+	err = filepath.WalkDir(container, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() || !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(container, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if prefix != "" && !strings.HasPrefix(name, prefix+"/") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		items = append(items, domain.SourceItem{
+			Key:     name,
+			Fields:  blobMetadata(acct.endpoint+"/"+cfg.Container+"/"+name, info, content),
+			Content: content,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Key < items[j].Key })
+	return items, nil
+}
+
+// blobMetadata returns the metadata_storage_* properties of a blob.
+func blobMetadata(url string, info fs.FileInfo, content []byte) map[string]interface{} {
+	ext := path.Ext(info.Name())
+	md5sum := md5.Sum(content)
+	contentType := mime.TypeByExtension(ext)
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return map[string]interface{}{
+		"metadata_storage_path":           url,
+		"metadata_storage_name":           info.Name(),
+		"metadata_storage_size":           info.Size(),
+		"metadata_storage_last_modified":  info.ModTime().UTC().Format(time.RFC3339Nano),
+		"metadata_storage_content_type":   contentType,
+		"metadata_storage_file_extension": ext,
+		"metadata_storage_content_md5":    base64.StdEncoding.EncodeToString(md5sum[:]),
+	}
+}
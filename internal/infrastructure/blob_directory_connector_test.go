@@ -0,0 +1,127 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ai-search-emulator/internal/domain"
+)
+
+// writeFiles creates files (relative path → content) below dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBlobDirectoryConnector_Read(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	writeFiles(t, filepath.Join(root, "acct", "docs"), map[string]string{
+		"b.json":         `{"id":"b"}`,
+		"a.txt":          "hello",
+		"reports/q1.csv": "id\n1",
+	})
+	conn := NewBlobDirectoryConnector(root)
+	cfg := domain.DataSourceConfig{Type: "azureblob", ConnectionString: "AccountName=acct;AccountKey=k", Container: "docs"}
+
+	items, err := conn.Read(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 3 || items[0].Key != "a.txt" || items[1].Key != "b.json" || items[2].Key != "reports/q1.csv" {
+		t.Fatalf("items = %+v, want a.txt, b.json, reports/q1.csv", items)
+	}
+	a := items[0]
+	if string(a.Content) != "hello" {
+		t.Errorf("content = %q, want hello", a.Content)
+	}
+	if a.Fields["metadata_storage_path"] != "https://acct.blob.core.windows.net/docs/a.txt" {
+		t.Errorf("metadata_storage_path = %v", a.Fields["metadata_storage_path"])
+	}
+	if a.Fields["metadata_storage_name"] != "a.txt" || a.Fields["metadata_storage_size"] != int64(5) ||
+		a.Fields["metadata_storage_file_extension"] != ".txt" || a.Fields["metadata_storage_content_md5"] != "XUFAKrxLKna5cZ2REBfFkg==" {
+		t.Errorf("metadata = %v", a.Fields)
+	}
+
+	cfg.Query = "reports"
+	items, err = conn.Read(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 1 || items[0].Key != "reports/q1.csv" {
+		t.Errorf("items with query = %+v, want reports/q1.csv only", items)
+	}
+}
+
+func TestBlobDirectoryConnector_LocalPathAndDevStorage(t *testing.T) {
+	t.Parallel()
+	local := t.TempDir()
+	writeFiles(t, filepath.Join(local, "c"), map[string]string{"x.txt": "x"})
+	root := t.TempDir()
+	writeFiles(t, filepath.Join(root, devStorageAccount, "c"), map[string]string{"y.txt": "y"})
+	conn := NewBlobDirectoryConnector(root)
+
+	items, err := conn.Read(context.Background(), domain.DataSourceConfig{ConnectionString: "LocalPath=" + local, Container: "c"})
+	if err != nil || len(items) != 1 || items[0].Key != "x.txt" {
+		t.Errorf("LocalPath: items = %+v, err = %v", items, err)
+	}
+	items, err = conn.Read(context.Background(), domain.DataSourceConfig{ConnectionString: "UseDevelopmentStorage=true", Container: "c"})
+	if err != nil || len(items) != 1 || items[0].Key != "y.txt" {
+		t.Errorf("UseDevelopmentStorage: items = %+v, err = %v", items, err)
+	}
+}
+
+func TestBlobDirectoryConnector_Errors(t *testing.T) {
+	t.Parallel()
+	conn := NewBlobDirectoryConnector(t.TempDir())
+	cases := []struct {
+		name string
+		cfg  domain.DataSourceConfig
+	}{
+		{"no account", domain.DataSourceConfig{ConnectionString: "Foo=bar", Container: "c"}},
+		{"no key", domain.DataSourceConfig{ConnectionString: "AccountName=acct", Container: "c"}},
+		{"missing container", domain.DataSourceConfig{ConnectionString: "AccountName=acct;AccountKey=k", Container: "c"}},
+		{"container escapes", domain.DataSourceConfig{ConnectionString: "AccountName=acct;AccountKey=k", Container: ".."}},
+	}
+	for _, tc := range cases {
+		if _, err := conn.Read(context.Background(), tc.cfg); !errors.Is(err, domain.ErrInvalidDataSource) {
+			t.Errorf("%s: err = %v, want ErrInvalidDataSource", tc.name, err)
+		}
+	}
+	if err := conn.ValidateCredentials(domain.DataSourceConfig{ConnectionString: "ResourceId=/subscriptions/s/providers/Microsoft.Storage/storageAccounts/acct"}); err != nil {
+		t.Errorf("ResourceId credentials: %v", err)
+	}
+}
+
+func TestBlobDirectoryConnector_AccountEscapesRoot(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"outside/c/secret.txt": "secret"})
+	conn := NewBlobDirectoryConnector(filepath.Join(dir, "root"))
+	for _, cs := range []string{
+		"AccountName=../outside;AccountKey=k",
+		`AccountName=..\outside;AccountKey=k`,
+		"AccountName=..;AccountKey=k",
+		"ResourceId=/subscriptions/s/providers/Microsoft.Storage/storageAccounts/..",
+		`ResourceId=/subscriptions/s/providers/Microsoft.Storage/storageAccounts/..\outside`,
+	} {
+		cfg := domain.DataSourceConfig{ConnectionString: cs, Container: "c"}
+		if err := conn.ValidateCredentials(cfg); !errors.Is(err, domain.ErrInvalidDataSource) {
+			t.Errorf("%s: ValidateCredentials err = %v, want ErrInvalidDataSource", cs, err)
+		}
+		if items, err := conn.Read(context.Background(), cfg); !errors.Is(err, domain.ErrInvalidDataSource) {
+			t.Errorf("%s: Read = %d items, err %v, want ErrInvalidDataSource", cs, len(items), err)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package infrastructure
+
+import "strings"
+
+// parseConnectionString splits a "Key=Value;Key=Value" connection string.
+// Keys are lower-cased; values keep their case and may contain '='.
+func parseConnectionString(s string) map[string]string {
+	out := map[string]string{}
+	for _, part := range strings.Split(s, ";") {
+		k, v, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		if k = strings.ToLower(strings.TrimSpace(k)); k != "" {
+			out[k] = strings.TrimSpace(v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,267 @@
+package infrastructure
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"ai-search-emulator/internal/domain"
+)
+
+// Defaults shared by the server and the token minting tool.
+const (
+	DefaultTokenIssuer   = "https://login.microsoftonline.com/emulator/v2.0"
+	DefaultTokenAudience = "https://search.azure.com"
+)
+
+// clockSkew is the leeway applied to exp and nbf checks.
+const clockSkew = 5 * time.Minute
+
+// builtInRoleIDs maps the role definition IDs of Azure's built-in search
+// roles to their names, so tokens may carry either form in the roles claim.
+var builtInRoleIDs = map[string]string{
+	"7ca78c08-252a-4471-8644-bb5ff32d4ba0": domain.RoleSearchServiceContributor,
+	"8ebe5a00-799e-43f5-93ac-243d3dce84a7": domain.RoleSearchIndexDataContributor,
+	"1407120a-92aa-4202-b7e9-c0e197c71c8f": domain.RoleSearchIndexDataReader,
+}
+
+// JWK is an RSA public key in JSON Web Key form.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWTVerifier validates RS256 bearer tokens against a local key set, the way
+// Microsoft Entra ID tokens are validated, without contacting any network
+// endpoint.
+type JWTVerifier struct {
+	issuer   string
+	audience string
+	keys     map[string]*rsa.PublicKey
+	now      func() time.Time
+}
+
+// NewJWTVerifierFromFile loads a JWKS file and returns a verifier accepting
+// tokens from issuer for audience.
+func NewJWTVerifierFromFile(path, issuer, audience string) (*JWTVerifier, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var set JWKS
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parse JWKS %s: %w", path, err)
+	}
+	return NewJWTVerifier(set, issuer, audience)
+}
+
+func NewJWTVerifier(set JWKS, issuer, audience string) (*JWTVerifier, error) {
+	v := &JWTVerifier{issuer: issuer, audience: audience, keys: map[string]*rsa.PublicKey{}, now: time.Now}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("JWK %s: %w", k.Kid, err)
+		}
+		v.keys[k.Kid] = pub
+	}
+	if len(v.keys) == 0 {
+		return nil, fmt.Errorf("the key set contains no RSA keys")
+	}
+	return v, nil
+}
+
+func (k JWK) publicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+}
+
+// JWKFromKey returns the public JWK for a private key. The kid is derived
+// from the modulus so it is stable for a given key.
+func JWKFromKey(key *rsa.PrivateKey) JWK {
+	n := key.N.Bytes()
+	sum := sha256.Sum256(n)
+	return JWK{
+		Kty: "RSA",
+		Kid: base64.RawURLEncoding.EncodeToString(sum[:12]),
+		Use: "sig",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(n),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+type jwtClaims struct {
+	Iss   string          `json:"iss"`
+	Aud   json.RawMessage `json:"aud"`
+	Sub   string          `json:"sub"`
+	Oid   string          `json:"oid,omitempty"`
+	Exp   int64           `json:"exp"`
+	Nbf   int64           `json:"nbf,omitempty"`
+	Iat   int64           `json:"iat,omitempty"`
+	Roles []string        `json:"roles,omitempty"`
+}
+
+// Verify checks the token signature, issuer, audience and lifetime and
+// returns its principal. The subject is the oid claim when present.
+func (v *JWTVerifier) Verify(token string) (*domain.Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", domain.ErrInvalidToken)
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("%w: unsupported signing algorithm '%s'", domain.ErrInvalidToken, header.Alg)
+	}
+	pub, ok := v.keys[header.Kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown signing key '%s'", domain.ErrInvalidToken, header.Kid)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", domain.ErrInvalidToken)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig); err != nil {
+		return nil, fmt.Errorf("%w: signature verification failed", domain.ErrInvalidToken)
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	now := v.now()
+	switch {
+	case v.issuer != "" && claims.Iss != v.issuer:
+		return nil, fmt.Errorf("%w: issuer '%s' is not trusted", domain.ErrInvalidToken, claims.Iss)
+	case v.audience != "" && !audienceContains(claims.Aud, v.audience):
+		return nil, fmt.Errorf("%w: the token audience does not include '%s'", domain.ErrInvalidToken, v.audience)
+	case claims.Exp == 0 || now.After(time.Unix(claims.Exp, 0).Add(clockSkew)):
+		return nil, fmt.Errorf("%w: the token has expired", domain.ErrInvalidToken)
+	case claims.Nbf != 0 && now.Add(clockSkew).Before(time.Unix(claims.Nbf, 0)):
+		return nil, fmt.Errorf("%w: the token is not valid yet", domain.ErrInvalidToken)
+	}
+
+	p := &domain.Principal{Subject: claims.Sub}
+	if claims.Oid != "" {
+		p.Subject = claims.Oid
+	}
+	for _, r := range claims.Roles {
+		if name, ok := builtInRoleIDs[strings.ToLower(r)]; ok {
+			r = name
+		}
+		p.Roles = append(p.Roles, r)
+	}
+	return p, nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return fmt.Errorf("%w: malformed token", domain.ErrInvalidToken)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: malformed token", domain.ErrInvalidToken)
+	}
+	return nil
+}
+
+// audienceContains accepts the aud claim as a string or an array.
+func audienceContains(raw json.RawMessage, audience string) bool {
+	var single string
+	if json.Unmarshal(raw, &single) == nil {
+		return single == audience
+	}
+	var many []string
+	if json.Unmarshal(raw, &many) == nil {
+		for _, a := range many {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// TokenRequest describes a test token to mint.
+type TokenRequest struct {
+	Issuer   string
+	Audience string
+	Subject  string
+	Roles    []string
+	TTL      time.Duration
+}
+
+// MintJWT signs an RS256 token for local testing.
+func MintJWT(key *rsa.PrivateKey, req TokenRequest) (string, error) {
+	now := time.Now()
+	aud, _ := json.Marshal(req.Audience)
+	header := jwtHeader{Alg: "RS256", Typ: "JWT", Kid: JWKFromKey(key).Kid}
+	claims := jwtClaims{
+		Iss:   req.Issuer,
+		Aud:   aud,
+		Sub:   req.Subject,
+		Oid:   req.Subject,
+		Iat:   now.Unix(),
+		Nbf:   now.Unix(),
+		Exp:   now.Add(req.TTL).Unix(),
+		Roles: req.Roles,
+	}
+	h, err := encodeSegment(header)
+	if err != nil {
+		return "", err
+	}
+	c, err := encodeSegment(claims)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256([]byte(h + "." + c))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return h + "." + c + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func encodeSegment(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
@@ -0,0 +1,97 @@
+package infrastructure
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ai-search-emulator/internal/domain"
+)
+
+func newTestKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return key
+}
+
+func testTokenRequest() TokenRequest {
+	return TokenRequest{
+		Issuer:   DefaultTokenIssuer,
+		Audience: DefaultTokenAudience,
+		Subject:  "user-1",
+		Roles:    []string{"1407120a-92aa-4202-b7e9-c0e197c71c8f", domain.RoleSearchServiceContributor},
+		TTL:      time.Hour,
+	}
+}
+
+func TestJWTVerifier_RoundTrip(t *testing.T) {
+	key := newTestKey(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "jwks.json")
+	data, _ := json.Marshal(JWKS{Keys: []JWK{JWKFromKey(key)}})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	v, err := NewJWTVerifierFromFile(path, DefaultTokenIssuer, DefaultTokenAudience)
+	if err != nil {
+		t.Fatalf("load JWKS: %v", err)
+	}
+
+	token, err := MintJWT(key, testTokenRequest())
+	if err != nil {
+		t.Fatalf("mint: %v", err)
+	}
+	p, err := v.Verify(token)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if p.Subject != "user-1" {
+		t.Errorf("subject = %q", p.Subject)
+	}
+	want := []string{domain.RoleSearchIndexDataReader, domain.RoleSearchServiceContributor}
+	if strings.Join(p.Roles, ",") != strings.Join(want, ",") {
+		t.Errorf("roles = %v, want %v (role IDs mapped to names)", p.Roles, want)
+	}
+}
+
+func TestJWTVerifier_Rejects(t *testing.T) {
+	key := newTestKey(t)
+	v, err := NewJWTVerifier(JWKS{Keys: []JWK{JWKFromKey(key)}}, DefaultTokenIssuer, DefaultTokenAudience)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mint := func(k *rsa.PrivateKey, edit func(*TokenRequest)) string {
+		req := testTokenRequest()
+		edit(&req)
+		token, err := MintJWT(k, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	valid := mint(key, func(*TokenRequest) {})
+	parts := strings.Split(valid, ".")
+
+	tests := map[string]string{
+		"malformed":      "not-a-token",
+		"wrong issuer":   mint(key, func(r *TokenRequest) { r.Issuer = "https://evil.example" }),
+		"wrong audience": mint(key, func(r *TokenRequest) { r.Audience = "https://other.example" }),
+		"expired":        mint(key, func(r *TokenRequest) { r.TTL = -time.Hour }),
+		"unknown key":    mint(newTestKey(t), func(*TokenRequest) {}),
+		"tampered":       parts[0] + "." + parts[1] + "x." + parts[2],
+	}
+	for name, token := range tests {
+		if _, err := v.Verify(token); !errors.Is(err, domain.ErrInvalidToken) {
+			t.Errorf("%s: err = %v, want ErrInvalidToken", name, err)
+		}
+	}
+}
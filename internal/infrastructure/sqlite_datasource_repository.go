@@ -0,0 +1,105 @@
+package infrastructure
+
+import (
+	"ai-search-emulator/internal/domain"
+	"database/sql"
+)
+
+type SQLiteDataSourceRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteDataSourceRepository(db *sql.DB) *SQLiteDataSourceRepository {
+	return &SQLiteDataSourceRepository{db: db}
+}
+
+func (r *SQLiteDataSourceRepository) Create(ds *domain.DataSource) error {
+	_, err := r.db.Exec("INSERT INTO datasources (name, definition, etag) VALUES (?, ?, ?)", ds.Name, ds.Definition, ds.ETag)
+	return err
+}
+
+func (r *SQLiteDataSourceRepository) Update(ds *domain.DataSource) error {
+	result, err := r.db.Exec("UPDATE datasources SET definition = ?, etag = ? WHERE name = ?", ds.Definition, ds.ETag, ds.Name)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrDataSourceNotFound
+	}
+	return nil
+}
+
+func (r *SQLiteDataSourceRepository) UpdateIfMatch(ds *domain.DataSource, etag string) error {
+	result, err := r.db.Exec("UPDATE datasources SET definition = ?, etag = ? WHERE name = ? AND etag = ?", ds.Definition, ds.ETag, ds.Name, etag)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrPreconditionFailed
+	}
+	return nil
+}
+
+func (r *SQLiteDataSourceRepository) FindByName(name string) (*domain.DataSource, error) {
+	var ds domain.DataSource
+	err := r.db.QueryRow("SELECT name, definition, etag FROM datasources WHERE name = ?", name).Scan(&ds.Name, &ds.Definition, &ds.ETag)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrDataSourceNotFound
+	}
+	return &ds, err
+}
+
+func (r *SQLiteDataSourceRepository) List() ([]*domain.DataSource, error) {
+	rows, err := r.db.Query("SELECT name, definition, etag FROM datasources ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*domain.DataSource
+	for rows.Next() {
+		var ds domain.DataSource
+		if err := rows.Scan(&ds.Name, &ds.Definition, &ds.ETag); err != nil {
+			return nil, err
+		}
+		result = append(result, &ds)
+	}
+	return result, rows.Err()
+}
+
+func (r *SQLiteDataSourceRepository) Delete(name string) error {
+	result, err := r.db.Exec("DELETE FROM datasources WHERE name = ?", name)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrDataSourceNotFound
+	}
+	return nil
+}
+
+func (r *SQLiteDataSourceRepository) DeleteIfMatch(name, etag string) error {
+	result, err := r.db.Exec("DELETE FROM datasources WHERE name = ? AND etag = ?", name, etag)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrPreconditionFailed
+	}
+	return nil
+}
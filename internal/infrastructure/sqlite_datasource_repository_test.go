@@ -0,0 +1,65 @@
+package infrastructure
+
+import (
+	"errors"
+	"testing"
+
+	"ai-search-emulator/internal/domain"
+)
+
+func TestSQLiteDataSourceRepository_CRUD(t *testing.T) {
+	t.Parallel()
+	repo := NewSQLiteDataSourceRepository(newTestDB(t))
+
+	if err := repo.Create(&domain.DataSource{Name: "ds", Definition: `{"type":"azureblob"}`, ETag: `"1"`}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := repo.Update(&domain.DataSource{Name: "ds", Definition: `{"type":"azuresql"}`, ETag: `"2"`}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	got, err := repo.FindByName("ds")
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	if got.Definition != `{"type":"azuresql"}` || got.ETag != `"2"` {
+		t.Errorf("got %+v, want the updated definition", got)
+	}
+	list, err := repo.List()
+	if err != nil || len(list) != 1 {
+		t.Fatalf("list = %v, err = %v", list, err)
+	}
+	if err := repo.Delete("ds"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := repo.FindByName("ds"); !errors.Is(err, domain.ErrDataSourceNotFound) {
+		t.Errorf("find after delete: err = %v, want ErrDataSourceNotFound", err)
+	}
+}
+
+func TestSQLiteDataSourceRepository_MissingReturnsNotFound(t *testing.T) {
+	t.Parallel()
+	repo := NewSQLiteDataSourceRepository(newTestDB(t))
+
+	if err := repo.Update(&domain.DataSource{Name: "missing"}); !errors.Is(err, domain.ErrDataSourceNotFound) {
+		t.Errorf("update: err = %v, want ErrDataSourceNotFound", err)
+	}
+	if err := repo.Delete("missing"); !errors.Is(err, domain.ErrDataSourceNotFound) {
+		t.Errorf("delete: err = %v, want ErrDataSourceNotFound", err)
+	}
+}
+
+func TestSQLiteDataSourceRepository_StaleETag(t *testing.T) {
+	t.Parallel()
+	repo := NewSQLiteDataSourceRepository(newTestDB(t))
+	_ = repo.Create(&domain.DataSource{Name: "x", Definition: "{}", ETag: `"1"`})
+
+	if err := repo.UpdateIfMatch(&domain.DataSource{Name: "x", Definition: "{}", ETag: `"2"`}, `"0"`); !errors.Is(err, domain.ErrPreconditionFailed) {
+		t.Errorf("update: err = %v, want ErrPreconditionFailed", err)
+	}
+	if err := repo.DeleteIfMatch("x", `"0"`); !errors.Is(err, domain.ErrPreconditionFailed) {
+		t.Errorf("delete: err = %v, want ErrPreconditionFailed", err)
+	}
+	if err := repo.DeleteIfMatch("x", `"1"`); err != nil {
+		t.Errorf("delete with the current ETag: %v", err)
+	}
+}
@@ -34,6 +34,80 @@ func (r *SQLiteDocumentRepository) Delete(indexName, key string) error {
 	return err
 }
 
+// Batch runs fn inside one SQLite transaction using prepared statements, so a
+// large /docs/index call is both fast and all-or-nothing.
+func (r *SQLiteDocumentRepository) Batch(indexName string, fn func(b domain.DocumentBatch) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	b, err := prepareSQLiteBatch(tx, indexName)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer b.close()
+	if err := fn(b); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+type sqliteBatch struct {
+	indexName string
+	find      *sql.Stmt
+	upsert    *sql.Stmt
+	delete    *sql.Stmt
+}
+
+func prepareSQLiteBatch(tx *sql.Tx, indexName string) (*sqliteBatch, error) {
+	b := &sqliteBatch{indexName: indexName}
+	var err error
+	if b.find, err = tx.Prepare("SELECT content FROM documents WHERE index_name = ? AND key = ?"); err != nil {
+		return nil, err
+	}
+	if b.upsert, err = tx.Prepare("INSERT OR REPLACE INTO documents (index_name, key, content) VALUES (?, ?, ?)"); err != nil {
+		b.close()
+		return nil, err
+	}
+	if b.delete, err = tx.Prepare("DELETE FROM documents WHERE index_name = ? AND key = ?"); err != nil {
+		b.close()
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *sqliteBatch) close() {
+	for _, stmt := range []*sql.Stmt{b.find, b.upsert, b.delete} {
+		if stmt != nil {
+			_ = stmt.Close()
+		}
+	}
+}
+
+func (b *sqliteBatch) Find(key string) (*domain.Document, error) {
+	doc := domain.Document{IndexName: b.indexName, Key: key}
+	err := b.find.QueryRow(b.indexName, key).Scan(&doc.Content)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrDocumentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (b *sqliteBatch) Upsert(key, content string) error {
+	_, err := b.upsert.Exec(b.indexName, key, content)
+	return err
+}
+
+func (b *sqliteBatch) Delete(key string) error {
+	_, err := b.delete.Exec(b.indexName, key)
+	return err
+}
+
 func (r *SQLiteDocumentRepository) List(indexName string) ([]*domain.Document, error) {
 	rows, err := r.db.Query("SELECT index_name, key, content FROM documents WHERE index_name = ?", indexName)
 	if err != nil {
@@ -72,11 +146,13 @@ func (r *SQLiteDocumentRepository) Search(indexName string, opts domain.SearchOp
 	if opts.OrderSQL != "" {
 		mainSQL += " ORDER BY " + opts.OrderSQL
 	}
-	top := opts.Top
-	if top <= 0 {
-		top = 50
+	if !opts.Unpaged {
+		top := opts.Top
+		if top <= 0 {
+			top = 50
+		}
+		mainSQL += fmt.Sprintf(" LIMIT %d OFFSET %d", top, opts.Skip)
 	}
-	mainSQL += fmt.Sprintf(" LIMIT %d OFFSET %d", top, opts.Skip)
 
 	rows, err := r.db.Query(mainSQL, args...)
 	if err != nil {
@@ -343,3 +343,61 @@ func TestSQLiteDocumentRepository_Search_OrderBy(t *testing.T) {
 		t.Errorf("unexpected order: %v", docs)
 	}
 }
+
+func TestSQLiteDocumentRepository_Batch_Commit(t *testing.T) {
+	t.Parallel()
+	db := newTestDB(t)
+	seedTestIndex(t, NewSQLiteIndexRepository(db), "idx")
+	repo := NewSQLiteDocumentRepository(db)
+	_ = repo.Upsert(&domain.Document{IndexName: "idx", Key: "old", Content: `{"id":"old"}`})
+
+	err := repo.Batch("idx", func(b domain.DocumentBatch) error {
+		if _, err := b.Find("missing"); !errors.Is(err, domain.ErrDocumentNotFound) {
+			t.Errorf("Find(missing) err = %v, want ErrDocumentNotFound", err)
+		}
+		for i := 0; i < 3; i++ {
+			key := fmt.Sprintf("k%d", i)
+			if err := b.Upsert(key, `{"id":"`+key+`"}`); err != nil {
+				return err
+			}
+		}
+		doc, err := b.Find("k1")
+		if err != nil || doc.Content != `{"id":"k1"}` {
+			t.Errorf("Find inside batch = %+v, %v", doc, err)
+		}
+		return b.Delete("old")
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n, _ := repo.Count("idx"); n != 3 {
+		t.Errorf("count = %d, want 3", n)
+	}
+	if _, err := repo.Find("idx", "old"); !errors.Is(err, domain.ErrDocumentNotFound) {
+		t.Errorf("old should be deleted, got %v", err)
+	}
+}
+
+func TestSQLiteDocumentRepository_Batch_RollbackOnError(t *testing.T) {
+	t.Parallel()
+	db := newTestDB(t)
+	seedTestIndex(t, NewSQLiteIndexRepository(db), "idx")
+	repo := NewSQLiteDocumentRepository(db)
+	_ = repo.Upsert(&domain.Document{IndexName: "idx", Key: "keep", Content: `{"id":"keep"}`})
+
+	boom := errors.New("boom")
+	err := repo.Batch("idx", func(b domain.DocumentBatch) error {
+		_ = b.Upsert("new", `{"id":"new"}`)
+		_ = b.Delete("keep")
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want boom", err)
+	}
+	if _, err := repo.Find("idx", "keep"); err != nil {
+		t.Errorf("rolled-back delete must not apply: %v", err)
+	}
+	if _, err := repo.Find("idx", "new"); !errors.Is(err, domain.ErrDocumentNotFound) {
+		t.Errorf("rolled-back upsert must not apply, got %v", err)
+	}
+}
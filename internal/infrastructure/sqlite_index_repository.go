@@ -14,12 +14,12 @@ func NewSQLiteIndexRepository(db *sql.DB) *SQLiteIndexRepository {
 }
 
 func (r *SQLiteIndexRepository) Create(index *domain.Index) error {
-	_, err := r.db.Exec("INSERT INTO indexes (name, schema) VALUES (?, ?)", index.Name, index.Schema)
+	_, err := r.db.Exec("INSERT INTO indexes (name, schema, etag) VALUES (?, ?, ?)", index.Name, index.Schema, index.ETag)
 	return err
 }
 
 func (r *SQLiteIndexRepository) Update(index *domain.Index) error {
-	result, err := r.db.Exec("UPDATE indexes SET schema = ? WHERE name = ?", index.Schema, index.Name)
+	result, err := r.db.Exec("UPDATE indexes SET schema = ?, etag = ? WHERE name = ?", index.Schema, index.ETag, index.Name)
 	if err != nil {
 		return err
 	}
@@ -33,6 +33,23 @@ func (r *SQLiteIndexRepository) Update(index *domain.Index) error {
 	return nil
 }
 
+// UpdateIfMatch checks the ETag in the UPDATE itself so that no concurrent
+// write can slip in between the check and the update.
+func (r *SQLiteIndexRepository) UpdateIfMatch(index *domain.Index, etag string) error {
+	result, err := r.db.Exec("UPDATE indexes SET schema = ?, etag = ? WHERE name = ? AND etag = ?", index.Schema, index.ETag, index.Name, etag)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrPreconditionFailed
+	}
+	return nil
+}
+
 func (r *SQLiteIndexRepository) Exists(name string) (bool, error) {
 	var count int
 	err := r.db.QueryRow("SELECT COUNT(*) FROM indexes WHERE name = ?", name).Scan(&count)
@@ -41,7 +58,7 @@ func (r *SQLiteIndexRepository) Exists(name string) (bool, error) {
 
 func (r *SQLiteIndexRepository) FindByName(name string) (*domain.Index, error) {
 	var idx domain.Index
-	err := r.db.QueryRow("SELECT name, schema FROM indexes WHERE name = ?", name).Scan(&idx.Name, &idx.Schema)
+	err := r.db.QueryRow("SELECT name, schema, etag FROM indexes WHERE name = ?", name).Scan(&idx.Name, &idx.Schema, &idx.ETag)
 	if err == sql.ErrNoRows {
 		return nil, domain.ErrIndexNotFound
 	}
@@ -49,7 +66,7 @@ func (r *SQLiteIndexRepository) FindByName(name string) (*domain.Index, error) {
 }
 
 func (r *SQLiteIndexRepository) List() ([]*domain.Index, error) {
-	rows, err := r.db.Query("SELECT name, schema FROM indexes")
+	rows, err := r.db.Query("SELECT name, schema, etag FROM indexes")
 	if err != nil {
 		return nil, err
 	}
@@ -57,7 +74,7 @@ func (r *SQLiteIndexRepository) List() ([]*domain.Index, error) {
 	var result []*domain.Index
 	for rows.Next() {
 		var idx domain.Index
-		if err := rows.Scan(&idx.Name, &idx.Schema); err != nil {
+		if err := rows.Scan(&idx.Name, &idx.Schema, &idx.ETag); err != nil {
 			return nil, err
 		}
 		result = append(result, &idx)
@@ -79,3 +96,18 @@ func (r *SQLiteIndexRepository) Delete(name string) error {
 	}
 	return nil
 }
+
+func (r *SQLiteIndexRepository) DeleteIfMatch(name, etag string) error {
+	result, err := r.db.Exec("DELETE FROM indexes WHERE name = ? AND etag = ?", name, etag)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrPreconditionFailed
+	}
+	return nil
+}
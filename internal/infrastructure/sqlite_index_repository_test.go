@@ -173,3 +173,45 @@ func TestSQLiteIndexRepository_OperationsOnClosedDB(t *testing.T) {
 		t.Errorf("expected error on closed db (Delete)")
 	}
 }
+
+func TestSQLiteIndexRepository_PersistsETag(t *testing.T) {
+	t.Parallel()
+	db := newTestDB(t)
+	repo := NewSQLiteIndexRepository(db)
+	_ = repo.Create(&domain.Index{Name: "idx", Schema: "{}", ETag: `"0x1"`})
+
+	got, _ := repo.FindByName("idx")
+	if got.ETag != `"0x1"` {
+		t.Errorf("etag = %q, want \"0x1\"", got.ETag)
+	}
+	_ = repo.Update(&domain.Index{Name: "idx", Schema: "{}", ETag: `"0x2"`})
+	list, _ := repo.List()
+	if len(list) != 1 || list[0].ETag != `"0x2"` {
+		t.Errorf("etag after update = %+v", list)
+	}
+}
+
+func TestSQLiteIndexRepository_IfMatch(t *testing.T) {
+	t.Parallel()
+	repo := NewSQLiteIndexRepository(newTestDB(t))
+	_ = repo.Create(&domain.Index{Name: "idx", Schema: "old", ETag: `"1"`})
+
+	if err := repo.UpdateIfMatch(&domain.Index{Name: "idx", Schema: "new", ETag: `"2"`}, `"0"`); !errors.Is(err, domain.ErrPreconditionFailed) {
+		t.Errorf("update with a stale ETag: err = %v, want ErrPreconditionFailed", err)
+	}
+	if err := repo.UpdateIfMatch(&domain.Index{Name: "idx", Schema: "new", ETag: `"2"`}, `"1"`); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if got, _ := repo.FindByName("idx"); got.Schema != "new" || got.ETag != `"2"` {
+		t.Errorf("index = %+v", got)
+	}
+	if err := repo.DeleteIfMatch("idx", `"1"`); !errors.Is(err, domain.ErrPreconditionFailed) {
+		t.Errorf("delete with a stale ETag: err = %v, want ErrPreconditionFailed", err)
+	}
+	if err := repo.DeleteIfMatch("idx", `"2"`); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if ok, _ := repo.Exists("idx"); ok {
+		t.Error("index should be deleted")
+	}
+}
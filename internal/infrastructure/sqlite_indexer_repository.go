@@ -0,0 +1,123 @@
+package infrastructure
+
+import (
+	"ai-search-emulator/internal/domain"
+	"database/sql"
+)
+
+type SQLiteIndexerRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteIndexerRepository(db *sql.DB) *SQLiteIndexerRepository {
+	return &SQLiteIndexerRepository{db: db}
+}
+
+func (r *SQLiteIndexerRepository) Create(ix *domain.Indexer) error {
+	_, err := r.db.Exec("INSERT INTO indexers (name, definition, etag) VALUES (?, ?, ?)", ix.Name, ix.Definition, ix.ETag)
+	return err
+}
+
+func (r *SQLiteIndexerRepository) Update(ix *domain.Indexer) error {
+	result, err := r.db.Exec("UPDATE indexers SET definition = ?, etag = ? WHERE name = ?", ix.Definition, ix.ETag, ix.Name)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrIndexerNotFound
+	}
+	return nil
+}
+
+func (r *SQLiteIndexerRepository) UpdateIfMatch(ix *domain.Indexer, etag string) error {
+	result, err := r.db.Exec("UPDATE indexers SET definition = ?, etag = ? WHERE name = ? AND etag = ?", ix.Definition, ix.ETag, ix.Name, etag)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrPreconditionFailed
+	}
+	return nil
+}
+
+func (r *SQLiteIndexerRepository) FindByName(name string) (*domain.Indexer, error) {
+	var ix domain.Indexer
+	err := r.db.QueryRow("SELECT name, definition, etag FROM indexers WHERE name = ?", name).Scan(&ix.Name, &ix.Definition, &ix.ETag)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrIndexerNotFound
+	}
+	return &ix, err
+}
+
+func (r *SQLiteIndexerRepository) List() ([]*domain.Indexer, error) {
+	rows, err := r.db.Query("SELECT name, definition, etag FROM indexers ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*domain.Indexer
+	for rows.Next() {
+		var ix domain.Indexer
+		if err := rows.Scan(&ix.Name, &ix.Definition, &ix.ETag); err != nil {
+			return nil, err
+		}
+		result = append(result, &ix)
+	}
+	return result, rows.Err()
+}
+
+func (r *SQLiteIndexerRepository) Delete(name string) error {
+	return r.delete(domain.ErrIndexerNotFound, "DELETE FROM indexers WHERE name = ?", name)
+}
+
+func (r *SQLiteIndexerRepository) DeleteIfMatch(name, etag string) error {
+	return r.delete(domain.ErrPreconditionFailed, "DELETE FROM indexers WHERE name = ? AND etag = ?", name, etag)
+}
+
+// delete runs a DELETE of one indexer row and removes its status in the same
+// transaction. notFound is returned when the statement deletes nothing.
+func (r *SQLiteIndexerRepository) delete(notFound error, query string, name string, args ...interface{}) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	result, err := tx.Exec(query, append([]interface{}{name}, args...)...)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return notFound
+	}
+	if _, err := tx.Exec("DELETE FROM indexer_status WHERE name = ?", name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SQLiteIndexerRepository) FindStatus(name string) (string, error) {
+	var status string
+	err := r.db.QueryRow("SELECT status FROM indexer_status WHERE name = ?", name).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return status, err
+}
+
+func (r *SQLiteIndexerRepository) SaveStatus(name string, status string) error {
+	_, err := r.db.Exec(`INSERT INTO indexer_status (name, status) VALUES (?, ?)
+		ON CONFLICT(name) DO UPDATE SET status = excluded.status`, name, status)
+	return err
+}
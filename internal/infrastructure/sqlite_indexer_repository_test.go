@@ -0,0 +1,81 @@
+package infrastructure
+
+import (
+	"errors"
+	"testing"
+
+	"ai-search-emulator/internal/domain"
+)
+
+func TestSQLiteIndexerRepository_CRUD(t *testing.T) {
+	t.Parallel()
+	repo := NewSQLiteIndexerRepository(newTestDB(t))
+
+	if err := repo.Create(&domain.Indexer{Name: "ix", Definition: `{"a":1}`, ETag: `"1"`}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := repo.Update(&domain.Indexer{Name: "ix", Definition: `{"a":2}`, ETag: `"2"`}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	got, err := repo.FindByName("ix")
+	if err != nil || got.Definition != `{"a":2}` || got.ETag != `"2"` {
+		t.Fatalf("find = %+v, err = %v", got, err)
+	}
+	if list, err := repo.List(); err != nil || len(list) != 1 {
+		t.Fatalf("list = %v, err = %v", list, err)
+	}
+	if err := repo.Update(&domain.Indexer{Name: "missing"}); !errors.Is(err, domain.ErrIndexerNotFound) {
+		t.Errorf("update missing: err = %v, want ErrIndexerNotFound", err)
+	}
+}
+
+func TestSQLiteIndexerRepository_Status(t *testing.T) {
+	t.Parallel()
+	repo := NewSQLiteIndexerRepository(newTestDB(t))
+	_ = repo.Create(&domain.Indexer{Name: "ix", Definition: "{}"})
+
+	if status, err := repo.FindStatus("ix"); err != nil || status != "" {
+		t.Fatalf("status before any run = %q, err = %v", status, err)
+	}
+	_ = repo.SaveStatus("ix", `{"v":1}`)
+	_ = repo.SaveStatus("ix", `{"v":2}`)
+	if status, _ := repo.FindStatus("ix"); status != `{"v":2}` {
+		t.Errorf("status = %q, want the last saved", status)
+	}
+
+	if err := repo.Delete("ix"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if status, _ := repo.FindStatus("ix"); status != "" {
+		t.Errorf("status after delete = %q, want empty", status)
+	}
+	if err := repo.Delete("ix"); !errors.Is(err, domain.ErrIndexerNotFound) {
+		t.Errorf("second delete: err = %v, want ErrIndexerNotFound", err)
+	}
+}
+
+func TestSQLiteIndexerRepository_IfMatch(t *testing.T) {
+	t.Parallel()
+	repo := NewSQLiteIndexerRepository(newTestDB(t))
+	_ = repo.Create(&domain.Indexer{Name: "ix", Definition: "{}", ETag: `"1"`})
+	_ = repo.SaveStatus("ix", `{"v":1}`)
+
+	if err := repo.UpdateIfMatch(&domain.Indexer{Name: "ix", Definition: `{"a":1}`, ETag: `"2"`}, `"0"`); !errors.Is(err, domain.ErrPreconditionFailed) {
+		t.Errorf("update with a stale ETag: err = %v, want ErrPreconditionFailed", err)
+	}
+	if err := repo.UpdateIfMatch(&domain.Indexer{Name: "ix", Definition: `{"a":1}`, ETag: `"2"`}, `"1"`); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if err := repo.DeleteIfMatch("ix", `"1"`); !errors.Is(err, domain.ErrPreconditionFailed) {
+		t.Errorf("delete with a stale ETag: err = %v, want ErrPreconditionFailed", err)
+	}
+	if status, _ := repo.FindStatus("ix"); status == "" {
+		t.Error("a failed conditional delete should keep the status")
+	}
+	if err := repo.DeleteIfMatch("ix", `"2"`); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if status, _ := repo.FindStatus("ix"); status != "" {
+		t.Errorf("status after delete = %q, want empty", status)
+	}
+}
@@ -0,0 +1,105 @@
+package infrastructure
+
+import (
+	"ai-search-emulator/internal/domain"
+	"database/sql"
+)
+
+type SQLiteSkillsetRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteSkillsetRepository(db *sql.DB) *SQLiteSkillsetRepository {
+	return &SQLiteSkillsetRepository{db: db}
+}
+
+func (r *SQLiteSkillsetRepository) Create(ss *domain.Skillset) error {
+	_, err := r.db.Exec("INSERT INTO skillsets (name, definition, etag) VALUES (?, ?, ?)", ss.Name, ss.Definition, ss.ETag)
+	return err
+}
+
+func (r *SQLiteSkillsetRepository) Update(ss *domain.Skillset) error {
+	result, err := r.db.Exec("UPDATE skillsets SET definition = ?, etag = ? WHERE name = ?", ss.Definition, ss.ETag, ss.Name)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrSkillsetNotFound
+	}
+	return nil
+}
+
+func (r *SQLiteSkillsetRepository) UpdateIfMatch(ss *domain.Skillset, etag string) error {
+	result, err := r.db.Exec("UPDATE skillsets SET definition = ?, etag = ? WHERE name = ? AND etag = ?", ss.Definition, ss.ETag, ss.Name, etag)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrPreconditionFailed
+	}
+	return nil
+}
+
+func (r *SQLiteSkillsetRepository) FindByName(name string) (*domain.Skillset, error) {
+	var ss domain.Skillset
+	err := r.db.QueryRow("SELECT name, definition, etag FROM skillsets WHERE name = ?", name).Scan(&ss.Name, &ss.Definition, &ss.ETag)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrSkillsetNotFound
+	}
+	return &ss, err
+}
+
+func (r *SQLiteSkillsetRepository) List() ([]*domain.Skillset, error) {
+	rows, err := r.db.Query("SELECT name, definition, etag FROM skillsets ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*domain.Skillset
+	for rows.Next() {
+		var ss domain.Skillset
+		if err := rows.Scan(&ss.Name, &ss.Definition, &ss.ETag); err != nil {
+			return nil, err
+		}
+		result = append(result, &ss)
+	}
+	return result, rows.Err()
+}
+
+func (r *SQLiteSkillsetRepository) Delete(name string) error {
+	result, err := r.db.Exec("DELETE FROM skillsets WHERE name = ?", name)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrSkillsetNotFound
+	}
+	return nil
+}
+
+func (r *SQLiteSkillsetRepository) DeleteIfMatch(name, etag string) error {
+	result, err := r.db.Exec("DELETE FROM skillsets WHERE name = ? AND etag = ?", name, etag)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrPreconditionFailed
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package infrastructure
+
+import (
+	"errors"
+	"testing"
+
+	"ai-search-emulator/internal/domain"
+)
+
+func TestSQLiteSkillsetRepository_CRUD(t *testing.T) {
+	t.Parallel()
+	repo := NewSQLiteSkillsetRepository(newTestDB(t))
+
+	if err := repo.Create(&domain.Skillset{Name: "ss", Definition: `{"skills":[]}`, ETag: `"1"`}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := repo.Update(&domain.Skillset{Name: "ss", Definition: `{"skills":[{}]}`, ETag: `"2"`}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	got, err := repo.FindByName("ss")
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	if got.Definition != `{"skills":[{}]}` || got.ETag != `"2"` {
+		t.Errorf("got %+v, want the updated definition", got)
+	}
+	list, err := repo.List()
+	if err != nil || len(list) != 1 {
+		t.Fatalf("list = %v, err = %v", list, err)
+	}
+	if err := repo.Delete("ss"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := repo.FindByName("ss"); !errors.Is(err, domain.ErrSkillsetNotFound) {
+		t.Errorf("find after delete: err = %v, want ErrSkillsetNotFound", err)
+	}
+}
+
+func TestSQLiteSkillsetRepository_MissingReturnsNotFound(t *testing.T) {
+	t.Parallel()
+	repo := NewSQLiteSkillsetRepository(newTestDB(t))
+
+	if err := repo.Update(&domain.Skillset{Name: "missing"}); !errors.Is(err, domain.ErrSkillsetNotFound) {
+		t.Errorf("update: err = %v, want ErrSkillsetNotFound", err)
+	}
+	if err := repo.Delete("missing"); !errors.Is(err, domain.ErrSkillsetNotFound) {
+		t.Errorf("delete: err = %v, want ErrSkillsetNotFound", err)
+	}
+}
+
+func TestSQLiteSkillsetRepository_StaleETag(t *testing.T) {
+	t.Parallel()
+	repo := NewSQLiteSkillsetRepository(newTestDB(t))
+	_ = repo.Create(&domain.Skillset{Name: "x", Definition: "{}", ETag: `"1"`})
+
+	if err := repo.UpdateIfMatch(&domain.Skillset{Name: "x", Definition: "{}", ETag: `"2"`}, `"0"`); !errors.Is(err, domain.ErrPreconditionFailed) {
+		t.Errorf("update: err = %v, want ErrPreconditionFailed", err)
+	}
+	if err := repo.DeleteIfMatch("x", `"0"`); !errors.Is(err, domain.ErrPreconditionFailed) {
+		t.Errorf("delete: err = %v, want ErrPreconditionFailed", err)
+	}
+	if err := repo.DeleteIfMatch("x", `"1"`); err != nil {
+		t.Errorf("delete with the current ETag: %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ai-search-emulator/internal/domain"
+)
+
+// SQLiteTableConnector serves azuresql data sources from local SQLite
+// databases. container.name is the table or view, optionally prefixed with a
+// schema ("dbo.Hotels").
+//
+// The connection string may name the database file with
+// "Data Source=<file>". SQL Server style strings (Server and Database or
+// Initial Catalog, with User ID and Password, Authentication or ResourceId)
+// resolve to <Root>/<database>.db.
+type SQLiteTableConnector struct {
+	Root string
+}
+
+func NewSQLiteTableConnector(root string) *SQLiteTableConnector {
+	return &SQLiteTableConnector{Root: root}
+}
+
+func (c *SQLiteTableConnector) databasePath(connectionString string) (string, error) {
+	cs := parseConnectionString(connectionString)
+	database := cs["database"]
+	if database == "" {
+		database = cs["initial catalog"]
+	}
+	if p := cs["data source"]; p != "" && cs["server"] == "" && database == "" {
+		return p, nil
+	}
+	if cs["server"] == "" && cs["data source"] == "" || database == "" {
+		return "", fmt.Errorf("%w: the connection string must specify Data Source=<file>, or Server and Database", domain.ErrInvalidDataSource)
+	}
+	authenticated := cs["user id"] != "" && cs["password"] != "" ||
+		cs["authentication"] != "" || cs["resourceid"] != "" ||
+		strings.EqualFold(cs["integrated security"], "true")
+	if !authenticated {
+		return "", fmt.Errorf("%w: the connection string must include User ID and Password, Authentication or ResourceId", domain.ErrInvalidDataSource)
+	}
+	if strings.ContainsAny(database, `/\`) || strings.Contains(database, "..") {
+		return "", fmt.Errorf("%w: invalid database name '%s'", domain.ErrInvalidDataSource, database)
+	}
+	return filepath.Join(c.Root, database+".db"), nil
+}
+
+func (c *SQLiteTableConnector) ValidateCredentials(cfg domain.DataSourceConfig) error {
+	_, err := c.databasePath(cfg.ConnectionString)
+	return err
+}
+
+// Read returns every row of the table. The item key is the primary key
+// column, or the rowid when the table has none.
+func (c *SQLiteTableConnector) Read(ctx context.Context, cfg domain.DataSourceConfig) ([]domain.SourceItem, error) {
+	path, err := c.databasePath(cfg.ConnectionString)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("%w: the database '%s' does not exist", domain.ErrInvalidDataSource, path)
+	}
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	table := cfg.Container
+	if i := strings.LastIndex(table, "."); i >= 0 {
+		table = table[i+1:]
+	}
+	table = strings.Trim(table, "[]")
+	keyColumn, err := primaryKeyColumn(ctx, db, table)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without a primary key the rowid identifies rows; it is selected first.
+	query, offset := "SELECT * FROM "+quoteIdent(table), 0
+	if keyColumn == "" {
+		query, offset = "SELECT rowid, * FROM "+quoteIdent(table), 1
+	}
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot read table '%s': %v", domain.ErrInvalidDataSource, cfg.Container, err)
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var items []domain.SourceItem
+	for rows.Next() {
+		values := make([]interface{}, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		fields := make(map[string]interface{}, len(cols)-offset)
+		for i := offset; i < len(cols); i++ {
+			v := values[i]
+			if b, ok := v.([]byte); ok {
+				v = string(b)
+			}
+			fields[cols[i]] = v
+		}
+		var key string
+		if keyColumn != "" {
+			key = fmt.Sprint(fields[keyColumn])
+		} else {
+			key = fmt.Sprint(values[0])
+		}
+		items = append(items, domain.SourceItem{Key: key, Fields: fields})
+	}
+	return items, rows.Err()
+}
+
+// primaryKeyColumn returns the single-column primary key of a table, or ""
+// if it has none. A missing table is reported as an invalid data source.
+func primaryKeyColumn(ctx context.Context, db *sql.DB, table string) (string, error) {
+	rows, err := db.QueryContext(ctx, "SELECT name, pk FROM pragma_table_info(?)", table)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	found, key, keys := false, "", 0
+	for rows.Next() {
+		var name string
+		var pk int
+		if err := rows.Scan(&name, &pk); err != nil {
+			return "", err
+		}
+		found = true
+		if pk > 0 {
+			key = name
+			keys++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("%w: the table or view '%s' does not exist", domain.ErrInvalidDataSource, table)
+	}
+	if keys != 1 {
+		return "", nil
+	}
+	return key, nil
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,94 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"ai-search-emulator/internal/domain"
+)
+
+// newSourceDB creates a SQLite database file at path and runs stmts in it.
+func newSourceDB(t *testing.T, path string, stmts string) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(stmts); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLiteTableConnector_Read_PrimaryKey(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	newSourceDB(t, filepath.Join(root, "hotels.db"), `
+CREATE TABLE Hotels (HotelId TEXT PRIMARY KEY, Name TEXT, Rating REAL);
+INSERT INTO Hotels VALUES ('1', 'Alpha', 4.5), ('2', 'Beta', 3.0);`)
+	conn := NewSQLiteTableConnector(root)
+	cfg := domain.DataSourceConfig{
+		Type:             "azuresql",
+		ConnectionString: "Server=tcp:local;Database=hotels;User ID=sa;Password=p",
+		Container:        "dbo.Hotels",
+	}
+
+	items, err := conn.Read(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items))
+	}
+	if items[0].Key != "1" || items[0].Fields["Name"] != "Alpha" || items[0].Fields["Rating"] != 4.5 {
+		t.Errorf("first item = %+v", items[0])
+	}
+	if _, ok := items[0].Fields["rowid"]; ok {
+		t.Errorf("rowid should not be returned when the table has a primary key")
+	}
+}
+
+func TestSQLiteTableConnector_Read_RowID(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "src.db")
+	newSourceDB(t, path, `
+CREATE TABLE notes (body TEXT);
+INSERT INTO notes VALUES ('a'), ('b');`)
+	conn := NewSQLiteTableConnector(t.TempDir())
+
+	items, err := conn.Read(context.Background(), domain.DataSourceConfig{ConnectionString: "Data Source=" + path, Container: "notes"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 2 || items[0].Key != "1" || items[1].Key != "2" {
+		t.Fatalf("items = %+v, want rowid keys 1 and 2", items)
+	}
+	if len(items[1].Fields) != 1 || items[1].Fields["body"] != "b" {
+		t.Errorf("fields = %v, want only body", items[1].Fields)
+	}
+}
+
+func TestSQLiteTableConnector_Errors(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	newSourceDB(t, filepath.Join(root, "db.db"), `CREATE TABLE t (id INTEGER PRIMARY KEY);`)
+	conn := NewSQLiteTableConnector(root)
+	cases := []struct {
+		name string
+		cfg  domain.DataSourceConfig
+	}{
+		{"no database", domain.DataSourceConfig{ConnectionString: "Server=x", Container: "t"}},
+		{"no auth", domain.DataSourceConfig{ConnectionString: "Server=x;Database=db", Container: "t"}},
+		{"database escapes", domain.DataSourceConfig{ConnectionString: "Server=x;Database=../db;Authentication=Active Directory Default", Container: "t"}},
+		{"missing database", domain.DataSourceConfig{ConnectionString: "Server=x;Database=other;User ID=u;Password=p", Container: "t"}},
+		{"missing table", domain.DataSourceConfig{ConnectionString: "Server=x;Initial Catalog=db;User ID=u;Password=p", Container: "missing"}},
+	}
+	for _, tc := range cases {
+		if _, err := conn.Read(context.Background(), tc.cfg); !errors.Is(err, domain.ErrInvalidDataSource) {
+			t.Errorf("%s: err = %v, want ErrInvalidDataSource", tc.name, err)
+		}
+	}
+}
@@ -12,7 +12,8 @@ import (
 const schemaSQL = `
 CREATE TABLE IF NOT EXISTS indexes (
     name TEXT PRIMARY KEY,
-    schema TEXT NOT NULL
+    schema TEXT NOT NULL,
+    etag TEXT NOT NULL DEFAULT ''
 );
 CREATE TABLE IF NOT EXISTS documents (
     index_name TEXT NOT NULL,
@@ -20,6 +21,25 @@ CREATE TABLE IF NOT EXISTS documents (
     content TEXT NOT NULL,
     PRIMARY KEY (index_name, key),
     FOREIGN KEY (index_name) REFERENCES indexes(name) ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS datasources (
+    name TEXT PRIMARY KEY,
+    definition TEXT NOT NULL,
+    etag TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS indexers (
+    name TEXT PRIMARY KEY,
+    definition TEXT NOT NULL,
+    etag TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS indexer_status (
+    name TEXT PRIMARY KEY,
+    status TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS skillsets (
+    name TEXT PRIMARY KEY,
+    definition TEXT NOT NULL,
+    etag TEXT NOT NULL DEFAULT ''
 );`
 
 // newTestDB returns a fresh in-memory SQLite database with the production
@@ -1,11 +1,17 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"database/sql"
+	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -13,6 +19,7 @@ import (
 
 	"ai-search-emulator/internal/api"
 	"ai-search-emulator/internal/application"
+	"ai-search-emulator/internal/domain"
 	"ai-search-emulator/internal/infrastructure"
 )
 
@@ -23,6 +30,31 @@ func dbPath() string {
 	return "./data.db"
 }
 
+// dataSourceRoot is the directory that Azure-style data source connection
+// strings resolve to: <root>/<storage account> for blobs and
+// <root>/<database>.db for SQL.
+func dataSourceRoot() string {
+	if p := os.Getenv("DATASOURCE_ROOT"); p != "" {
+		return p
+	}
+	return "./datasources"
+}
+
+// indexerTimeAcceleration is the factor by which the indexer scheduler's
+// clock runs faster than real time, for testing schedules. The clock cannot
+// be slowed down, so factors below 1 are rejected.
+func indexerTimeAcceleration() float64 {
+	v := os.Getenv("INDEXER_TIME_ACCELERATION")
+	if v == "" {
+		return 1
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 1 || math.IsInf(f, 0) {
+		log.Fatalf("invalid INDEXER_TIME_ACCELERATION %q: expected a number of at least 1", v)
+	}
+	return f
+}
+
 func setupDB() *sql.DB {
 	db, err := sql.Open("sqlite3", dbPath())
 	if err != nil {
@@ -32,7 +64,8 @@ func setupDB() *sql.DB {
 	_, err = db.Exec(`
 	CREATE TABLE IF NOT EXISTS indexes (
 		name TEXT PRIMARY KEY,
-		schema TEXT NOT NULL
+		schema TEXT NOT NULL,
+		etag TEXT NOT NULL DEFAULT ''
 	);
 	CREATE TABLE IF NOT EXISTS documents (
 		index_name TEXT NOT NULL,
@@ -41,13 +74,93 @@ func setupDB() *sql.DB {
 		PRIMARY KEY (index_name, key),
 		FOREIGN KEY (index_name) REFERENCES indexes(name) ON DELETE CASCADE
 	);
+	CREATE TABLE IF NOT EXISTS datasources (
+		name TEXT PRIMARY KEY,
+		definition TEXT NOT NULL,
+		etag TEXT NOT NULL DEFAULT ''
+	);
+	CREATE TABLE IF NOT EXISTS indexers (
+		name TEXT PRIMARY KEY,
+		definition TEXT NOT NULL,
+		etag TEXT NOT NULL DEFAULT ''
+	);
+	CREATE TABLE IF NOT EXISTS indexer_status (
+		name TEXT PRIMARY KEY,
+		status TEXT NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS skillsets (
+		name TEXT PRIMARY KEY,
+		definition TEXT NOT NULL,
+		etag TEXT NOT NULL DEFAULT ''
+	);
 	`)
 	if err != nil {
 		log.Fatal("failed to create tables: ", err)
 	}
+	// 既存DBへのカラム追加マイグレーション
+	if err := addColumnIfMissing(db, "indexes", "etag", `TEXT NOT NULL DEFAULT '"0x1"'`); err != nil {
+		log.Fatal("failed to migrate tables: ", err)
+	}
 	return db
 }
 
+// addColumnIfMissing adds a column to a table created by an older version of
+// the emulator.
+func addColumnIfMissing(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+// setupTokenVerifier enables Microsoft Entra ID bearer tokens when
+// ENTRA_JWKS_FILE points at a local JSON Web Key Set.
+func setupTokenVerifier() domain.TokenVerifier {
+	path := os.Getenv("ENTRA_JWKS_FILE")
+	if path == "" {
+		return nil
+	}
+	issuer := os.Getenv("ENTRA_ISSUER")
+	if issuer == "" {
+		issuer = infrastructure.DefaultTokenIssuer
+	}
+	audience := os.Getenv("ENTRA_AUDIENCE")
+	if audience == "" {
+		audience = infrastructure.DefaultTokenAudience
+	}
+	verifier, err := infrastructure.NewJWTVerifierFromFile(path, issuer, audience)
+	if err != nil {
+		log.Fatal("failed to load JWKS: ", err)
+	}
+	return verifier
+}
+
+// splitList splits a comma-separated environment variable.
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
 func main() {
 	_ = godotenv.Load()
 
@@ -57,16 +170,47 @@ func main() {
 	// リポジトリ実装
 	indexRepo := infrastructure.NewSQLiteIndexRepository(db)
 	docRepo := infrastructure.NewSQLiteDocumentRepository(db)
+	dataSourceRepo := infrastructure.NewSQLiteDataSourceRepository(db)
+	indexerRepo := infrastructure.NewSQLiteIndexerRepository(db)
+	skillsetRepo := infrastructure.NewSQLiteSkillsetRepository(db)
+
+	// データソースコネクタ（ローカルディレクトリ / SQLite）
+	sourceRoot := dataSourceRoot()
+	blobConnector := infrastructure.NewBlobDirectoryConnector(sourceRoot)
+	connectors := map[string]domain.SourceConnector{
+		"azureblob": blobConnector,
+		"adlsgen2":  blobConnector,
+		"azuresql":  infrastructure.NewSQLiteTableConnector(sourceRoot),
+	}
 
 	// サービス層
+	availability := application.NewIndexAvailability(application.DefaultIndexDowntime)
 	appServices := &application.AppServices{
-		IndexService:    application.NewIndexService(indexRepo, docRepo),
-		DocumentService: application.NewDocumentService(docRepo, indexRepo),
+		IndexService:      application.NewIndexService(indexRepo, docRepo),
+		DocumentService:   application.NewDocumentService(docRepo, indexRepo),
+		KeyService:        application.NewKeyService(os.Getenv("API_KEY"), os.Getenv("API_KEY_SECONDARY"), splitList(os.Getenv("QUERY_KEYS"))),
+		DataSourceService: application.NewDataSourceService(dataSourceRepo, connectors),
+		SkillsetService:   application.NewSkillsetService(skillsetRepo),
+	}
+	appServices.IndexService.Availability = availability
+	appServices.DocumentService.Availability = availability
+	appServices.IndexerService = application.NewIndexerService(indexerRepo, appServices.DataSourceService, appServices.DocumentService)
+	appServices.IndexerService.Skillsets = appServices.SkillsetService
+
+	// インデクサースケジューラ（加速時はポーリング間隔も短縮）
+	acceleration := indexerTimeAcceleration()
+	appServices.IndexerService.Clock = application.NewAcceleratedClock(acceleration)
+	poll := time.Duration(float64(time.Second) / acceleration)
+	if poll < 10*time.Millisecond {
+		poll = 10 * time.Millisecond
 	}
+	go application.NewIndexerScheduler(appServices.IndexerService, poll).Run(context.Background())
 
 	r := gin.Default()
 	api.RegisterHealthCheck(r)
-	r.Use(api.ApiKeyAuthMiddleware())
+	r.Use(api.AuthMiddleware(appServices.KeyService, setupTokenVerifier()))
+	r.Use(api.APIVersionMiddleware())
+	r.Use(api.ContentNegotiationMiddleware())
 	api.RegisterRoutes(r, appServices)
 
 	port := os.Getenv("PORT")